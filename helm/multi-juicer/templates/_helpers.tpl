@@ -57,4 +57,15 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- else -}}
 {{- printf "%s" .Values.balancer.cookie.name -}}
 {{- end -}}
-{{- end -}}
\ No newline at end of file
+{{- end -}}
+
+{{/*
+Api keys of the progress watchdog in the `name:role:key` format expected by the watchdog
+*/}}
+{{- define "multi-juicer.progressWatchdog.apiKeys" -}}
+{{- $keys := list -}}
+{{- range .Values.progressWatchdog.apiKeys -}}
+{{- $keys = append $keys (printf "%s:%s:%s" .name .role .key) -}}
+{{- end -}}
+{{- join "," $keys -}}
+{{- end -}}
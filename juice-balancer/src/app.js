@@ -7,6 +7,7 @@ const basicAuth = require('basic-auth-connect');
 const onFinished = require('on-finished');
 
 const { get } = require('./config');
+const { keepRawBody } = require('./signature');
 
 const app = express();
 
@@ -47,9 +48,10 @@ if (get('metrics.enabled')) {
 const teamRoutes = require('./teams/teams');
 const adminRoutes = require('./admin/admin');
 const proxyRoutes = require('./proxy/proxy');
+const progressRoutes = require('./progress/progress');
 
 app.use(cookieParser(get('cookieParser.secret')));
-app.use('/balancer', express.json());
+app.use('/balancer', express.json({ verify: keepRawBody }));
 app.use((req, res, next) => {
   const teamname =
     process.env['NODE_ENV'] === 'test'
@@ -85,6 +87,7 @@ app.get('/balancer/admin', (req, res) => {
   res.sendFile(indexFile);
 });
 app.use('/balancer/admin', adminRoutes);
+app.use('/balancer/progress', progressRoutes);
 
 app.use(proxyRoutes);
 
@@ -69,6 +69,17 @@ const createDeploymentForTeam = async ({ team, passcodeHash }) => {
                   name: 'CTF_KEY',
                   value: get('juiceShop.ctfKey'),
                 },
+                // lets the progress watchdog cache the progress right after every solve
+                ...(get('juiceShop.solveWebhookUrl', null)
+                  ? [
+                      {
+                        name: 'SOLUTIONS_WEBHOOK',
+                        value: `${get('juiceShop.solveWebhookUrl')}/${team}?namespace=${get(
+                          'namespace'
+                        )}`,
+                      },
+                    ]
+                  : []),
                 ...get('juiceShop.env', []),
               ],
               envFrom: get('juiceShop.envFrom'),
@@ -0,0 +1,103 @@
+const express = require('express');
+const http = require('http');
+const promClient = require('prom-client');
+
+const { get } = require('../config');
+const { logger } = require('../logger');
+const { signatureHeaders, requireSignature } = require('../signature');
+
+const router = express.Router();
+
+const watchdogEventCounter = new promClient.Counter({
+  name: 'multijuicer_watchdog_events',
+  help: 'Number of events received from the progress watchdog, see label "type".',
+  labelNames: ['type'],
+});
+
+/**
+ * Fetches the progress of a team from the progress api of the watchdog,
+ * signed with the shared secret.
+ * Resolves to null if the watchdog doesn't know the team.
+ */
+function fetchTeamProgress(team) {
+  const requestUri = `/api/progress/${encodeURIComponent(team)}`;
+  const url = new URL(requestUri, get('progressWatchdog.url'));
+  const headers = signatureHeaders({
+    secret: get('progressWatchdog.signingSecret'),
+    method: 'GET',
+    requestUri,
+  });
+
+  return new Promise((resolve, reject) => {
+    const req = http.get(url, { headers, timeout: 5000 }, (res) => {
+      let body = '';
+      res.setEncoding('utf8');
+      res.on('data', (chunk) => (body += chunk));
+      res.on('end', () => {
+        if (res.statusCode === 404) {
+          return resolve(null);
+        }
+        if (res.statusCode !== 200) {
+          return reject(new Error(`Watchdog responded with status ${res.statusCode}`));
+        }
+        try {
+          resolve(JSON.parse(body));
+        } catch (error) {
+          reject(error);
+        }
+      });
+    });
+    req.on('timeout', () => req.destroy(new Error('Request to the watchdog timed out')));
+    req.on('error', reject);
+  });
+}
+
+/**
+ * @param {import("express").Request} req
+ * @param {import("express").Response} res
+ */
+async function getProgress(req, res) {
+  if (!req.cleanedTeamname) {
+    return res.status(401).send();
+  }
+  if (!get('progressWatchdog.url', null) || !get('progressWatchdog.signingSecret', null)) {
+    return res.status(404).send();
+  }
+
+  try {
+    const progress = await fetchTeamProgress(req.cleanedTeamname);
+    if (progress === null) {
+      return res.status(404).send();
+    }
+    return res.json({
+      team: progress.team,
+      challengesSolved: progress.challengesSolved,
+      solves: progress.solves,
+    });
+  } catch (error) {
+    logger.error(`Failed to fetch progress of team '${req.cleanedTeamname}' from the watchdog`);
+    logger.error(error.message);
+    return res.status(502).send({ message: 'Failed to fetch progress' });
+  }
+}
+
+/**
+ * Receives the hook events of the watchdog, e.g. solves and deleted JuiceShops
+ * @param {import("express").Request} req
+ * @param {import("express").Response} res
+ */
+function receiveEvent(req, res) {
+  const { type, team } = req.body || {};
+  if (!type) {
+    return res.status(400).send();
+  }
+  logger.info(`Received '${type}' event of team '${team}' from the progress watchdog`);
+  watchdogEventCounter.labels(type).inc();
+  return res.status(204).send();
+}
+
+router.get('/', getProgress);
+router.post('/events', requireSignature, receiveEvent);
+
+module.exports = router;
+module.exports.fetchTeamProgress = fetchTeamProgress;
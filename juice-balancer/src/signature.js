@@ -0,0 +1,96 @@
+const crypto = require('crypto');
+
+const { get } = require('./config');
+const { logger } = require('./logger');
+
+// Same scheme as the progress watchdog, see progress-watchdog/signature.go
+const signatureHeader = 'x-multijuicer-signature';
+const timestampHeader = 'x-multijuicer-timestamp';
+
+// limits how long a signed request can be replayed
+const maxSignatureAgeSeconds = 5 * 60;
+
+const computeSignature = ({ secret, timestamp, method, requestUri, body }) => {
+  return crypto
+    .createHmac('sha256', secret)
+    .update(`${timestamp}\n${method}\n${requestUri}\n`)
+    .update(body)
+    .digest('hex');
+};
+
+/**
+ * Returns the headers signing a request to another MultiJuicer component with the shared secret
+ */
+const signatureHeaders = ({ secret, method, requestUri, body = '', now = Date.now() }) => {
+  const timestamp = Math.floor(now / 1000).toString();
+  return {
+    [timestampHeader]: timestamp,
+    [signatureHeader]: computeSignature({ secret, timestamp, method, requestUri, body }),
+  };
+};
+
+/**
+ * Checks that the request was signed with the shared secret within the allowed time window.
+ * Requires the raw body of the request, see keepRawBody.
+ */
+const verifySignature = (req, secret, now = Date.now()) => {
+  const timestamp = req.get(timestampHeader);
+  const signature = req.get(signatureHeader);
+  if (!timestamp || !signature) {
+    throw new Error(`Request is missing the '${signatureHeader}' or '${timestampHeader}' header`);
+  }
+
+  const seconds = Number(timestamp);
+  if (!/^\d+$/.test(timestamp) || Math.abs(now / 1000 - seconds) > maxSignatureAgeSeconds) {
+    throw new Error(
+      `Signature timestamp '${timestamp}' is invalid or outside of the allowed window`
+    );
+  }
+
+  const expected = computeSignature({
+    secret,
+    timestamp,
+    method: req.method,
+    requestUri: req.originalUrl,
+    body: req.rawBody || '',
+  });
+  if (
+    expected.length !== signature.length ||
+    !crypto.timingSafeEqual(Buffer.from(expected), Buffer.from(signature))
+  ) {
+    throw new Error('Signature does not match');
+  }
+};
+
+/**
+ * Keeps the raw body of json requests for the signature verification,
+ * passed as `verify` option to express.json
+ */
+const keepRawBody = (req, res, buffer) => {
+  req.rawBody = buffer;
+};
+
+/**
+ * Only lets requests signed by the progress watchdog through
+ */
+const requireSignature = (req, res, next) => {
+  const secret = get('progressWatchdog.signingSecret', null);
+  if (!secret) {
+    logger.warn('Rejecting signed request, no signing secret is configured');
+    return res.status(401).send();
+  }
+  try {
+    verifySignature(req, secret);
+  } catch (error) {
+    logger.warn(`Rejecting request with invalid signature: ${error.message}`);
+    return res.status(401).send();
+  }
+  return next();
+};
+
+module.exports = {
+  signatureHeaders,
+  verifySignature,
+  keepRawBody,
+  requireSignature,
+};
@@ -0,0 +1,80 @@
+jest.mock('./kubernetes');
+
+const request = require('supertest');
+const app = require('./app');
+const { signatureHeaders, verifySignature } = require('./signature');
+
+const secret = 'shared-secret';
+
+// set before the memoized config is read for the first time
+process.env['PROGRESSWATCHDOG_SIGNINGSECRET'] = secret;
+
+const eventsUri = '/balancer/progress/events';
+
+const requestOf = (headers, rawBody) => ({
+  method: 'POST',
+  originalUrl: eventsUri,
+  rawBody,
+  get: (name) => headers[name.toLowerCase()],
+});
+
+test('signatures match the ones computed by the progress watchdog', () => {
+  // computed with computeSignature of progress-watchdog/signature.go
+  expect(
+    signatureHeaders({
+      secret,
+      method: 'GET',
+      requestUri: '/api/progress/team-a',
+      now: 1600000000000,
+    })
+  ).toEqual({
+    'x-multijuicer-timestamp': '1600000000',
+    'x-multijuicer-signature': '5dcf85ed01e1beb66f46235f798b650c67cebccae5250a004f99b353c4adb939',
+  });
+});
+
+test('accepts requests signed with the shared secret', () => {
+  const body = Buffer.from('{"type":"solve","team":"team-a"}');
+  const headers = signatureHeaders({ secret, method: 'POST', requestUri: eventsUri, body });
+
+  expect(() => verifySignature(requestOf(headers, body), secret)).not.toThrow();
+});
+
+test.each([
+  ['another secret', { secret: 'other' }],
+  ['another body', { body: '{"type":"solve","team":"team-b"}' }],
+  ['another uri', { requestUri: `${eventsUri}?team=team-b` }],
+  ['an expired timestamp', { now: Date.now() - 10 * 60 * 1000 }],
+])('rejects requests signed with %s', (_, overrides) => {
+  const body = Buffer.from('{"type":"solve","team":"team-a"}');
+  const headers = signatureHeaders({
+    secret,
+    method: 'POST',
+    requestUri: eventsUri,
+    body,
+    ...overrides,
+  });
+
+  expect(() => verifySignature(requestOf(headers, body), secret)).toThrow();
+});
+
+test('rejects unsigned requests', () => {
+  expect(() => verifySignature(requestOf({}), secret)).toThrow();
+});
+
+test('only accepts watchdog events with a valid signature', async () => {
+  const body = JSON.stringify({ type: 'solve', team: 'team-a', challenge: 1 });
+
+  await request(app)
+    .post(eventsUri)
+    .set('Content-Type', 'application/json')
+    .send(body)
+    .expect(401);
+
+  await request(app)
+    .post(eventsUri)
+    .set('Content-Type', 'application/json')
+    .set(signatureHeaders({ secret, method: 'POST', requestUri: eventsUri, body }))
+    .send(body)
+    .expect(204);
+});
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// errTeamNotReady is returned when a team is synced while its JuiceShop isn't ready
+var errTeamNotReady = errors.New("JuiceShop of the team isn't ready")
+
+// errTeamPaused is returned when a team is synced while its progress tracking is paused
+var errTeamPaused = errors.New("Progress tracking of the team is paused")
+
+// SyncResponse json format of the team sync response
+type SyncResponse struct {
+	Team   string      `json:"team"`
+	Result UpdateState `json:"result"`
+}
+
+// syncTeam immediately runs a progress update for a single team, instead of waiting for the next cycle
+func syncTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, scoreboardCache *ScoreboardCache) (UpdateState, error) {
+	deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if err != nil {
+		return "", err
+	}
+	if deployment.Status.ReadyReplicas < 1 {
+		return "", errTeamNotReady
+	}
+	if isPaused(*deployment) {
+		return "", errTeamPaused
+	}
+
+	log.Infof("Syncing progress of team '%s' out of cycle", teamname)
+	return runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, scoreboardCache)
+}
+
+// setTeamPaused pauses or resumes the progress tracking of a team by setting or removing its paused annotation
+func setTeamPaused(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, paused bool) (TeamProgress, error) {
+	var value interface{}
+	if paused {
+		value = "true"
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation("paused"): value,
+			},
+		},
+	})
+	if err != nil {
+		return TeamProgress{}, err
+	}
+
+	deployment, err := workloads.Patch(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname), types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return TeamProgress{}, err
+	}
+	if paused {
+		log.Infof("Paused progress tracking of team '%s'", teamname)
+	} else {
+		log.Infof("Resumed progress tracking of team '%s'", teamname)
+	}
+	return teamProgressFromDeployment(*deployment), nil
+}
+
+// handleAdminTeams dispatches the admin actions on single teams, e.g. `POST /api/admin/teams/{team}/sync`
+// or `POST /api/admin/teams/{team}/solves/{challenge}/approve`. If all namespaces are watched, the namespace of the team
+// is passed as `namespace` query parameter.
+func (s *Server) handleAdminTeams(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/api/admin/teams/"), "/")
+	isReview := len(parts) == 4 && parts[0] != "" && parts[1] == "solves"
+	if !isReview && (len(parts) != 2 || parts[0] == "") {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, ok := s.teamNamespace(w, req)
+	if !ok {
+		return
+	}
+	if isReview {
+		s.handleReviewSolve(w, req, namespace, parts[0], parts[2], parts[3])
+		return
+	}
+	teamname, action := parts[0], parts[1]
+
+	switch action {
+	case "sync":
+		s.handleSyncTeam(w, req, namespace, teamname)
+	case "pause":
+		s.handleSetTeamPaused(w, req, namespace, teamname, true)
+	case "resume":
+		s.handleSetTeamPaused(w, req, namespace, teamname, false)
+	case "rebuild":
+		s.handleRebuildTeam(w, req, namespace, teamname)
+	case "progress":
+		s.handleImportProgress(w, req, namespace, teamname)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *Server) handleSyncTeam(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := syncTeam(req.Context(), s.clientset, namespace, teamname, s.scoreboardCache)
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err == errTeamNotReady || err == errTeamPaused {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to sync team progress", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, SyncResponse{Team: teamname, Result: result})
+}
+
+func (s *Server) handleSetTeamPaused(w http.ResponseWriter, req *http.Request, namespace, teamname string, paused bool) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	progress, err := setTeamPaused(req.Context(), s.clientset, namespace, teamname, paused)
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to update paused state of team '%s'", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.scoreboardCache.Invalidate()
+
+	writeJSON(w, progress)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeJuiceShopAPI routes all requests to JuiceShop instances to the handler
+func fakeJuiceShopAPI(t *testing.T, handler http.HandlerFunc) {
+	previous := juiceShopClient
+	juiceShopClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Result(), nil
+	})}
+	t.Cleanup(func() { juiceShopClient = previous })
+}
+
+// fakeJuiceShops makes all requests to JuiceShop instances return the passed ContinueCode
+func fakeJuiceShops(t *testing.T, continueCode string) {
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"continueCode":"` + continueCode + `"}`))
+	})
+}
+
+func TestAdminSyncCachesCurrentProgressOfTeam(t *testing.T) {
+	fakeJuiceShops(t, "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg")
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/sync"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := SyncResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, SyncResponse{Team: "foobar", Result: UpdateCache}, response)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "10", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+}
+
+func TestAdminSyncRejectsTeamsWhichArentReady(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/sync"))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestAdminSyncReturnsNotFoundForUnknownTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/sync"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAdminSyncRequiresAdminScope(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	apiKeys := map[string]Principal{"observer-key": {Name: "booth", Role: RoleObserver}}
+	server := newTestServer(clientset, NewAuthenticator(nil, apiKeys, nil, clientset), NewScoreboardCache(0))
+
+	req := httptest.NewRequest("POST", "/api/admin/teams/foobar/sync", nil)
+	req.Header.Set("Authorization", "Bearer observer-key")
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAdminPausesAndResumesTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/pause"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	progress := TeamProgress{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &progress))
+	assert.True(t, progress.Paused)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar"))
+	assert.Contains(t, rr.Body.String(), `"paused":true`)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/resume"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/paused")
+}
+
+func TestAdminSyncRejectsPausedTeams(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 1
+	deployment.Annotations["multi-juicer.iteratec.dev/paused"] = "true"
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/sync"))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestAdminPausesTheTeamOfTheRequestedNamespace(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeploymentIn("team-a", "foobar", "", "0"),
+		createJuiceShopDeploymentIn("team-b", "foobar", "", "0"),
+	)
+	server := newTestServerWatching(metav1.NamespaceAll, clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/pause"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/pause?namespace=team-b"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	for namespace, paused := range map[string]bool{"team-a": false, "team-b": true} {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, paused, deployment.Annotations["multi-juicer.iteratec.dev/paused"] == "true", namespace)
+	}
+}
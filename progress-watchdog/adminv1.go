@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// adminAPISpec is the OpenAPI v3 document of the versioned admin api, served under `/api/v1/admin/openapi.yaml`.
+// The go client in `clients/go` is generated from it.
+//
+//go:embed openapi.yaml
+var adminAPISpec []byte
+
+// errRestartUnsupported is returned when a team is restarted while the JuiceShops run as plain Pods, which nothing would recreate
+var errRestartUnsupported = errors.New("JuiceShops running as plain Pods can't be restarted")
+
+// AdminTeam json format of a team in the v1 admin api.
+// Fields of the v1 api are only ever added, never renamed or removed, so that automation keeps working across releases.
+type AdminTeam struct {
+	Team string `json:"team"`
+	// Namespace of the JuiceShop of the team, passed as `namespace` query parameter to the team routes if all namespaces are watched
+	Namespace        string  `json:"namespace"`
+	ChallengesSolved int     `json:"challengesSolved"`
+	Score            float64 `json:"score"`
+	Paused           bool    `json:"paused"`
+	// Ready is true if at least one replica of the JuiceShop of the team is ready
+	Ready         bool      `json:"ready"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastRequestAt time.Time `json:"lastRequestAt"`
+}
+
+// AdminTeamDetail json format of a single team in the v1 admin api
+type AdminTeamDetail struct {
+	AdminTeam
+	ContinueCode  string     `json:"continueCode"`
+	Solves        SolveTimes `json:"solves"`
+	Replicas      int32      `json:"replicas"`
+	ReadyReplicas int32      `json:"readyReplicas"`
+}
+
+// AdminTeamListResponse json format of the v1 team list response
+type AdminTeamListResponse struct {
+	Teams []AdminTeam `json:"teams"`
+}
+
+// AdminRestartResponse json format of the v1 team restart response
+type AdminRestartResponse struct {
+	Team        string    `json:"team"`
+	RestartedAt time.Time `json:"restartedAt"`
+}
+
+func (s *Server) adminTeamDetail(deployment appsv1.Deployment) AdminTeamDetail {
+	progress := teamProgressFromDeployment(deployment)
+	solves := progress.Solves
+	if solves == nil {
+		solves = SolveTimes{}
+	}
+	return AdminTeamDetail{
+		AdminTeam: AdminTeam{
+			Team:             progress.Team,
+			Namespace:        progress.Namespace,
+			ChallengesSolved: progress.ChallengesSolved,
+			Score:            s.scoring.Score(progress),
+			Paused:           progress.Paused,
+			Ready:            deployment.Status.ReadyReplicas > 0,
+			CreatedAt:        progress.CreatedAt,
+			LastRequestAt:    lastRequestOf(deployment),
+		},
+		ContinueCode:  progress.ContinueCode,
+		Solves:        solves,
+		Replicas:      deployment.Status.Replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+	}
+}
+
+// restartTeam restarts the JuiceShop of the team like `kubectl rollout restart`, its cached progress gets restored once it's ready again
+func restartTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, now time.Time) error {
+	if workloads.Kind() == (PodWorkloads{}).Kind() {
+		return errRestartUnsupported
+	}
+	err := patchJuiceShopDeployment(ctx, clientset, namespace, teamname, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/restartedAt": now.UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		log.Infof("Restarted JuiceShop of team '%s'", teamname)
+	}
+	return err
+}
+
+// deleteTeam deletes the JuiceShop and the service of the team together with its cached progress, like the JuiceBalancer cleans up inactive teams.
+// The JuiceShop is deleted first, so that the progress is kept if the watchdog isn't allowed to delete it.
+func deleteTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	name := fmt.Sprintf("t-%s-juiceshop", teamname)
+	if err := workloads.Delete(ctx, clientset, namespace, name); err != nil {
+		return err
+	}
+	if err := progressStore.Delete(ctx, clientset, namespace, teamname); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err := clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	log.Infof("Deleted JuiceShop of team '%s'", teamname)
+	return nil
+}
+
+// handleAdminV1 dispatches the routes of the versioned admin api under `/api/v1/admin/`
+func (s *Server) handleAdminV1(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v1/admin/")
+	if path == "teams" {
+		s.handleAdminV1ListTeams(w, req)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "teams/"), "/")
+	if !strings.HasPrefix(path, "teams/") || parts[0] == "" || len(parts) > 2 {
+		http.NotFound(w, req)
+		return
+	}
+	teamname := parts[0]
+	if len(parts) == 2 && parts[1] != "restart" {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, ok := s.teamNamespace(w, req)
+	if !ok {
+		return
+	}
+
+	if len(parts) == 2 {
+		s.handleAdminV1RestartTeam(w, req, namespace, teamname)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		s.handleAdminV1GetTeam(w, req, namespace, teamname)
+	case http.MethodDelete:
+		s.handleAdminV1DeleteTeam(w, req, namespace, teamname)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAdminV1Spec(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(adminAPISpec)
+}
+
+func (s *Server) handleAdminV1ListTeams(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	juiceShops, err := listJuiceShops(req.Context(), s.clientset, s.namespace, juiceShopSelector)
+	if err != nil {
+		log.Error("Failed to list JuiceShop deployments")
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	teams := []AdminTeam{}
+	for _, deployment := range juiceShops {
+		teams = append(teams, s.adminTeamDetail(deployment).AdminTeam)
+	}
+	writeConditionalJSON(w, req, AdminTeamListResponse{Teams: teams}, time.Time{})
+}
+
+func (s *Server) handleAdminV1GetTeam(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	deployment, err := workloads.Get(req.Context(), s.clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to get JuiceShop deployment for team '%s'", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeConditionalJSON(w, req, s.adminTeamDetail(*deployment), time.Time{})
+}
+
+func (s *Server) handleAdminV1DeleteTeam(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	err := deleteTeam(req.Context(), s.clientset, namespace, teamname)
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if k8serrors.IsForbidden(err) {
+		http.Error(w, "The watchdog isn't allowed to delete teams, see `progressWatchdog.adminApi.teamDeletion`", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to delete team '%s'", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.scoreboardCache.Invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAdminV1RestartTeam(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	err := restartTeam(req.Context(), s.clientset, namespace, teamname, now)
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err == errRestartUnsupported {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to restart JuiceShop of team '%s'", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, AdminRestartResponse{Team: teamname, RestartedAt: now.UTC().Truncate(time.Second)})
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+func createJuiceShopService(team string) *corev1.Service {
+	return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "t-" + team + "-juiceshop", Namespace: "default"}}
+}
+
+func TestAdminV1ListsAndDescribesTeams(t *testing.T) {
+	ready := createJuiceShopDeployment("foobar", "abc", "2")
+	ready.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z","7":"2021-05-01T11:00:00Z"}`
+	ready.Status.Replicas = 1
+	ready.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(ready, createJuiceShopDeployment("barfoo", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	list := AdminTeamListResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Len(t, list.Teams, 2)
+	for _, team := range list.Teams {
+		assert.Equal(t, team.Team == "foobar", team.Ready)
+	}
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams/foobar"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	detail := AdminTeamDetail{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &detail))
+	assert.Equal(t, "foobar", detail.Team)
+	assert.Equal(t, 2, detail.ChallengesSolved)
+	assert.Equal(t, "abc", detail.ContinueCode)
+	assert.Equal(t, int32(1), detail.ReadyReplicas)
+	assert.Equal(t, time.Date(2021, 5, 1, 11, 0, 0, 0, time.UTC), detail.Solves[7].UTC())
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams/unknown"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAdminV1DeletesTheJuiceShopAndServiceOfTheTeam(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "2"), createJuiceShopService("foobar"), createJuiceShopDeployment("barfoo", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("DELETE", "/api/v1/admin/teams/foobar"))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	_, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	_, err = clientset.CoreV1().Services("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	_, err = clientset.AppsV1().Deployments("default").Get(context.Background(), "t-barfoo-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("DELETE", "/api/v1/admin/teams/foobar"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAdminV1KeepsTheProgressIfTheWatchdogIsntAllowedToDeleteTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "2"))
+	clientset.PrependReactor("delete", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "t-foobar-juiceshop", nil)
+	})
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("DELETE", "/api/v1/admin/teams/foobar"))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", deployment.Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestAdminV1RestartsTheJuiceShopOfTheTeam(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "2"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/v1/admin/teams/foobar/restart"))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	response := AdminRestartResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "foobar", response.Team)
+
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, response.RestartedAt.Format(time.RFC3339), deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"])
+	assert.Equal(t, "abc", deployment.Annotations["multi-juicer.iteratec.dev/continueCode"], "Should keep the progress of the team")
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams/foobar/restart"))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestAdminV1AddressesTeamsByNamespaceWhenWatchingAllNamespaces(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeploymentIn("team-a", "foobar", "abc", "2"),
+		createJuiceShopDeploymentIn("team-b", "foobar", "", "0"),
+	)
+	server := newTestServerWatching(metav1.NamespaceAll, clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	list := AdminTeamListResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	namespaces := []string{}
+	for _, team := range list.Teams {
+		namespaces = append(namespaces, team.Namespace)
+	}
+	assert.ElementsMatch(t, []string{"team-a", "team-b"}, namespaces)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams/foobar"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/v1/admin/teams/foobar?namespace=team-a"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	detail := AdminTeamDetail{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &detail))
+	assert.Equal(t, "team-a", detail.Namespace)
+	assert.Equal(t, "abc", detail.ContinueCode)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/v1/admin/teams/foobar/restart?namespace=team-b"))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	restarted, err := clientset.AppsV1().Deployments("team-b").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, restarted.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"])
+	untouched, err := clientset.AppsV1().Deployments("team-a").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, untouched.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"])
+}
+
+func TestAdminV1RejectsRestartsOfPods(t *testing.T) {
+	useWorkloads(t, "Pod")
+	deployment := createJuiceShopDeployment("foobar", "abc", "2")
+	clientset := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: deployment.ObjectMeta})
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/v1/admin/teams/foobar/restart"))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestAdminV1RequiresTheAdminScope(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "2"))
+	apiKeys := map[string]Principal{"observer-key": {Name: "booth", Role: RoleObserver}}
+	server := newTestServer(clientset, NewAuthenticator(nil, apiKeys, nil, clientset), NewScoreboardCache(0))
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/teams", nil)
+	req.Header.Set("Authorization", "Bearer observer-key")
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAdminV1ServesItsOpenAPIDocumentWithoutAuthentication(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/admin/openapi.yaml", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	spec := struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}{}
+	assert.NoError(t, yaml.Unmarshal(rr.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	operations := []string{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method != "parameters" {
+				operations = append(operations, method+" "+path)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"get /teams", "get /teams/{team}", "delete /teams/{team}", "post /teams/{team}/restart"}, operations)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// apiServerCheckTimeout limits how long a single connectivity check waits for the kubernetes api
+const apiServerCheckTimeout = 10 * time.Second
+
+// APIServerCheck periodically verifies that the kubernetes api can still be reached with the credentials of the watchdog.
+// The watchdog reports itself as not ready until the first check succeeded and while the last check failed. After too many consecutive failures (e.g. a rotated token which
+// can't be reloaded or a network partition) the watchdog exits, so that kubernetes restarts it instead of it looping on errors forever.
+type APIServerCheck struct {
+	clientset        kubernetes.Interface
+	namespace        string
+	interval         time.Duration
+	failureThreshold int
+	exit             func(code int)
+
+	mutex               sync.Mutex
+	checked             bool
+	consecutiveFailures int
+	lastError           error
+	// awaitingDiscovery is set while the watchdog tracks the progress of the teams but didn't complete its first discovery of them yet
+	awaitingDiscovery bool
+}
+
+// NewAPIServerCheck creates an APIServerCheck exiting the process after failureThreshold consecutive failed checks
+func NewAPIServerCheck(clientset kubernetes.Interface, namespace string, interval time.Duration, failureThreshold int) *APIServerCheck {
+	return &APIServerCheck{
+		clientset:        clientset,
+		namespace:        namespace,
+		interval:         interval,
+		failureThreshold: failureThreshold,
+		exit:             os.Exit,
+	}
+}
+
+// Check lists a single JuiceShop deployment, which requires a working connection, valid credentials and the required permissions
+func (c *APIServerCheck) Check() {
+	ctx, cancel := context.WithTimeout(context.Background(), apiServerCheckTimeout)
+	defer cancel()
+	_, err := c.clientset.AppsV1().Deployments(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: juiceShopSelector,
+		Limit:         1,
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checked = true
+	c.lastError = err
+	if err == nil {
+		if c.consecutiveFailures > 0 {
+			log.Infof("Kubernetes api is reachable again after %d failed checks", c.consecutiveFailures)
+		}
+		c.consecutiveFailures = 0
+		return
+	}
+
+	c.consecutiveFailures++
+	log.Warningf("Kubernetes api connectivity check failed (%d/%d): %s", c.consecutiveFailures, c.failureThreshold, err)
+	if c.consecutiveFailures >= c.failureThreshold {
+		log.Errorf("Kubernetes api unreachable for %d consecutive checks, exiting so that the watchdog gets restarted", c.consecutiveFailures)
+		c.exit(1)
+	}
+}
+
+// Run checks the kubernetes api right away and then in the configured interval
+func (c *APIServerCheck) Run() {
+	for {
+		c.Check()
+		time.Sleep(c.interval)
+	}
+}
+
+// AwaitDiscovery makes the watchdog unready until DiscoveryCompleted is called, so it only receives traffic once it knows the teams
+func (c *APIServerCheck) AwaitDiscovery() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.awaitingDiscovery = true
+}
+
+// DiscoveryCompleted records that the discovery of the teams completed
+func (c *APIServerCheck) DiscoveryCompleted() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.awaitingDiscovery = false
+}
+
+// Ready returns why the watchdog isn't ready, or nil if it is
+func (c *APIServerCheck) Ready() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.checked {
+		return errors.New("Kubernetes api wasn't checked yet")
+	}
+	if c.awaitingDiscovery {
+		return errors.New("Waiting for the first discovery of the teams")
+	}
+	if c.lastError != nil {
+		return fmt.Errorf("Kubernetes api unreachable: %v", c.lastError)
+	}
+	return nil
+}
+
+// handleReadiness serves the readiness probe of the watchdog
+func (c *APIServerCheck) handleReadiness(w http.ResponseWriter, req *http.Request) {
+	if err := c.Ready(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAPIServerCheckReportsReadinessOfLastCheck(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	failing := false
+	clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failing {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+	check := NewAPIServerCheck(clientset, "default", 0, 3)
+
+	check.Check()
+	rr := httptest.NewRecorder()
+	check.handleReadiness(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	failing = true
+	check.Check()
+	rr = httptest.NewRecorder()
+	check.handleReadiness(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	failing = false
+	check.Check()
+	assert.NoError(t, check.Ready())
+}
+
+func TestAPIServerCheckExitsAfterConsecutiveFailures(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("Unauthorized")
+	})
+	check := NewAPIServerCheck(clientset, "default", 0, 3)
+	exitCode := -1
+	check.exit = func(code int) { exitCode = code }
+
+	check.Check()
+	check.Check()
+	assert.Equal(t, -1, exitCode)
+	check.Check()
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestNotReadyBeforeFirstCheckAndDiscovery(t *testing.T) {
+	check := NewAPIServerCheck(fake.NewSimpleClientset(), "default", 0, 3)
+	assert.EqualError(t, check.Ready(), "Kubernetes api wasn't checked yet")
+
+	check.AwaitDiscovery()
+	check.Check()
+	assert.EqualError(t, check.Ready(), "Waiting for the first discovery of the teams")
+
+	check.DiscoveryCompleted()
+	assert.NoError(t, check.Ready())
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/op/go-logging"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var auditLog = logging.MustGetLogger("Audit")
+
+// Scope is required by a route of the api
+type Scope string
+
+const (
+	// ScopeRead allows to read team and progress data
+	ScopeRead Scope = "read"
+	// ScopeAdmin allows admin actions modifying teams, like resets, restores and deletions
+	ScopeAdmin Scope = "admin"
+)
+
+// Role of a principal, each role is granted a fixed set of scopes
+type Role string
+
+const (
+	// RoleObserver can only look at team and progress data, e.g. helpers at a booth
+	RoleObserver Role = "observer"
+	// RoleOrganizer can additionally reset, restore and delete teams
+	RoleOrganizer Role = "organizer"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleObserver:  {ScopeRead},
+	RoleOrganizer: {ScopeRead, ScopeAdmin},
+}
+
+// Grants checks if the role is allowed to access routes requiring the scope
+func (r Role) Grants(scope Scope) bool {
+	for _, granted := range roleScopes[r] {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleScopes[role]; !ok {
+		return "", fmt.Errorf("Unknown role '%s', must be one of '%s' or '%s'", value, RoleObserver, RoleOrganizer)
+	}
+	return role, nil
+}
+
+// Principal an authenticated caller of the api
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// signedRequestPrincipal is used for requests of other MultiJuicer components signed with the shared secret
+var signedRequestPrincipal = Principal{Name: "multi-juicer-service", Role: RoleOrganizer}
+
+type principalContextKey struct{}
+
+// principalFromContext returns the principal authenticated by requireScope
+func principalFromContext(ctx context.Context) Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(Principal)
+	return principal
+}
+
+// Authenticator identifies callers by request signatures, static api keys or kubernetes service account tokens
+type Authenticator struct {
+	signingSecret []byte
+	// apiKeys maps the api key to the principal using it
+	apiKeys map[string]Principal
+	// serviceAccountRoles maps service account usernames (e.g. `system:serviceaccount:default:juice-balancer`) to their role
+	serviceAccountRoles map[string]Role
+	clientset           kubernetes.Interface
+}
+
+// NewAuthenticator creates a new Authenticator. ServiceAccount tokens are only checked if serviceAccountRoles aren't empty
+func NewAuthenticator(signingSecret []byte, apiKeys map[string]Principal, serviceAccountRoles map[string]Role, clientset kubernetes.Interface) *Authenticator {
+	return &Authenticator{
+		signingSecret:       signingSecret,
+		apiKeys:             apiKeys,
+		serviceAccountRoles: serviceAccountRoles,
+		clientset:           clientset,
+	}
+}
+
+// Authenticate returns the principal sending the request or an error if the request couldn't be authenticated
+func (a *Authenticator) Authenticate(req *http.Request) (Principal, error) {
+	if req.Header.Get(SignatureHeader) != "" {
+		if len(a.signingSecret) == 0 {
+			return Principal{}, fmt.Errorf("Request is signed, but no signing secret is configured")
+		}
+		if err := verifySignature(req, a.signingSecret, time.Now()); err != nil {
+			return Principal{}, err
+		}
+		return signedRequestPrincipal, nil
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return Principal{}, fmt.Errorf("Request is neither signed nor contains a bearer token")
+	}
+	token := strings.TrimPrefix(authorization, "Bearer ")
+
+	for key, principal := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return principal, nil
+		}
+	}
+
+	if len(a.serviceAccountRoles) == 0 {
+		return Principal{}, fmt.Errorf("Unknown api key")
+	}
+	return a.reviewServiceAccountToken(req.Context(), token)
+}
+
+func (a *Authenticator) reviewServiceAccountToken(ctx context.Context, token string) (Principal, error) {
+	review, err := a.clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return Principal{}, fmt.Errorf("Failed to review service account token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return Principal{}, fmt.Errorf("Token is neither a known api key nor a valid service account token")
+	}
+
+	username := review.Status.User.Username
+	role, ok := a.serviceAccountRoles[username]
+	if !ok {
+		return Principal{}, fmt.Errorf("Service account '%s' has no role assigned", username)
+	}
+	return Principal{Name: username, Role: role}, nil
+}
+
+// statusRecorder captures the status code written by a handler for the audit log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// requireScope only passes requests of principals whose role grants the required scope on to the next handler.
+// Every request to a route requiring the admin scope gets written to the audit log.
+func (a *Authenticator) requireScope(scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		principal, err := a.Authenticate(req)
+		if err != nil {
+			log.Warningf("Rejecting unauthenticated request to '%s' from '%s': %s", req.URL.Path, req.RemoteAddr, err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !principal.Role.Grants(scope) {
+			auditLog.Warningf("Denied '%s' (role '%s') %s %s, requires scope '%s'", principal.Name, principal.Role, req.Method, req.URL.Path, scope)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		req = req.WithContext(context.WithValue(req.Context(), principalContextKey{}, principal))
+
+		if scope != ScopeAdmin {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		auditLog.Infof("'%s' (role '%s') %s %s from '%s' responded with status %d", principal.Name, principal.Role, req.Method, req.URL.RequestURI(), req.RemoteAddr, recorder.status)
+	})
+}
+
+// parseAPIKeys parses api keys configured as a comma separated list of `name:role:key` entries
+func parseAPIKeys(value string) (map[string]Principal, error) {
+	apiKeys := map[string]Principal{}
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			// don't include the entry in the error, it might contain the key
+			return nil, fmt.Errorf("Invalid api key entry at position %d, expected format 'name:role:key'", i)
+		}
+		role, err := parseRole(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		apiKeys[parts[2]] = Principal{Name: parts[0], Role: role}
+	}
+	return apiKeys, nil
+}
+
+// parseServiceAccountRoles parses service account roles configured as a comma separated list of `namespace/serviceaccount=role` entries
+func parseServiceAccountRoles(value string) (map[string]Role, error) {
+	roles := map[string]Role{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		serviceAccount := strings.Split(parts[0], "/")
+		if len(parts) != 2 || len(serviceAccount) != 2 {
+			return nil, fmt.Errorf("Invalid service account role entry '%s', expected format 'namespace/serviceaccount=role'", entry)
+		}
+		role, err := parseRole(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		roles[fmt.Sprintf("system:serviceaccount:%s:%s", serviceAccount[0], serviceAccount[1])] = role
+	}
+	return roles, nil
+}
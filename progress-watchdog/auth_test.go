@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func requestWithToken(token string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/progress", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestParsesAPIKeys(t *testing.T) {
+	apiKeys, err := parseAPIKeys("booth:observer:abc, ci:organizer:def:with:colons")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Principal{
+		"abc":             {Name: "booth", Role: RoleObserver},
+		"def:with:colons": {Name: "ci", Role: RoleOrganizer},
+	}, apiKeys)
+
+	_, err = parseAPIKeys("booth:admin:abc")
+	assert.Error(t, err, "Unknown roles should be rejected")
+	_, err = parseAPIKeys("abc")
+	assert.Error(t, err, "Entries without name and role should be rejected")
+	assert.NotContains(t, err.Error(), "abc", "Error should not leak the key")
+}
+
+func TestParsesServiceAccountRoles(t *testing.T) {
+	roles, err := parseServiceAccountRoles("default/juice-balancer=organizer")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Role{"system:serviceaccount:default:juice-balancer": RoleOrganizer}, roles)
+
+	_, err = parseServiceAccountRoles("juice-balancer=organizer")
+	assert.Error(t, err)
+}
+
+func TestRolesGrantScopes(t *testing.T) {
+	assert.True(t, RoleObserver.Grants(ScopeRead))
+	assert.False(t, RoleObserver.Grants(ScopeAdmin), "Observers should not be able to use admin routes")
+	assert.True(t, RoleOrganizer.Grants(ScopeRead))
+	assert.True(t, RoleOrganizer.Grants(ScopeAdmin))
+	assert.False(t, Role("unknown").Grants(ScopeRead))
+}
+
+func TestAuthenticatesRequests(t *testing.T) {
+	authenticator := NewAuthenticator([]byte("secret"), map[string]Principal{"abc": {Name: "booth", Role: RoleObserver}}, nil, nil)
+
+	principal, err := authenticator.Authenticate(requestWithToken("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Name: "booth", Role: RoleObserver}, principal)
+
+	_, err = authenticator.Authenticate(requestWithToken("unknown"))
+	assert.Error(t, err)
+
+	signed := httptest.NewRequest("GET", "/api/progress", nil)
+	SignRequest(signed, []byte("secret"), time.Now())
+	principal, err = authenticator.Authenticate(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, signedRequestPrincipal, principal)
+
+	_, err = authenticator.Authenticate(httptest.NewRequest("GET", "/api/progress", nil))
+	assert.Error(t, err)
+}
+
+func TestAuthenticatesServiceAccountTokens(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "valid-token" {
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:default:juice-balancer"
+		}
+		return true, review, nil
+	})
+	authenticator := NewAuthenticator(nil, nil, map[string]Role{"system:serviceaccount:default:juice-balancer": RoleOrganizer}, clientset)
+
+	principal, err := authenticator.Authenticate(requestWithToken("valid-token"))
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Name: "system:serviceaccount:default:juice-balancer", Role: RoleOrganizer}, principal)
+
+	_, err = authenticator.Authenticate(requestWithToken("invalid-token"))
+	assert.Error(t, err)
+}
+
+func TestRequireScopeEnforcesScopes(t *testing.T) {
+	authenticator := NewAuthenticator(nil, map[string]Principal{
+		"observer-key":  {Name: "booth", Role: RoleObserver},
+		"organizer-key": {Name: "organizer", Role: RoleOrganizer},
+	}, nil, nil)
+	handler := authenticator.requireScope(ScopeAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, requestWithToken("observer-key"))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, requestWithToken("organizer-key"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/progress", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
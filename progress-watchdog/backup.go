@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const backupFilePrefix = "snapshot-"
+const backupFileSuffix = ".mjsnap"
+
+// restorableAnnotations are the names of the annotations of a team which get restored from the backup if they are missing in the cluster.
+// `paused` isn't restored, as resuming a team removes it, so that a missing annotation can't be told apart from a resume after the backup.
+var restorableAnnotations = []string{
+	"passcode",
+	"solveReviews",
+	"disabledChallenges",
+	"tutorialChallenges",
+	"solveDetails",
+	"continueCodeFindIt",
+	"continueCodeFixIt",
+}
+
+// BackupTarget is the storage the backups are written to, addressing every backup by its file name
+type BackupTarget interface {
+	// List returns the names of all stored backups
+	List(ctx context.Context) ([]string, error)
+	// Write stores the backup, replacing it atomically if it exists
+	Write(ctx context.Context, name string, data []byte) error
+	// Read returns the content of the backup
+	Read(ctx context.Context, name string) ([]byte, error)
+	// Delete removes the backup
+	Delete(ctx context.Context, name string) error
+	// String describes the location of the backups for logs and error messages
+	String() string
+}
+
+// DirectoryBackupTarget stores the backups as files in a directory, e.g. on a PersistentVolume or a mounted bucket
+type DirectoryBackupTarget string
+
+// List implements BackupTarget
+func (d DirectoryBackupTarget) List(ctx context.Context) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(string(d), backupFilePrefix+"*"+backupFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, match := range matches {
+		names = append(names, filepath.Base(match))
+	}
+	return names, nil
+}
+
+// Write implements BackupTarget
+func (d DirectoryBackupTarget) Write(ctx context.Context, name string, data []byte) error {
+	// written to a temporary file first, so that a crash never leaves a truncated backup behind
+	file, err := ioutil.TempFile(string(d), ".snapshot-")
+	if err != nil {
+		return fmt.Errorf("Failed to create backup file: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("Failed to write backup: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(string(d), name)); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// Read implements BackupTarget
+func (d DirectoryBackupTarget) Read(ctx context.Context, name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), name))
+}
+
+// Delete implements BackupTarget
+func (d DirectoryBackupTarget) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(string(d), name))
+}
+
+func (d DirectoryBackupTarget) String() string {
+	return string(d)
+}
+
+// S3BackupTarget stores the backups as objects below a prefix of an S3 bucket, so that they survive the loss of the whole cluster
+type S3BackupTarget struct {
+	client *S3Client
+	prefix string
+}
+
+// NewS3BackupTarget creates a target storing the backups with the client, the prefix is prepended to the names of the backups
+func NewS3BackupTarget(client *S3Client, prefix string) *S3BackupTarget {
+	return &S3BackupTarget{client: client, prefix: prefix}
+}
+
+// List implements BackupTarget
+func (s *S3BackupTarget) List(ctx context.Context) ([]string, error) {
+	keys, err := s.client.ListObjects(ctx, s.prefix+backupFilePrefix)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, s.prefix)
+		// the prefix may be shared with other objects, e.g. the backups of another event in a nested prefix
+		if strings.HasSuffix(name, backupFileSuffix) && !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Write implements BackupTarget
+func (s *S3BackupTarget) Write(ctx context.Context, name string, data []byte) error {
+	return s.client.PutObject(ctx, s.prefix+name, data)
+}
+
+// Read implements BackupTarget
+func (s *S3BackupTarget) Read(ctx context.Context, name string) ([]byte, error) {
+	return s.client.GetObject(ctx, s.prefix+name)
+}
+
+// Delete implements BackupTarget
+func (s *S3BackupTarget) Delete(ctx context.Context, name string) error {
+	return s.client.DeleteObject(ctx, s.prefix+name)
+}
+
+func (s *S3BackupTarget) String() string {
+	return fmt.Sprintf("s3://%s/%s", s.client.bucket, s.prefix)
+}
+
+// BackupStore keeps periodic snapshots of the event outside of the cluster state, in a directory or an S3 bucket.
+// The snapshots are encrypted like the ones of `snapshot create`, the oldest ones are removed once there are more than retention.
+type BackupStore struct {
+	target     BackupTarget
+	passphrase []byte
+	retention  int
+
+	mutex sync.Mutex
+	// latest is the most recent backup, kept for recovering the progress of recreated JuiceShops
+	latest *Snapshot
+}
+
+// NewBackupStore creates a BackupStore writing to the target
+func NewBackupStore(target BackupTarget, passphrase []byte, retention int) *BackupStore {
+	return &BackupStore{target: target, passphrase: passphrase, retention: retention}
+}
+
+// backups lists the names of the backups in the store, oldest first
+func (b *BackupStore) backups(ctx context.Context) ([]string, error) {
+	names, err := b.target.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the timestamp in the names sorts chronologically
+	sort.Strings(names)
+	return names, nil
+}
+
+// Save writes the snapshot to the store and removes the backups exceeding the retention
+func (b *BackupStore) Save(ctx context.Context, snapshot Snapshot) error {
+	name := backupFilePrefix + snapshot.CreatedAt.UTC().Format("20060102T150405.000Z") + backupFileSuffix
+	archive := &bytes.Buffer{}
+	if err := writeSnapshot(archive, snapshot, b.passphrase); err != nil {
+		return fmt.Errorf("Failed to write backup: %v", err)
+	}
+	if err := b.target.Write(ctx, name, archive.Bytes()); err != nil {
+		return fmt.Errorf("Failed to store backup in '%s': %v", b.target, err)
+	}
+	b.mutex.Lock()
+	b.latest = &snapshot
+	b.mutex.Unlock()
+
+	backups, err := b.backups(ctx)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(backups)-b.retention; i++ {
+		if err := b.target.Delete(ctx, backups[i]); err != nil {
+			log.Warningf("Failed to remove expired backup '%s': %s", backups[i], err)
+		}
+	}
+	return nil
+}
+
+// Latest reads the most recent backup, returns false if there is none
+func (b *BackupStore) Latest(ctx context.Context) (Snapshot, bool, error) {
+	backups, err := b.backups(ctx)
+	if err != nil || len(backups) == 0 {
+		return Snapshot{}, false, err
+	}
+	name := backups[len(backups)-1]
+	archive, err := b.target.Read(ctx, name)
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("Failed to read backup '%s': %v", name, err)
+	}
+	snapshot, err := readSnapshot(bytes.NewReader(archive), b.passphrase)
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("Failed to read backup '%s': %v", name, err)
+	}
+	return snapshot, true, nil
+}
+
+// RecoverProgress implements ProgressRecoverySource with the progress of the team in the latest backup, if the backup is newer than the JuiceShop
+func (b *BackupStore) RecoverProgress(ctx context.Context, namespace, teamname string, createdAt time.Time) (map[string]string, bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.latest == nil {
+		latest, ok, err := b.Latest(ctx)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		b.latest = &latest
+	}
+	if !createdAt.After(b.latest.CreatedAt) {
+		return nil, false, nil
+	}
+	for _, team := range b.latest.Teams {
+		if team.Team == teamname && b.latest.namespaceOf(team) == namespace {
+			return team.Deployment.Annotations, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (b *BackupStore) String() string {
+	return fmt.Sprintf("the backups in '%s'", b.target)
+}
+
+// Run backs up the event every interval until the context is cancelled
+func (b *BackupStore) Run(ctx context.Context, clientset kubernetes.Interface, namespace string, scoring Scoring, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := createSnapshot(ctx, clientset, namespace, scoring, time.Now())
+			if err == nil {
+				err = b.Save(ctx, snapshot)
+			}
+			if err != nil {
+				log.Warningf("Failed to back up event: %s", err)
+				continue
+			}
+			log.Debugf("Backed up %d teams", len(snapshot.Teams))
+		}
+	}
+}
+
+// ReconcileResult what the reconciliation with the backup healed for a single team
+type ReconcileResult struct {
+	Team      string `json:"team"`
+	Namespace string `json:"namespace"`
+	// Healed describes every repaired progress record, empty if the team was up to date
+	Healed []string `json:"healed,omitempty"`
+	// Missing is set if the JuiceShop of the team doesn't exist anymore, it can be recreated with `snapshot restore`
+	Missing bool   `json:"missing,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// healingPatch compares the progress records of the team in the cluster with the backup. Solves only known to the backup are merged
+// into the cached progress, keeping the earlier solve time of challenges known to both. Restorable annotations missing in the cluster are
+// copied from the backup. Progress the cluster has beyond the backup is never removed. Returns nil if nothing needs to be healed.
+func healingPatch(current appsv1.Deployment, backup appsv1.Deployment) (map[string]interface{}, []string) {
+	annotations := map[string]interface{}{}
+	healed := []string{}
+
+	currentSolved, _ := ParseContinueCode(current.Annotations[annotation("continueCode")])
+	backupSolved, _ := ParseContinueCode(backup.Annotations[annotation("continueCode")])
+	merged := append([]int{}, currentSolved...)
+	for _, challenge := range backupSolved {
+		if !contains(merged, challenge) {
+			merged = append(merged, challenge)
+		}
+	}
+	if len(merged) > len(currentSolved) {
+		continueCode, err := EncodeContinueCode(merged)
+		if err == nil {
+			annotations[annotation("continueCode")] = continueCode
+			annotations[annotation("challengesSolved")] = fmt.Sprintf("%d", len(merged))
+			healed = append(healed, fmt.Sprintf("restored %d solved challenges", len(merged)-len(currentSolved)))
+		}
+	}
+
+	currentSolves := parseSolveTimes(current.Annotations[annotation("solves")])
+	mergedSolves := SolveTimes{}
+	for challenge, solvedAt := range currentSolves {
+		mergedSolves[challenge] = solvedAt
+	}
+	restoredSolveTimes := 0
+	for challenge, solvedAt := range parseSolveTimes(backup.Annotations[annotation("solves")]) {
+		if existing, ok := mergedSolves[challenge]; !ok || solvedAt.Before(existing) {
+			mergedSolves[challenge] = solvedAt
+			restoredSolveTimes++
+		}
+	}
+	if restoredSolveTimes > 0 {
+		solves, err := json.Marshal(mergedSolves)
+		if err == nil {
+			annotations[annotation("solves")] = string(solves)
+			healed = append(healed, fmt.Sprintf("restored %d solve times", restoredSolveTimes))
+		}
+	}
+
+	for _, name := range restorableAnnotations {
+		value, ok := backup.Annotations[annotation(name)]
+		if _, exists := current.Annotations[annotation(name)]; ok && !exists {
+			annotations[annotation(name)] = value
+			healed = append(healed, fmt.Sprintf("restored missing %s", name))
+		}
+	}
+
+	if len(healed) == 0 {
+		return nil, nil
+	}
+	return map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}}, healed
+}
+
+// resetSince returns whether the progress of the team was reset after the time, see resetTeam
+func resetSince(deployment appsv1.Deployment, since time.Time) bool {
+	resetAt, err := time.Parse(time.RFC3339, deployment.Spec.Template.Annotations[annotation("resetAt")])
+	return err == nil && resetAt.After(since)
+}
+
+// reconcileWithBackup repairs missing or stale progress records of the teams in the cluster from the backup, e.g. after the cluster was restored
+// from an outdated etcd backup. Teams are matched by namespace and name, teams of the backup outside of the namespace are ignored unless it is
+// metav1.NamespaceAll. Teams reset after the backup was taken are skipped, their missing progress was removed deliberately.
+// A dry run only reports what would be healed.
+func reconcileWithBackup(ctx context.Context, clientset kubernetes.Interface, namespace string, backup Snapshot, dryRun bool) ([]ReconcileResult, error) {
+	juiceShops, err := listJuiceShops(ctx, clientset, namespace, juiceShopSelector)
+	if err != nil {
+		return nil, err
+	}
+	deployments := map[string]appsv1.Deployment{}
+	for _, deployment := range juiceShops {
+		deployment.Annotations = withProgressRecords(deployment)
+		deployments[teamKey(deployment.Namespace, deployment.Labels["team"])] = deployment
+	}
+
+	results := []ReconcileResult{}
+	for _, team := range backup.Teams {
+		teamNamespace := backup.namespaceOf(team)
+		if teamNamespace == "" {
+			teamNamespace = namespace
+		}
+		if namespace != metav1.NamespaceAll && teamNamespace != namespace {
+			continue
+		}
+		current, ok := deployments[teamKey(teamNamespace, team.Team)]
+		if !ok {
+			results = append(results, ReconcileResult{Team: team.Team, Namespace: teamNamespace, Missing: true})
+			continue
+		}
+		if resetSince(current, backup.CreatedAt) {
+			log.Debugf("Skipping healing of team '%s' in namespace '%s', it was reset after the backup", team.Team, teamNamespace)
+			continue
+		}
+		patch, healed := healingPatch(current, team.Deployment)
+		if patch == nil {
+			continue
+		}
+		result := ReconcileResult{Team: team.Team, Namespace: teamNamespace, Healed: healed}
+		if !dryRun {
+			if err := patchProgress(ctx, clientset, teamNamespace, team.Team, patch); err != nil {
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// reconcileOnStartup heals the progress records of the cluster from the latest backup and logs a report of what was healed
+func reconcileOnStartup(ctx context.Context, clientset kubernetes.Interface, namespace string, store *BackupStore) {
+	backup, ok, err := store.Latest(ctx)
+	if err != nil {
+		log.Warningf("Skipping reconciliation with backup: %s", err)
+		return
+	}
+	if !ok {
+		log.Info("No backup found, skipping reconciliation")
+		return
+	}
+	results, err := reconcileWithBackup(ctx, clientset, namespace, backup, false)
+	if err != nil {
+		log.Warningf("Failed to reconcile with backup: %s", err)
+		return
+	}
+	log.Infof("Reconciled %d teams with the backup from %s, %d needed healing", len(backup.Teams), backup.CreatedAt.Format(time.RFC3339), len(results))
+	for _, result := range results {
+		teamname := teamKey(result.Namespace, result.Team)
+		switch {
+		case result.Missing:
+			log.Warningf("JuiceShop of team '%s' is missing, recreate it with `snapshot restore`", teamname)
+		case result.Error != "":
+			log.Warningf("Failed to heal progress of team '%s': %s", teamname, result.Error)
+		default:
+			auditLog.Infof("Healed progress of team '%s' from backup: %s", teamname, strings.Join(result.Healed, ", "))
+		}
+	}
+}
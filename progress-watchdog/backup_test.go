@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBackupStoreKeepsLatestBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backups")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := NewBackupStore(DirectoryBackupTarget(dir), []byte("secret"), 2)
+
+	_, ok, err := store.Latest(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, store.Save(context.Background(), Snapshot{Version: snapshotVersion, CreatedAt: eventStart.Add(time.Duration(i) * time.Hour), Teams: []TeamSnapshot{}}))
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "snapshot-20210501T110000.000Z.mjsnap"),
+		filepath.Join(dir, "snapshot-20210501T120000.000Z.mjsnap"),
+	}, files)
+
+	latest, ok, err := store.Latest(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, latest.CreatedAt.Equal(eventStart.Add(2*time.Hour)))
+}
+
+func TestBackupStoreKeepsLatestBackupsInS3(t *testing.T) {
+	bucket, client := newFakeS3(t, "backups")
+	bucket.objects["event/other.txt"] = []byte("unrelated")
+	store := NewBackupStore(NewS3BackupTarget(client, "event/"), []byte("secret"), 2)
+	ctx := context.Background()
+
+	_, ok, err := store.Latest(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, store.Save(ctx, Snapshot{Version: snapshotVersion, CreatedAt: eventStart.Add(time.Duration(i) * time.Hour), Teams: []TeamSnapshot{}}))
+	}
+	keys := []string{}
+	for key := range bucket.objects {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"event/other.txt", "event/snapshot-20210501T110000.000Z.mjsnap", "event/snapshot-20210501T120000.000Z.mjsnap"}, keys)
+
+	latest, ok, err := store.Latest(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, latest.CreatedAt.Equal(eventStart.Add(2*time.Hour)))
+}
+
+func TestBackupStoreRecoversTheProgressOfTheTeamInTheNamespace(t *testing.T) {
+	store := NewBackupStore(DirectoryBackupTarget(""), []byte("secret"), 2)
+	store.latest = &Snapshot{CreatedAt: eventStart, Namespace: "", Teams: []TeamSnapshot{
+		{Team: "foobar", Namespace: "team-a", Deployment: *createJuiceShopDeploymentIn("team-a", "foobar", "abc", "3")},
+		{Team: "foobar", Namespace: "team-b", Deployment: *createJuiceShopDeploymentIn("team-b", "foobar", "def", "5")},
+	}}
+
+	records, ok, err := store.RecoverProgress(context.Background(), "team-b", "foobar", eventStart.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "def", records["multi-juicer.iteratec.dev/continueCode"])
+
+	_, ok, err = store.RecoverProgress(context.Background(), "team-c", "foobar", eventStart.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestHealingPatchMergesProgressFromBackup(t *testing.T) {
+	currentCode, _ := EncodeContinueCode([]int{1})
+	backupCode, _ := EncodeContinueCode([]int{1, 2})
+	current := createJuiceShopDeployment("foobar", currentCode, "1")
+	current.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:30:00Z"}`
+	backup := createJuiceShopDeployment("foobar", backupCode, "2")
+	backup.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:10:00Z","2":"2021-05-01T10:20:00Z"}`
+	backup.Annotations["multi-juicer.iteratec.dev/passcode"] = "hash"
+
+	patch, healed := healingPatch(*current, *backup)
+	assert.Equal(t, []string{"restored 1 solved challenges", "restored 2 solve times", "restored missing passcode"}, healed)
+	annotations := patch["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	assert.Equal(t, backupCode, annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "2", annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	assert.Equal(t, `{"1":"2021-05-01T10:10:00Z","2":"2021-05-01T10:20:00Z"}`, annotations["multi-juicer.iteratec.dev/solves"])
+	assert.Equal(t, "hash", annotations["multi-juicer.iteratec.dev/passcode"])
+}
+
+func TestHealingPatchNeverRemovesNewerProgress(t *testing.T) {
+	currentCode, _ := EncodeContinueCode([]int{1, 2})
+	backupCode, _ := EncodeContinueCode([]int{1})
+	current := createJuiceShopDeployment("foobar", currentCode, "2")
+	current.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:10:00Z","2":"2021-05-01T10:20:00Z"}`
+	backup := createJuiceShopDeployment("foobar", backupCode, "1")
+	backup.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:10:00Z"}`
+
+	patch, healed := healingPatch(*current, *backup)
+	assert.Nil(t, patch)
+	assert.Empty(t, healed)
+}
+
+func TestHealingPatchKeepsResumedTeamsResumed(t *testing.T) {
+	code, _ := EncodeContinueCode([]int{1})
+	current := createJuiceShopDeployment("foobar", code, "1")
+	backup := createJuiceShopDeployment("foobar", code, "1")
+	backup.Annotations["multi-juicer.iteratec.dev/paused"] = "true"
+
+	patch, healed := healingPatch(*current, *backup)
+	assert.Nil(t, patch)
+	assert.Empty(t, healed)
+}
+
+func TestReconcileWithBackupSkipsTeamsResetAfterTheBackup(t *testing.T) {
+	backupCode, _ := EncodeContinueCode([]int{1, 2})
+	backupDeployment := createJuiceShopDeployment("reset", backupCode, "2")
+	backupDeployment.Annotations["multi-juicer.iteratec.dev/continueCodeFindIt"] = "findit"
+	backup := Snapshot{CreatedAt: eventStart, Teams: []TeamSnapshot{{Team: "reset", Deployment: *backupDeployment}}}
+	reset := createJuiceShopDeployment("reset", "", "0")
+	reset.Spec.Template.Annotations = map[string]string{"multi-juicer.iteratec.dev/resetAt": eventStart.Add(time.Minute).Format(time.RFC3339)}
+	clientset := fake.NewSimpleClientset(reset)
+
+	results, err := reconcileWithBackup(context.Background(), clientset, "default", backup, false)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, "", getTestDeployment(t, clientset, "reset").Annotations["multi-juicer.iteratec.dev/continueCode"])
+
+	reset.Spec.Template.Annotations["multi-juicer.iteratec.dev/resetAt"] = eventStart.Add(-time.Minute).Format(time.RFC3339)
+	results, err = reconcileWithBackup(context.Background(), fake.NewSimpleClientset(reset), "default", backup, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReconcileResult{{Team: "reset", Namespace: "default", Healed: []string{"restored 2 solved challenges", "restored missing continueCodeFindIt"}}}, results)
+}
+
+func TestReconcileWithBackupMatchesTeamsByNamespace(t *testing.T) {
+	backupCode, _ := EncodeContinueCode([]int{1, 2})
+	backup := Snapshot{CreatedAt: eventStart, Teams: []TeamSnapshot{
+		{Team: "foobar", Namespace: "team-a", Deployment: *createJuiceShopDeploymentIn("team-a", "foobar", backupCode, "2")},
+		{Team: "foobar", Namespace: "team-b", Deployment: *createJuiceShopDeploymentIn("team-b", "foobar", "", "0")},
+	}}
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeploymentIn("team-a", "foobar", "", "0"),
+		createJuiceShopDeploymentIn("team-b", "foobar", "", "0"),
+	)
+
+	results, err := reconcileWithBackup(context.Background(), clientset, "team-b", backup, false)
+	assert.NoError(t, err)
+	assert.Empty(t, results, "Should ignore the teams of other namespaces")
+
+	results, err = reconcileWithBackup(context.Background(), clientset, metav1.NamespaceAll, backup, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReconcileResult{{Team: "foobar", Namespace: "team-a", Healed: []string{"restored 2 solved challenges"}}}, results)
+	for namespace, continueCode := range map[string]string{"team-a": backupCode, "team-b": ""} {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, continueCode, deployment.Annotations["multi-juicer.iteratec.dev/continueCode"], namespace)
+	}
+}
+
+func TestReconcileWithBackupReportsHealedAndMissingTeams(t *testing.T) {
+	backupCode, _ := EncodeContinueCode([]int{1, 2})
+	backup := Snapshot{CreatedAt: eventStart, Teams: []TeamSnapshot{
+		{Team: "missing", Deployment: *createJuiceShopDeployment("missing", backupCode, "2")},
+		{Team: "stale", Deployment: *createJuiceShopDeployment("stale", backupCode, "2")},
+		{Team: "uptodate", Deployment: *createJuiceShopDeployment("uptodate", backupCode, "2")},
+	}}
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeployment("stale", "", "0"),
+		createJuiceShopDeployment("uptodate", backupCode, "2"),
+	)
+
+	dryRun, err := reconcileWithBackup(context.Background(), clientset, "default", backup, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReconcileResult{
+		{Team: "missing", Namespace: "default", Missing: true},
+		{Team: "stale", Namespace: "default", Healed: []string{"restored 2 solved challenges"}},
+	}, dryRun)
+	assert.Equal(t, "", getTestDeployment(t, clientset, "stale").Annotations["multi-juicer.iteratec.dev/continueCode"])
+
+	results, err := reconcileWithBackup(context.Background(), clientset, "default", backup, false)
+	assert.NoError(t, err)
+	assert.Equal(t, dryRun, results)
+	assert.Equal(t, backupCode, getTestDeployment(t, clientset, "stale").Annotations["multi-juicer.iteratec.dev/continueCode"])
+
+	results, err = reconcileWithBackup(context.Background(), clientset, "default", backup, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReconcileResult{{Team: "missing", Namespace: "default", Missing: true}}, results)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// passcodeHashCost matches the bcrypt cost the JuiceBalancer hashes passcodes with in production
+const passcodeHashCost = 12
+
+// BulkAction an admin action applied to a set of teams at once
+type BulkAction string
+
+const (
+	// BulkActionSync immediately syncs the progress of the teams, see syncTeam
+	BulkActionSync BulkAction = "sync"
+	// BulkActionRebuild rebuilds the cached progress of the teams from their JuiceShops, see rebuildProgress
+	BulkActionRebuild BulkAction = "rebuild"
+	// BulkActionPause pauses the progress tracking of the teams
+	BulkActionPause BulkAction = "pause"
+	// BulkActionResume resumes the progress tracking of the teams
+	BulkActionResume BulkAction = "resume"
+	// BulkActionReset discards the cached progress of the teams and restarts their JuiceShops with a fresh database
+	BulkActionReset BulkAction = "reset"
+	// BulkActionArchive scales the JuiceShops of the teams down to free their resources, their cached progress is kept
+	BulkActionArchive BulkAction = "archive"
+	// BulkActionRotatePasscode replaces the passcodes of the teams with new random ones
+	BulkActionRotatePasscode BulkAction = "rotatePasscode"
+)
+
+// bulkActions contains all supported bulk actions
+var bulkActions = []BulkAction{BulkActionSync, BulkActionRebuild, BulkActionPause, BulkActionResume, BulkActionReset, BulkActionArchive, BulkActionRotatePasscode}
+
+// TeamSelector selects the teams a bulk action is applied to. All set criteria must match, an empty selector matches all teams.
+type TeamSelector struct {
+	// Teams limits the selection to the listed team names, `{namespace}/{team}` only selects the team of that namespace
+	Teams []string `json:"teams,omitempty"`
+	// LabelSelector is matched against the labels of the JuiceShop deployments, e.g. `event=workshop-1`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// IdleFor only selects teams which didn't send a request to their JuiceShop for at least this duration, e.g. `2h`
+	IdleFor string `json:"idleFor,omitempty"`
+}
+
+// BulkRequest json format of the bulk action request
+type BulkRequest struct {
+	Action   BulkAction   `json:"action"`
+	Selector TeamSelector `json:"selector"`
+	// DryRun only lists the selected teams without changing them
+	DryRun bool `json:"dryRun"`
+}
+
+// BulkResult the outcome of a bulk action for a single team
+type BulkResult struct {
+	Team      string `json:"team"`
+	Namespace string `json:"namespace"`
+	// Detail describes what was done, e.g. the result of a sync
+	Detail string `json:"detail,omitempty"`
+	// Passcode is the new passcode of the team after a passcode rotation
+	Passcode string `json:"passcode,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkResponse json format of the bulk action response
+type BulkResponse struct {
+	Action BulkAction   `json:"action"`
+	DryRun bool         `json:"dryRun"`
+	Teams  []BulkResult `json:"teams"`
+}
+
+func isBulkAction(action BulkAction) bool {
+	for _, known := range bulkActions {
+		if action == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the label selector and idle duration of the selector
+func (s TeamSelector) Validate() error {
+	if _, err := labels.Parse(s.LabelSelector); err != nil {
+		return fmt.Errorf("Invalid label selector: %v", err)
+	}
+	if s.IdleFor != "" {
+		if _, err := time.ParseDuration(s.IdleFor); err != nil {
+			return fmt.Errorf("Invalid idle duration: %v", err)
+		}
+	}
+	return nil
+}
+
+// selectTeams lists the JuiceShop deployments of the teams matching the selector, sorted by namespace and team.
+// The namespace is metav1.NamespaceAll if the teams of all namespaces are selected.
+func selectTeams(ctx context.Context, clientset kubernetes.Interface, namespace string, selector TeamSelector, now time.Time) ([]appsv1.Deployment, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+	labelSelector := juiceShopSelector
+	if selector.LabelSelector != "" {
+		labelSelector += "," + selector.LabelSelector
+	}
+	var idleFor time.Duration
+	if selector.IdleFor != "" {
+		idleFor, _ = time.ParseDuration(selector.IdleFor)
+	}
+
+	juiceShops, err := listJuiceShops(ctx, clientset, namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []appsv1.Deployment{}
+	for _, deployment := range juiceShops {
+		teamname := deployment.Labels["team"]
+		if len(selector.Teams) > 0 && !containsString(selector.Teams, teamname) && !containsString(selector.Teams, teamKey(deployment.Namespace, teamname)) {
+			continue
+		}
+		if idleFor > 0 && now.Sub(lastRequestOf(deployment)) < idleFor {
+			continue
+		}
+		selected = append(selected, deployment)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return teamKey(selected[i].Namespace, selected[i].Labels["team"]) < teamKey(selected[j].Namespace, selected[j].Labels["team"])
+	})
+	return selected, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runBulkAction applies the action to all teams matching the selector. Failures of single teams are reported in their result,
+// they don't stop the action for the other teams. A dry run only lists the teams the action would be applied to.
+func runBulkAction(ctx context.Context, clientset kubernetes.Interface, namespace string, request BulkRequest, scoreboardCache *ScoreboardCache) ([]BulkResult, error) {
+	if !isBulkAction(request.Action) {
+		return nil, fmt.Errorf("Unknown action '%s'", request.Action)
+	}
+	selected, err := selectTeams(ctx, clientset, namespace, request.Selector, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	results := []BulkResult{}
+	for _, deployment := range selected {
+		teamname := deployment.Labels["team"]
+		if request.DryRun {
+			results = append(results, BulkResult{Team: teamname, Namespace: deployment.Namespace, Detail: "selected"})
+			continue
+		}
+		result := applyBulkAction(ctx, clientset, request.Action, deployment, scoreboardCache)
+		if result.Error != "" {
+			log.Warningf("Failed to %s team '%s' in namespace '%s': %s", request.Action, teamname, deployment.Namespace, result.Error)
+		}
+		results = append(results, result)
+	}
+	if !request.DryRun && len(selected) > 0 {
+		scoreboardCache.Invalidate()
+	}
+	return results, nil
+}
+
+// applyBulkAction applies the action to a single team, in the namespace of its JuiceShop
+func applyBulkAction(ctx context.Context, clientset kubernetes.Interface, action BulkAction, deployment appsv1.Deployment, scoreboardCache *ScoreboardCache) BulkResult {
+	teamname, namespace := deployment.Labels["team"], deployment.Namespace
+	result := BulkResult{Team: teamname, Namespace: namespace}
+
+	var err error
+	switch action {
+	case BulkActionSync:
+		var state UpdateState
+		state, err = syncTeam(ctx, clientset, namespace, teamname, scoreboardCache)
+		result.Detail = string(state)
+	case BulkActionRebuild:
+		rebuilt := rebuildProgress(ctx, clientset, deployment)
+		if rebuilt.Error != "" {
+			result.Error = rebuilt.Error
+			return result
+		}
+		result.Detail = fmt.Sprintf("%d challenges solved (%d were cached)", rebuilt.ChallengesSolved, rebuilt.PreviouslySolved)
+	case BulkActionPause:
+		_, err = setTeamPaused(ctx, clientset, namespace, teamname, true)
+		result.Detail = "paused"
+	case BulkActionResume:
+		_, err = setTeamPaused(ctx, clientset, namespace, teamname, false)
+		result.Detail = "resumed"
+	case BulkActionReset:
+		err = resetTeam(ctx, clientset, namespace, teamname, time.Now())
+		result.Detail = "progress discarded, JuiceShop restarting"
+	case BulkActionArchive:
+		err = archiveTeam(ctx, clientset, namespace, teamname)
+		result.Detail = "archived"
+	case BulkActionRotatePasscode:
+		result.Passcode, err = rotatePasscode(ctx, clientset, namespace, teamname)
+		result.Detail = "passcode rotated"
+	}
+	if err != nil {
+		result.Detail = ""
+		result.Passcode = ""
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func patchJuiceShopDeployment(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, patch map[string]interface{}) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = workloads.Patch(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname), types.MergePatchType, body, metav1.PatchOptions{})
+	return err
+}
+
+// resetTeam discards the cached progress of the team and restarts its JuiceShop, which starts over with an empty database
+func resetTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, now time.Time) error {
+	// removed before the restart, so that the cached progress never gets restored into the fresh JuiceShop
+	if err := progressStore.Delete(ctx, clientset, namespace, teamname); err != nil {
+		return err
+	}
+	err := patchJuiceShopDeployment(ctx, clientset, namespace, teamname, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation("solveReviews"):       nil,
+				annotation("continueCodeFindIt"): nil,
+				annotation("continueCodeFixIt"):  nil,
+				annotation("score"):              nil,
+			},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						annotation("resetAt"): now.UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		log.Infof("Reset progress of team '%s'", teamname)
+	}
+	return err
+}
+
+// archiveTeam scales the JuiceShop of the team down to zero replicas, its cached progress gets restored once it's scaled up again
+func archiveTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	err := patchJuiceShopDeployment(ctx, clientset, namespace, teamname, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation("archived"): "true",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 0,
+		},
+	})
+	if err == nil {
+		log.Infof("Archived JuiceShop of team '%s'", teamname)
+	}
+	return err
+}
+
+// generatePasscode generates a passcode in the format of the JuiceBalancer, 8 uppercase hex characters
+func generatePasscode() (string, error) {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(random)), nil
+}
+
+// rotatePasscode replaces the passcode of the team with a new random one and returns it. The JuiceBalancer checks logins against
+// the bcrypt hash of the passcode stored on the JuiceShop deployment.
+func rotatePasscode(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) (string, error) {
+	passcode, err := generatePasscode()
+	if err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(passcode), passcodeHashCost)
+	if err != nil {
+		return "", err
+	}
+	err = patchJuiceShopDeployment(ctx, clientset, namespace, teamname, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation("passcode"): string(hash),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Rotated passcode of team '%s'", teamname)
+	return passcode, nil
+}
+
+// handleBulkAction applies an admin action to a set of teams via `POST /api/admin/bulk`
+func (s *Server) handleBulkAction(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var request BulkRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !isBulkAction(request.Action) {
+		http.Error(w, fmt.Sprintf("Unknown action '%s'", request.Action), http.StatusBadRequest)
+		return
+	}
+	if err := request.Selector.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := runBulkAction(req.Context(), s.clientset, s.namespace, request, s.scoreboardCache)
+	if err != nil {
+		log.Errorf("Failed to select teams for bulk action '%s'", request.Action)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !request.DryRun {
+		auditLog.Infof("'%s' applied bulk action '%s' to %d teams", principalFromContext(req.Context()).Name, request.Action, len(results))
+	}
+
+	writeJSON(w, BulkResponse{Action: request.Action, DryRun: request.DryRun, Teams: results})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createBulkTestDeployment(team, event string, lastRequest time.Time) *appsv1.Deployment {
+	deployment := createJuiceShopDeployment(team, "abc", "3")
+	deployment.Labels["event"] = event
+	deployment.Annotations["multi-juicer.iteratec.dev/lastRequest"] = strconv.FormatInt(lastRequest.UnixNano()/int64(time.Millisecond), 10)
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z"}`
+	return deployment
+}
+
+func createBulkTestClientset() *fake.Clientset {
+	now := time.Now()
+	return fake.NewSimpleClientset(
+		createBulkTestDeployment("active", "workshop", now),
+		createBulkTestDeployment("idle", "workshop", now.Add(-3*time.Hour)),
+		createBulkTestDeployment("other", "ctf", now.Add(-3*time.Hour)),
+	)
+}
+
+func getTestDeployment(t *testing.T, clientset *fake.Clientset, team string) *appsv1.Deployment {
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-"+team+"-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	return deployment
+}
+
+func TestSelectTeamsCombinesCriteria(t *testing.T) {
+	clientset := createBulkTestClientset()
+	teamsOf := func(selector TeamSelector) []string {
+		selected, err := selectTeams(context.Background(), clientset, "default", selector, time.Now())
+		assert.NoError(t, err)
+		teams := []string{}
+		for _, deployment := range selected {
+			teams = append(teams, deployment.Labels["team"])
+		}
+		return teams
+	}
+
+	assert.Equal(t, []string{"active", "idle", "other"}, teamsOf(TeamSelector{}))
+	assert.Equal(t, []string{"active", "idle"}, teamsOf(TeamSelector{LabelSelector: "event=workshop"}))
+	assert.Equal(t, []string{"idle", "other"}, teamsOf(TeamSelector{IdleFor: "2h"}))
+	assert.Equal(t, []string{"idle"}, teamsOf(TeamSelector{LabelSelector: "event=workshop", IdleFor: "2h"}))
+	assert.Equal(t, []string{"active", "other"}, teamsOf(TeamSelector{Teams: []string{"active", "other", "unknown"}}))
+}
+
+func TestTeamSelectorValidation(t *testing.T) {
+	assert.NoError(t, TeamSelector{LabelSelector: "event in (a, b)", IdleFor: "30m"}.Validate())
+	assert.Error(t, TeamSelector{LabelSelector: "event in (a"}.Validate())
+	assert.Error(t, TeamSelector{IdleFor: "soon"}.Validate())
+}
+
+func TestBulkDryRunDoesntChangeTeams(t *testing.T) {
+	clientset := createBulkTestClientset()
+	results, err := runBulkAction(context.Background(), clientset, "default", BulkRequest{Action: BulkActionReset, Selector: TeamSelector{LabelSelector: "event=workshop"}, DryRun: true}, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, []BulkResult{{Team: "active", Namespace: "default", Detail: "selected"}, {Team: "idle", Namespace: "default", Detail: "selected"}}, results)
+	assert.Equal(t, "abc", getTestDeployment(t, clientset, "active").Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestBulkResetDiscardsProgressAndRestartsJuiceShops(t *testing.T) {
+	clientset := createBulkTestClientset()
+	results, err := runBulkAction(context.Background(), clientset, "default", BulkRequest{Action: BulkActionReset, Selector: TeamSelector{LabelSelector: "event=workshop"}}, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	reset := getTestDeployment(t, clientset, "idle")
+	assert.NotContains(t, reset.Annotations, "multi-juicer.iteratec.dev/continueCode")
+	assert.NotContains(t, reset.Annotations, "multi-juicer.iteratec.dev/solves")
+	assert.NotEmpty(t, reset.Spec.Template.Annotations["multi-juicer.iteratec.dev/resetAt"])
+	assert.Equal(t, "abc", getTestDeployment(t, clientset, "other").Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestBulkArchiveScalesDownIdleTeams(t *testing.T) {
+	clientset := createBulkTestClientset()
+	results, err := runBulkAction(context.Background(), clientset, "default", BulkRequest{Action: BulkActionArchive, Selector: TeamSelector{IdleFor: "2h"}}, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, []BulkResult{{Team: "idle", Namespace: "default", Detail: "archived"}, {Team: "other", Namespace: "default", Detail: "archived"}}, results)
+
+	archived := getTestDeployment(t, clientset, "idle")
+	assert.Equal(t, int32(0), *archived.Spec.Replicas)
+	assert.Equal(t, "true", archived.Annotations["multi-juicer.iteratec.dev/archived"])
+	assert.Equal(t, "abc", archived.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Nil(t, getTestDeployment(t, clientset, "active").Spec.Replicas)
+}
+
+func TestBulkRotatePasscodeReturnsNewPasscodes(t *testing.T) {
+	clientset := createBulkTestClientset()
+	results, err := runBulkAction(context.Background(), clientset, "default", BulkRequest{Action: BulkActionRotatePasscode, Selector: TeamSelector{Teams: []string{"active"}}}, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Regexp(t, "^[0-9A-F]{8}$", results[0].Passcode)
+
+	hash := getTestDeployment(t, clientset, "active").Annotations["multi-juicer.iteratec.dev/passcode"]
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(results[0].Passcode)))
+}
+
+func TestBulkReportsFailuresPerTeam(t *testing.T) {
+	clientset := createBulkTestClientset()
+	results, err := runBulkAction(context.Background(), clientset, "default", BulkRequest{Action: BulkActionSync, Selector: TeamSelector{Teams: []string{"active"}}}, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, []BulkResult{{Team: "active", Namespace: "default", Error: errTeamNotReady.Error()}}, results)
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, 1, printBulkResults(out, "default", BulkRequest{Action: BulkActionSync}, results))
+	assert.Equal(t, "active: failed: JuiceShop of the team isn't ready\n", out.String())
+}
+
+func TestBulkEndpoint(t *testing.T) {
+	clientset := createBulkTestClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/bulk", `{"action":"pause","selector":{"labelSelector":"event=ctf"}}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	response := BulkResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, BulkResponse{Action: BulkActionPause, Teams: []BulkResult{{Team: "other", Namespace: "default", Detail: "paused"}}}, response)
+	assert.Equal(t, "true", getTestDeployment(t, clientset, "other").Annotations["multi-juicer.iteratec.dev/paused"])
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/bulk", `{"action":"explode"}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/bulk", `{"action":"pause","selector":{"idleFor":"soon"}}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// ScoreboardCache caches the computed ranking for a short time, so that scoreboard viewers don't cause kubernetes api requests.
+// The cache gets invalidated when the watchdog detects a new solve.
+type ScoreboardCache struct {
+	mutex      sync.Mutex
+	ttl        time.Duration
+	now        func() time.Time
+	teams      []ScoreboardTeam
+	computedAt time.Time
+	valid      bool
+	// lastChange is the time of the last detected solve, or the creation of the cache
+	lastChange time.Time
+}
+
+// NewScoreboardCache creates a cache holding rankings for at most the passed ttl
+func NewScoreboardCache(ttl time.Duration) *ScoreboardCache {
+	return &ScoreboardCache{
+		ttl:        ttl,
+		now:        time.Now,
+		lastChange: time.Now(),
+	}
+}
+
+// Get returns the cached ranking or computes it if the cache is empty or expired.
+// Concurrent callers wait for a single computation instead of each computing it themselves.
+func (c *ScoreboardCache) Get(compute func() ([]ScoreboardTeam, error)) ([]ScoreboardTeam, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.valid && c.now().Sub(c.computedAt) < c.ttl {
+		return c.teams, nil
+	}
+
+	teams, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	c.teams = teams
+	c.computedAt = c.now()
+	c.valid = true
+	return teams, nil
+}
+
+// Invalidate drops the cached ranking, the next call to Get will recompute it
+func (c *ScoreboardCache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.valid = false
+	c.teams = nil
+	c.lastChange = c.now()
+}
+
+// LastModified returns the time the ranking last changed
+func (c *ScoreboardCache) LastModified() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lastChange
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreboardCacheReturnsCachedRankingUntilExpired(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cache := NewScoreboardCache(10 * time.Second)
+	cache.now = func() time.Time { return now }
+
+	computations := 0
+	compute := func() ([]ScoreboardTeam, error) {
+		computations++
+		return []ScoreboardTeam{{Position: 1, Team: "foobar", ChallengesSolved: computations}}, nil
+	}
+
+	first, _ := cache.Get(compute)
+	second, _ := cache.Get(compute)
+	assert.Equal(t, 1, computations)
+	assert.Equal(t, first, second)
+
+	now = now.Add(11 * time.Second)
+	third, _ := cache.Get(compute)
+	assert.Equal(t, 2, computations, "Should recompute after the ttl expired")
+	assert.Equal(t, 2, third[0].ChallengesSolved)
+}
+
+func TestScoreboardCacheRecomputesAfterInvalidation(t *testing.T) {
+	cache := NewScoreboardCache(time.Hour)
+
+	computations := 0
+	compute := func() ([]ScoreboardTeam, error) {
+		computations++
+		return []ScoreboardTeam{}, nil
+	}
+
+	cache.Get(compute)
+	cache.Invalidate()
+	cache.Get(compute)
+	assert.Equal(t, 2, computations)
+}
+
+func TestScoreboardCacheDoesNotCacheErrors(t *testing.T) {
+	cache := NewScoreboardCache(time.Hour)
+
+	_, err := cache.Get(func() ([]ScoreboardTeam, error) {
+		return nil, errors.New("Failed to list deployments")
+	})
+	assert.Error(t, err)
+
+	teams, err := cache.Get(func() ([]ScoreboardTeam, error) {
+		return []ScoreboardTeam{}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []ScoreboardTeam{}, teams)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ChallengeStatus json format of a challenge in the JuiceShop challenge api
+type ChallengeStatus struct {
+	ID     int    `json:"id"`
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Solved bool   `json:"solved"`
+	// UpdatedAt is the last change of the challenge, for solved challenges the time they got solved
+	UpdatedAt time.Time `json:"updatedAt"`
+	// DisabledEnv names the environment (e.g. `Docker`) the challenge is disabled in, if the JuiceShop runs without safety overrides
+	DisabledEnv string `json:"disabledEnv"`
+	// TutorialOrder is set for the challenges which are part of the guided tutorial
+	TutorialOrder *int `json:"tutorialOrder"`
+}
+
+// ChallengeConfiguration the challenges which are treated specially in the environment of a JuiceShop
+type ChallengeConfiguration struct {
+	Disabled []int
+	Tutorial []int
+	// SolveDetails of the solved challenges, only collected if the solveDetails or ctfFlags feature is enabled
+	SolveDetails []SolveDetail
+	// SolvedAt are the solve times reported by the JuiceShop, only collected if the challengeApiScoring feature is enabled
+	SolvedAt SolveTimes
+}
+
+// ChallengeListPayload json format of the JuiceShop challenge api response
+type ChallengeListPayload struct {
+	Data []ChallengeStatus `json:"data"`
+}
+
+// getChallengeStatuses fetches the state of all challenges from the challenge api of the JuiceShop
+func getChallengeStatuses(ctx context.Context, namespace, teamname string) ([]ChallengeStatus, error) {
+	url := juiceShopURL(namespace, teamname) + "/api/Challenges/"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create http request: %v", err)
+	}
+	res, err := juiceShopClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch challenges from JuiceShop: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response status code '%d' from Juice Shop", res.StatusCode)
+	}
+
+	payload := ChallengeListPayload{}
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("Failed to parse JSON from Juice Shop challenge response: %v", err)
+	}
+	return payload.Data, nil
+}
+
+// getChallengeConfiguration fetches the challenge configuration of the JuiceShop.
+// Returns nil if it couldn't be fetched, so that the previously cached one is kept.
+func getChallengeConfiguration(ctx context.Context, namespace, teamname string) *ChallengeConfiguration {
+	challenges, err := getChallengeStatuses(ctx, namespace, teamname)
+	if err != nil {
+		log.Warningf("Failed to fetch challenge configuration of team '%s'", teamname)
+		log.Warning(err)
+		return nil
+	}
+	return challengeConfigurationOf(challenges)
+}
+
+// challengeConfigurationOf collects the disabled and tutorial challenges
+func challengeConfigurationOf(challenges []ChallengeStatus) *ChallengeConfiguration {
+	configuration := &ChallengeConfiguration{Disabled: []int{}, Tutorial: []int{}}
+	for _, challenge := range challenges {
+		if challenge.DisabledEnv != "" {
+			configuration.Disabled = append(configuration.Disabled, challenge.ID)
+		}
+		if challenge.TutorialOrder != nil {
+			configuration.Tutorial = append(configuration.Tutorial, challenge.ID)
+		}
+	}
+	sort.Ints(configuration.Disabled)
+	sort.Ints(configuration.Tutorial)
+	if featureFlags.Enabled(FeatureSolveDetails) || featureFlags.Enabled(FeatureCTFFlags) {
+		configuration.SolveDetails = solveDetailsOf(challenges)
+	}
+	if featureFlags.Enabled(FeatureChallengeAPIScoring) {
+		configuration.SolvedAt = reportedSolveTimesOf(challenges)
+	}
+	return configuration
+}
+
+// solvedChallengeIDs returns the sorted ids of the solved challenges
+func solvedChallengeIDs(challenges []ChallengeStatus) []int {
+	solved := []int{}
+	for _, challenge := range challenges {
+		if challenge.Solved {
+			solved = append(solved, challenge.ID)
+		}
+	}
+	sort.Ints(solved)
+	return solved
+}
+
+// parseChallengeList decodes a json encoded list of challenge ids (e.g. the disabledChallenges annotation), returning no challenges if it is empty or invalid
+func parseChallengeList(annotation string) []int {
+	challenges := []int{}
+	if annotation == "" {
+		return challenges
+	}
+	if err := json.Unmarshal([]byte(annotation), &challenges); err != nil {
+		log.Warningf("Could not decode challenge list annotation '%s'", annotation)
+		return []int{}
+	}
+	return challenges
+}
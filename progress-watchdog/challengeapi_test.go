@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallengeConfigurationOf(t *testing.T) {
+	tutorialOrder := 1
+	assert.Equal(t, &ChallengeConfiguration{Disabled: []int{2, 7}, Tutorial: []int{1}}, challengeConfigurationOf([]ChallengeStatus{
+		{ID: 7, DisabledEnv: "Docker"},
+		{ID: 1, Solved: true, TutorialOrder: &tutorialOrder},
+		{ID: 2, DisabledEnv: "Heroku"},
+	}))
+}
+
+func TestParseChallengeListIgnoresInvalidAnnotations(t *testing.T) {
+	assert.Equal(t, []int{3, 4}, parseChallengeList("[3,4]"))
+	assert.Equal(t, []int{}, parseChallengeList("not json"))
+}
+
+func TestSolvesOfDisabledChallengesDontCount(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "2")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-04-10T10:00:00Z","2":"2021-04-10T11:00:00Z"}`
+	deployment.Annotations["multi-juicer.iteratec.dev/disabledChallenges"] = "[2]"
+
+	progress := teamProgressFromDeployment(*deployment)
+	assert.Equal(t, 1, progress.ChallengesSolved)
+	assert.Equal(t, SolveTimes{1: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)}, progress.Solves)
+}
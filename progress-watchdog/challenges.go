@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ChallengeSolve a team which solved a challenge
+type ChallengeSolve struct {
+	Team     string    `json:"team"`
+	SolvedAt time.Time `json:"solvedAt"`
+}
+
+// ChallengeDistribution all solves of a single challenge, ordered by time
+type ChallengeDistribution struct {
+	Challenge int              `json:"challenge"`
+	Solves    []ChallengeSolve `json:"solves"`
+}
+
+// ChallengeDistributionResponse json format of the challenge distribution response
+type ChallengeDistributionResponse struct {
+	Challenges []ChallengeDistribution `json:"challenges"`
+}
+
+// computeChallengeDistribution groups the solves of all teams by challenge
+func computeChallengeDistribution(teams []TeamProgress) []ChallengeDistribution {
+	solvesByChallenge := map[int][]ChallengeSolve{}
+	for _, team := range teams {
+		for challenge, solvedAt := range team.Solves {
+			solvesByChallenge[challenge] = append(solvesByChallenge[challenge], ChallengeSolve{Team: team.Team, SolvedAt: solvedAt})
+		}
+	}
+
+	distribution := []ChallengeDistribution{}
+	for challenge, solves := range solvesByChallenge {
+		sort.Slice(solves, func(i, j int) bool {
+			if !solves[i].SolvedAt.Equal(solves[j].SolvedAt) {
+				return solves[i].SolvedAt.Before(solves[j].SolvedAt)
+			}
+			return solves[i].Team < solves[j].Team
+		})
+		distribution = append(distribution, ChallengeDistribution{Challenge: challenge, Solves: solves})
+	}
+	sort.Slice(distribution, func(i, j int) bool { return distribution[i].Challenge < distribution[j].Challenge })
+	return distribution
+}
+
+// handleChallengeDistribution serves which teams solved each challenge and when
+func (s *Server) handleChallengeDistribution(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeConditionalJSON(w, req, ChallengeDistributionResponse{Challenges: computeChallengeDistribution(teams)}, time.Time{})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestComputesChallengeDistribution(t *testing.T) {
+	distribution := computeChallengeDistribution(createStatisticsTeams())
+
+	assert.Equal(t, []ChallengeDistribution{
+		{Challenge: 1, Solves: []ChallengeSolve{
+			{Team: "first", SolvedAt: eventStart.Add(10 * time.Minute)},
+			{Team: "second", SolvedAt: eventStart.Add(30 * time.Minute)},
+		}},
+		{Challenge: 2, Solves: []ChallengeSolve{
+			{Team: "first", SolvedAt: eventStart.Add(70 * time.Minute)},
+			{Team: "second", SolvedAt: eventStart.Add(75 * time.Minute)},
+		}},
+		{Challenge: 3, Solves: []ChallengeSolve{
+			{Team: "first", SolvedAt: eventStart.Add(80 * time.Minute)},
+		}},
+	}, distribution)
+}
+
+func TestChallengeDistributionEndpoint(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "", "1")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"7":"2021-05-01T10:00:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/statistics/challenges"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := ChallengeDistributionResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []ChallengeDistribution{
+		{Challenge: 7, Solves: []ChallengeSolve{{Team: "foobar", SolvedAt: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)}}},
+	}, response.Challenges)
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runCommand runs the cli command named by the first argument and returns the exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "statistics", "sync", "rebuild", "import", "bulk", "snapshot", "reconcile":
+		if getEnv("PROGRESS_STORE", "annotations") != "annotations" {
+			// the commands read and write the progress like the watchdog itself
+			progressStore = withDryRun(createProgressStore(context.Background(), createClientset(), defaultWatchedNamespace(detectInstanceIdentity().Namespace)))
+		}
+	}
+	switch args[0] {
+	case "statistics":
+		return runStatisticsCommand(args[1:], os.Stdout)
+	case "sync":
+		return runSyncCommand(args[1:], os.Stdout)
+	case "rebuild":
+		return runRebuildCommand(args[1:], os.Stdout)
+	case "import":
+		return runImportCommand(args[1:], os.Stdin, os.Stdout)
+	case "bulk":
+		return runBulkCommand(args[1:], os.Stdout)
+	case "snapshot":
+		return runSnapshotCommand(args[1:], os.Stdin, os.Stdout)
+	case "reconcile":
+		return runReconcileCommand(args[1:], os.Stdout)
+	case "results":
+		return runResultsCommand(args[1:], os.Stdout)
+	case "aggregate":
+		return runAggregateCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'. Available commands: statistics, sync, rebuild, import, bulk, snapshot, reconcile, results, aggregate\n", args[0])
+		return 2
+	}
+}
+
+// runStatisticsCommand prints the event statistics, e.g. via `kubectl exec deploy/progress-watchdog -- /home/app/progress-watchdog statistics --format csv`.
+// With `--format xlsx` the final results are written as Excel workbook, e.g. `... statistics --format xlsx > results.xlsx`
+func runStatisticsCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("statistics", flag.ContinueOnError)
+	format := flags.String("format", "json", "Output format, either 'json', 'csv' or 'xlsx'")
+	timeZoneName := flags.String("time-zone", getEnv("TIME_ZONE", "UTC"), "Time zone the hourly statistics are grouped and rendered in, e.g. 'Europe/Berlin'")
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments, empty for all namespaces")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	location, err := parseTimeZone(*timeZoneName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	scoring, err := scoringFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	teams, err := listTeamProgress(context.Background(), createClientset(), *namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list teams: %s\n", err)
+		return 1
+	}
+	for i := range teams {
+		teams[i].Score = scoring.Score(teams[i])
+	}
+	statistics := computeStatistics(teams, location)
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(statistics)
+	case "csv":
+		err = writeStatisticsCSV(out, statistics)
+	case "xlsx":
+		err = writeResultsXLSX(out, teams, scoring, location)
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported format '%s', use 'json', 'csv' or 'xlsx'\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write statistics: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runSyncCommand immediately syncs the progress of a single team, e.g. via `kubectl exec deploy/progress-watchdog -- /home/app/progress-watchdog sync <team>`
+func runSyncCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: sync [--namespace <namespace>] <team>")
+		return 2
+	}
+	teamname := flags.Arg(0)
+
+	// the scoreboard cache of the running watchdog expires on its own, this one only exists for the sync
+	result, err := syncTeam(context.Background(), createClientset(), *namespace, teamname, NewScoreboardCache(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to sync team '%s': %s\n", teamname, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Synced team '%s': %s\n", teamname, result)
+	return 0
+}
+
+// runRebuildCommand rebuilds the cached progress of a single team or, with `--all`, of every team from their JuiceShop instances
+func runRebuildCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("rebuild", flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments")
+	all := flags.Bool("all", false, "Rebuild the progress of all teams")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (*all && flags.NArg() != 0) || (!*all && flags.NArg() != 1) {
+		fmt.Fprintln(os.Stderr, "Usage: rebuild [--namespace <namespace>] (--all | <team>)")
+		return 2
+	}
+
+	clientset := createClientset()
+	results := []RebuildResult{}
+	if *all {
+		var err error
+		results, err = rebuildAllTeams(context.Background(), clientset, *namespace, NewScoreboardCache(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list teams: %s\n", err)
+			return 1
+		}
+	} else {
+		result, err := rebuildTeam(context.Background(), clientset, *namespace, flags.Arg(0), NewScoreboardCache(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to rebuild team '%s': %s\n", flags.Arg(0), err)
+			return 1
+		}
+		results = append(results, result)
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(out, "%s: failed: %s\n", result.Team, result.Error)
+			exitCode = 1
+			continue
+		}
+		fmt.Fprintf(out, "%s: %d challenges solved (%d were cached)\n", result.Team, result.ChallengesSolved, result.PreviouslySolved)
+	}
+	return exitCode
+}
+
+// runImportCommand imports the progress of a team read as json from stdin or a file, e.g. `... import <team> < progress.json`
+// with `{"continueCode": "...", "solves": {"1": "2021-05-01T10:00:00Z"}}`, see ProgressImport
+func runImportCommand(args []string, in io.Reader, out io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments")
+	file := flags.String("file", "", "File the progress is read from, defaults to stdin")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: import [--namespace <namespace>] [--file <file>] <team>")
+		return 2
+	}
+	teamname := flags.Arg(0)
+
+	if *file != "" {
+		input, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open progress file: %s\n", err)
+			return 1
+		}
+		defer input.Close()
+		in = input
+	}
+	progress := ProgressImport{}
+	if err := json.NewDecoder(in).Decode(&progress); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read progress: %s\n", err)
+		return 2
+	}
+
+	result, err := importProgress(context.Background(), createClientset(), *namespace, teamname, progress, NewScoreboardCache(0), time.Now())
+	if err != nil && result.Team == "" {
+		fmt.Fprintf(os.Stderr, "Failed to import progress of team '%s': %s\n", teamname, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Imported progress of team '%s': %d challenges solved, %d newly imported\n", teamname, result.ChallengesSolved, result.Imported)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to apply the imported progress, it gets applied by the next progress update: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runBulkCommand applies an admin action to all teams matching the selector, e.g. `progress-watchdog bulk --idle-for 2h --dry-run archive`.
+// Without `--dry-run` the action is applied and the result of every team is printed.
+func runBulkCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("bulk", flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments, empty for all namespaces")
+	labelSelector := flags.String("selector", "", "Label selector the JuiceShop deployments of the teams must match, e.g. 'event=workshop-1'")
+	teams := flags.String("teams", "", "Comma separated list of the teams to select")
+	idleFor := flags.String("idle-for", "", "Only select teams without a request to their JuiceShop for at least this duration, e.g. '2h'")
+	dryRun := flags.Bool("dry-run", false, "Only list the selected teams without changing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || !isBulkAction(BulkAction(flags.Arg(0))) {
+		fmt.Fprintf(os.Stderr, "Usage: bulk [--namespace <namespace>] [--selector <labels>] [--teams <teams>] [--idle-for <duration>] [--dry-run] <action>\nAvailable actions: %v\n", bulkActions)
+		return 2
+	}
+	request := BulkRequest{
+		Action:   BulkAction(flags.Arg(0)),
+		Selector: TeamSelector{Teams: parseCommaSeparatedList(*teams), LabelSelector: *labelSelector, IdleFor: *idleFor},
+		DryRun:   *dryRun,
+	}
+	if err := request.Selector.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	results, err := runBulkAction(context.Background(), createClientset(), *namespace, request, NewScoreboardCache(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to select teams: %s\n", err)
+		return 1
+	}
+	return printBulkResults(out, *namespace, request, results)
+}
+
+// displayedTeamName qualifies the name of the team with its namespace if it isn't the namespace of the command, e.g. if all namespaces were selected
+func displayedTeamName(namespace, teamNamespace, teamname string) string {
+	if teamNamespace == namespace {
+		return teamname
+	}
+	return teamKey(teamNamespace, teamname)
+}
+
+// printBulkResults prints one line per team and returns the exit code, 1 if the action failed for any team
+func printBulkResults(out io.Writer, namespace string, request BulkRequest, results []BulkResult) int {
+	if request.DryRun {
+		fmt.Fprintf(out, "Dry run, '%s' would be applied to %d teams:\n", request.Action, len(results))
+	}
+	exitCode := 0
+	for _, result := range results {
+		teamname := displayedTeamName(namespace, result.Namespace, result.Team)
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(out, "%s: failed: %s\n", teamname, result.Error)
+			exitCode = 1
+		case result.Passcode != "":
+			fmt.Fprintf(out, "%s: %s, new passcode: %s\n", teamname, result.Detail, result.Passcode)
+		default:
+			fmt.Fprintf(out, "%s: %s\n", teamname, result.Detail)
+		}
+	}
+	return exitCode
+}
+
+// runSnapshotCommand creates or restores an encrypted snapshot of the whole event, e.g. to pause an event spanning multiple weekends:
+// `kubectl exec deploy/progress-watchdog -- /home/app/progress-watchdog snapshot create > event.snapshot` and later
+// `kubectl exec -i deploy/progress-watchdog -- /home/app/progress-watchdog snapshot restore < event.snapshot`
+func runSnapshotCommand(args []string, in io.Reader, out io.Writer) int {
+	if len(args) == 0 || (args[0] != "create" && args[0] != "restore") {
+		fmt.Fprintln(os.Stderr, "Usage: snapshot (create | restore) [--namespace <namespace>] [--passphrase-file <file>] [--file <file>] [--dry-run]")
+		return 2
+	}
+	flags := flag.NewFlagSet("snapshot "+args[0], flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments, empty for all namespaces. A restore then recreates the teams in their snapshotted namespaces")
+	passphraseFile := flags.String("passphrase-file", "", "File containing the passphrase the snapshot is encrypted with, defaults to the SNAPSHOT_PASSPHRASE env var")
+	file := flags.String("file", "", "File the snapshot is written to or read from, defaults to stdout / stdin")
+	dryRun := flags.Bool("dry-run", false, "Only report what a restore would do")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	passphrase, err := readSnapshotPassphrase(*passphraseFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if args[0] == "create" {
+		scoring, err := scoringFromEnv()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		snapshot, err := createSnapshot(context.Background(), createClientset(), *namespace, scoring, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *file != "" {
+			output, err := os.Create(*file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create snapshot file: %s\n", err)
+				return 1
+			}
+			defer output.Close()
+			out = output
+		}
+		if err := writeSnapshot(out, snapshot, passphrase); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write snapshot: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Created snapshot of %d teams\n", len(snapshot.Teams))
+		return 0
+	}
+
+	if *file != "" {
+		input, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open snapshot file: %s\n", err)
+			return 1
+		}
+		defer input.Close()
+		in = input
+	}
+	snapshot, err := readSnapshot(in, passphrase)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Restoring snapshot of %d teams created at %s\n", len(snapshot.Teams), snapshot.CreatedAt.Format(time.RFC3339))
+	for _, difference := range configDifferences(snapshot.Config, func(name string) string { return getEnv(name, "") }) {
+		fmt.Fprintf(out, "Warning: %s, update the helm values to restore it\n", difference)
+	}
+
+	exitCode := 0
+	for _, result := range restoreSnapshot(context.Background(), createClientset(), *namespace, snapshot, *dryRun, time.Now()) {
+		teamname := displayedTeamName(*namespace, result.Namespace, result.Team)
+		if result.Error != "" {
+			fmt.Fprintf(out, "%s: failed: %s\n", teamname, result.Error)
+			exitCode = 1
+			continue
+		}
+		if *dryRun {
+			fmt.Fprintf(out, "%s: would be %s\n", teamname, result.Action)
+		} else {
+			fmt.Fprintf(out, "%s: %s\n", teamname, result.Action)
+		}
+	}
+	return exitCode
+}
+
+// runReconcileCommand heals the progress records of the cluster from the latest backup in `BACKUP_DIR` or `BACKUP_S3_BUCKET` and prints what was healed.
+// The watchdog does this on every start, the command allows to preview it with `--dry-run`.
+func runReconcileCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments, empty for all namespaces")
+	dir := flags.String("dir", os.Getenv("BACKUP_DIR"), "Directory containing the backups")
+	passphraseFile := flags.String("passphrase-file", "", "File containing the passphrase the backups are encrypted with, defaults to the SNAPSHOT_PASSPHRASE env var")
+	dryRun := flags.Bool("dry-run", false, "Only report what would be healed")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	var target BackupTarget = DirectoryBackupTarget(*dir)
+	if *dir == "" {
+		if target = createBackupTarget(); target == nil {
+			fmt.Fprintln(os.Stderr, "No backups configured, set --dir, BACKUP_DIR or BACKUP_S3_BUCKET")
+			return 2
+		}
+	}
+	passphrase, err := readSnapshotPassphrase(*passphraseFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	backup, ok, err := NewBackupStore(target, passphrase, 1).Latest(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No backup found in '%s'\n", target)
+		return 1
+	}
+	results, err := reconcileWithBackup(context.Background(), createClientset(), *namespace, backup, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list teams: %s\n", err)
+		return 1
+	}
+	return printReconcileResults(out, *namespace, backup, results)
+}
+
+// printReconcileResults prints the healing report and returns the exit code, 1 if healing failed for any team
+func printReconcileResults(out io.Writer, namespace string, backup Snapshot, results []ReconcileResult) int {
+	fmt.Fprintf(out, "Backup from %s contains %d teams, %d need healing\n", backup.CreatedAt.Format(time.RFC3339), len(backup.Teams), len(results))
+	exitCode := 0
+	for _, result := range results {
+		teamname := displayedTeamName(namespace, result.Namespace, result.Team)
+		switch {
+		case result.Missing:
+			fmt.Fprintf(out, "%s: missing, recreate it with `snapshot restore`\n", teamname)
+		case result.Error != "":
+			fmt.Fprintf(out, "%s: failed: %s\n", teamname, result.Error)
+			exitCode = 1
+		default:
+			fmt.Fprintf(out, "%s: %s\n", teamname, strings.Join(result.Healed, ", "))
+		}
+	}
+	return exitCode
+}
+
+// runResultsCommand exports the final results signed with the key from `RESULTS_SIGNING_KEY` (`results sign`) or verifies published
+// results against the public key (`results verify`), which doesn't need access to the cluster
+func runResultsCommand(args []string, out io.Writer) int {
+	if len(args) == 0 || (args[0] != "sign" && args[0] != "verify") {
+		fmt.Fprintln(os.Stderr, "Usage: results sign [--namespace <namespace>] [--key-file <file>] [--output <file>] [--signature <file>] [--public-key <file>]")
+		fmt.Fprintln(os.Stderr, "       results verify --public-key <file> <results> <signature>")
+		return 2
+	}
+	flags := flag.NewFlagSet("results "+args[0], flag.ContinueOnError)
+	namespace := flags.String("namespace", detectInstanceIdentity().Namespace, "Namespace of the JuiceShop deployments, empty for all namespaces")
+	keyFile := flags.String("key-file", "", "PEM encoded ed25519 private key the results are signed with, defaults to the RESULTS_SIGNING_KEY env var")
+	output := flags.String("output", "results.json", "File the results are written to")
+	signatureFile := flags.String("signature", "results.json.sig", "File the detached signature is written to")
+	publicKeyFile := flags.String("public-key", "", "File the public key is written to when signing, or read from when verifying")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if args[0] == "verify" {
+		if flags.NArg() != 2 || *publicKeyFile == "" {
+			fmt.Fprintln(os.Stderr, "Usage: results verify --public-key <file> <results> <signature>")
+			return 2
+		}
+		return verifyResultsFiles(out, *publicKeyFile, flags.Arg(0), flags.Arg(1))
+	}
+
+	keyPEM := []byte(os.Getenv("RESULTS_SIGNING_KEY"))
+	if *keyFile != "" {
+		var err error
+		keyPEM, err = ioutil.ReadFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read signing key: %s\n", err)
+			return 2
+		}
+	}
+	key, err := parseResultsSigningKey(keyPEM)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid signing key, set --key-file or RESULTS_SIGNING_KEY: %s\n", err)
+		return 2
+	}
+	scoring, err := scoringFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	teams, err := listTeamProgress(context.Background(), createClientset(), *namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list teams: %s\n", err)
+		return 1
+	}
+	for i := range teams {
+		teams[i].Score = scoring.Score(teams[i])
+	}
+	results := buildSignedResults(teams, time.Now())
+	encoded, signature, err := signResults(results, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to sign results: %s\n", err)
+		return 1
+	}
+	if err := ioutil.WriteFile(*output, encoded, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write results: %s\n", err)
+		return 1
+	}
+	if err := ioutil.WriteFile(*signatureFile, []byte(signature+"\n"), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write signature: %s\n", err)
+		return 1
+	}
+	if *publicKeyFile != "" {
+		publicKey, err := encodeResultsPublicKey(key)
+		if err == nil {
+			err = ioutil.WriteFile(*publicKeyFile, publicKey, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write public key: %s\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(out, "Signed results of %d teams with %d solves, chain head %s\n", len(results.Standings), len(results.Solves), results.ChainHead)
+	return 0
+}
+
+// verifyResultsFiles verifies signed results and prints the verified chain head
+func verifyResultsFiles(out io.Writer, publicKeyFile, resultsFile, signatureFile string) int {
+	publicKeyPEM, err := ioutil.ReadFile(publicKeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read public key: %s\n", err)
+		return 2
+	}
+	publicKey, err := parseResultsPublicKey(publicKeyPEM)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid public key: %s\n", err)
+		return 2
+	}
+	encoded, err := ioutil.ReadFile(resultsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read results: %s\n", err)
+		return 2
+	}
+	signature, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read signature: %s\n", err)
+		return 2
+	}
+
+	results, err := verifySignedResults(encoded, string(signature), publicKey)
+	if err != nil {
+		fmt.Fprintf(out, "Verification failed: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Results generated at %s are authentic: %d teams, %d solves, chain head %s\n", results.GeneratedAt.Format(time.RFC3339), len(results.Standings), len(results.Solves), results.ChainHead)
+	return 0
+}
+
+// runAggregateCommand runs the watchdog in aggregator mode, serving the combined scoreboard of multiple MultiJuicer installations.
+// Doesn't need access to a kubernetes cluster, e.g. `progress-watchdog aggregate --sources berlin=https://berlin.example.com,tokyo=https://tokyo.example.com`
+func runAggregateCommand(args []string) int {
+	flags := flag.NewFlagSet("aggregate", flag.ContinueOnError)
+	listenAddress := flags.String("listen", getEnv("LISTEN_ADDRESS", ":8080"), "Address to serve the combined scoreboard on")
+	sourcesValue := flags.String("sources", os.Getenv("FEDERATION_SOURCES"), "Comma separated list of 'name=url' entries of the installations to aggregate")
+	ttl := flags.Duration("ttl", 10*time.Second, "How long the scoreboards of the installations are cached")
+	allowedOrigins := flags.String("cors-allowed-origins", os.Getenv("CORS_ALLOWED_ORIGINS"), "Origins allowed to access the combined scoreboard from browsers")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	sources, err := parseFederationSources(*sourcesValue)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(sources) == 0 {
+		fmt.Fprintln(os.Stderr, "At least one installation to aggregate is required, set --sources or FEDERATION_SOURCES")
+		return 2
+	}
+
+	cors := &CORS{AllowedOrigins: parseCommaSeparatedList(*allowedOrigins), AllowedMethods: []string{"GET", "OPTIONS"}}
+	handler := NewFederationServer(NewFederation(sources, *ttl), NewRateLimiter(5, 10), cors)
+
+	log.Infof("Aggregating the scoreboards of %d installations, serving them on '%s'", len(sources), *listenAddress)
+	if err := http.ListenAndServe(*listenAddress, handler); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to serve combined scoreboard: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,3 @@
+package adminv1
+
+//go:generate oapi-codegen -generate types,client -package adminv1 -o client.gen.go ../../../openapi.yaml
@@ -0,0 +1,5 @@
+module github.com/iteratec/multi-juicer/progress-watchdog/clients/go
+
+go 1.16
+
+require github.com/deepmap/oapi-codegen v1.10.1
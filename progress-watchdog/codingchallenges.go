@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/speps/go-hashids"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CodingChallengeContinueCode describes one of the separate ContinueCodes newer JuiceShops use for the progress of the coding challenges
+type CodingChallengeContinueCode struct {
+	// Name of the phase of the coding challenges, `findIt` or `fixIt`
+	Name string
+	// AnnotationName is the name of the annotation the ContinueCode is cached in
+	AnnotationName string
+	// Salt the JuiceShop uses for the hashids of the ContinueCode
+	Salt string
+}
+
+// codingChallengeContinueCodes are the ContinueCodes of the "Find It" and "Fix It" phases of the coding challenges
+var codingChallengeContinueCodes = []CodingChallengeContinueCode{
+	{Name: "findIt", AnnotationName: "continueCodeFindIt", Salt: "this is the salt for findIt challenges"},
+	{Name: "fixIt", AnnotationName: "continueCodeFixIt", Salt: "yet another salt for the fixIt challenges"},
+}
+
+// errContinueCodeUnsupported is returned for ContinueCodes the JuiceShop doesn't know, e.g. coding challenge codes of versions before v12
+var errContinueCodeUnsupported = errors.New("ContinueCode isn't supported by the JuiceShop")
+
+// Annotation the ContinueCode is cached in
+func (c CodingChallengeContinueCode) Annotation() string {
+	return annotation(c.AnnotationName)
+}
+
+// Path of the api of the ContinueCode in the JuiceShop
+func (c CodingChallengeContinueCode) Path() string {
+	return "/rest/continue-code-" + c.Name
+}
+
+// Parse returns the challenges solved in the phase by the ContinueCode
+func (c CodingChallengeContinueCode) Parse(continueCode string) ([]int, error) {
+	decoded, err := c.hashID().DecodeWithError(continueCode)
+	if err != nil {
+		return make([]int, 0), err
+	}
+	return decoded, nil
+}
+
+// Encode creates the ContinueCode the JuiceShop would create for the challenges solved in the phase
+func (c CodingChallengeContinueCode) Encode(solvedChallenges []int) (string, error) {
+	if len(solvedChallenges) == 0 {
+		return "", nil
+	}
+	return c.hashID().Encode(mergeSolvedChallenges(solvedChallenges))
+}
+
+func (c CodingChallengeContinueCode) hashID() *hashids.HashID {
+	hd := hashids.NewData()
+	hd.Salt = c.Salt
+	hd.MinLength = 60
+	hd.Alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+
+	hashIDClient, _ := hashids.NewWithData(hd)
+	return hashIDClient
+}
+
+// codingChallengeCodesOf reads the cached coding challenge ContinueCodes from the annotations of a JuiceShop deployment, keyed by their name
+func codingChallengeCodesOf(annotations map[string]string) map[string]string {
+	codes := map[string]string{}
+	for _, code := range codingChallengeContinueCodes {
+		if value, ok := annotations[code.Annotation()]; ok {
+			codes[code.Name] = value
+		}
+	}
+	return codes
+}
+
+// syncCodingChallengeProgress compares the coding challenge ContinueCodes of the JuiceShop with the cached ones like the regular ContinueCode:
+// lost progress is restored by applying the cached code, new progress gets cached. JuiceShops without coding challenges are skipped.
+func syncCodingChallengeProgress(job ProgressUpdateJobs, clientset kubernetes.Interface) error {
+	baseURL := juiceShopURL(job.Namespace, job.Teamname)
+	annotations := map[string]string{}
+	for _, code := range codingChallengeContinueCodes {
+		current, err := fetchContinueCodeAt(job.Context(), baseURL+code.Path())
+		if err == errContinueCodeUnsupported {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to fetch %s ContinueCode: %v", code.Name, err)
+		}
+
+		cached := job.LastCodingChallengeCodes[code.Name]
+		currentSolved, _ := code.Parse(current)
+		cachedSolved, _ := code.Parse(cached)
+		switch CompareChallengeStates(currentSolved, cachedSolved) {
+		case ApplyCode:
+			log.Infof("Restoring cached %s progress of team '%s'", code.Name, job.Teamname)
+			if err := putContinueCodeAt(job.Context(), fmt.Sprintf("%s%s/apply/%s", baseURL, code.Path(), cached)); err != nil {
+				return fmt.Errorf("Failed to apply %s ContinueCode: %v", code.Name, err)
+			}
+			restoresApplied.Inc()
+			reapplied, err := fetchContinueCodeAt(job.Context(), baseURL+code.Path())
+			if err != nil {
+				return fmt.Errorf("Failed to fetch %s ContinueCode to reapply it: %v", code.Name, err)
+			}
+			reappliedSolved, _ := code.Parse(reapplied)
+			if CompareChallengeStates(reappliedSolved, cachedSolved) == NoOp {
+				continue
+			}
+			// like for the regular ContinueCode, a regressed state is never cached
+			merged, err := code.Encode(mergeSolvedChallenges(reappliedSolved, cachedSolved))
+			if err != nil {
+				return fmt.Errorf("Failed to encode merged %s ContinueCode: %v", code.Name, err)
+			}
+			if merged != cached {
+				annotations[code.Annotation()] = merged
+			}
+		case UpdateCache:
+			annotations[code.Annotation()] = current
+		}
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	log.Infof("Updating saved coding challenge ContinueCodes of team '%s'", job.Teamname)
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := kubernetesPacer.WaitForPatch(ctx); err != nil {
+		return err
+	}
+	_, err = workloads.Patch(ctx, clientset, job.Namespace, fmt.Sprintf("t-%s-juiceshop", job.Teamname), types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		patchErrors.Inc()
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var findIt = codingChallengeContinueCodes[0]
+
+func TestCodingChallengeContinueCodesRoundTrip(t *testing.T) {
+	continueCode, err := findIt.Encode([]int{12, 3})
+	assert.NoError(t, err)
+	solved, err := findIt.Parse(continueCode)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 12}, solved)
+
+	// the codes of the phases use different salts
+	_, err = codingChallengeContinueCodes[1].Parse(continueCode)
+	assert.Error(t, err)
+}
+
+func TestRestoresLostCodingChallengeProgress(t *testing.T) {
+	cachedFindIt, _ := findIt.Encode([]int{3, 12})
+	currentFindIt := ""
+	applied := []string{}
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/rest/continue-code-findIt":
+			w.Write([]byte(`{"continueCode":"` + currentFindIt + `"}`))
+		case strings.HasPrefix(req.URL.Path, "/rest/continue-code-findIt/apply/"):
+			currentFindIt = strings.TrimPrefix(req.URL.Path, "/rest/continue-code-findIt/apply/")
+			applied = append(applied, currentFindIt)
+		default:
+			// the fixIt phase isn't supported
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Annotations["multi-juicer.iteratec.dev/continueCodeFindIt"] = cachedFindIt
+	clientset := fake.NewSimpleClientset(deployment)
+
+	assert.NoError(t, syncCodingChallengeProgress(progressUpdateJobForDeployment(*deployment), clientset))
+	assert.Equal(t, []string{cachedFindIt}, applied)
+
+	// progress made afterwards gets cached
+	currentFindIt, _ = findIt.Encode([]int{3, 7, 12})
+	assert.NoError(t, syncCodingChallengeProgress(progressUpdateJobForDeployment(*deployment), clientset))
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, currentFindIt, updated.Annotations["multi-juicer.iteratec.dev/continueCodeFindIt"])
+	_, hasFixIt := updated.Annotations["multi-juicer.iteratec.dev/continueCodeFixIt"]
+	assert.False(t, hasFixIt)
+}
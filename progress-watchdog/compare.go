@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// TeamComparison the differences between the progress of two teams
+type TeamComparison struct {
+	Teams        [2]string                  `json:"teams"`
+	SolvedByBoth []int                      `json:"solvedByBoth"`
+	OnlySolvedBy map[string][]int           `json:"onlySolvedBy"`
+	Timelines    map[string][]TimelinePoint `json:"timelines"`
+	// Similarity is the jaccard index of the solved challenges, 1 meaning both teams solved exactly the same challenges
+	Similarity float64 `json:"similarity"`
+	// SameSolveOrder is true if both teams solved the challenges they have in common in the same order
+	SameSolveOrder bool `json:"sameSolveOrder"`
+}
+
+// solveOrder returns the passed challenges ordered by the time they were solved
+func solveOrder(solves SolveTimes, challenges []int) []int {
+	ordered := make([]int, len(challenges))
+	copy(ordered, challenges)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return solves[ordered[i]].Before(solves[ordered[j]])
+	})
+	return ordered
+}
+
+// compareTeams diffs the solved challenges and score timelines of two teams
+func compareTeams(a, b TeamProgress) TeamComparison {
+	comparison := TeamComparison{
+		Teams:        [2]string{a.Team, b.Team},
+		SolvedByBoth: []int{},
+		OnlySolvedBy: map[string][]int{a.Team: {}, b.Team: {}},
+		Timelines: map[string][]TimelinePoint{
+			a.Team: a.Solves.Timeline(),
+			b.Team: b.Solves.Timeline(),
+		},
+	}
+
+	for challenge := range a.Solves {
+		if _, ok := b.Solves[challenge]; ok {
+			comparison.SolvedByBoth = append(comparison.SolvedByBoth, challenge)
+		} else {
+			comparison.OnlySolvedBy[a.Team] = append(comparison.OnlySolvedBy[a.Team], challenge)
+		}
+	}
+	for challenge := range b.Solves {
+		if _, ok := a.Solves[challenge]; !ok {
+			comparison.OnlySolvedBy[b.Team] = append(comparison.OnlySolvedBy[b.Team], challenge)
+		}
+	}
+	sort.Ints(comparison.SolvedByBoth)
+	sort.Ints(comparison.OnlySolvedBy[a.Team])
+	sort.Ints(comparison.OnlySolvedBy[b.Team])
+
+	union := len(comparison.SolvedByBoth) + len(comparison.OnlySolvedBy[a.Team]) + len(comparison.OnlySolvedBy[b.Team])
+	if union > 0 {
+		comparison.Similarity = float64(len(comparison.SolvedByBoth)) / float64(union)
+	}
+
+	orderA := solveOrder(a.Solves, comparison.SolvedByBoth)
+	orderB := solveOrder(b.Solves, comparison.SolvedByBoth)
+	comparison.SameSolveOrder = len(orderA) > 0
+	for i := range orderA {
+		if orderA[i] != orderB[i] {
+			comparison.SameSolveOrder = false
+			break
+		}
+	}
+
+	return comparison
+}
+
+// handleCompareTeams serves the comparison of the two teams passed as the `a` and `b` query parameters.
+// If all namespaces are watched, both teams are looked up in the namespace passed as `namespace` query parameter.
+func (s *Server) handleCompareTeams(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamnameA := req.URL.Query().Get("a")
+	teamnameB := req.URL.Query().Get("b")
+	if teamnameA == "" || teamnameB == "" || teamnameA == teamnameB {
+		http.Error(w, "Two different teams have to be passed as the 'a' and 'b' query parameters", http.StatusBadRequest)
+		return
+	}
+
+	namespace, ok := s.teamNamespace(w, req)
+	if !ok {
+		return
+	}
+
+	teams := []TeamProgress{}
+	for _, teamname := range []string{teamnameA, teamnameB} {
+		progress, err := s.getTeamProgress(req.Context(), namespace, teamname)
+		if errors.IsNotFound(err) {
+			http.Error(w, "Team '"+teamname+"' not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		teams = append(teams, progress)
+	}
+
+	writeConditionalJSON(w, req, compareTeams(teams[0], teams[1]), time.Time{})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestComparesTeams(t *testing.T) {
+	a := TeamProgress{Team: "a", Solves: SolveTimes{
+		1: eventStart,
+		2: eventStart.Add(time.Minute),
+		3: eventStart.Add(2 * time.Minute),
+	}}
+	b := TeamProgress{Team: "b", Solves: SolveTimes{
+		1: eventStart.Add(time.Hour),
+		2: eventStart.Add(2 * time.Hour),
+		4: eventStart.Add(3 * time.Hour),
+	}}
+
+	comparison := compareTeams(a, b)
+
+	assert.Equal(t, [2]string{"a", "b"}, comparison.Teams)
+	assert.Equal(t, []int{1, 2}, comparison.SolvedByBoth)
+	assert.Equal(t, map[string][]int{"a": {3}, "b": {4}}, comparison.OnlySolvedBy)
+	assert.Equal(t, 0.5, comparison.Similarity)
+	assert.True(t, comparison.SameSolveOrder)
+	assert.Len(t, comparison.Timelines["a"], 3)
+	assert.Len(t, comparison.Timelines["b"], 3)
+}
+
+func TestDetectsDifferentSolveOrder(t *testing.T) {
+	a := TeamProgress{Team: "a", Solves: SolveTimes{1: eventStart, 2: eventStart.Add(time.Minute)}}
+	b := TeamProgress{Team: "b", Solves: SolveTimes{1: eventStart.Add(time.Minute), 2: eventStart}}
+
+	comparison := compareTeams(a, b)
+	assert.Equal(t, 1.0, comparison.Similarity)
+	assert.False(t, comparison.SameSolveOrder)
+}
+
+func TestComparesTeamsWithoutSolves(t *testing.T) {
+	comparison := compareTeams(TeamProgress{Team: "a", Solves: SolveTimes{}}, TeamProgress{Team: "b", Solves: SolveTimes{}})
+	assert.Equal(t, 0.0, comparison.Similarity)
+	assert.False(t, comparison.SameSolveOrder)
+	assert.Equal(t, []int{}, comparison.SolvedByBoth)
+}
+
+func TestCompareEndpointValidatesTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"), createJuiceShopDeployment("barfoo", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/compare?a=foobar&b=barfoo"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/compare?a=foobar"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/compare?a=foobar&b=unknown"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipResponseWriter compresses the response body if the handler writes one
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gzipWriter    *gzip.Writer
+	headerWritten bool
+	compress      bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.headerWritten {
+		return
+	}
+	w.headerWritten = true
+
+	header := w.Header()
+	w.compress = status != http.StatusNotModified && status != http.StatusNoContent && header.Get("Content-Encoding") == ""
+	if w.compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// the compressed body is only semantically equivalent to the uncompressed one
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.gzipWriter = gzipWriterPool.Get().(*gzip.Writer)
+		w.gzipWriter.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(body []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(body)
+	}
+	return w.gzipWriter.Write(body)
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gzipWriter == nil {
+		return
+	}
+	if err := w.gzipWriter.Close(); err != nil {
+		log.Warning("Failed to finish gzip compressed response")
+		log.Warning(err)
+	}
+	gzipWriterPool.Put(w.gzipWriter)
+	w.gzipWriter = nil
+}
+
+// acceptsGzip checks if the client accepts gzip encoded responses
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(strings.TrimSpace(encoding), ";")
+		if name := strings.TrimSpace(parts[0]); name != "gzip" && name != "*" {
+			continue
+		}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if quality, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil && quality == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressResponses gzip compresses responses for clients which accept it
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		gzipWriter := &gzipResponseWriter{ResponseWriter: w}
+		defer gzipWriter.close()
+		next.ServeHTTP(gzipWriter, req)
+	})
+}
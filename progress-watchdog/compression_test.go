@@ -0,0 +1,78 @@
+package main
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"deflate, gzip;q=1": true,
+		"br;q=1.0, *;q=0.5": true,
+		"gzip;q=0":          false,
+		"deflate, br":       false,
+	}
+	for acceptEncoding, expected := range cases {
+		req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		assert.Equal(t, expected, acceptsGzip(req), "Unexpected result for Accept-Encoding '%s'", acceptEncoding)
+	}
+}
+
+func TestCompressesResponsesForClientsAcceptingGzip(t *testing.T) {
+	body := strings.Repeat(`{"team":"foobar"}`, 100)
+	handler := compressResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Equal(t, `W/"abc"`, rr.Header().Get("ETag"), "ETag of compressed responses should be weak")
+
+	reader, err := gzip.NewReader(rr.Body)
+	assert.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+}
+
+func TestDoesNotCompressResponsesForOtherClients(t *testing.T) {
+	handler := compressResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard", nil))
+
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "plain", rr.Body.String())
+}
+
+func TestDoesNotCompressNotModifiedResponses(t *testing.T) {
+	handler := compressResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Body.String())
+}
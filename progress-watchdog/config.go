@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MountedConfig is the configuration of the watchdog read from a mounted ConfigMap, every key of the ConfigMap is a file named like the
+// env var it configures, e.g. `POLL_INTERVAL`. Kubernetes updates mounted ConfigMaps in place, so the config is reloaded periodically
+// and the settings which support it are applied without restarting the watchdog and losing its in-flight progress updates.
+type MountedConfig struct {
+	dir string
+
+	mutex   sync.RWMutex
+	values  map[string]string
+	reloads []configReload
+}
+
+// configReload applies the settings it's registered for after one of them changed
+type configReload struct {
+	names []string
+	apply func() error
+}
+
+// mountedConfig is the config of the running watchdog, configured via `CONFIG_DIR`. Nil without a mounted config.
+var mountedConfig *MountedConfig
+
+// loadMountedConfig loads the config from the directory configured via `CONFIG_DIR`, nil if none is configured
+func loadMountedConfig() (*MountedConfig, error) {
+	dir := os.Getenv("CONFIG_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	return LoadMountedConfig(dir)
+}
+
+// LoadMountedConfig reads the config from the directory the ConfigMap is mounted to
+func LoadMountedConfig(dir string) (*MountedConfig, error) {
+	values, err := readConfigDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &MountedConfig{dir: dir, values: values}, nil
+}
+
+// readConfigDir reads the settings of a mounted ConfigMap. The hidden files and directories kubernetes uses to swap the ConfigMap atomically,
+// e.g. `..data`, are skipped.
+func readConfigDir(dir string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config directory '%s': %v", dir, err)
+	}
+	values := map[string]string{}
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		// keys of ConfigMaps are symlinks into the current `..data` directory
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read config '%s': %v", file.Name(), err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read config '%s': %v", file.Name(), err)
+		}
+		values[file.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+	return values, nil
+}
+
+// Lookup returns the value of the setting, false if the ConfigMap doesn't contain it
+func (c *MountedConfig) Lookup(name string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	value, ok := c.values[name]
+	return value, ok
+}
+
+// OnChange registers a function applying the settings with the names after one of them changed. Changes of settings without
+// a registered function are only applied after a restart.
+func (c *MountedConfig) OnChange(apply func() error, names ...string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reloads = append(c.reloads, configReload{names: names, apply: apply})
+}
+
+// Reload rereads the ConfigMap and applies the changed settings. Invalid settings are logged and don't stop the other settings from being
+// applied, the watchdog keeps running with the last valid value until the ConfigMap gets fixed. Returns the names of the changed settings.
+func (c *MountedConfig) Reload() ([]string, error) {
+	values, err := readConfigDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	changed := changedConfigNames(c.values, values)
+	c.values = values
+	reloads := c.reloads
+	c.mutex.Unlock()
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	log.Infof("Config changed: %s", strings.Join(changed, ", "))
+	applied := map[string]bool{}
+	for _, reload := range reloads {
+		if !containsAny(changed, reload.names) {
+			continue
+		}
+		for _, name := range reload.names {
+			applied[name] = true
+		}
+		if err := reload.apply(); err != nil {
+			log.Errorf("Failed to apply changed config: %v", err)
+		}
+	}
+	for _, name := range changed {
+		if !applied[name] {
+			log.Warningf("Changed config %s is only applied after a restart of the watchdog", name)
+		}
+	}
+	return changed, nil
+}
+
+// Watch reloads the config every interval until the context is cancelled
+func (c *MountedConfig) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Reload(); err != nil {
+				log.Warningf("Failed to reload config: %v", err)
+			}
+		}
+	}
+}
+
+// changedConfigNames lists the settings added, changed or removed between the configs, sorted by name
+func changedConfigNames(previous, current map[string]string) []string {
+	changed := []string{}
+	for name, value := range current {
+		if previousValue, ok := previous[name]; !ok || previousValue != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// containsAny checks if any of the candidates is one of the values
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupEnv returns the setting from the mounted config or, if it doesn't contain it, from the env var.
+// Settings of the ConfigMap take precedence, as they can be changed while the watchdog is running.
+func lookupEnv(name string) (string, bool) {
+	if value, ok := mountedConfig.Lookup(name); ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// configReloadInterval is the interval in which the mounted config is checked for changes, kubernetes itself only updates mounted
+// ConfigMaps about every minute
+const configReloadInterval = 10 * time.Second
+
+// registerConfigReloads registers the settings which are applied without a restart when they change in the mounted config
+func registerConfigReloads(c *MountedConfig) {
+	c.OnChange(func() error {
+		level, err := parseLogLevel(getEnv("LOG_LEVEL", "info"))
+		if err != nil {
+			return err
+		}
+		setLogLevel(level)
+		return nil
+	}, "LOG_LEVEL")
+	c.OnChange(configureOutboundRateLimit, "OUTBOUND_RATE_LIMIT", "OUTBOUND_RATE_LIMIT_BURST", "OUTBOUND_RATE_LIMIT_PER_HOST", "OUTBOUND_RATE_LIMIT_PER_HOST_BURST")
+	c.OnChange(func() error {
+		parsed, err := parseJuiceShopServiceTemplate(getEnv("JUICE_SHOP_SERVICE_TEMPLATE", defaultJuiceShopServiceTemplate))
+		if err != nil {
+			return fmt.Errorf("Invalid JUICE_SHOP_SERVICE_TEMPLATE: %v", err)
+		}
+		setJuiceShopServiceTemplate(parsed)
+		return nil
+	}, "JUICE_SHOP_SERVICE_TEMPLATE")
+	c.OnChange(func() error {
+		configured, err := parseFeatureFlags(getEnv("FEATURE_FLAGS", ""))
+		if err != nil {
+			return fmt.Errorf("Invalid FEATURE_FLAGS: %v", err)
+		}
+		featureFlags.Configure(configured)
+		return nil
+	}, "FEATURE_FLAGS")
+}
+
+// configPrecedence documents where the settings of the watchdog are read from, printed with the usage of the flags
+const configPrecedence = `Every flag can also be set via the env var named like it in upper snake case, e.g. --poll-interval via POLL_INTERVAL.
+Settings are read in this order, the first one found is used:
+  1. flags passed on the command line
+  2. the ConfigMap mounted to CONFIG_DIR, with a file named like the env var per setting
+  3. env vars
+  4. the defaults of the flags`
+
+// envVarName is the env var of a flag, e.g. `POLL_INTERVAL` for `--poll-interval`
+func envVarName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// flagsFromEnv sets the flags which weren't passed on the command line from the mounted config or the env vars named like them.
+// Empty values are ignored like in getEnv.
+func flagsFromEnv(flags *flag.FlagSet) error {
+	passed := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		name := envVarName(f.Name)
+		value := getEnv(name, "")
+		if err != nil || passed[f.Name] || value == "" {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("Invalid %s: %s", name, value)
+		}
+	})
+	return err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mountConfigMap writes the values into the directory like kubernetes mounts ConfigMaps: into a hidden timestamped directory,
+// linked via `..data`, with a symlink per key
+func mountConfigMap(t *testing.T, dir string, values map[string]string) {
+	data, err := ioutil.TempDir(dir, "..data_")
+	assert.NoError(t, err)
+	for name, value := range values {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(data, name), []byte(value), 0644))
+	}
+	os.Remove(filepath.Join(dir, "..data_tmp"))
+	assert.NoError(t, os.Symlink(filepath.Base(data), filepath.Join(dir, "..data_tmp")))
+	assert.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, file := range files {
+		if file.Name()[0] != '.' {
+			assert.NoError(t, os.Remove(filepath.Join(dir, file.Name())))
+		}
+	}
+	for name := range values {
+		assert.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+}
+
+// useMountedConfig mounts the values as config of the watchdog for the test
+func useMountedConfig(t *testing.T, values map[string]string) string {
+	dir := t.TempDir()
+	mountConfigMap(t, dir, values)
+	config, err := LoadMountedConfig(dir)
+	assert.NoError(t, err)
+	previous := mountedConfig
+	mountedConfig = config
+	t.Cleanup(func() { mountedConfig = previous })
+	return dir
+}
+
+func TestReadsTheSettingsOfMountedConfigMaps(t *testing.T) {
+	dir := t.TempDir()
+	mountConfigMap(t, dir, map[string]string{"POLL_INTERVAL": "30s\n", "JUICE_SHOP_SELECTOR": "app=juice-shop"})
+
+	config, err := LoadMountedConfig(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"POLL_INTERVAL": "30s", "JUICE_SHOP_SELECTOR": "app=juice-shop"}, config.values)
+
+	_, err = LoadMountedConfig(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestSettingsOfTheMountedConfigTakePrecedenceOverEnvVars(t *testing.T) {
+	os.Setenv("POLL_INTERVAL", "5s")
+	defer os.Unsetenv("POLL_INTERVAL")
+	os.Setenv("WORKERS", "20")
+	defer os.Unsetenv("WORKERS")
+	useMountedConfig(t, map[string]string{"POLL_INTERVAL": "30s", "NAMESPACE": ""})
+
+	assert.Equal(t, "30s", getEnv("POLL_INTERVAL", "1s"))
+	assert.Equal(t, "20", getEnv("WORKERS", "10"))
+	assert.Equal(t, "info", getEnv("LOG_LEVEL", "info"))
+	assert.Equal(t, "", defaultWatchedNamespace("juicy-ctf"))
+}
+
+func TestReloadAppliesTheChangedSettings(t *testing.T) {
+	dir := useMountedConfig(t, map[string]string{"LOG_LEVEL": "info", "WORKERS": "10"})
+	applied := 0
+	mountedConfig.OnChange(func() error {
+		applied++
+		return nil
+	}, "LOG_LEVEL")
+
+	changed, err := mountedConfig.Reload()
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	mountConfigMap(t, dir, map[string]string{"LOG_LEVEL": "debug", "WORKERS": "10"})
+	changed, err = mountedConfig.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"LOG_LEVEL"}, changed)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, "debug", getEnv("LOG_LEVEL", "info"))
+
+	mountConfigMap(t, dir, map[string]string{"LOG_LEVEL": "debug", "WORKERS": "20", "POLL_INTERVAL": "10s"})
+	changed, err = mountedConfig.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POLL_INTERVAL", "WORKERS"}, changed)
+	assert.Equal(t, 1, applied)
+}
+
+func TestChangedServiceTemplatesAndFeatureFlagsApplyWithoutRestart(t *testing.T) {
+	previousTemplate := juiceShopServiceTemplate
+	defer setJuiceShopServiceTemplate(previousTemplate)
+	defer featureFlags.Configure(nil)
+	dir := useMountedConfig(t, map[string]string{})
+	registerConfigReloads(mountedConfig)
+
+	mountConfigMap(t, dir, map[string]string{"JUICE_SHOP_SERVICE_TEMPLATE": "{{ .Team }}-shop", "FEATURE_FLAGS": "replicaMerge=false"})
+	_, err := mountedConfig.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://foobar-shop:3000", juiceShopURL("default", "foobar"))
+	assert.False(t, featureFlags.Enabled(FeatureReplicaMerge))
+
+	// invalid settings are skipped, the watchdog keeps running with the previous ones
+	mountConfigMap(t, dir, map[string]string{"JUICE_SHOP_SERVICE_TEMPLATE": "{{ .Teams }}", "FEATURE_FLAGS": "replicaMerge=true"})
+	_, err = mountedConfig.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://foobar-shop:3000", juiceShopURL("default", "foobar"))
+	assert.True(t, featureFlags.Enabled(FeatureReplicaMerge))
+}
+
+func TestFlagsFallBackToTheirEnvVars(t *testing.T) {
+	os.Setenv("POLL_INTERVAL", "30s")
+	defer os.Unsetenv("POLL_INTERVAL")
+	os.Setenv("WORKERS", "20")
+	defer os.Unsetenv("WORKERS")
+	os.Setenv("CONNECT_MODE", "pod-ip")
+	defer os.Unsetenv("CONNECT_MODE")
+	useMountedConfig(t, map[string]string{"CONNECT_MODE": "service"})
+
+	flags := flag.NewFlagSet("progress-watchdog", flag.ContinueOnError)
+	pollInterval := flags.String("poll-interval", "5s", "")
+	workers := flags.String("workers", "10", "")
+	connectMode := flags.String("connect-mode", "pod-ip", "")
+	logFormat := flags.String("log-format", "text", "")
+	assert.NoError(t, flags.Parse([]string{"--workers", "5"}))
+	assert.NoError(t, flagsFromEnv(flags))
+
+	assert.Equal(t, "30s", *pollInterval)
+	assert.Equal(t, "5", *workers)
+	assert.Equal(t, "service", *connectMode)
+	assert.Equal(t, "text", *logFormat)
+}
+
+func TestFlagsFromEnvRejectInvalidValues(t *testing.T) {
+	os.Setenv("WORKERS", "many")
+	defer os.Unsetenv("WORKERS")
+
+	flags := flag.NewFlagSet("progress-watchdog", flag.ContinueOnError)
+	flags.Int("workers", 10, "")
+	assert.NoError(t, flags.Parse(nil))
+	assert.EqualError(t, flagsFromEnv(flags), "Invalid WORKERS: many")
+}
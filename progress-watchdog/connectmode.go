@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// ConnectMode is how the watchdog connects to the JuiceShops, configured via `--connect-mode`
+type ConnectMode string
+
+const (
+	// ConnectModeService connects to the JuiceShops through their services
+	ConnectModeService ConnectMode = "service"
+	// ConnectModePodIP connects to a ready pod of the JuiceShops directly, for clusters without reliable internal DNS or with headless services
+	ConnectModePodIP ConnectMode = "pod-ip"
+)
+
+// podIPResolver resolves the pod IPs of the JuiceShops in the `pod-ip` connect mode, nil in the `service` mode
+var podIPResolver *PodIPResolver
+
+// PodIPResolver resolves the IP of a ready JuiceShop pod of a team from an informer cache, so that resolving doesn't add requests against the kubernetes api
+type PodIPResolver struct {
+	lister corelisters.PodLister
+}
+
+// NewPodIPResolver creates a PodIPResolver for the JuiceShop pods of the namespace, its informer runs until the context is cancelled
+func NewPodIPResolver(ctx context.Context, clientset kubernetes.Interface, namespace string) (*PodIPResolver, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = juiceShopSelector
+	}))
+	lister := factory.Core().V1().Pods().Lister()
+	factory.Start(ctx.Done())
+	for _, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("Failed to sync the JuiceShop pods")
+		}
+	}
+	return &PodIPResolver{lister: lister}, nil
+}
+
+// Resolve returns the IP of a ready pod of the team. The pods are sorted by name, so that the requests of a job go to the same pod.
+func (r *PodIPResolver) Resolve(namespace, teamname string) (string, bool) {
+	pods, err := r.lister.Pods(namespace).List(labels.SelectorFromSet(labels.Set{"team": teamname}))
+	if err != nil {
+		return "", false
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && pod.Status.PodIP != "" && isPodReady(*pod) {
+			return pod.Status.PodIP, true
+		}
+	}
+	return "", false
+}
+
+// createPodIPResolver creates the resolver of the connect mode, nil for the `service` mode
+func createPodIPResolver(ctx context.Context, clientset kubernetes.Interface, namespace string, mode ConnectMode) *PodIPResolver {
+	switch mode {
+	case ConnectModeService:
+		return nil
+	case ConnectModePodIP:
+		resolver, err := NewPodIPResolver(ctx, clientset, namespace)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the pod IP resolver: %v", err))
+		}
+		return resolver
+	default:
+		panic(fmt.Sprintf("Invalid connect mode: %s", mode))
+	}
+}
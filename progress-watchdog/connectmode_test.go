@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestJuiceShopURLAddressesAReadyPodInPodIPConnectMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopPod("t-foobar-juiceshop-a", "foobar", "10.0.0.1", false),
+		createJuiceShopPod("t-foobar-juiceshop-b", "foobar", "10.0.0.2", true),
+		createJuiceShopPod("t-other-juiceshop-a", "other", "10.0.0.3", true),
+	)
+	previous := podIPResolver
+	defer func() { podIPResolver = previous }()
+	podIPResolver = createPodIPResolver(ctx, clientset, "default", ConnectModePodIP)
+
+	assert.Equal(t, "http://10.0.0.2:3000", juiceShopURL("default", "foobar"))
+	assert.Equal(t, "http://t-missing-juiceshop.default.svc.cluster.local:3000", juiceShopURL("default", "missing"), "Should fall back to the service of teams without ready pod")
+}
+
+func TestCreatePodIPResolverReturnsNoResolverInServiceConnectMode(t *testing.T) {
+	assert.Nil(t, createPodIPResolver(context.Background(), fake.NewSimpleClientset(), "default", ConnectModeService))
+	assert.Panics(t, func() { createPodIPResolver(context.Background(), fake.NewSimpleClientset(), "default", "dns") })
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS configures which origins are allowed to access the api from browsers
+type CORS struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+}
+
+// parseCommaSeparatedList splits a comma separated config value into its trimmed, non empty entries
+func parseCommaSeparatedList(value string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler adds CORS headers for allowed origins and answers preflight requests.
+// Without any allowed origins no CORS headers are sent at all.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" || len(c.AllowedOrigins) == 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !c.isOriginAllowed(origin) {
+			log.Debugf("Origin '%s' is not allowed to access '%s'", origin, req.URL.Path)
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Retry-After")
+
+		isPreflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+		if !isPreflight {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, If-None-Match, If-Modified-Since")
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsesCommaSeparatedLists(t *testing.T) {
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, parseCommaSeparatedList(" https://a.example,,https://b.example "))
+	assert.Equal(t, []string{}, parseCommaSeparatedList(""))
+}
+
+func TestCORSAddsHeadersForAllowedOrigins(t *testing.T) {
+	cors := &CORS{AllowedOrigins: []string{"https://scoreboard.example"}, AllowedMethods: []string{"GET", "OPTIONS"}}
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("Origin", "https://scoreboard.example")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "https://scoreboard.example", rr.Header().Get("Access-Control-Allow-Origin"))
+
+	req = httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAnswersPreflightRequests(t *testing.T) {
+	cors := &CORS{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "OPTIONS"}}
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Preflight requests should not be passed on")
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/api/progress", nil)
+	req.Header.Set("Origin", "https://scoreboard.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://scoreboard.example", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestCORSIsDisabledWithoutAllowedOrigins(t *testing.T) {
+	handler := (&CORS{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("Origin", "https://scoreboard.example")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
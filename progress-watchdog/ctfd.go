@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ctfdChallengeFetchAttempts is the number of JuiceShops the names of the challenges are requested from before the sync is retried
+const ctfdChallengeFetchAttempts = 3
+
+// CTFdExporter pushes the solves of the teams into a CTFd instance (https://ctfd.io) via its REST api, so that events running the JuiceShop
+// alongside other CTF challenges get one unified scoreboard. Every team is synced into a CTFd team of the same name, named `{namespace}/{team}`
+// if the teams of all namespaces are synced. Every solve is recorded as correct submission of the CTFd challenge named like the JuiceShop challenge,
+// e.g. as imported by juice-shop-ctf-cli.
+// CTFd has to run in team mode. Solves are only ever added, solves of reset or deleted teams are kept in CTFd.
+type CTFdExporter struct {
+	url    string
+	token  string
+	client *http.Client
+	// namespace of the synced teams, metav1.NamespaceAll if the teams of all namespaces are synced
+	namespace string
+	// challengeNames returns the names of the JuiceShop challenges by their id, fetched from the JuiceShop of one of the teams
+	challengeNames func(ctx context.Context, teams []TeamProgress) (map[int]string, error)
+	// names of the JuiceShop challenges, only fetched once as they don't change during an event
+	names map[int]string
+}
+
+// ctfdResponse json format of the CTFd api responses
+type ctfdResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Meta    struct {
+		Pagination struct {
+			Next *int `json:"next"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// ctfdObject a team, challenge or solve returned by the CTFd api, only the fields used by the exporter are decoded
+type ctfdObject struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ChallengeID int    `json:"challenge_id"`
+}
+
+// ctfdSubmission json format of a submission created via the CTFd admin api
+type ctfdSubmission struct {
+	ChallengeID int       `json:"challenge_id"`
+	TeamID      int       `json:"team_id"`
+	Provided    string    `json:"provided"`
+	Type        string    `json:"type"`
+	Date        time.Time `json:"date"`
+}
+
+// NewCTFdExporter creates an exporter pushing the solves of the teams in the namespace into the CTFd at the url, authenticated with the access token of a CTFd admin
+func NewCTFdExporter(url, token, namespace string, client *http.Client) *CTFdExporter {
+	return &CTFdExporter{
+		url:            strings.TrimSuffix(url, "/"),
+		token:          token,
+		client:         client,
+		namespace:      namespace,
+		challengeNames: fetchChallengeNames,
+	}
+}
+
+// createCTFdExporter creates the exporter configured via `CTFD_URL` and `CTFD_ACCESS_TOKEN`, nil if no CTFd is configured
+func createCTFdExporter(namespace string) *CTFdExporter {
+	url := os.Getenv("CTFD_URL")
+	if url == "" {
+		return nil
+	}
+	token := os.Getenv("CTFD_ACCESS_TOKEN")
+	if token == "" {
+		panic("CTFD_URL requires an access token of a CTFd admin in CTFD_ACCESS_TOKEN")
+	}
+	return NewCTFdExporter(url, token, namespace, &http.Client{Timeout: 10 * time.Second, Transport: newTaggingRoundTripper(http.DefaultTransport)})
+}
+
+// fetchChallengeNames fetches the names of the challenges from the challenge api of the first JuiceShop which responds, trying ctfdChallengeFetchAttempts teams
+func fetchChallengeNames(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+	var lastErr error = fmt.Errorf("No JuiceShop to fetch the challenges from")
+	for i, team := range teams {
+		if i == ctfdChallengeFetchAttempts {
+			break
+		}
+		statuses, err := getChallengeStatuses(ctx, team.Namespace, team.Team)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		names := map[int]string{}
+		for _, status := range statuses {
+			names[status.ID] = status.Name
+		}
+		return names, nil
+	}
+	return nil, lastErr
+}
+
+// Run syncs the solves of the teams into CTFd until the context is cancelled, once immediately and then every interval
+func (e *CTFdExporter) Run(ctx context.Context, clientset kubernetes.Interface, namespace string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		teams, err := listTeamProgress(ctx, clientset, namespace)
+		if err == nil {
+			var pushed int
+			pushed, err = e.Sync(ctx, teams)
+			if pushed > 0 {
+				log.Infof("Pushed %d solves to CTFd", pushed)
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Warningf("Failed to sync solves to CTFd: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pushes the solves of the teams which are missing in CTFd and returns the number of pushed solves. Paused teams aren't synced.
+func (e *CTFdExporter) Sync(ctx context.Context, teams []TeamProgress) (int, error) {
+	names, err := e.juiceShopChallengeNames(ctx, teams)
+	if err != nil {
+		return 0, err
+	}
+	challenges, err := e.list(ctx, "/api/v1/challenges?view=admin")
+	if err != nil {
+		return 0, err
+	}
+	challengeIDs := map[string]int{}
+	for _, challenge := range challenges {
+		challengeIDs[challenge.Name] = challenge.ID
+	}
+	ctfdTeams, err := e.list(ctx, "/api/v1/teams")
+	if err != nil {
+		return 0, err
+	}
+	teamIDs := map[string]int{}
+	for _, team := range ctfdTeams {
+		teamIDs[team.Name] = team.ID
+	}
+
+	sorted := append([]TeamProgress{}, teams...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key() < sorted[j].Key() })
+	pushed := 0
+	unmapped := []string{}
+	for _, team := range sorted {
+		if team.Paused || len(team.Solves) == 0 {
+			continue
+		}
+		name := e.teamName(team)
+		teamID, ok := teamIDs[name]
+		if !ok {
+			if teamID, err = e.createTeam(ctx, name); err != nil {
+				return pushed, err
+			}
+		}
+		solves, err := e.list(ctx, fmt.Sprintf("/api/v1/teams/%d/solves", teamID))
+		if err != nil {
+			return pushed, err
+		}
+		solved := map[int]bool{}
+		for _, solve := range solves {
+			solved[solve.ChallengeID] = true
+		}
+
+		for _, challenge := range sortedChallenges(team.Solves) {
+			challengeID, ok := challengeIDs[names[challenge]]
+			if !ok {
+				unmapped = append(unmapped, fmt.Sprintf("'%s' (%d)", names[challenge], challenge))
+				continue
+			}
+			if solved[challengeID] {
+				continue
+			}
+			submission := ctfdSubmission{
+				ChallengeID: challengeID,
+				TeamID:      teamID,
+				Provided:    ctfdProvided(names[challenge], challenge),
+				Type:        "correct",
+				Date:        team.Solves[challenge].UTC(),
+			}
+			if err := e.request(ctx, http.MethodPost, "/api/v1/submissions", submission, nil); err != nil {
+				return pushed, err
+			}
+			pushed++
+		}
+	}
+	if len(unmapped) > 0 {
+		log.Debugf("Skipped solves of JuiceShop challenges without CTFd challenge of the same name: %s", strings.Join(unmapped, ", "))
+	}
+	return pushed, nil
+}
+
+// teamName is the name of the CTFd team of the team, qualified with its namespace if the teams of all namespaces are synced
+func (e *CTFdExporter) teamName(team TeamProgress) string {
+	if e.namespace == metav1.NamespaceAll {
+		return team.Key()
+	}
+	return team.Team
+}
+
+func (e *CTFdExporter) juiceShopChallengeNames(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+	if e.names == nil {
+		names, err := e.challengeNames(ctx, teams)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch the JuiceShop challenges: %v", err)
+		}
+		e.names = names
+	}
+	return e.names, nil
+}
+
+func (e *CTFdExporter) createTeam(ctx context.Context, name string) (int, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return 0, err
+	}
+	created := ctfdObject{}
+	// the password is never used, the team only exists to hold the solves of the MultiJuicer team
+	team := map[string]string{"name": name, "password": hex.EncodeToString(random)}
+	if err := e.request(ctx, http.MethodPost, "/api/v1/teams", team, &created); err != nil {
+		return 0, err
+	}
+	log.Infof("Created CTFd team for team '%s'", name)
+	return created.ID, nil
+}
+
+// list fetches all pages of a list endpoint of the CTFd api
+func (e *CTFdExporter) list(ctx context.Context, path string) ([]ctfdObject, error) {
+	objects := []ctfdObject{}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	for page := 1; ; {
+		items := []ctfdObject{}
+		response, err := e.do(ctx, http.MethodGet, fmt.Sprintf("%s%spage=%d", path, separator, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(response.Data, &items); err != nil {
+			return nil, fmt.Errorf("Failed to decode CTFd response of '%s': %v", path, err)
+		}
+		objects = append(objects, items...)
+		if response.Meta.Pagination.Next == nil || *response.Meta.Pagination.Next <= page {
+			return objects, nil
+		}
+		page = *response.Meta.Pagination.Next
+	}
+}
+
+// request sends the payload to the CTFd api and decodes the data of the response into the result, if it isn't nil
+func (e *CTFdExporter) request(ctx context.Context, method, path string, payload, result interface{}) error {
+	response, err := e.do(ctx, method, path, payload)
+	if err != nil || result == nil {
+		return err
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return fmt.Errorf("Failed to decode CTFd response of '%s': %v", path, err)
+	}
+	return nil
+}
+
+func (e *CTFdExporter) do(ctx context.Context, method, path string, payload interface{}) (ctfdResponse, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return ctfdResponse{}, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.url+path, body)
+	if err != nil {
+		return ctfdResponse{}, err
+	}
+	req.Header.Set("Authorization", "Token "+e.token)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.client.Do(req)
+	if err != nil {
+		return ctfdResponse{}, fmt.Errorf("Failed to reach CTFd: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return ctfdResponse{}, fmt.Errorf("CTFd responded with status %d to %s %s", res.StatusCode, method, path)
+	}
+	response := ctfdResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return ctfdResponse{}, fmt.Errorf("Failed to decode CTFd response of '%s': %v", path, err)
+	}
+	if !response.Success {
+		return ctfdResponse{}, fmt.Errorf("CTFd rejected %s %s", method, path)
+	}
+	return response, nil
+}
+
+// ctfdProvided is the submission recorded for a solve, the flag of the challenge if the CTF key is known
+func ctfdProvided(challengeName string, challenge int) string {
+	if ctfKey != "" {
+		return ctfFlag(ctfKey, challengeName)
+	}
+	return fmt.Sprintf("multi-juicer:%d", challenge)
+}
+
+// sortedChallenges returns the ids of the solved challenges ordered by their solve time
+func sortedChallenges(solves SolveTimes) []int {
+	challenges := []int{}
+	for challenge := range solves {
+		challenges = append(challenges, challenge)
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		if !solves[challenges[i]].Equal(solves[challenges[j]]) {
+			return solves[challenges[i]].Before(solves[challenges[j]])
+		}
+		return challenges[i] < challenges[j]
+	})
+	return challenges
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCTFd implements the parts of the CTFd api used by the exporter, listing teams with a page size of one
+type fakeCTFd struct {
+	mutex       sync.Mutex
+	challenges  map[string]int
+	teams       []string
+	submissions []ctfdSubmission
+}
+
+func (c *fakeCTFd) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if req.Header.Get("Authorization") != "Token admin-token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	respond := func(data interface{}, next *int) {
+		payload, _ := json.Marshal(data)
+		response := ctfdResponse{Success: true, Data: payload}
+		response.Meta.Pagination.Next = next
+		json.NewEncoder(w).Encode(response)
+	}
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v1/challenges":
+		challenges := []ctfdObject{}
+		for name, id := range c.challenges {
+			challenges = append(challenges, ctfdObject{ID: id, Name: name})
+		}
+		respond(challenges, nil)
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v1/teams":
+		page := 0
+		fmt.Sscanf(req.URL.Query().Get("page"), "%d", &page)
+		var next *int
+		if page < len(c.teams) {
+			next = new(int)
+			*next = page + 1
+		}
+		teams := []ctfdObject{}
+		if page >= 1 && page <= len(c.teams) {
+			teams = append(teams, ctfdObject{ID: page, Name: c.teams[page-1]})
+		}
+		respond(teams, next)
+	case req.Method == http.MethodPost && req.URL.Path == "/api/v1/teams":
+		team := map[string]string{}
+		json.NewDecoder(req.Body).Decode(&team)
+		c.teams = append(c.teams, team["name"])
+		respond(ctfdObject{ID: len(c.teams), Name: team["name"]}, nil)
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/solves"):
+		teamID := 0
+		fmt.Sscanf(req.URL.Path, "/api/v1/teams/%d/solves", &teamID)
+		solves := []ctfdObject{}
+		for _, submission := range c.submissions {
+			if submission.TeamID == teamID {
+				solves = append(solves, ctfdObject{ChallengeID: submission.ChallengeID})
+			}
+		}
+		respond(solves, nil)
+	case req.Method == http.MethodPost && req.URL.Path == "/api/v1/submissions":
+		submission := ctfdSubmission{}
+		json.NewDecoder(req.Body).Decode(&submission)
+		c.submissions = append(c.submissions, submission)
+		respond(submission, nil)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCTFdExporterPushesTheMissingSolvesOfTheTeams(t *testing.T) {
+	ctfd := &fakeCTFd{challenges: map[string]int{"Score Board": 11, "DOM XSS": 12}, teams: []string{"other", "foo"}}
+	server := httptest.NewServer(ctfd)
+	defer server.Close()
+	exporter := NewCTFdExporter(server.URL+"/", "admin-token", "default", server.Client())
+	exporter.challengeNames = func(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+		return map[int]string{1: "Score Board", 2: "DOM XSS", 3: "Only in JuiceShop"}, nil
+	}
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	teams := []TeamProgress{
+		{Team: "foo", Solves: SolveTimes{2: start.Add(time.Minute), 1: start, 3: start}},
+		{Team: "bar", Solves: SolveTimes{1: start.Add(time.Hour)}},
+		{Team: "paused", Solves: SolveTimes{1: start}, Paused: true},
+		{Team: "new"},
+	}
+
+	pushed, err := exporter.Sync(context.Background(), teams)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pushed)
+	assert.Equal(t, []string{"other", "foo", "bar"}, ctfd.teams)
+	assert.Equal(t, []ctfdSubmission{
+		{ChallengeID: 11, TeamID: 3, Provided: "multi-juicer:1", Type: "correct", Date: start.Add(time.Hour)},
+		{ChallengeID: 11, TeamID: 2, Provided: "multi-juicer:1", Type: "correct", Date: start},
+		{ChallengeID: 12, TeamID: 2, Provided: "multi-juicer:2", Type: "correct", Date: start.Add(time.Minute)},
+	}, ctfd.submissions)
+
+	pushed, err = exporter.Sync(context.Background(), teams)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pushed, "Should only push solves missing in CTFd")
+	assert.Len(t, ctfd.teams, 3)
+}
+
+func TestCTFdExporterFailsOnRejectedRequests(t *testing.T) {
+	server := httptest.NewServer(&fakeCTFd{})
+	defer server.Close()
+	exporter := NewCTFdExporter(server.URL, "wrong-token", "default", server.Client())
+	exporter.challengeNames = func(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+		return map[int]string{}, nil
+	}
+
+	_, err := exporter.Sync(context.Background(), []TeamProgress{{Team: "foo", Solves: SolveTimes{1: time.Now()}}})
+	assert.EqualError(t, err, "CTFd responded with status 403 to GET /api/v1/challenges?view=admin&page=1")
+}
+
+func TestCTFdExporterSubmitsTheFlagsOfTheChallengesIfTheCTFKeyIsKnown(t *testing.T) {
+	defer func() { ctfKey = "" }()
+	ctfKey = "secret-key"
+	ctfd := &fakeCTFd{challenges: map[string]int{"DOM XSS": 12}, teams: []string{"foo"}}
+	server := httptest.NewServer(ctfd)
+	defer server.Close()
+	exporter := NewCTFdExporter(server.URL, "admin-token", "default", server.Client())
+	exporter.challengeNames = func(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+		return map[int]string{2: "DOM XSS"}, nil
+	}
+	solvedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	_, err := exporter.Sync(context.Background(), []TeamProgress{{Team: "foo", Solves: SolveTimes{2: solvedAt}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []ctfdSubmission{
+		{ChallengeID: 12, TeamID: 1, Provided: "a713b3cbc8f6ca78671f8d9a7ae3a44c2bbcea4d", Type: "correct", Date: solvedAt},
+	}, ctfd.submissions)
+}
+
+func TestCTFdExporterQualifiesTheTeamsWithTheirNamespaceWhenSyncingAllNamespaces(t *testing.T) {
+	ctfd := &fakeCTFd{challenges: map[string]int{"Score Board": 11}}
+	server := httptest.NewServer(ctfd)
+	defer server.Close()
+	exporter := NewCTFdExporter(server.URL, "admin-token", "", server.Client())
+	exporter.challengeNames = func(ctx context.Context, teams []TeamProgress) (map[int]string, error) {
+		return map[int]string{1: "Score Board"}, nil
+	}
+	solvedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	pushed, err := exporter.Sync(context.Background(), []TeamProgress{
+		{Team: "foo", Namespace: "team-b", Solves: SolveTimes{1: solvedAt}},
+		{Team: "foo", Namespace: "team-a", Solves: SolveTimes{1: solvedAt}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pushed)
+	assert.Equal(t, []string{"team-a/foo", "team-b/foo"}, ctfd.teams)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dryRun is enabled via `--dry-run`. The watchdog then fetches the progress of all teams like it normally does, but only logs what it would
+// apply to the JuiceShops or save in the progress store, e.g. to safely test a new version against a running event.
+var dryRun = false
+
+var dryRunChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "progress_watchdog_dry_run_changes_total",
+	Help: "Changes the watchdog skipped in dry run mode, by the target it would have changed",
+}, []string{"target"})
+
+const (
+	dryRunTargetJuiceShop     = "juiceshop"
+	dryRunTargetKubernetes    = "kubernetes"
+	dryRunTargetProgressStore = "progress-store"
+)
+
+// wrapDryRun wraps the transport of the clients of the target in a DryRunRoundTripper if the dry run is enabled
+func wrapDryRun(next http.RoundTripper, target string) http.RoundTripper {
+	if !dryRun {
+		return next
+	}
+	return &DryRunRoundTripper{Next: next, Target: target}
+}
+
+// DryRunRoundTripper keeps write requests from changing anything. Write requests to the kubernetes api are sent as server-side dry run,
+// so that they are still validated by the api server. Write requests to the JuiceShops, e.g. applying a ContinueCode, aren't sent at all
+// and answered with an empty success.
+type DryRunRoundTripper struct {
+	Next   http.RoundTripper
+	Target string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DryRunRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isWriteRequest(req) || isKubernetesReview(req) {
+		return t.Next.RoundTrip(req)
+	}
+	dryRunChanges.WithLabelValues(t.Target).Inc()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the body of the dry run request: %v", err)
+		}
+	}
+	fields := LogFields{"target": t.Target, "method": req.Method, "url": req.URL.String()}
+	if len(body) > 0 {
+		fields["body"] = string(body)
+	}
+	log.Info("Dry run, not changing", fields)
+
+	if t.Target != dryRunTargetKubernetes {
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+			Request:    req,
+		}, nil
+	}
+	dryRunReq := req.Clone(req.Context())
+	query := dryRunReq.URL.Query()
+	query.Set("dryRun", "All")
+	dryRunReq.URL.RawQuery = query.Encode()
+	dryRunReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return t.Next.RoundTrip(dryRunReq)
+}
+
+// isKubernetesReview reports the TokenReviews and SubjectAccessReviews used to authenticate api requests, they are created but never stored
+func isKubernetesReview(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/apis/authentication.k8s.io/") || strings.HasPrefix(req.URL.Path, "/apis/authorization.k8s.io/")
+}
+
+// withDryRun wraps the store in a DryRunProgressStore if the dry run is enabled and the store doesn't write through the kubernetes api,
+// whose writes are already sent as server-side dry run
+func withDryRun(store ProgressStore) ProgressStore {
+	if !dryRun {
+		return store
+	}
+	switch store.(type) {
+	case AnnotationProgressStore, *ConfigMapProgressStore:
+		return store
+	}
+	return DryRunProgressStore{Next: store}
+}
+
+// DryRunProgressStore reads the progress from the next store, but only logs the progress it would save
+type DryRunProgressStore struct {
+	Next ProgressStore
+}
+
+// Records implements ProgressStore
+func (s DryRunProgressStore) Records(deployment appsv1.Deployment) map[string]string {
+	return s.Next.Records(deployment)
+}
+
+// Save implements ProgressStore, it checks the expected records like the next store, but doesn't save the records
+func (s DryRunProgressStore) Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error) {
+	deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpectedRecords(s.Next.Records(*deployment), expected); err != nil {
+		return nil, err
+	}
+	dryRunChanges.WithLabelValues(dryRunTargetProgressStore).Inc()
+	fields := LogFields{"target": dryRunTargetProgressStore, "team": teamname}
+	for name, value := range records {
+		fields[name] = value
+	}
+	log.Info("Dry run, not saving progress", fields)
+	return deployment, nil
+}
+
+// Delete implements ProgressStore, it only logs the deletion
+func (s DryRunProgressStore) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	dryRunChanges.WithLabelValues(dryRunTargetProgressStore).Inc()
+	log.Info("Dry run, not deleting progress", LogFields{"target": dryRunTargetProgressStore, "team": teamname})
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// useDryRun enables the dry run for the test
+func useDryRun(t *testing.T) {
+	dryRun = true
+	t.Cleanup(func() { dryRun = false })
+}
+
+func TestDryRunDoesntSendWritesToJuiceShops(t *testing.T) {
+	useDryRun(t)
+	sent := []string{}
+	client := &http.Client{Transport: wrapDryRun(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Method)
+		rr := httptest.NewRecorder()
+		rr.Write([]byte(`{"continueCode":"foo"}`))
+		return rr.Result(), nil
+	}), dryRunTargetJuiceShop)}
+
+	res, err := client.Get("http://t-foobar-juiceshop:3000/rest/continue-code")
+	assert.NoError(t, err)
+	res.Body.Close()
+	req, _ := http.NewRequest(http.MethodPut, "http://t-foobar-juiceshop:3000/rest/continue-code/apply/foo", nil)
+	res, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	assert.Equal(t, []string{http.MethodGet}, sent)
+}
+
+func TestDryRunSendsKubernetesWritesAsServerSideDryRun(t *testing.T) {
+	useDryRun(t)
+	sent := []string{}
+	client := &http.Client{Transport: wrapDryRun(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		sent = append(sent, req.Method+" "+req.URL.String()+" "+string(body))
+		return httptest.NewRecorder().Result(), nil
+	}), dryRunTargetKubernetes)}
+
+	req, _ := http.NewRequest(http.MethodPatch, "https://kubernetes/apis/apps/v1/namespaces/default/deployments/t-foobar-juiceshop?fieldManager=progress-watchdog", strings.NewReader(`{"metadata":{}}`))
+	_, err := client.Do(req)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, "https://kubernetes/apis/authentication.k8s.io/v1/tokenreviews", strings.NewReader(`{}`))
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`PATCH https://kubernetes/apis/apps/v1/namespaces/default/deployments/t-foobar-juiceshop?dryRun=All&fieldManager=progress-watchdog {"metadata":{}}`,
+		`POST https://kubernetes/apis/authentication.k8s.io/v1/tokenreviews {}`,
+	}, sent)
+}
+
+func TestDryRunProgressStoreDoesntSaveProgress(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "foo", "1")
+	clientset := fake.NewSimpleClientset(deployment)
+	store := DryRunProgressStore{Next: AnnotationProgressStore{}}
+
+	records := map[string]string{annotation("continueCode"): "bar", annotation("challengesSolved"): "2"}
+	updated, err := store.Save(context.Background(), clientset, "default", "foobar", records, map[string]string{annotation("continueCode"): "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", updated.Annotations[annotation("continueCode")])
+	_, err = store.Save(context.Background(), clientset, "default", "foobar", records, map[string]string{annotation("continueCode"): "baz"})
+	assert.Equal(t, errProgressChanged, err)
+
+	stored, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", stored.Annotations[annotation("continueCode")])
+}
+
+func TestDryRunSkipsRestoresWithoutRetrying(t *testing.T) {
+	useDryRun(t)
+	applied := 0
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			applied++
+		}
+		w.Write([]byte(`{"continueCode":""}`))
+	})
+	juiceShopClient.Transport = wrapDryRun(juiceShopClient.Transport, dryRunTargetJuiceShop)
+	deployment := createJuiceShopDeployment("foobar", "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg", "10")
+	deployment.Status.ReadyReplicas = 1
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), fake.NewSimpleClientset(deployment), NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, ApplyCode, state)
+	assert.Equal(t, 0, applied)
+}
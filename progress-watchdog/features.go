@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature names an experimental behavior of the watchdog which can be switched on or off per event
+type Feature string
+
+const (
+	// FeatureReplicaMerge merges the progress of teams running multiple JuiceShop replicas and applies it to all of them
+	FeatureReplicaMerge Feature = "replicaMerge"
+	// FeatureSolveDetails stores the key and solve time of every solved challenge reported by the challenge api of the JuiceShops
+	FeatureSolveDetails Feature = "solveDetails"
+	// FeatureChallengeAPIScoring records the solve times reported by the challenge api of the JuiceShops, caches the score of every team
+	// in its score annotation and ranks teams with the same score by who reached it first
+	FeatureChallengeAPIScoring Feature = "challengeApiScoring"
+	// FeatureCTFFlags stores the CTF flag of every solved challenge in its solve details, generated from the CTF key the JuiceShops run with
+	FeatureCTFFlags Feature = "ctfFlags"
+	// FeatureServerSideApply writes the progress annotations of the JuiceShop deployments with server-side apply as the `progress-watchdog` field manager
+	FeatureServerSideApply Feature = "serverSideApply"
+)
+
+// FeatureDefinition describes a feature and whether it's enabled if not configured otherwise
+type FeatureDefinition struct {
+	Description string
+	Default     bool
+}
+
+// features contains the definitions of all known features
+var features = map[Feature]FeatureDefinition{
+	FeatureReplicaMerge: {
+		Description: "Merge the progress of teams running multiple JuiceShop replicas and apply it to all replicas",
+		Default:     true,
+	},
+	FeatureSolveDetails: {
+		Description: "Store the key and solve time of every solved challenge as reported by the JuiceShop, in addition to the ContinueCode",
+		Default:     false,
+	},
+	FeatureChallengeAPIScoring: {
+		Description: "Record the solve times reported by the JuiceShop, cache the score of every team and rank teams with the same score by who reached it first",
+		Default:     false,
+	},
+	FeatureCTFFlags: {
+		Description: "Store the CTF flag of every solved challenge in its solve details, generated from CTF_KEY like by the JuiceShop and juice-shop-ctf-cli, so that solves can be cross-checked against CTF exports",
+		Default:     false,
+	},
+	FeatureServerSideApply: {
+		Description: "Write the progress annotations with server-side apply as the progress-watchdog field manager, so that they don't conflict with Helm or other controllers managing the deployments. Requires Kubernetes 1.18 or newer",
+		Default:     false,
+	},
+}
+
+// FeatureStatus json format of a feature in the feature flag api
+type FeatureStatus struct {
+	Name        Feature `json:"name"`
+	Description string  `json:"description"`
+	Default     bool    `json:"default"`
+	Enabled     bool    `json:"enabled"`
+}
+
+// FeatureFlags tracks which features are enabled. Flags are configured on startup and can be toggled at runtime via the admin api,
+// so that a misbehaving feature can be rolled back instantly. Runtime changes are lost on restart.
+type FeatureFlags struct {
+	mutex   sync.RWMutex
+	enabled map[Feature]bool
+}
+
+// featureFlags are the feature flags of the running watchdog, configured via the `FEATURE_FLAGS` env var
+var featureFlags = NewFeatureFlags(nil)
+
+// NewFeatureFlags creates the feature flags, overriding the defaults of the features with the configured values
+func NewFeatureFlags(configured map[Feature]bool) *FeatureFlags {
+	enabled := map[Feature]bool{}
+	for feature, definition := range features {
+		enabled[feature] = definition.Default
+	}
+	for feature, value := range configured {
+		enabled[feature] = value
+	}
+	return &FeatureFlags{enabled: enabled}
+}
+
+// Enabled checks if the feature is enabled
+func (f *FeatureFlags) Enabled(feature Feature) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.enabled[feature]
+}
+
+// Configure resets the features to their defaults overridden by the configured values, e.g. after the mounted config changed.
+// Features toggled at runtime are reset as well.
+func (f *FeatureFlags) Configure(configured map[Feature]bool) {
+	enabled := NewFeatureFlags(configured).enabled
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.enabled = enabled
+}
+
+// Set enables or disables a feature at runtime
+func (f *FeatureFlags) Set(feature Feature, enabled bool) error {
+	if _, ok := features[feature]; !ok {
+		return fmt.Errorf("Unknown feature '%s'", feature)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.enabled[feature] = enabled
+	return nil
+}
+
+// Statuses lists all known features, sorted by name
+func (f *FeatureFlags) Statuses() []FeatureStatus {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	statuses := []FeatureStatus{}
+	for feature, definition := range features {
+		statuses = append(statuses, FeatureStatus{
+			Name:        feature,
+			Description: definition.Description,
+			Default:     definition.Default,
+			Enabled:     f.enabled[feature],
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// parseFeatureFlags parses feature flags configured as a comma separated list of `feature=true|false` entries.
+// A feature without value (e.g. `replicaMerge`) gets enabled.
+func parseFeatureFlags(value string) (map[Feature]bool, error) {
+	configured := map[Feature]bool{}
+	for _, entry := range parseCommaSeparatedList(value) {
+		parts := strings.SplitN(entry, "=", 2)
+		feature := Feature(strings.TrimSpace(parts[0]))
+		if _, ok := features[feature]; !ok {
+			return nil, fmt.Errorf("Unknown feature '%s'", feature)
+		}
+		enabled := true
+		if len(parts) == 2 {
+			var err error
+			enabled, err = strconv.ParseBool(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for feature '%s': %v", feature, err)
+			}
+		}
+		configured[feature] = enabled
+	}
+	return configured, nil
+}
+
+// FeatureUpdateRequest json format of the feature toggle request
+type FeatureUpdateRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleFeatures lists the feature flags under `GET /api/admin/features` and toggles single ones via `PUT /api/admin/features/{feature}`
+func (s *Server) handleFeatures(w http.ResponseWriter, req *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/admin/features"), "/")
+	if name == "" {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, featureFlags.Statuses())
+		return
+	}
+
+	if req.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	feature := Feature(name)
+	if _, ok := features[feature]; !ok {
+		http.NotFound(w, req)
+		return
+	}
+	var update FeatureUpdateRequest
+	if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := featureFlags.Set(feature, update.Enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	auditLog.Infof("'%s' set feature '%s' to enabled=%t", principalFromContext(req.Context()).Name, feature, update.Enabled)
+
+	writeJSON(w, featureFlags.Statuses())
+}
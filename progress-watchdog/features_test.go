@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseFeatureFlags(t *testing.T) {
+	configured, err := parseFeatureFlags("replicaMerge=false")
+	assert.Nil(t, err)
+	assert.Equal(t, map[Feature]bool{FeatureReplicaMerge: false}, configured)
+
+	configured, err = parseFeatureFlags(" replicaMerge ")
+	assert.Nil(t, err)
+	assert.Equal(t, map[Feature]bool{FeatureReplicaMerge: true}, configured)
+
+	configured, err = parseFeatureFlags("")
+	assert.Nil(t, err)
+	assert.Equal(t, map[Feature]bool{}, configured)
+
+	_, err = parseFeatureFlags("timeTravel=true")
+	assert.EqualError(t, err, "Unknown feature 'timeTravel'")
+	_, err = parseFeatureFlags("replicaMerge=maybe")
+	assert.Error(t, err)
+}
+
+func TestFeatureFlagsFallBackToDefaults(t *testing.T) {
+	assert.True(t, NewFeatureFlags(nil).Enabled(FeatureReplicaMerge))
+	assert.False(t, NewFeatureFlags(map[Feature]bool{FeatureReplicaMerge: false}).Enabled(FeatureReplicaMerge))
+	assert.False(t, NewFeatureFlags(nil).Enabled(Feature("unknown")))
+}
+
+func TestFeaturesCanBeToggledAtRuntime(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("PUT", "/api/admin/features/replicaMerge", `{"enabled":false}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.False(t, featureFlags.Enabled(FeatureReplicaMerge))
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/admin/features"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	statuses := []FeatureStatus{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	assert.Len(t, statuses, len(features))
+	assert.Contains(t, statuses, FeatureStatus{
+		Name:        FeatureReplicaMerge,
+		Description: features[FeatureReplicaMerge].Description,
+		Default:     true,
+		Enabled:     false,
+	})
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("PUT", "/api/admin/features/timeTravel", `{"enabled":true}`))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// federationRequestTimeout limits how long the scoreboard of a single installation is waited for
+const federationRequestTimeout = 5 * time.Second
+
+// FederationSource a MultiJuicer installation whose scoreboard is aggregated
+type FederationSource struct {
+	Name string
+	// URL the public scoreboard api of the installation is served under, e.g. `https://berlin.example.com`
+	URL string
+}
+
+// FederatedScoreboardTeam a team listed on the combined scoreboard of all installations
+type FederatedScoreboardTeam struct {
+	Position         int     `json:"position"`
+	Installation     string  `json:"installation"`
+	Team             string  `json:"team"`
+	ChallengesSolved int     `json:"challengesSolved"`
+	Score            float64 `json:"score"`
+}
+
+// FederationSourceStatus reports when the scoreboard of an installation was last fetched successfully
+type FederationSourceStatus struct {
+	Name        string     `json:"name"`
+	LastUpdated *time.Time `json:"lastUpdated"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// FederatedScoreboardResponse json format of the combined scoreboard response
+type FederatedScoreboardResponse struct {
+	Teams         []FederatedScoreboardTeam `json:"teams"`
+	Installations []FederationSourceStatus  `json:"installations"`
+}
+
+type federatedStandings struct {
+	teams     []ScoreboardTeam
+	fetchedAt time.Time
+	err       error
+}
+
+// Federation aggregates the scoreboards of multiple independent MultiJuicer installations into a global scoreboard.
+// If an installation can't be reached its last known standings are kept.
+type Federation struct {
+	sources []FederationSource
+	client  *http.Client
+	ttl     time.Duration
+	now     func() time.Time
+
+	mutex     sync.Mutex
+	standings map[string]federatedStandings
+	fetchedAt time.Time
+}
+
+// NewFederation creates a Federation refetching the scoreboards of the sources at most once per ttl
+func NewFederation(sources []FederationSource, ttl time.Duration) *Federation {
+	return &Federation{
+		sources:   sources,
+		client:    &http.Client{Transport: newTaggingRoundTripper(http.DefaultTransport), Timeout: federationRequestTimeout},
+		ttl:       ttl,
+		now:       time.Now,
+		standings: map[string]federatedStandings{},
+	}
+}
+
+// parseFederationSources parses the sources configured as a comma separated list of `name=url` entries
+func parseFederationSources(value string) ([]FederationSource, error) {
+	sources := []FederationSource{}
+	for _, entry := range parseCommaSeparatedList(value) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid federation source '%s', expected format 'name=url'", entry)
+		}
+		sourceURL, err := url.Parse(parts[1])
+		if err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") {
+			return nil, fmt.Errorf("Invalid url of federation source '%s', must be a http or https url", parts[0])
+		}
+		sources = append(sources, FederationSource{Name: parts[0], URL: strings.TrimSuffix(parts[1], "/")})
+	}
+	return sources, nil
+}
+
+func (f *Federation) fetchScoreboard(ctx context.Context, source FederationSource) ([]ScoreboardTeam, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL+"/api/scoreboard", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch scoreboard: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response status code '%d'", res.StatusCode)
+	}
+	scoreboard := ScoreboardResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&scoreboard); err != nil {
+		return nil, fmt.Errorf("Failed to parse scoreboard: %v", err)
+	}
+	return scoreboard.Teams, nil
+}
+
+// refresh fetches the scoreboards of all sources in parallel, if the last fetch is older than the ttl
+func (f *Federation) refresh(ctx context.Context) {
+	now := f.now()
+	if !f.fetchedAt.IsZero() && now.Sub(f.fetchedAt) < f.ttl {
+		return
+	}
+
+	var wait sync.WaitGroup
+	var resultMutex sync.Mutex
+	for _, source := range f.sources {
+		wait.Add(1)
+		go func(source FederationSource) {
+			defer wait.Done()
+			teams, err := f.fetchScoreboard(ctx, source)
+
+			resultMutex.Lock()
+			defer resultMutex.Unlock()
+			previous := f.standings[source.Name]
+			if err != nil {
+				log.Warningf("Failed to fetch scoreboard of installation '%s', keeping its last known standings", source.Name)
+				log.Warning(err)
+				previous.err = err
+				f.standings[source.Name] = previous
+				return
+			}
+			f.standings[source.Name] = federatedStandings{teams: teams, fetchedAt: now}
+		}(source)
+	}
+	wait.Wait()
+	f.fetchedAt = now
+}
+
+// Scoreboard returns the combined scoreboard of all installations, ranked by score
+func (f *Federation) Scoreboard(ctx context.Context) FederatedScoreboardResponse {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.refresh(ctx)
+
+	response := FederatedScoreboardResponse{
+		Teams:         []FederatedScoreboardTeam{},
+		Installations: []FederationSourceStatus{},
+	}
+	for _, source := range f.sources {
+		standings := f.standings[source.Name]
+		status := FederationSourceStatus{Name: source.Name}
+		if !standings.fetchedAt.IsZero() {
+			fetchedAt := standings.fetchedAt
+			status.LastUpdated = &fetchedAt
+		}
+		if standings.err != nil {
+			status.Error = standings.err.Error()
+		}
+		response.Installations = append(response.Installations, status)
+
+		for _, team := range standings.teams {
+			response.Teams = append(response.Teams, FederatedScoreboardTeam{
+				Installation:     source.Name,
+				Team:             team.Team,
+				ChallengesSolved: team.ChallengesSolved,
+				Score:            team.Score,
+			})
+		}
+	}
+
+	teams := response.Teams
+	sort.SliceStable(teams, func(i, j int) bool {
+		if teams[i].Score != teams[j].Score {
+			return teams[i].Score > teams[j].Score
+		}
+		if teams[i].Installation != teams[j].Installation {
+			return teams[i].Installation < teams[j].Installation
+		}
+		return teams[i].Team < teams[j].Team
+	})
+	for i := range teams {
+		teams[i].Position = i + 1
+		if i > 0 && teams[i].Score == teams[i-1].Score {
+			teams[i].Position = teams[i-1].Position
+		}
+	}
+	return response
+}
+
+func (f *Federation) handleScoreboard(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeConditionalJSON(w, req, f.Scoreboard(req.Context()), time.Time{})
+}
+
+// NewFederationServer creates the http handler of the aggregator, serving the combined scoreboard under `/api/federation/scoreboard`
+func NewFederationServer(federation *Federation, rateLimiter *RateLimiter, cors *CORS) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/federation/scoreboard", rateLimiter.Limit(http.HandlerFunc(federation.handleScoreboard)))
+	return cors.Handler(compressResponses(mux))
+}
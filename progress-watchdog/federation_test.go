@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newScoreboardSource(teams []ScoreboardTeam) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, ScoreboardResponse{Teams: teams})
+	}))
+}
+
+func TestParseFederationSources(t *testing.T) {
+	sources, err := parseFederationSources("berlin=https://berlin.example.com/, tokyo=http://tokyo.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []FederationSource{
+		{Name: "berlin", URL: "https://berlin.example.com"},
+		{Name: "tokyo", URL: "http://tokyo.example.com"},
+	}, sources)
+
+	_, err = parseFederationSources("berlin")
+	assert.Error(t, err)
+	_, err = parseFederationSources("berlin=ftp://berlin.example.com")
+	assert.Error(t, err)
+}
+
+func TestFederationCombinesScoreboardsOfAllInstallations(t *testing.T) {
+	berlin := newScoreboardSource([]ScoreboardTeam{{Position: 1, Team: "bears", ChallengesSolved: 5, Score: 5}})
+	defer berlin.Close()
+	tokyo := newScoreboardSource([]ScoreboardTeam{
+		{Position: 1, Team: "cranes", ChallengesSolved: 7, Score: 7},
+		{Position: 2, Team: "koi", ChallengesSolved: 5, Score: 5},
+	})
+	defer tokyo.Close()
+
+	federation := NewFederation([]FederationSource{{Name: "berlin", URL: berlin.URL}, {Name: "tokyo", URL: tokyo.URL}}, time.Minute)
+	scoreboard := federation.Scoreboard(context.Background())
+
+	assert.Equal(t, []FederatedScoreboardTeam{
+		{Position: 1, Installation: "tokyo", Team: "cranes", ChallengesSolved: 7, Score: 7},
+		{Position: 2, Installation: "berlin", Team: "bears", ChallengesSolved: 5, Score: 5},
+		{Position: 2, Installation: "tokyo", Team: "koi", ChallengesSolved: 5, Score: 5},
+	}, scoreboard.Teams)
+	assert.Len(t, scoreboard.Installations, 2)
+	assert.NotNil(t, scoreboard.Installations[0].LastUpdated)
+}
+
+func TestFederationKeepsLastKnownStandingsOfUnreachableInstallations(t *testing.T) {
+	berlin := newScoreboardSource([]ScoreboardTeam{{Position: 1, Team: "bears", ChallengesSolved: 5, Score: 5}})
+
+	federation := NewFederation([]FederationSource{{Name: "berlin", URL: berlin.URL}}, time.Minute)
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	federation.now = func() time.Time { return now }
+	federation.Scoreboard(context.Background())
+
+	berlin.Close()
+	now = now.Add(2 * time.Minute)
+	scoreboard := federation.Scoreboard(context.Background())
+
+	assert.Len(t, scoreboard.Teams, 1)
+	assert.NotEmpty(t, scoreboard.Installations[0].Error)
+	assert.Equal(t, now.Add(-2*time.Minute), *scoreboard.Installations[0].LastUpdated)
+}
+
+func TestFederationServerServesCombinedScoreboard(t *testing.T) {
+	berlin := newScoreboardSource([]ScoreboardTeam{{Position: 1, Team: "bears", ChallengesSolved: 5, Score: 5}})
+	defer berlin.Close()
+	server := NewFederationServer(NewFederation([]FederationSource{{Name: "berlin", URL: berlin.URL}}, time.Minute), NewRateLimiter(10, 10), &CORS{})
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/federation/scoreboard", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := FederatedScoreboardResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "bears", response.Teams[0].Team)
+}
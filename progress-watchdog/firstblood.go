@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FirstBlood the team which solved a challenge first
+type FirstBlood struct {
+	Challenge int       `json:"challenge"`
+	Team      string    `json:"team"`
+	Namespace string    `json:"namespace"`
+	SolvedAt  time.Time `json:"solvedAt"`
+}
+
+// FirstBloodResponse json format of the first blood response
+type FirstBloodResponse struct {
+	FirstBloods []FirstBlood `json:"firstBloods"`
+}
+
+// FirstBloodTracker remembers which team solved each challenge first. First bloods of teams whose JuiceShop got deleted are kept for
+// the lifetime of the watchdog, first bloods of existing teams follow their current solves, so that rejected solves and resets lose them.
+// Paused teams can't take first bloods, just like they aren't ranked.
+type FirstBloodTracker struct {
+	mutex       sync.Mutex
+	firstBloods map[int]FirstBlood
+}
+
+var firstBloods = NewFirstBloodTracker()
+
+// NewFirstBloodTracker creates a tracker without any first bloods
+func NewFirstBloodTracker() *FirstBloodTracker {
+	return &FirstBloodTracker{firstBloods: map[int]FirstBlood{}}
+}
+
+// Observe updates the first bloods with the solves of the teams and returns them ordered by challenge
+func (t *FirstBloodTracker) Observe(teams []TeamProgress) []FirstBlood {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	existing := map[string]bool{}
+	for _, team := range teams {
+		existing[team.Key()] = true
+	}
+	updated := map[int]FirstBlood{}
+	claim := func(candidate FirstBlood) {
+		current, ok := updated[candidate.Challenge]
+		if !ok || candidate.SolvedAt.Before(current.SolvedAt) || (candidate.SolvedAt.Equal(current.SolvedAt) && teamKey(candidate.Namespace, candidate.Team) < teamKey(current.Namespace, current.Team)) {
+			updated[candidate.Challenge] = candidate
+		}
+	}
+	for _, firstBlood := range t.firstBloods {
+		if !existing[teamKey(firstBlood.Namespace, firstBlood.Team)] {
+			claim(firstBlood)
+		}
+	}
+	for _, team := range teams {
+		if team.Paused {
+			continue
+		}
+		for challenge, solvedAt := range team.Solves {
+			claim(FirstBlood{Challenge: challenge, Team: team.Team, Namespace: team.Namespace, SolvedAt: solvedAt.UTC()})
+		}
+	}
+	t.firstBloods = updated
+
+	list := []FirstBlood{}
+	challengeFirstBlood.Reset()
+	for _, firstBlood := range updated {
+		list = append(list, firstBlood)
+		challengeFirstBlood.WithLabelValues(strconv.Itoa(firstBlood.Challenge), firstBlood.Team).Set(float64(firstBlood.SolvedAt.Unix()))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Challenge < list[j].Challenge })
+	return list
+}
+
+// handleFirstBloods serves the team which solved each challenge first, so that organizers can award first blood bonuses
+func (s *Server) handleFirstBloods(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeConditionalJSON(w, req, FirstBloodResponse{FirstBloods: firstBloods.Observe(teams)}, s.scoreboardCache.LastModified())
+}
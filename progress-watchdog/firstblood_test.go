@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFirstBloodTrackerRemembersTheFirstSolverOfEachChallenge(t *testing.T) {
+	tracker := NewFirstBloodTracker()
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	teams := []TeamProgress{
+		{Team: "foo", Solves: SolveTimes{1: start.Add(time.Minute), 2: start}},
+		{Team: "bar", Solves: SolveTimes{1: start, 2: start}},
+		{Team: "paused", Solves: SolveTimes{1: start.Add(-time.Hour), 3: start}, Paused: true},
+	}
+
+	assert.Equal(t, []FirstBlood{
+		{Challenge: 1, Team: "bar", SolvedAt: start},
+		{Challenge: 2, Team: "bar", SolvedAt: start},
+	}, tracker.Observe(teams))
+	assert.Equal(t, float64(start.Unix()), testutil.ToFloat64(challengeFirstBlood.WithLabelValues("1", "bar")))
+
+	assert.Equal(t, []FirstBlood{
+		{Challenge: 1, Team: "bar", SolvedAt: start},
+		{Challenge: 2, Team: "bar", SolvedAt: start},
+	}, tracker.Observe(teams[:1]), "Should keep the first bloods of deleted teams")
+
+	assert.Equal(t, []FirstBlood{
+		{Challenge: 1, Team: "foo", SolvedAt: start.Add(time.Minute)},
+		{Challenge: 2, Team: "foo", SolvedAt: start},
+	}, tracker.Observe([]TeamProgress{teams[0], {Team: "bar"}}), "Should drop the first bloods of teams which lost their solves")
+	assert.Equal(t, 2, testutil.CollectAndCount(challengeFirstBlood))
+}
+
+func TestFirstBloodEndpointServesTheFirstSolvers(t *testing.T) {
+	defer func() { firstBloods = NewFirstBloodTracker() }()
+	foo := createJuiceShopDeployment("foo", "", "2")
+	foo.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z","12":"2021-05-01T11:30:00Z"}`
+	bar := createJuiceShopDeployment("bar", "", "1")
+	bar.Annotations["multi-juicer.iteratec.dev/solves"] = `{"12":"2021-05-01T11:00:00Z"}`
+	clientset := fake.NewSimpleClientset(foo, bar)
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard/first-bloods", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	response := FirstBloodResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []FirstBlood{
+		{Challenge: 1, Team: "foo", Namespace: "default", SolvedAt: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{Challenge: 12, Team: "bar", Namespace: "default", SolvedAt: time.Date(2021, 5, 1, 11, 0, 0, 0, time.UTC)},
+	}, response.FirstBloods)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("POST", "/api/scoreboard/first-bloods", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
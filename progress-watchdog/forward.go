@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+const (
+	// forwardBufferSize is the number of log records buffered while the forward endpoint is slow or unreachable, further records are dropped
+	forwardBufferSize   = 1000
+	forwardDialTimeout  = 5 * time.Second
+	forwardWriteTimeout = 5 * time.Second
+	forwardRetryDelay   = 5 * time.Second
+)
+
+// ForwardBackend sends log records to a Fluentd / Fluent Bit endpoint using the forward protocol (message mode).
+// Records are sent in the background, so that logging never blocks on the log endpoint.
+type ForwardBackend struct {
+	address string
+	tag     string
+	records chan []byte
+
+	mutex   sync.Mutex
+	dropped int
+}
+
+// NewForwardBackend creates a ForwardBackend sending to the `host:port` address, tagging all records with the tag
+func NewForwardBackend(address, tag string) *ForwardBackend {
+	backend := &ForwardBackend{
+		address: address,
+		tag:     tag,
+		records: make(chan []byte, forwardBufferSize),
+	}
+	go backend.send()
+	return backend
+}
+
+// Log implements the logging.Backend interface
+func (b *ForwardBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	message := encodeForwardMessage(b.tag, rec.Time, map[string]string{
+		"level":   level.String(),
+		"module":  rec.Module,
+		"message": rec.Message(),
+	})
+	select {
+	case b.records <- message:
+	default:
+		b.mutex.Lock()
+		b.dropped++
+		b.mutex.Unlock()
+	}
+	return nil
+}
+
+func (b *ForwardBackend) send() {
+	var conn net.Conn
+	for record := range b.records {
+		for {
+			if conn == nil {
+				var err error
+				conn, err = net.DialTimeout("tcp", b.address, forwardDialTimeout)
+				if err != nil {
+					// log endpoints being unreachable can't be logged via the logger itself, it would end up in this backend again
+					time.Sleep(forwardRetryDelay)
+					continue
+				}
+				b.reportDropped(conn)
+			}
+			conn.SetWriteDeadline(time.Now().Add(forwardWriteTimeout))
+			if _, err := conn.Write(record); err != nil {
+				conn.Close()
+				conn = nil
+				continue
+			}
+			break
+		}
+	}
+}
+
+// reportDropped sends a record about the records dropped while the endpoint wasn't reachable
+func (b *ForwardBackend) reportDropped(conn net.Conn) {
+	b.mutex.Lock()
+	dropped := b.dropped
+	b.dropped = 0
+	b.mutex.Unlock()
+	if dropped == 0 {
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(forwardWriteTimeout))
+	conn.Write(encodeForwardMessage(b.tag, time.Now(), map[string]string{
+		"level":   logging.WARNING.String(),
+		"module":  "ForwardBackend",
+		"message": "Dropped log records while the log endpoint wasn't reachable",
+		"dropped": strconv.Itoa(dropped),
+	}))
+}
+
+// encodeForwardMessage encodes a record as msgpack `[tag, time, record]` array, the message mode of the forward protocol
+func encodeForwardMessage(tag string, timestamp time.Time, record map[string]string) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteByte(0x93) // fixarray with 3 elements
+	writeMsgpackString(&buffer, tag)
+
+	buffer.WriteByte(0xce) // uint 32, the time in seconds
+	binary.Write(&buffer, binary.BigEndian, uint32(timestamp.Unix()))
+
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	writeMsgpackMapHeader(&buffer, len(record))
+	for _, key := range keys {
+		writeMsgpackString(&buffer, key)
+		writeMsgpackString(&buffer, record[key])
+	}
+	return buffer.Bytes()
+}
+
+func writeMsgpackString(buffer *bytes.Buffer, value string) {
+	length := len(value)
+	switch {
+	case length < 32:
+		buffer.WriteByte(0xa0 | byte(length))
+	case length <= 0xff:
+		buffer.WriteByte(0xd9)
+		buffer.WriteByte(byte(length))
+	case length <= 0xffff:
+		buffer.WriteByte(0xda)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(0xdb)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+	buffer.WriteString(value)
+}
+
+func writeMsgpackMapHeader(buffer *bytes.Buffer, length int) {
+	if length < 16 {
+		buffer.WriteByte(0x80 | byte(length))
+		return
+	}
+	buffer.WriteByte(0xde)
+	binary.Write(buffer, binary.BigEndian, uint16(length))
+}
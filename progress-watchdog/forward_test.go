@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeForwardMessage(t *testing.T) {
+	message := encodeForwardMessage("mj", time.Unix(1618048800, 0), map[string]string{"message": "hi", "level": "INFO"})
+
+	assert.Equal(t, []byte{
+		0x93,
+		0xa2, 'm', 'j',
+		0xce, 0x60, 0x71, 0x77, 0x20,
+		0x82,
+		0xa5, 'l', 'e', 'v', 'e', 'l', 0xa4, 'I', 'N', 'F', 'O',
+		0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e', 0xa2, 'h', 'i',
+	}, message)
+}
+
+func TestWriteMsgpackStringUsesLongerHeadersForLongStrings(t *testing.T) {
+	var buffer bytes.Buffer
+	writeMsgpackString(&buffer, string(make([]byte, 40)))
+	assert.Equal(t, []byte{0xd9, 40}, buffer.Bytes()[:2])
+}
+
+func TestForwardBackendSendsRecordsToTheEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	backend := NewForwardBackend(listener.Addr().String(), "mj")
+	record := &logging.Record{Module: "Test", Time: time.Unix(1618048800, 0), Args: []interface{}{"hello"}}
+	assert.NoError(t, backend.Log(logging.INFO, 0, record))
+
+	conn, err := listener.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	expected := encodeForwardMessage("mj", time.Unix(1618048800, 0), map[string]string{"level": "INFO", "module": "Test", "message": "hello"})
+	received := make([]byte, len(expected))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadFull(conn, received)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, received)
+}
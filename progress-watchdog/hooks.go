@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hookBufferSize is the number of events buffered while the hooks are busy, further events are dropped
+	hookBufferSize = 1000
+	// hookTimeout limits how long a single hook may take to handle an event
+	hookTimeout = 30 * time.Second
+)
+
+// HookEventType the kind of event passed to the hooks
+type HookEventType string
+
+const (
+	// HookEventSolve a team solved a challenge
+	HookEventSolve HookEventType = "solve"
+	// HookEventTeamCreated the JuiceShop of a new team was discovered
+	HookEventTeamCreated HookEventType = "teamCreated"
+	// HookEventTeamIdle the team didn't send a request to its JuiceShop for longer than the idle threshold
+	HookEventTeamIdle HookEventType = "teamIdle"
+	// HookEventInstanceDeleted the JuiceShop of a team was deleted
+	HookEventInstanceDeleted HookEventType = "instanceDeleted"
+	// HookEventRestoreFailed applying the cached progress to the JuiceShop of a team didn't restore all of its solves
+	HookEventRestoreFailed HookEventType = "restoreFailed"
+)
+
+// hookEventTypes contains all types of hook events
+var hookEventTypes = []HookEventType{HookEventSolve, HookEventTeamCreated, HookEventTeamIdle, HookEventInstanceDeleted, HookEventRestoreFailed}
+
+// HookEvent json format of the events passed to the hooks
+type HookEvent struct {
+	Type      HookEventType `json:"type"`
+	Team      string        `json:"team"`
+	Challenge int           `json:"challenge,omitempty"`
+	// ChallengesSolved is the number of challenges the team solved, set for solve events and for restoreFailed events as reported by the JuiceShop after the restore
+	ChallengesSolved int       `json:"challengesSolved,omitempty"`
+	Time             time.Time `json:"time"`
+	// Message is rendered from the notification template of the event type, empty if there is none
+	Message string `json:"message,omitempty"`
+}
+
+// Hook extends the watchdog with custom behavior on solves and team lifecycle events
+type Hook interface {
+	// Name identifies the hook in logs
+	Name() string
+	// Handle handles a single event. Events are handled one after another in the background, the context is cancelled after hookTimeout
+	Handle(ctx context.Context, event HookEvent) error
+}
+
+var (
+	compiledHooksMutex sync.Mutex
+	// compiledHooks are registered via RegisterHook by compiled-in extensions, usually from an `init` function
+	compiledHooks = []Hook{}
+)
+
+// RegisterHook registers a compiled-in hook which gets invoked for all events
+func RegisterHook(hook Hook) {
+	compiledHooksMutex.Lock()
+	defer compiledHooksMutex.Unlock()
+	compiledHooks = append(compiledHooks, hook)
+}
+
+// Hooks dispatches the events of the watchdog to all hooks in the background, so that slow hooks never block progress updates
+type Hooks struct {
+	hooks     []Hook
+	templates *NotificationTemplates
+	events    chan HookEvent
+}
+
+// hooks are the hooks of the running watchdog, configured in main
+var hooks = NewHooks(nil)
+
+// NewHooks creates the dispatcher for the passed hooks and all compiled-in hooks. The messages of the events are rendered with the templates, which can be nil.
+func NewHooks(templates *NotificationTemplates, external ...Hook) *Hooks {
+	compiledHooksMutex.Lock()
+	all := append(append([]Hook{}, compiledHooks...), external...)
+	compiledHooksMutex.Unlock()
+
+	h := &Hooks{
+		hooks:     all,
+		templates: templates,
+		events:    make(chan HookEvent, hookBufferSize),
+	}
+	if len(all) > 0 {
+		go h.run()
+	}
+	return h
+}
+
+// Dispatch queues the event for all hooks
+func (h *Hooks) Dispatch(event HookEvent) {
+	if len(h.hooks) == 0 {
+		return
+	}
+	select {
+	case h.events <- event:
+	default:
+		log.Warningf("Hooks are too slow, dropping '%s' event of team '%s'", event.Type, event.Team)
+	}
+}
+
+func (h *Hooks) run() {
+	for event := range h.events {
+		h.handle(event)
+	}
+}
+
+func (h *Hooks) handle(event HookEvent) {
+	message, err := h.templates.Render(event)
+	if err != nil {
+		log.Warning(err)
+	}
+	event.Message = message
+	for _, hook := range h.hooks {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		if err := hook.Handle(ctx, event); err != nil {
+			log.Warningf("Hook '%s' failed to handle '%s' event of team '%s': %s", hook.Name(), event.Type, event.Team, err)
+		}
+		cancel()
+	}
+}
+
+// dispatchSolves dispatches a solve event for every challenge solved since the last update
+func (h *Hooks) dispatchSolves(teamname string, lastSolves, solves SolveTimes) {
+	challenges := []int{}
+	for challenge := range solves {
+		if _, ok := lastSolves[challenge]; !ok {
+			challenges = append(challenges, challenge)
+		}
+	}
+	sort.Ints(challenges)
+	for _, challenge := range challenges {
+		h.Dispatch(HookEvent{Type: HookEventSolve, Team: teamname, Challenge: challenge, ChallengesSolved: len(solves), Time: solves[challenge]})
+	}
+}
+
+// TeamChanges the teams whose lifecycle changed since the last discovery, each sorted by name
+type TeamChanges struct {
+	Created []string
+	Idle    []string
+	Deleted []string
+}
+
+// TeamTracker detects created, idle and deleted teams by comparing the teams found by consecutive discoveries
+type TeamTracker struct {
+	idleThreshold time.Duration
+	// known maps the known teams to whether they are idle
+	known map[string]bool
+}
+
+// NewTeamTracker creates a TeamTracker considering teams idle once their last request is older than the idle threshold, 0 disables the idle detection.
+// The teams of the first discovery are only recorded, they weren't necessarily created or became idle just now.
+func NewTeamTracker(idleThreshold time.Duration) *TeamTracker {
+	return &TeamTracker{idleThreshold: idleThreshold}
+}
+
+// Update records the currently existing teams with the time of their last request and returns the changes since the last update
+func (t *TeamTracker) Update(lastRequests map[string]time.Time, now time.Time) TeamChanges {
+	current := map[string]bool{}
+	for team, lastRequest := range lastRequests {
+		current[team] = t.idleThreshold > 0 && now.Sub(lastRequest) > t.idleThreshold
+	}
+	if t.known == nil {
+		t.known = current
+		return TeamChanges{}
+	}
+
+	changes := TeamChanges{}
+	for team, idle := range current {
+		wasIdle, known := t.known[team]
+		if !known {
+			changes.Created = append(changes.Created, team)
+		}
+		if idle && !wasIdle {
+			changes.Idle = append(changes.Idle, team)
+		}
+	}
+	for team := range t.known {
+		if _, ok := current[team]; !ok {
+			changes.Deleted = append(changes.Deleted, team)
+		}
+	}
+	sort.Strings(changes.Created)
+	sort.Strings(changes.Idle)
+	sort.Strings(changes.Deleted)
+	t.known = current
+	return changes
+}
+
+// ExecHook runs a command for every event, passing the event as json on stdin and its type, team and message in the
+// `MULTIJUICER_EVENT_TYPE`, `MULTIJUICER_TEAM` and `MULTIJUICER_MESSAGE` env vars
+type ExecHook struct {
+	Command string
+	Args    []string
+}
+
+// Name implements Hook
+func (h *ExecHook) Name() string {
+	return "exec:" + h.Command
+}
+
+// Handle implements Hook
+func (h *ExecHook) Handle(ctx context.Context, event HookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, h.Command, h.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("MULTIJUICER_EVENT_TYPE=%s", event.Type),
+		fmt.Sprintf("MULTIJUICER_TEAM=%s", event.Team),
+		fmt.Sprintf("MULTIJUICER_MESSAGE=%s", event.Message),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Command failed: %v, output: %s", err, output)
+	}
+	return nil
+}
+
+// WebhookPayload json format of the webhook requests. The message is additionally sent as `text` and `content`,
+// so that Slack and Discord incoming webhooks can be used directly
+type WebhookPayload struct {
+	HookEvent
+	Text    string `json:"text,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// WebhookHook posts every event as json to a url. If a signing secret is set, the requests are signed like the
+// requests between MultiJuicer components, see SignRequest
+type WebhookHook struct {
+	URL           string
+	SigningSecret []byte
+	Client        *http.Client
+}
+
+// Name implements Hook
+func (h *WebhookHook) Name() string {
+	return "webhook:" + h.URL
+}
+
+// Handle implements Hook
+func (h *WebhookHook) Handle(ctx context.Context, event HookEvent) error {
+	payload, err := json.Marshal(WebhookPayload{HookEvent: event, Text: event.Message, Content: event.Message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(h.SigningSecret) > 0 {
+		if err := SignRequest(req, h.SigningSecret, time.Now()); err != nil {
+			return err
+		}
+	}
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// createExternalHooks creates the exec and webhook hooks configured via `HOOK_EXEC_COMMANDS` and `HOOK_WEBHOOK_URLS`
+func createExternalHooks(signingSecret []byte) []Hook {
+	external := []Hook{}
+	for _, command := range parseCommaSeparatedList(os.Getenv("HOOK_EXEC_COMMANDS")) {
+		external = append(external, &ExecHook{Command: command})
+	}
+	client := &http.Client{Transport: newTaggingRoundTripper(http.DefaultTransport)}
+	for _, url := range parseCommaSeparatedList(os.Getenv("HOOK_WEBHOOK_URLS")) {
+		external = append(external, &WebhookHook{URL: url, SigningSecret: signingSecret, Client: client})
+	}
+	return external
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingHook struct {
+	events chan HookEvent
+}
+
+func (h *recordingHook) Name() string {
+	return "recording"
+}
+
+func (h *recordingHook) Handle(ctx context.Context, event HookEvent) error {
+	h.events <- event
+	return nil
+}
+
+func TestHooksDispatchNewSolves(t *testing.T) {
+	hook := &recordingHook{events: make(chan HookEvent, 10)}
+	dispatcher := NewHooks(nil, hook)
+
+	solvedAt := time.Date(2021, 4, 10, 11, 0, 0, 0, time.UTC)
+	lastSolves := SolveTimes{1: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)}
+	dispatcher.dispatchSolves("foobar", lastSolves, SolveTimes{1: lastSolves[1], 3: solvedAt, 2: solvedAt})
+
+	assert.Equal(t, HookEvent{Type: HookEventSolve, Team: "foobar", Challenge: 2, ChallengesSolved: 3, Time: solvedAt}, <-hook.events)
+	assert.Equal(t, HookEvent{Type: HookEventSolve, Team: "foobar", Challenge: 3, ChallengesSolved: 3, Time: solvedAt}, <-hook.events)
+	assert.Len(t, hook.events, 0)
+}
+
+func TestTeamTrackerDetectsCreatedIdleAndDeletedTeams(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	tracker := NewTeamTracker(time.Hour)
+
+	changes := tracker.Update(map[string]time.Time{"a": now, "b": now.Add(-2 * time.Hour)}, now)
+	assert.Equal(t, TeamChanges{}, changes)
+
+	now = now.Add(30 * time.Minute)
+	changes = tracker.Update(map[string]time.Time{"b": now.Add(-3 * time.Hour), "d": now, "c": now}, now)
+	assert.Equal(t, TeamChanges{Created: []string{"c", "d"}, Deleted: []string{"a"}}, changes)
+
+	now = now.Add(90 * time.Minute)
+	changes = tracker.Update(map[string]time.Time{"b": now, "c": now.Add(-2 * time.Hour), "d": now}, now)
+	assert.Equal(t, TeamChanges{Idle: []string{"c"}}, changes)
+
+	changes = tracker.Update(map[string]time.Time{"b": now, "c": now.Add(-2 * time.Hour), "d": now}, now)
+	assert.Equal(t, TeamChanges{}, changes)
+}
+
+func TestTeamTrackerWithoutIdleThreshold(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	tracker := NewTeamTracker(0)
+	tracker.Update(map[string]time.Time{"a": now}, now)
+	assert.Equal(t, TeamChanges{}, tracker.Update(map[string]time.Time{"a": now}, now.Add(24*time.Hour)))
+}
+
+func TestWebhookHookPostsSignedEvents(t *testing.T) {
+	received := make(chan HookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.NoError(t, verifySignature(req, []byte("secret"), time.Now()))
+		event := HookEvent{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&event))
+		received <- event
+	}))
+	defer server.Close()
+
+	hook := &WebhookHook{URL: server.URL, SigningSecret: []byte("secret"), Client: http.DefaultClient}
+	event := HookEvent{Type: HookEventTeamCreated, Team: "foobar", Time: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)}
+	assert.NoError(t, hook.Handle(context.Background(), event))
+	assert.Equal(t, event, <-received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	hook.URL = failing.URL
+	assert.EqualError(t, hook.Handle(context.Background(), event), "Webhook responded with status 500")
+}
+
+func TestExecHookPassesEventToCommand(t *testing.T) {
+	directory := t.TempDir()
+	output := filepath.Join(directory, "event.json")
+	script := filepath.Join(directory, "hook.sh")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$MULTIJUICER_EVENT_TYPE $MULTIJUICER_TEAM\" > "+output+".env\ncat > "+output+"\n"), 0755)
+
+	hook := &ExecHook{Command: script}
+	event := HookEvent{Type: HookEventInstanceDeleted, Team: "foobar", Time: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)}
+	assert.NoError(t, hook.Handle(context.Background(), event))
+
+	written := HookEvent{}
+	content, err := ioutil.ReadFile(output)
+	assert.Nil(t, err)
+	assert.NoError(t, json.Unmarshal(content, &written))
+	assert.Equal(t, event, written)
+	env, err := ioutil.ReadFile(output + ".env")
+	assert.Nil(t, err)
+	assert.Equal(t, "instanceDeleted foobar\n", string(env))
+
+	assert.Error(t, (&ExecHook{Command: filepath.Join(directory, "missing.sh")}).Handle(context.Background(), event))
+}
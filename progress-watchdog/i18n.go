@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Messages maps the keys of translated strings to their text in a single locale
+type Messages map[string]string
+
+// Catalog contains the messages of all supported locales, loaded from the embedded `static/locales/<locale>.json` files.
+// Messages missing in a locale fall back to the default locale.
+type Catalog struct {
+	defaultLocale string
+	locales       map[string]Messages
+	matcher       language.Matcher
+	// names of the locales in the order of the tags of the matcher
+	names []string
+}
+
+// I18nResponse json format of the messages response
+type I18nResponse struct {
+	Locale   string   `json:"locale"`
+	Messages Messages `json:"messages"`
+}
+
+// catalog contains the translations of the running watchdog, configured in main
+var catalog = mustLoadCatalog("en")
+
+// loadCatalog loads the embedded message catalogs, the default locale must be one of them
+func loadCatalog(defaultLocale string) (*Catalog, error) {
+	files, err := fs.Glob(staticFiles, "static/locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	locales := map[string]Messages{}
+	for _, file := range files {
+		content, err := staticFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		messages := Messages{}
+		if err := json.Unmarshal(content, &messages); err != nil {
+			return nil, fmt.Errorf("Failed to decode message catalog '%s': %v", file, err)
+		}
+		locales[strings.TrimSuffix(path.Base(file), ".json")] = messages
+	}
+	if _, ok := locales[defaultLocale]; !ok {
+		return nil, fmt.Errorf("Unsupported default locale '%s'", defaultLocale)
+	}
+
+	// the default locale comes first, so that the matcher falls back to it
+	names := []string{defaultLocale}
+	for locale := range locales {
+		if locale != defaultLocale {
+			names = append(names, locale)
+		}
+	}
+	sort.Strings(names[1:])
+	tags := []language.Tag{}
+	for _, name := range names {
+		tags = append(tags, language.Make(name))
+	}
+
+	return &Catalog{
+		defaultLocale: defaultLocale,
+		locales:       locales,
+		matcher:       language.NewMatcher(tags),
+		names:         names,
+	}, nil
+}
+
+func mustLoadCatalog(defaultLocale string) *Catalog {
+	loaded, err := loadCatalog(defaultLocale)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load message catalogs: %s", err))
+	}
+	return loaded
+}
+
+// Negotiate picks the best supported locale for the `Accept-Language` header value, falling back to the default locale
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(preferred) == 0 {
+		return c.defaultLocale
+	}
+	_, index, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		return c.defaultLocale
+	}
+	return c.names[index]
+}
+
+// LocaleOf returns the locale of the request. An explicit `locale` query parameter wins over the `Accept-Language` header,
+// e.g. for embedded widgets, whose requests can't set headers.
+func (c *Catalog) LocaleOf(req *http.Request) string {
+	if locale := req.URL.Query().Get("locale"); locale != "" {
+		return c.Negotiate(locale)
+	}
+	return c.Negotiate(req.Header.Get("Accept-Language"))
+}
+
+// Messages returns all messages of the locale, completed with the messages of the default locale
+func (c *Catalog) Messages(locale string) Messages {
+	messages := Messages{}
+	for key, text := range c.locales[c.defaultLocale] {
+		messages[key] = text
+	}
+	for key, text := range c.locales[locale] {
+		messages[key] = text
+	}
+	return messages
+}
+
+// DefaultLocale returns the locale used when no supported locale was requested, e.g. for notifications
+func (c *Catalog) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+// handleI18n serves the messages of the negotiated locale to the scoreboard ui
+func (s *Server) handleI18n(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	locale := catalog.LocaleOf(req)
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+	writeJSON(w, I18nResponse{Locale: locale, Messages: catalog.Messages(locale)})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCatalogNegotiatesLocale(t *testing.T) {
+	assert.Equal(t, "de", catalog.Negotiate("de-DE,de;q=0.9,en;q=0.8"))
+	assert.Equal(t, "en", catalog.Negotiate("fr-FR,en;q=0.5"))
+	assert.Equal(t, "de", catalog.Negotiate("fr-FR,de;q=0.5"))
+	assert.Equal(t, "en", catalog.Negotiate("fr-FR"))
+	assert.Equal(t, "en", catalog.Negotiate(""))
+	assert.Equal(t, "en", catalog.Negotiate("not a language;;"))
+
+	german, err := loadCatalog("de")
+	assert.Nil(t, err)
+	assert.Equal(t, "de", german.Negotiate("fr-FR"))
+
+	_, err = loadCatalog("xx")
+	assert.EqualError(t, err, "Unsupported default locale 'xx'")
+}
+
+func TestCatalogsContainTheSameKeys(t *testing.T) {
+	english := catalog.locales["en"]
+	for locale, messages := range catalog.locales {
+		for key := range english {
+			assert.Contains(t, messages, key, "locale '%s' is missing key '%s'", locale, key)
+		}
+		for key := range messages {
+			assert.Contains(t, english, key, "locale '%s' has unknown key '%s'", locale, key)
+		}
+	}
+}
+
+func TestI18nServesMessagesOfNegotiatedLocale(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	req := httptest.NewRequest("GET", "/api/i18n", nil)
+	req.Header.Set("Accept-Language", "de-AT,de;q=0.9")
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+
+	assert.Equal(t, "de", rr.Header().Get("Content-Language"))
+	response := I18nResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "de", response.Locale)
+	assert.Equal(t, "Bestenliste", response.Messages["scoreboard.heading"])
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/i18n?locale=en", nil))
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "Scoreboard", response.Messages["scoreboard.heading"])
+}
+
+func TestWidgetIsTranslated(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/widget?locale=de", nil))
+	assert.Equal(t, "de", rr.Header().Get("Content-Language"))
+	assert.Contains(t, rr.Body.String(), `<html lang="de">`)
+	assert.Contains(t, rr.Body.String(), "Noch keine Teams")
+}
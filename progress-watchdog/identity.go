@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// podInfoDirectory is where the Downward API volume with the metadata of the watchdog pod is mounted
+	podInfoDirectory = "/etc/podinfo"
+	// serviceAccountNamespaceFile is mounted into every pod using a service account token
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// InstanceIdentity identifies the running watchdog instance, detected via the Downward API
+type InstanceIdentity struct {
+	Namespace string
+	PodName   string
+	Labels    map[string]string
+}
+
+// ID uniquely identifies the instance, e.g. as holder identity of leases
+func (i InstanceIdentity) ID() string {
+	return i.Namespace + "/" + i.PodName
+}
+
+// detectInstanceIdentity reads the identity of the watchdog pod from the Downward API volume.
+// The `NAMESPACE` and `POD_NAME` env vars take precedence, the namespace falls back to the one of the service account and the pod name to the hostname.
+func detectInstanceIdentity() InstanceIdentity {
+	return detectInstanceIdentityFrom(podInfoDirectory, serviceAccountNamespaceFile)
+}
+
+func detectInstanceIdentityFrom(podInfo, serviceAccountNamespace string) InstanceIdentity {
+	identity := InstanceIdentity{
+		Namespace: firstNonEmpty(
+			os.Getenv("NAMESPACE"),
+			readPodInfoFile(filepath.Join(podInfo, "namespace")),
+			readPodInfoFile(serviceAccountNamespace),
+		),
+		PodName: firstNonEmpty(
+			os.Getenv("POD_NAME"),
+			readPodInfoFile(filepath.Join(podInfo, "name")),
+		),
+		Labels: parsePodInfoLabels(readPodInfoFile(filepath.Join(podInfo, "labels"))),
+	}
+	if identity.PodName == "" {
+		// the hostname of a pod defaults to its name
+		hostname, err := os.Hostname()
+		if err == nil {
+			identity.PodName = hostname
+		}
+	}
+	return identity
+}
+
+func readPodInfoFile(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// parsePodInfoLabels parses labels in the format of the Downward API, one `key="value"` pair per line
+func parsePodInfoLabels(content string) map[string]string {
+	labels := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.Unquote(parts[1])
+		if err != nil {
+			value = parts[1]
+		}
+		labels[parts[0]] = value
+	}
+	return labels
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// metricLabels are added to all metrics of the instance, so that the metrics of multiple installations can be told apart
+func metricLabels(identity InstanceIdentity) map[string]string {
+	labels := map[string]string{
+		"namespace": identity.Namespace,
+		"pod":       identity.PodName,
+	}
+	if release, ok := identity.Labels["app.kubernetes.io/instance"]; ok {
+		labels["release"] = release
+	}
+	return labels
+}
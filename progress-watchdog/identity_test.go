@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectInstanceIdentityFromDownwardAPI(t *testing.T) {
+	os.Unsetenv("NAMESPACE")
+	os.Unsetenv("POD_NAME")
+	podInfo := t.TempDir()
+	ioutil.WriteFile(filepath.Join(podInfo, "namespace"), []byte("multi-juicer\n"), 0644)
+	ioutil.WriteFile(filepath.Join(podInfo, "name"), []byte("progress-watchdog-5d8f7b9c4-x2x7q"), 0644)
+	ioutil.WriteFile(filepath.Join(podInfo, "labels"), []byte("app.kubernetes.io/instance=\"event\"\napp.kubernetes.io/name=\"progress-watchdog\""), 0644)
+
+	identity := detectInstanceIdentityFrom(podInfo, filepath.Join(podInfo, "missing"))
+
+	assert.Equal(t, InstanceIdentity{
+		Namespace: "multi-juicer",
+		PodName:   "progress-watchdog-5d8f7b9c4-x2x7q",
+		Labels: map[string]string{
+			"app.kubernetes.io/instance": "event",
+			"app.kubernetes.io/name":     "progress-watchdog",
+		},
+	}, identity)
+	assert.Equal(t, "multi-juicer/progress-watchdog-5d8f7b9c4-x2x7q", identity.ID())
+	assert.Equal(t, map[string]string{"namespace": "multi-juicer", "pod": "progress-watchdog-5d8f7b9c4-x2x7q", "release": "event"}, metricLabels(identity))
+}
+
+func TestDetectInstanceIdentityPrefersEnvAndFallsBackToServiceAccountNamespace(t *testing.T) {
+	directory := t.TempDir()
+	serviceAccountNamespace := filepath.Join(directory, "namespace")
+	ioutil.WriteFile(serviceAccountNamespace, []byte("from-service-account"), 0644)
+
+	os.Unsetenv("NAMESPACE")
+	os.Setenv("POD_NAME", "from-env")
+	defer os.Unsetenv("POD_NAME")
+
+	identity := detectInstanceIdentityFrom(filepath.Join(directory, "missing"), serviceAccountNamespace)
+	assert.Equal(t, "from-service-account", identity.Namespace)
+	assert.Equal(t, "from-env", identity.PodName)
+	assert.Equal(t, map[string]string{}, identity.Labels)
+
+	os.Setenv("NAMESPACE", "from-env")
+	defer os.Unsetenv("NAMESPACE")
+	assert.Equal(t, "from-env", detectInstanceIdentityFrom(filepath.Join(directory, "missing"), serviceAccountNamespace).Namespace)
+}
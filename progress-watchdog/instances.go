@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// juiceShopInstanceResource is the JuiceShopInstance custom resource, see the CRD in the helm chart
+var juiceShopInstanceResource = schema.GroupVersionResource{Group: "multi-juicer.iteratec.dev", Version: "v1alpha1", Resource: "juiceshopinstances"}
+
+// JuiceShopInstance represents the JuiceShop of a team as custom resource, so that admins get an overview via `kubectl get juiceshopinstances`
+type JuiceShopInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JuiceShopInstanceSpec   `json:"spec"`
+	Status JuiceShopInstanceStatus `json:"status,omitempty"`
+}
+
+// JuiceShopInstanceSpec the team and the deployment running its JuiceShop
+type JuiceShopInstanceSpec struct {
+	Team       string `json:"team"`
+	Deployment string `json:"deployment"`
+}
+
+// JuiceShopInstanceStatus the progress of the team as tracked by the watchdog
+type JuiceShopInstanceStatus struct {
+	ContinueCode     string `json:"continueCode,omitempty"`
+	ChallengesSolved int    `json:"challengesSolved"`
+	ReadyReplicas    int32  `json:"readyReplicas"`
+	Paused           bool   `json:"paused,omitempty"`
+	// LastSeen is the last request of the team to its JuiceShop
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+	// LastSolvedAt is the time of the latest solve of the team
+	LastSolvedAt *metav1.Time `json:"lastSolvedAt,omitempty"`
+}
+
+// InstanceController reconciles a JuiceShopInstance for every JuiceShop deployment. The instances are owned by their deployment,
+// so kubernetes deletes them together with the JuiceShop.
+type InstanceController struct {
+	client    dynamic.Interface
+	namespace string
+}
+
+// NewInstanceController creates an InstanceController for the JuiceShops in the namespace, all namespaces if it's empty
+func NewInstanceController(client dynamic.Interface, namespace string) *InstanceController {
+	return &InstanceController{client: client, namespace: namespace}
+}
+
+// juiceShopInstanceOf returns the desired JuiceShopInstance of the JuiceShop deployment
+func juiceShopInstanceOf(deployment appsv1.Deployment) JuiceShopInstance {
+	progress := teamProgressFromDeployment(deployment)
+	status := JuiceShopInstanceStatus{
+		ContinueCode:     progress.ContinueCode,
+		ChallengesSolved: progress.ChallengesSolved,
+		ReadyReplicas:    deployment.Status.ReadyReplicas,
+		Paused:           progress.Paused,
+	}
+	if lastSeen := lastRequestOf(deployment); !lastSeen.IsZero() {
+		// serialized with second precision, truncated so that unchanged instances compare equal
+		status.LastSeen = &metav1.Time{Time: lastSeen.UTC().Truncate(time.Second)}
+	}
+	if lastSolvedAt := progress.Solves.LastSolvedAt(); !lastSolvedAt.IsZero() {
+		status.LastSolvedAt = &metav1.Time{Time: lastSolvedAt.UTC().Truncate(time.Second)}
+	}
+
+	instance := JuiceShopInstance{
+		TypeMeta: metav1.TypeMeta{APIVersion: juiceShopInstanceResource.GroupVersion().String(), Kind: "JuiceShopInstance"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      progress.Team,
+			Namespace: deployment.Namespace,
+			Labels:    map[string]string{"team": progress.Team},
+		},
+		Spec:   JuiceShopInstanceSpec{Team: progress.Team, Deployment: deployment.Name},
+		Status: status,
+	}
+	if deployment.UID != "" {
+		controller := true
+		instance.OwnerReferences = []metav1.OwnerReference{{APIVersion: workloads.APIVersion(), Kind: workloads.Kind(), Name: deployment.Name, UID: deployment.UID, Controller: &controller}}
+	}
+	return instance
+}
+
+// Reconcile creates the missing JuiceShopInstances of the deployments and updates the status of the changed ones
+func (c *InstanceController) Reconcile(ctx context.Context, deployments []appsv1.Deployment) error {
+	list, err := c.client.Resource(juiceShopInstanceResource).Namespace(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to list JuiceShopInstances: %v", err)
+	}
+	existing := map[string]unstructured.Unstructured{}
+	for _, item := range list.Items {
+		existing[item.GetNamespace()+"/"+item.GetName()] = item
+	}
+
+	for _, deployment := range deployments {
+		desired := juiceShopInstanceOf(deployment)
+		if current, ok := existing[desired.Namespace+"/"+desired.Name]; ok {
+			err = c.updateStatus(ctx, current, desired.Status)
+		} else {
+			err = c.create(ctx, desired)
+		}
+		if err != nil {
+			log.Warningf("Failed to reconcile JuiceShopInstance of team '%s': %s", desired.Spec.Team, err)
+		}
+	}
+	return nil
+}
+
+func (c *InstanceController) create(ctx context.Context, instance JuiceShopInstance) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&instance)
+	if err != nil {
+		return err
+	}
+	created, err := c.client.Resource(juiceShopInstanceResource).Namespace(instance.Namespace).Create(ctx, &unstructured.Unstructured{Object: content}, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	log.Infof("Created JuiceShopInstance of team '%s'", instance.Spec.Team)
+	// the status isn't set on creation if the status subresource is enabled
+	return c.updateStatus(ctx, *created, instance.Status)
+}
+
+func (c *InstanceController) updateStatus(ctx context.Context, current unstructured.Unstructured, status JuiceShopInstanceStatus) error {
+	instance := JuiceShopInstance{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(current.Object, &instance); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(instance.Status, status) {
+		return nil
+	}
+	instance.Status = status
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&instance)
+	if err != nil {
+		return err
+	}
+	// updated with the resourceVersion of the listed instance, conflicts are resolved with the next reconciliation
+	_, err = c.client.Resource(juiceShopInstanceResource).Namespace(current.GetNamespace()).UpdateStatus(ctx, &unstructured.Unstructured{Object: content}, metav1.UpdateOptions{})
+	return err
+}
+
+// Run reconciles the JuiceShopInstances every interval until the context is cancelled
+func (c *InstanceController) Run(ctx context.Context, clientset kubernetes.Interface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		juiceShops, err := listJuiceShops(ctx, clientset, c.namespace, juiceShopSelector)
+		if err == nil {
+			err = c.Reconcile(ctx, juiceShops)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Warningf("Failed to reconcile JuiceShopInstances: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeInstanceClient() *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		juiceShopInstanceResource: "JuiceShopInstanceList",
+	})
+}
+
+func getJuiceShopInstance(t *testing.T, client *dynamicfake.FakeDynamicClient, team string) JuiceShopInstance {
+	object, err := client.Resource(juiceShopInstanceResource).Namespace("default").Get(context.Background(), team, metav1.GetOptions{})
+	assert.NoError(t, err)
+	instance := JuiceShopInstance{}
+	assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, &instance))
+	return instance
+}
+
+func TestReconcileCreatesJuiceShopInstancesWithTheProgressOfTheTeams(t *testing.T) {
+	client := newFakeInstanceClient()
+	controller := NewInstanceController(client, "default")
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	deployment.Status.ReadyReplicas = 1
+	deployment.Annotations["multi-juicer.iteratec.dev/lastRequest"] = "1620000000000"
+
+	assert.NoError(t, controller.Reconcile(context.Background(), []appsv1.Deployment{*deployment}))
+
+	instance := getJuiceShopInstance(t, client, "foobar")
+	assert.Equal(t, JuiceShopInstanceSpec{Team: "foobar", Deployment: "t-foobar-juiceshop"}, instance.Spec)
+	assert.Equal(t, "abc", instance.Status.ContinueCode)
+	assert.Equal(t, 3, instance.Status.ChallengesSolved)
+	assert.Equal(t, int32(1), instance.Status.ReadyReplicas)
+	assert.Equal(t, time.Unix(1620000000, 0).UTC(), instance.Status.LastSeen.UTC())
+}
+
+func TestReconcileUpdatesStatusOfExistingJuiceShopInstances(t *testing.T) {
+	client := newFakeInstanceClient()
+	controller := NewInstanceController(client, "default")
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	assert.NoError(t, controller.Reconcile(context.Background(), []appsv1.Deployment{*deployment}))
+
+	deployment.Annotations["multi-juicer.iteratec.dev/continueCode"] = "def"
+	deployment.Annotations["multi-juicer.iteratec.dev/challengesSolved"] = "4"
+	assert.NoError(t, controller.Reconcile(context.Background(), []appsv1.Deployment{*deployment}))
+
+	instance := getJuiceShopInstance(t, client, "foobar")
+	assert.Equal(t, "def", instance.Status.ContinueCode)
+	assert.Equal(t, 4, instance.Status.ChallengesSolved)
+}
@@ -0,0 +1,255 @@
+// Package fakejuiceshop provides a fake JuiceShop serving the apis the progress-watchdog uses: the ContinueCodes of the regular and the
+// coding challenges, their apply endpoints and the challenge api. The progress of the fake can be changed by the tests and its responses
+// can be scripted, e.g. to let the next requests fail, so that the watchdog can be tested without running real JuiceShops.
+package fakejuiceshop
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/speps/go-hashids"
+)
+
+// Routes of the apis served by the fake, used to script their responses
+const (
+	RouteContinueCode      = "GET /rest/continue-code"
+	RouteApplyContinueCode = "PUT /rest/continue-code/apply"
+	RouteFindItCode        = "GET /rest/continue-code-findIt"
+	RouteApplyFindItCode   = "PUT /rest/continue-code-findIt/apply"
+	RouteFixItCode         = "GET /rest/continue-code-fixIt"
+	RouteApplyFixItCode    = "PUT /rest/continue-code-fixIt/apply"
+	RouteChallenges        = "GET /api/Challenges/"
+)
+
+// salts the JuiceShop uses for the hashids of its ContinueCodes, keyed by the phase of the challenges
+var salts = map[string]string{
+	"":       "this is my salt",
+	"findIt": "this is the salt for findIt challenges",
+	"fixIt":  "yet another salt for the fixIt challenges",
+}
+
+// Challenge a challenge of the fake, listed by its challenge api
+type Challenge struct {
+	ID            int       `json:"id"`
+	Key           string    `json:"key"`
+	Solved        bool      `json:"solved"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	DisabledEnv   string    `json:"disabledEnv,omitempty"`
+	TutorialOrder *int      `json:"tutorialOrder,omitempty"`
+}
+
+// Response is a scripted response of the fake
+type Response struct {
+	Status int
+	Body   string
+}
+
+// JuiceShop is a fake JuiceShop, it implements http.Handler. The zero value isn't usable, use New.
+type JuiceShop struct {
+	mutex sync.Mutex
+	// solved are the solved challenges by the phase, the regular challenges have the empty phase
+	solved     map[string][]int
+	challenges []Challenge
+	scripted   map[string][]Response
+	requests   []string
+	// codingChallenges is false for JuiceShops before v12, which don't know the ContinueCodes of the coding challenges
+	codingChallenges bool
+	ignoreApplies    bool
+}
+
+// New creates a fake JuiceShop without solved challenges, supporting the coding challenges like current JuiceShops
+func New() *JuiceShop {
+	return &JuiceShop{solved: map[string][]int{}, scripted: map[string][]Response{}, codingChallenges: true}
+}
+
+// Solve marks the challenges as solved, e.g. `Solve(1, 2)`
+func (j *JuiceShop) Solve(ids ...int) {
+	j.SolvePhase("", ids...)
+}
+
+// SolvePhase marks the coding challenges of the phase, `findIt` or `fixIt`, as solved
+func (j *JuiceShop) SolvePhase(phase string, ids ...int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.solved[phase] = merge(j.solved[phase], ids)
+}
+
+// Solved returns the sorted ids of the solved challenges
+func (j *JuiceShop) Solved() []int {
+	return j.SolvedPhase("")
+}
+
+// SolvedPhase returns the sorted ids of the solved coding challenges of the phase
+func (j *JuiceShop) SolvedPhase(phase string) []int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return append([]int{}, j.solved[phase]...)
+}
+
+// Reset drops the progress of all challenges, like a JuiceShop whose database got wiped by a restart
+func (j *JuiceShop) Reset() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.solved = map[string][]int{}
+}
+
+// SetChallenges sets the challenges listed by the challenge api, their solved state is taken from the solved challenges of the fake.
+// Without challenges the api lists the solved challenges only.
+func (j *JuiceShop) SetChallenges(challenges []Challenge) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.challenges = append([]Challenge{}, challenges...)
+}
+
+// DisableCodingChallenges makes the fake answer the apis of the coding challenges with 404, like JuiceShops before v12
+func (j *JuiceShop) DisableCodingChallenges() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.codingChallenges = false
+}
+
+// IgnoreApplies makes the fake answer applied ContinueCodes with success without restoring their progress,
+// like JuiceShops silently ignore invalid ContinueCodes
+func (j *JuiceShop) IgnoreApplies() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.ignoreApplies = true
+}
+
+// Script queues responses of the route, e.g. `Script(RouteContinueCode, Response{Status: 503})`. The next requests of the route are
+// answered with the queued responses in order, afterwards the fake answers like a JuiceShop again.
+func (j *JuiceShop) Script(route string, responses ...Response) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.scripted[route] = append(j.scripted[route], responses...)
+}
+
+// Requests returns the routes of all requests received so far, e.g. `PUT /rest/continue-code/apply`
+func (j *JuiceShop) Requests() []string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return append([]string{}, j.requests...)
+}
+
+// ServeHTTP implements http.Handler
+func (j *JuiceShop) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	route, phase, argument := routeOf(req)
+	w.Header().Set("Content-Type", "application/json")
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.requests = append(j.requests, route)
+	if scripted := j.scripted[route]; len(scripted) > 0 {
+		j.scripted[route] = scripted[1:]
+		status := scripted[0].Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(scripted[0].Body))
+		return
+	}
+
+	if phase != "" && !j.codingChallenges {
+		http.NotFound(w, req)
+		return
+	}
+	switch route {
+	case RouteContinueCode, RouteFindItCode, RouteFixItCode:
+		continueCode, _ := Encode(phase, j.solved[phase])
+		json.NewEncoder(w).Encode(map[string]string{"continueCode": continueCode})
+	case RouteApplyContinueCode, RouteApplyFindItCode, RouteApplyFixItCode:
+		applied, err := Decode(phase, argument)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid continue code"})
+			return
+		}
+		if !j.ignoreApplies {
+			j.solved[phase] = merge(j.solved[phase], applied)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"data": "ok"})
+	case RouteChallenges:
+		json.NewEncoder(w).Encode(map[string][]Challenge{"data": j.challengeList()})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// challengeList lists the challenges with their solved state, must be called with the mutex held
+func (j *JuiceShop) challengeList() []Challenge {
+	solved := map[int]bool{}
+	for _, id := range j.solved[""] {
+		solved[id] = true
+	}
+	challenges := j.challenges
+	if len(challenges) == 0 {
+		challenges = []Challenge{}
+		for _, id := range j.solved[""] {
+			challenges = append(challenges, Challenge{ID: id})
+		}
+	}
+	listed := []Challenge{}
+	for _, challenge := range challenges {
+		challenge.Solved = solved[challenge.ID]
+		listed = append(listed, challenge)
+	}
+	return listed
+}
+
+// routeOf returns the route of the request, the phase of the coding challenges it's about and the ContinueCode of applies
+func routeOf(req *http.Request) (string, string, string) {
+	path := req.URL.Path
+	phase := ""
+	if strings.HasPrefix(path, "/rest/continue-code-") {
+		phase = strings.SplitN(strings.TrimPrefix(path, "/rest/continue-code-"), "/", 2)[0]
+		if _, ok := salts[phase]; !ok {
+			return req.Method + " " + path, "", ""
+		}
+	}
+	if req.Method == http.MethodPut && strings.Contains(path, "/apply/") {
+		parts := strings.SplitN(path, "/apply/", 2)
+		return req.Method + " " + parts[0] + "/apply", phase, parts[1]
+	}
+	return req.Method + " " + path, phase, ""
+}
+
+// Encode creates the ContinueCode a JuiceShop creates for the solved challenges of the phase, the empty phase for the regular challenges
+func Encode(phase string, solved []int) (string, error) {
+	if len(solved) == 0 {
+		return "", nil
+	}
+	return hashID(phase).Encode(merge(nil, solved))
+}
+
+// Decode returns the challenges solved according to the ContinueCode of the phase
+func Decode(phase, continueCode string) ([]int, error) {
+	return hashID(phase).DecodeWithError(continueCode)
+}
+
+func hashID(phase string) *hashids.HashID {
+	data := hashids.NewData()
+	data.Salt = salts[phase]
+	data.MinLength = 60
+	data.Alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+	hashID, _ := hashids.NewWithData(data)
+	return hashID
+}
+
+// merge returns the sorted union of the challenge ids
+func merge(solved []int, additional []int) []int {
+	unique := map[int]bool{}
+	for _, id := range append(append([]int{}, solved...), additional...) {
+		unique[id] = true
+	}
+	merged := []int{}
+	for id := range unique {
+		merged = append(merged, id)
+	}
+	sort.Ints(merged)
+	return merged
+}
@@ -0,0 +1,116 @@
+package fakejuiceshop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, server *httptest.Server, path string) (int, map[string]interface{}) {
+	res, err := server.Client().Get(server.URL + path)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	body := map[string]interface{}{}
+	json.NewDecoder(res.Body).Decode(&body)
+	return res.StatusCode, body
+}
+
+func put(t *testing.T, server *httptest.Server, path string) int {
+	req, _ := http.NewRequest(http.MethodPut, server.URL+path, nil)
+	res, err := server.Client().Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestServesTheContinueCodeOfTheSolvedChallenges(t *testing.T) {
+	juiceShop := New()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+
+	status, body := get(t, server, "/rest/continue-code")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "", body["continueCode"])
+
+	juiceShop.Solve(3, 1, 2)
+	_, body = get(t, server, "/rest/continue-code")
+	solved, err := Decode("", body["continueCode"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, solved)
+}
+
+func TestAppliedContinueCodesRestoreTheProgress(t *testing.T) {
+	juiceShop := New()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+	juiceShop.Solve(1)
+
+	continueCode, err := Encode("", []int{2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, put(t, server, "/rest/continue-code/apply/"+continueCode))
+	assert.Equal(t, []int{1, 2, 3}, juiceShop.Solved())
+
+	findIt, err := Encode("findIt", []int{4})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, put(t, server, "/rest/continue-code-findIt/apply/"+findIt))
+	assert.Equal(t, []int{4}, juiceShop.SolvedPhase("findIt"))
+
+	assert.Equal(t, http.StatusBadRequest, put(t, server, "/rest/continue-code/apply/invalid"))
+	assert.Equal(t, []string{RouteApplyContinueCode, RouteApplyFindItCode, RouteApplyContinueCode}, juiceShop.Requests())
+}
+
+func TestIgnoredAppliesDontRestoreTheProgress(t *testing.T) {
+	juiceShop := New()
+	juiceShop.IgnoreApplies()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+
+	continueCode, _ := Encode("", []int{2, 3})
+	assert.Equal(t, http.StatusOK, put(t, server, "/rest/continue-code/apply/"+continueCode))
+	assert.Empty(t, juiceShop.Solved())
+}
+
+func TestListsTheChallengesWithTheirSolvedState(t *testing.T) {
+	juiceShop := New()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+	juiceShop.SetChallenges([]Challenge{{ID: 1, Key: "scoreBoardChallenge"}, {ID: 2, Key: "loginAdminChallenge", DisabledEnv: "Docker"}})
+	juiceShop.Solve(1)
+
+	_, body := get(t, server, "/api/Challenges/")
+	challenges := body["data"].([]interface{})
+	assert.Len(t, challenges, 2)
+	assert.Equal(t, true, challenges[0].(map[string]interface{})["solved"])
+	assert.Equal(t, false, challenges[1].(map[string]interface{})["solved"])
+	assert.Equal(t, "Docker", challenges[1].(map[string]interface{})["disabledEnv"])
+}
+
+func TestScriptedResponsesAreServedInOrder(t *testing.T) {
+	juiceShop := New()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+	juiceShop.Script(RouteContinueCode, Response{Status: http.StatusServiceUnavailable}, Response{Body: `{"continueCode":"scripted"}`})
+
+	status, _ := get(t, server, "/rest/continue-code")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	status, body := get(t, server, "/rest/continue-code")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "scripted", body["continueCode"])
+	_, body = get(t, server, "/rest/continue-code")
+	assert.Equal(t, "", body["continueCode"])
+}
+
+func TestJuiceShopsBeforeV12DontKnowCodingChallenges(t *testing.T) {
+	juiceShop := New()
+	juiceShop.DisableCodingChallenges()
+	server := httptest.NewServer(juiceShop)
+	defer server.Close()
+
+	status, _ := get(t, server, "/rest/continue-code-fixIt")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = get(t, server, "/rest/continue-code")
+	assert.Equal(t, http.StatusOK, status)
+}
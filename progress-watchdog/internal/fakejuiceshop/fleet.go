@@ -0,0 +1,78 @@
+package fakejuiceshop
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fleet are the fake JuiceShops of many teams, addressed like the watchdog addresses them: by the host of their service,
+// e.g. `t-<team>-juiceshop.<namespace>.svc.cluster.local:3000`. It implements http.RoundTripper, so that clients talk to the fakes
+// without opening a server per team.
+type Fleet struct {
+	// Latency is added to every request, e.g. to simulate a busy cluster network
+	Latency time.Duration
+
+	mutex sync.Mutex
+	teams map[string]*JuiceShop
+}
+
+// NewFleet creates a fleet without JuiceShops, the JuiceShop of a team is created with its first request
+func NewFleet() *Fleet {
+	return &Fleet{teams: map[string]*JuiceShop{}}
+}
+
+// Team returns the JuiceShop of the team, creating it if the team doesn't have one yet
+func (f *Fleet) Team(team string) *JuiceShop {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	juiceShop, ok := f.teams[team]
+	if !ok {
+		juiceShop = New()
+		f.teams[team] = juiceShop
+	}
+	return juiceShop
+}
+
+// Teams returns the sorted names of the teams with a JuiceShop
+func (f *Fleet) Teams() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	teams := []string{}
+	for team := range f.teams {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// Client returns a client sending its requests to the JuiceShops of the fleet
+func (f *Fleet) Client() *http.Client {
+	return &http.Client{Transport: f}
+}
+
+// RoundTrip implements http.RoundTripper, requests to hosts which aren't JuiceShop services are answered with 502
+func (f *Fleet) RoundTrip(req *http.Request) (*http.Response, error) {
+	time.Sleep(f.Latency)
+	rr := httptest.NewRecorder()
+	if team, ok := TeamOf(req.URL.Hostname()); ok {
+		f.Team(team).ServeHTTP(rr, req)
+	} else {
+		rr.WriteHeader(http.StatusBadGateway)
+	}
+	res := rr.Result()
+	res.Request = req
+	return res, nil
+}
+
+// TeamOf returns the team of the host of a JuiceShop service, false if the host isn't one
+func TeamOf(host string) (string, bool) {
+	service := strings.SplitN(host, ".", 2)[0]
+	if !strings.HasPrefix(service, "t-") || !strings.HasSuffix(service, "-juiceshop") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(service, "t-"), "-juiceshop"), true
+}
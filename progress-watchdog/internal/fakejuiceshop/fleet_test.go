@@ -0,0 +1,35 @@
+package fakejuiceshop
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleetRoutesRequestsToTheJuiceShopOfTheTeam(t *testing.T) {
+	fleet := NewFleet()
+	fleet.Team("foobar").Solve(1)
+	client := fleet.Client()
+
+	res, err := client.Get("http://t-foobar-juiceshop.default.svc.cluster.local:3000/rest/continue-code")
+	assert.NoError(t, err)
+	res.Body.Close()
+	res, err = client.Get("http://t-barfoo-juiceshop:3000/rest/continue-code")
+	assert.NoError(t, err)
+	res.Body.Close()
+	res, err = client.Get("http://kubernetes.default.svc/api")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+
+	assert.Equal(t, []string{"barfoo", "foobar"}, fleet.Teams())
+	assert.Equal(t, []string{RouteContinueCode}, fleet.Team("foobar").Requests())
+}
+
+func TestTeamOfJuiceShopServices(t *testing.T) {
+	team, ok := TeamOf("t-team-0001-juiceshop.ctf.svc.cluster.local")
+	assert.True(t, ok)
+	assert.Equal(t, "team-0001", team)
+	_, ok = TeamOf("10.0.0.12")
+	assert.False(t, ok)
+}
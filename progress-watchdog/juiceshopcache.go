@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// JuiceShopCache is the informer cache of the JuiceShop workloads shared by all subsystems of the watchdog, so that the discovery,
+// the api, backups and the JuiceShopInstances read the state of the JuiceShops from one watch instead of listing them on their own.
+type JuiceShopCache struct {
+	namespace string
+	factory   informers.SharedInformerFactory
+	informer  cache.SharedIndexInformer
+
+	mutex    sync.RWMutex
+	handlers map[int]cache.ResourceEventHandler
+	nextID   int
+}
+
+// juiceShopCache is the cache of the running watchdog, nil for commands which read the JuiceShops from the api
+var juiceShopCache *JuiceShopCache
+
+// NewJuiceShopCache creates the cache of the JuiceShops of the namespace, all namespaces if it's empty. Its subscribers get all JuiceShops
+// redelivered as updates every resync interval.
+func NewJuiceShopCache(clientset kubernetes.Interface, namespace string, resync time.Duration) *JuiceShopCache {
+	factory := newJuiceShopInformerFactory(clientset, namespace, resync)
+	c := &JuiceShopCache{namespace: namespace, factory: factory, informer: workloads.Informer(factory), handlers: map[int]cache.ResourceEventHandler{}}
+	c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.dispatch(func(handler cache.ResourceEventHandler) { handler.OnAdd(obj) })
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.dispatch(func(handler cache.ResourceEventHandler) { handler.OnUpdate(oldObj, newObj) })
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.dispatch(func(handler cache.ResourceEventHandler) { handler.OnDelete(obj) })
+		},
+	})
+	return c
+}
+
+// newJuiceShopInformerFactory creates an informer factory restricted to the JuiceShop workloads of the namespace
+func newJuiceShopInformerFactory(clientset kubernetes.Interface, namespace string, resync time.Duration) informers.SharedInformerFactory {
+	return informers.NewSharedInformerFactoryWithOptions(clientset, resync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = juiceShopSelector
+		}),
+	)
+}
+
+func (c *JuiceShopCache) dispatch(notify func(handler cache.ResourceEventHandler)) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, handler := range c.handlers {
+		notify(handler)
+	}
+}
+
+// Start starts watching the JuiceShops until the context is cancelled
+func (c *JuiceShopCache) Start(ctx context.Context) {
+	c.factory.Start(ctx.Done())
+}
+
+// WaitForSync waits until the cache holds all JuiceShops, returns false if the context was cancelled before
+func (c *JuiceShopCache) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced)
+}
+
+// Subscribe registers a handler notified about all changes of the JuiceShops, the JuiceShops already in the cache are delivered as adds.
+// Unlike handlers of the informer, subscriptions can be cancelled with the returned function, e.g. when a replica loses its leadership.
+func (c *JuiceShopCache) Subscribe(handler cache.ResourceEventHandler) func() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.handlers[id] = handler
+	for _, obj := range c.informer.GetStore().List() {
+		handler.OnAdd(obj)
+	}
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.handlers, id)
+	}
+}
+
+// Covers checks if the cache holds the JuiceShops of the namespace, metav1.NamespaceAll only if it watches all namespaces
+func (c *JuiceShopCache) Covers(namespace string) bool {
+	return c.namespace == metav1.NamespaceAll || c.namespace == namespace
+}
+
+// List returns the cached JuiceShops of the namespace matching the selector, sorted like the api sorts them
+func (c *JuiceShopCache) List(namespace string, selector labels.Selector) []appsv1.Deployment {
+	juiceShops := []appsv1.Deployment{}
+	for _, obj := range c.informer.GetStore().List() {
+		instance, ok := workloads.Convert(obj)
+		if !ok || (namespace != metav1.NamespaceAll && instance.Namespace != namespace) || !selector.Matches(labels.Set(instance.Labels)) {
+			continue
+		}
+		juiceShops = append(juiceShops, *instance)
+	}
+	sort.Slice(juiceShops, func(i, j int) bool {
+		if juiceShops[i].Namespace != juiceShops[j].Namespace {
+			return juiceShops[i].Namespace < juiceShops[j].Namespace
+		}
+		return juiceShops[i].Name < juiceShops[j].Name
+	})
+	return juiceShops
+}
+
+// listJuiceShops lists the JuiceShops of the namespace matching the label selector. They are read from the shared cache if it covers
+// the namespace and is synced, otherwise from the api, e.g. when running a command.
+func listJuiceShops(ctx context.Context, clientset kubernetes.Interface, namespace, labelSelector string) ([]appsv1.Deployment, error) {
+	if juiceShopCache != nil && juiceShopCache.Covers(namespace) && juiceShopCache.informer.HasSynced() {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		return juiceShopCache.List(namespace, selector), nil
+	}
+	return workloads.List(ctx, clientset, namespace, labelSelector)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+// startJuiceShopCache starts a cache of the JuiceShops of the default namespace, which runs until the context is cancelled
+func startJuiceShopCache(ctx context.Context, clientset *fake.Clientset) *JuiceShopCache {
+	juiceShops := NewJuiceShopCache(clientset, "default", time.Hour)
+	juiceShops.Start(ctx)
+	return juiceShops
+}
+
+// useJuiceShopCache starts a synced cache of the JuiceShops of the default namespace as the cache of the watchdog
+func useJuiceShopCache(t *testing.T, clientset *fake.Clientset) *JuiceShopCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	juiceShops := startJuiceShopCache(ctx, clientset)
+	assert.True(t, juiceShops.WaitForSync(ctx))
+	previous := juiceShopCache
+	juiceShopCache = juiceShops
+	t.Cleanup(func() {
+		cancel()
+		juiceShopCache = previous
+	})
+	return juiceShops
+}
+
+func TestJuiceShopCacheListsTheJuiceShopsMatchingTheSelectorSortedByName(t *testing.T) {
+	teamB := createJuiceShopDeployment("team-b", "", "0")
+	teamA := createJuiceShopDeployment("team-a", "", "0")
+	teamA.Labels["tier"] = "pro"
+	other := createJuiceShopDeployment("other", "", "0")
+	other.Labels["app"] = "something-else"
+	juiceShops := useJuiceShopCache(t, fake.NewSimpleClientset(teamB, teamA, other))
+
+	all := juiceShops.List("default", labels.Everything())
+	assert.Len(t, all, 2)
+	assert.Equal(t, "t-team-a-juiceshop", all[0].Name)
+	assert.Equal(t, "t-team-b-juiceshop", all[1].Name)
+
+	pro := juiceShops.List(metav1.NamespaceAll, labels.SelectorFromSet(labels.Set{"tier": "pro"}))
+	assert.Len(t, pro, 1)
+	assert.Empty(t, juiceShops.List("other-namespace", labels.Everything()))
+}
+
+func TestListJuiceShopsReadsFromTheCacheInsteadOfTheAPI(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	useJuiceShopCache(t, clientset)
+	clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		t.Error("Should not list the deployments from the api")
+		return false, nil, nil
+	})
+
+	juiceShops, err := listJuiceShops(context.Background(), clientset, "default", juiceShopSelector)
+
+	assert.NoError(t, err)
+	assert.Len(t, juiceShops, 1)
+}
+
+func TestListJuiceShopsFallsBackToTheAPIForNamespacesNotCovered(t *testing.T) {
+	other := createJuiceShopDeployment("foobar", "", "0")
+	other.Namespace = "other-namespace"
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"), other)
+	useJuiceShopCache(t, clientset)
+
+	juiceShops, err := listJuiceShops(context.Background(), clientset, "other-namespace", juiceShopSelector)
+
+	assert.NoError(t, err)
+	assert.Len(t, juiceShops, 1)
+	assert.Equal(t, "other-namespace", juiceShops[0].Namespace)
+}
+
+func TestJuiceShopCacheReplaysCachedJuiceShopsToNewSubscribersAndStopsNotifyingCancelledOnes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("team-a", "", "0"))
+	juiceShops := useJuiceShopCache(t, clientset)
+	added := make(chan string, 10)
+	unsubscribe := juiceShops.Subscribe(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			instance, _ := workloads.Convert(obj)
+			added <- instance.Labels["team"]
+		},
+	})
+	assert.Equal(t, "team-a", <-added)
+
+	unsubscribe()
+	_, err := clientset.AppsV1().Deployments("default").Create(context.Background(), createJuiceShopDeployment("team-b", "", "0"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(juiceShops.List("default", labels.Everything())) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, added)
+}
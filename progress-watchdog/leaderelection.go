@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaderElectionLease is the name of the lease the replicas of the watchdog compete for
+const leaderElectionLease = "progress-watchdog"
+
+// leaderGauge reports whether the instance is the active replica, so that a missing leader can be alerted on
+var leaderGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "progress_watchdog_leader",
+	Help: "1 if the instance is the active replica tracking the progress of the teams, 0 otherwise",
+})
+
+// runAsLeader runs the function only on the replica holding the leader lease, so that multiple replicas never track the progress of the same teams.
+// The other replicas keep serving the api and take over once the leader stops renewing its lease.
+// Once the context is cancelled, the lease is only released after the function returned, so that the in-flight updates are drained
+// before the next leader starts. The process exits if the leadership is lost before, as the running function can't be stopped safely mid-update.
+func runAsLeader(ctx context.Context, clientset kubernetes.Interface, namespace string, identity InstanceIdentity, leaseDuration time.Duration, run func(ctx context.Context)) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: leaderElectionLease, Namespace: namespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity.ID()},
+	}
+
+	// the election keeps renewing the lease until the function returned, it is only cancelled right away on standby replicas
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+	var mutex sync.Mutex
+	started := false
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-electionCtx.Done():
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !started {
+			cancelElection()
+		}
+	}()
+
+	leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseDuration * 2 / 3,
+		RetryPeriod:     leaseDuration / 7,
+		ReleaseOnCancel: true,
+		Name:            leaderElectionLease,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				mutex.Lock()
+				if ctx.Err() != nil {
+					// shut down while acquiring the lease, the election is cancelled already
+					mutex.Unlock()
+					return
+				}
+				started = true
+				mutex.Unlock()
+				defer cancelElection()
+				defer close(done)
+
+				log.Info("Became the leader, starting to track progress")
+				leaderGauge.Set(1)
+				runCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				go func() {
+					<-leaderCtx.Done()
+					cancel()
+				}()
+				run(runCtx)
+			},
+			OnStoppedLeading: func() {
+				leaderGauge.Set(0)
+				if electionCtx.Err() != nil {
+					log.Info("Released leadership")
+					return
+				}
+				log.Fatal("Lost leadership, exiting so that the new leader takes over")
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity.ID() {
+					log.Infof("'%s' is the leader, standing by", leader)
+				}
+			},
+		},
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if started {
+		<-done
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunsOnlyWhileHoldingTheLeaderLease(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	identity := InstanceIdentity{Namespace: "default", PodName: "progress-watchdog-1"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	leading := false
+	runAsLeader(ctx, clientset, "default", identity, 3*time.Second, func(ctx context.Context) {
+		leading = true
+		lease, err := clientset.CoordinationV1().Leases("default").Get(ctx, leaderElectionLease, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "default/progress-watchdog-1", *lease.Spec.HolderIdentity)
+		cancel()
+	})
+	assert.True(t, leading)
+}
+
+func TestReleasesTheLeaderLeaseOnlyAfterDraining(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	identity := InstanceIdentity{Namespace: "default", PodName: "progress-watchdog-1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	drained := false
+	runAsLeader(ctx, clientset, "default", identity, 3*time.Second, func(runCtx context.Context) {
+		cancel()
+		<-runCtx.Done()
+		// in-flight updates are finished after the shutdown started, the lease has to be held meanwhile
+		time.Sleep(200 * time.Millisecond)
+		lease, err := clientset.CoordinationV1().Leases("default").Get(context.Background(), leaderElectionLease, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "default/progress-watchdog-1", *lease.Spec.HolderIdentity, "Should hold the lease until drained")
+		drained = true
+	})
+	assert.True(t, drained, "Should only return once the function drained")
+
+	lease, err := clientset.CoordinationV1().Leases("default").Get(context.Background(), leaderElectionLease, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, lease.Spec.HolderIdentity, "Should release the lease after draining")
+}
+
+func TestStandbyReplicasStopWithoutLeading(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	leader := InstanceIdentity{Namespace: "default", PodName: "progress-watchdog-1"}
+	standby := InstanceIdentity{Namespace: "default", PodName: "progress-watchdog-2"}
+
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	leading := make(chan struct{})
+	leaderStopped := make(chan struct{})
+	go func() {
+		defer close(leaderStopped)
+		runAsLeader(leaderCtx, clientset, "default", leader, 3*time.Second, func(ctx context.Context) {
+			close(leading)
+			<-ctx.Done()
+		})
+	}()
+	<-leading
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	runAsLeader(ctx, clientset, "default", standby, 3*time.Second, func(ctx context.Context) {
+		t.Error("Standby replica should not run")
+	})
+
+	stopLeader()
+	<-leaderStopped
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// hookJobTTL is the time finished hook jobs are kept for debugging before kubernetes deletes them
+const hookJobTTL = 24 * time.Hour
+
+// LifecycleHookConfig configures a command run on some of the hook events, e.g. to provision per team DNS records or to revoke
+// credentials in external systems. Without an image the command runs inside the watchdog container, otherwise as kubernetes Job.
+type LifecycleHookConfig struct {
+	Name    string          `json:"name"`
+	Events  []HookEventType `json:"events"`
+	Command []string        `json:"command"`
+	Image   string          `json:"image,omitempty"`
+}
+
+// FilteredHook only passes the configured event types on to its hook
+type FilteredHook struct {
+	Events []HookEventType
+	Hook   Hook
+}
+
+// Name implements Hook
+func (h *FilteredHook) Name() string {
+	return h.Hook.Name()
+}
+
+// Handle implements Hook
+func (h *FilteredHook) Handle(ctx context.Context, event HookEvent) error {
+	for _, eventType := range h.Events {
+		if eventType == event.Type {
+			return h.Hook.Handle(ctx, event)
+		}
+	}
+	return nil
+}
+
+// JobHook runs its command as kubernetes Job for every event. The event is passed like for ExecHooks via env vars,
+// the json encoded event in `MULTIJUICER_EVENT`, as jobs have no stdin
+type JobHook struct {
+	HookName  string
+	Image     string
+	Command   []string
+	Namespace string
+	Clientset kubernetes.Interface
+}
+
+// Name implements Hook
+func (h *JobHook) Name() string {
+	return "job:" + h.HookName
+}
+
+// Handle implements Hook
+func (h *JobHook) Handle(ctx context.Context, event HookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ttl := int32(hookJobTTL.Seconds())
+	backoffLimit := int32(2)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("hook-%s-%s-", h.HookName, strings.ToLower(string(event.Type))),
+			Labels: map[string]string{
+				"app":  "progress-watchdog-hook",
+				"hook": h.HookName,
+				"team": event.Team,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			TTLSecondsAfterFinished: &ttl,
+			BackoffLimit:            &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":  "progress-watchdog-hook",
+						"hook": h.HookName,
+						"team": event.Team,
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "hook",
+						Image:   h.Image,
+						Command: h.Command,
+						Env: []corev1.EnvVar{
+							{Name: "MULTIJUICER_EVENT_TYPE", Value: string(event.Type)},
+							{Name: "MULTIJUICER_TEAM", Value: event.Team},
+							{Name: "MULTIJUICER_EVENT", Value: string(payload)},
+							{Name: "MULTIJUICER_MESSAGE", Value: event.Message},
+						},
+					}},
+				},
+			},
+		},
+	}
+	created, err := h.Clientset.BatchV1().Jobs(h.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to create hook job: %v", err)
+	}
+	log.Infof("Created job '%s' for '%s' event of team '%s'", created.Name, event.Type, event.Team)
+	return nil
+}
+
+// parseLifecycleHooks creates the hooks configured as json list of LifecycleHookConfigs
+func parseLifecycleHooks(value string, clientset kubernetes.Interface, namespace string) ([]Hook, error) {
+	if value == "" {
+		return []Hook{}, nil
+	}
+	configs := []LifecycleHookConfig{}
+	if err := json.Unmarshal([]byte(value), &configs); err != nil {
+		return nil, fmt.Errorf("Failed to decode lifecycle hooks: %v", err)
+	}
+
+	lifecycleHooks := []Hook{}
+	for _, config := range configs {
+		if config.Name == "" || len(config.Command) == 0 || len(config.Events) == 0 {
+			return nil, fmt.Errorf("Lifecycle hook '%s' requires a name, a command and at least one event", config.Name)
+		}
+		if len(validation.IsDNS1123Label(config.Name)) > 0 {
+			return nil, fmt.Errorf("Lifecycle hook name '%s' must consist of lower case alphanumeric characters or '-'", config.Name)
+		}
+		for _, eventType := range config.Events {
+			if !isLifecycleEvent(eventType) {
+				return nil, fmt.Errorf("Lifecycle hook '%s' has unknown event '%s'", config.Name, eventType)
+			}
+		}
+
+		var hook Hook
+		if config.Image == "" {
+			hook = &ExecHook{Command: config.Command[0], Args: config.Command[1:]}
+		} else {
+			hook = &JobHook{HookName: config.Name, Image: config.Image, Command: config.Command, Namespace: namespace, Clientset: clientset}
+		}
+		lifecycleHooks = append(lifecycleHooks, &FilteredHook{Events: config.Events, Hook: hook})
+	}
+	return lifecycleHooks, nil
+}
+
+func isLifecycleEvent(eventType HookEventType) bool {
+	switch eventType {
+	case HookEventTeamCreated, HookEventTeamIdle, HookEventInstanceDeleted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseLifecycleHooks(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lifecycleHooks, err := parseLifecycleHooks(`[
+		{"name": "dns", "events": ["teamCreated", "instanceDeleted"], "command": ["/hooks/dns.sh", "--zone", "event.example.com"]},
+		{"name": "revoke", "events": ["teamIdle"], "command": ["revoke"], "image": "example/revoke:1.0"}
+	]`, clientset, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, []Hook{
+		&FilteredHook{
+			Events: []HookEventType{HookEventTeamCreated, HookEventInstanceDeleted},
+			Hook:   &ExecHook{Command: "/hooks/dns.sh", Args: []string{"--zone", "event.example.com"}},
+		},
+		&FilteredHook{
+			Events: []HookEventType{HookEventTeamIdle},
+			Hook:   &JobHook{HookName: "revoke", Image: "example/revoke:1.0", Command: []string{"revoke"}, Namespace: "default", Clientset: clientset},
+		},
+	}, lifecycleHooks)
+
+	lifecycleHooks, err = parseLifecycleHooks("", clientset, "default")
+	assert.Nil(t, err)
+	assert.Empty(t, lifecycleHooks)
+
+	_, err = parseLifecycleHooks(`[{"name": "dns", "events": ["solve"], "command": ["dns"]}]`, clientset, "default")
+	assert.EqualError(t, err, "Lifecycle hook 'dns' has unknown event 'solve'")
+	_, err = parseLifecycleHooks(`[{"name": "Not A Label", "events": ["teamIdle"], "command": ["dns"]}]`, clientset, "default")
+	assert.Error(t, err)
+	_, err = parseLifecycleHooks(`[{"name": "dns", "events": ["teamIdle"]}]`, clientset, "default")
+	assert.Error(t, err)
+}
+
+func TestFilteredHookOnlyPassesConfiguredEvents(t *testing.T) {
+	recording := &recordingHook{events: make(chan HookEvent, 10)}
+	hook := &FilteredHook{Events: []HookEventType{HookEventTeamIdle}, Hook: recording}
+
+	assert.NoError(t, hook.Handle(context.Background(), HookEvent{Type: HookEventTeamCreated, Team: "foobar"}))
+	assert.NoError(t, hook.Handle(context.Background(), HookEvent{Type: HookEventTeamIdle, Team: "foobar"}))
+	assert.Len(t, recording.events, 1)
+	assert.Equal(t, HookEventTeamIdle, (<-recording.events).Type)
+}
+
+func TestJobHookCreatesJobForEvent(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	hook := &JobHook{HookName: "revoke", Image: "example/revoke:1.0", Command: []string{"revoke"}, Namespace: "default", Clientset: clientset}
+
+	event := HookEvent{Type: HookEventTeamIdle, Team: "foobar", Time: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)}
+	assert.NoError(t, hook.Handle(context.Background(), event))
+
+	jobs, err := clientset.BatchV1().Jobs("default").List(context.Background(), metav1.ListOptions{LabelSelector: "team=foobar"})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+	job := jobs.Items[0]
+	assert.Equal(t, "hook-revoke-teamidle-", job.GenerateName)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "example/revoke:1.0", container.Image)
+	assert.Equal(t, []string{"revoke"}, container.Command)
+	assert.Equal(t, "teamIdle", container.Env[0].Value)
+	assert.Equal(t, "foobar", container.Env[1].Value)
+	assert.JSONEq(t, `{"type":"teamIdle","team":"foobar","time":"2021-04-10T10:00:00Z"}`, container.Env[2].Value)
+}
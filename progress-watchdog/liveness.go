@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	heartbeatAgeDesc = prometheus.NewDesc(
+		"progress_watchdog_loop_heartbeat_age_seconds",
+		"Seconds since the last heartbeat of a loop of the watchdog, idle workers report 0",
+		[]string{"loop"}, nil,
+	)
+	loopStalledDesc = prometheus.NewDesc(
+		"progress_watchdog_loop_stalled",
+		"1 if the loop hasn't reported a heartbeat within the stall threshold, 0 otherwise",
+		[]string{"loop"}, nil,
+	)
+)
+
+type heartbeat struct {
+	at time.Time
+	// idle loops are waiting for work (e.g. workers waiting for a queued team) and can't stall
+	idle bool
+}
+
+// Heartbeats tracks the liveness of the long running loops of the watchdog, so that silent deadlocks can be detected.
+// The discovery loop beats once per iteration, workers beat when they start a job and are idle after finishing it.
+type Heartbeats struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mutex sync.Mutex
+	beats map[string]heartbeat
+	// reported contains the stalled loops which were already logged, so every stall is only logged once
+	reported map[string]bool
+}
+
+// NewHeartbeats creates a Heartbeats tracker considering loops without a heartbeat for longer than the threshold as stalled
+func NewHeartbeats(threshold time.Duration) *Heartbeats {
+	return &Heartbeats{
+		threshold: threshold,
+		now:       time.Now,
+		beats:     map[string]heartbeat{},
+		reported:  map[string]bool{},
+	}
+}
+
+// Beat records that the loop is alive and busy
+func (h *Heartbeats) Beat(loop string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.beats[loop] = heartbeat{at: h.now()}
+}
+
+// Idle records that the loop is alive and waiting for work
+func (h *Heartbeats) Idle(loop string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.beats[loop] = heartbeat{at: h.now(), idle: true}
+}
+
+// Stalled returns the sorted names of all busy loops without a heartbeat within the threshold
+func (h *Heartbeats) Stalled() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.stalled()
+}
+
+func (h *Heartbeats) stalled() []string {
+	stalled := []string{}
+	now := h.now()
+	for loop, beat := range h.beats {
+		if !beat.idle && now.Sub(beat.at) > h.threshold {
+			stalled = append(stalled, loop)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
+
+// Check logs loops which newly stalled together with a dump of all goroutines, and loops which recovered
+func (h *Heartbeats) Check() {
+	h.mutex.Lock()
+	stalled := h.stalled()
+	newlyStalled := []string{}
+	isStalled := map[string]bool{}
+	for _, loop := range stalled {
+		isStalled[loop] = true
+		if !h.reported[loop] {
+			newlyStalled = append(newlyStalled, loop)
+			h.reported[loop] = true
+		}
+	}
+	for loop := range h.reported {
+		if !isStalled[loop] {
+			log.Infof("Loop '%s' is reporting heartbeats again", loop)
+			delete(h.reported, loop)
+		}
+	}
+	h.mutex.Unlock()
+
+	if len(newlyStalled) == 0 {
+		return
+	}
+	log.Errorf("Loops '%s' didn't report a heartbeat for more than %s, dumping goroutines:\n%s", strings.Join(newlyStalled, "', '"), h.threshold, dumpGoroutines())
+}
+
+// Run checks the heartbeats in the passed interval
+func (h *Heartbeats) Run(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		h.Check()
+	}
+}
+
+func dumpGoroutines() string {
+	var buffer bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buffer, 2); err != nil {
+		return fmt.Sprintf("Failed to dump goroutines: %v", err)
+	}
+	return buffer.String()
+}
+
+// handleHealth serves the liveness probe of the watchdog, failing while any loop is stalled
+func (h *Heartbeats) handleHealth(w http.ResponseWriter, req *http.Request) {
+	if stalled := h.Stalled(); len(stalled) > 0 {
+		http.Error(w, fmt.Sprintf("Stalled loops: %s", strings.Join(stalled, ", ")), http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// Describe implements prometheus.Collector
+func (h *Heartbeats) Describe(ch chan<- *prometheus.Desc) {
+	ch <- heartbeatAgeDesc
+	ch <- loopStalledDesc
+}
+
+// Collect implements prometheus.Collector
+func (h *Heartbeats) Collect(ch chan<- prometheus.Metric) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := h.now()
+	for loop, beat := range h.beats {
+		age := 0.0
+		stalled := 0.0
+		if !beat.idle {
+			age = now.Sub(beat.at).Seconds()
+			if now.Sub(beat.at) > h.threshold {
+				stalled = 1
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(heartbeatAgeDesc, prometheus.GaugeValue, age, loop)
+		ch <- prometheus.MustNewConstMetric(loopStalledDesc, prometheus.GaugeValue, stalled, loop)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHeartbeats(now *time.Time) *Heartbeats {
+	heartbeats := NewHeartbeats(time.Minute)
+	heartbeats.now = func() time.Time { return *now }
+	return heartbeats
+}
+
+func TestHeartbeatsReportBusyLoopsWithoutRecentBeatAsStalled(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	heartbeats := newTestHeartbeats(&now)
+
+	heartbeats.Beat("discovery")
+	heartbeats.Beat("worker-0")
+	heartbeats.Idle("worker-1")
+	assert.Equal(t, []string{}, heartbeats.Stalled())
+
+	now = now.Add(2 * time.Minute)
+	heartbeats.Beat("discovery")
+	assert.Equal(t, []string{"worker-0"}, heartbeats.Stalled())
+
+	heartbeats.Idle("worker-0")
+	assert.Equal(t, []string{}, heartbeats.Stalled())
+}
+
+func TestHeartbeatsHealthFailsWhileLoopsAreStalled(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	heartbeats := newTestHeartbeats(&now)
+	heartbeats.Beat("discovery")
+
+	rr := httptest.NewRecorder()
+	heartbeats.handleHealth(rr, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	now = now.Add(2 * time.Minute)
+	heartbeats.Check()
+	rr = httptest.NewRecorder()
+	heartbeats.handleHealth(rr, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "discovery")
+	assert.True(t, heartbeats.reported["discovery"])
+
+	heartbeats.Beat("discovery")
+	heartbeats.Check()
+	assert.Empty(t, heartbeats.reported)
+}
+
+func TestHeartbeatsExposeMetrics(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	heartbeats := newTestHeartbeats(&now)
+	heartbeats.Beat("discovery")
+	heartbeats.Idle("worker-0")
+	now = now.Add(90 * time.Second)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(heartbeats)
+
+	expected := `
+# HELP progress_watchdog_loop_stalled 1 if the loop hasn't reported a heartbeat within the stall threshold, 0 otherwise
+# TYPE progress_watchdog_loop_stalled gauge
+progress_watchdog_loop_stalled{loop="discovery"} 1
+progress_watchdog_loop_stalled{loop="worker-0"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "progress_watchdog_loop_stalled"))
+}
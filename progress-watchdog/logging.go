@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+// logLevels filters the logs of all outputs, the level of the watchdog logs can be changed at runtime via setLogLevel
+var logLevels = NewLogLevels(logging.NewLogBackend(os.Stderr, "", 0), logging.INFO)
+
+// setupLogging writes the logs to stdout in the log format (`text` or `json`) and to the additional log outputs, e.g. a log file.
+// The watchdog logs are written from the level on, the audit log is always written from INFO on.
+func setupLogging(logFormat string, level logging.Level, outputs ...logging.Backend) {
+	var logBackend logging.Backend = logging.NewLogBackend(os.Stdout, "", 0)
+	if logFormat == "json" {
+		logBackend = NewJSONBackend(os.Stdout)
+	}
+
+	backends := []logging.Backend{logBackend}
+	for _, output := range outputs {
+		backends = append(backends, logging.NewBackendFormatter(output, format))
+	}
+
+	logFormatter := logging.NewBackendFormatter(logBackend, format)
+	logLevels = NewLogLevels(logging.MultiLogger(backends...), logging.INFO)
+	logLevels.SetLevel(level, log.Module)
+
+	log.SetBackend(logLevels)
+	logging.SetBackend(logLevels, logFormatter)
+}
+
+// setLogLevel changes the level of the watchdog logs while it's running
+func setLogLevel(level logging.Level) {
+	logLevels.SetLevel(level, log.Module)
+}
+
+// currentLogLevel returns the level of the watchdog logs
+func currentLogLevel() logging.Level {
+	return logLevels.GetLevel(log.Module)
+}
+
+// toggleDebugLogsOnSIGHUP switches between debug logs and the configured level on every SIGHUP until the context is cancelled,
+// e.g. to debug a misbehaving JuiceShop without restarting the watchdog
+func toggleDebugLogsOnSIGHUP(ctx context.Context, configured logging.Level) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			level := logging.DEBUG
+			if currentLogLevel() == logging.DEBUG {
+				level = configured
+			}
+			setLogLevel(level)
+			log.Noticef("Received SIGHUP, changed log level to %s", level)
+		}
+	}
+}
+
+// parseLogLevel parses the level of the watchdog logs, e.g. `debug` or `WARNING`
+func parseLogLevel(value string) (logging.Level, error) {
+	level, err := logging.LogLevel(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid log level '%s', expected 'debug', 'info', 'notice', 'warning', 'error' or 'critical'", value)
+	}
+	return level, nil
+}
+
+// LogLevels is a leveled logging backend whose levels can be changed while other goroutines are logging, unlike the one of go-logging.
+// Modules without an own level are logged from the default level on.
+type LogLevels struct {
+	next         logging.Backend
+	mutex        sync.RWMutex
+	defaultLevel logging.Level
+	levels       map[string]logging.Level
+}
+
+// NewLogLevels creates LogLevels writing the records of enabled levels to the next backend
+func NewLogLevels(next logging.Backend, defaultLevel logging.Level) *LogLevels {
+	return &LogLevels{next: next, defaultLevel: defaultLevel, levels: map[string]logging.Level{}}
+}
+
+// Log implements the logging.Backend interface
+func (l *LogLevels) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	if !l.IsEnabledFor(level, rec.Module) {
+		return nil
+	}
+	return l.next.Log(level, calldepth+1, rec)
+}
+
+// GetLevel implements logging.Leveled
+func (l *LogLevels) GetLevel(module string) logging.Level {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if level, ok := l.levels[module]; ok {
+		return level
+	}
+	return l.defaultLevel
+}
+
+// SetLevel implements logging.Leveled, the empty module sets the default level
+func (l *LogLevels) SetLevel(level logging.Level, module string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if module == "" {
+		l.defaultLevel = level
+		return
+	}
+	l.levels[module] = level
+}
+
+// IsEnabledFor implements logging.Leveled
+func (l *LogLevels) IsEnabledFor(level logging.Level, module string) bool {
+	return level <= l.GetLevel(module)
+}
+
+// validateLogFormat validates the format of the logs written to stdout
+func validateLogFormat(value string) error {
+	if value != "text" && value != "json" {
+		return fmt.Errorf("Invalid log format '%s', expected 'text' or 'json'", value)
+	}
+	return nil
+}
+
+// LogFields are structured fields of a log record, e.g. the team a record is about.
+// Passed as last argument of the unformatted log functions, they're appended as `key=value` pairs to text logs and as separate fields to json logs.
+// Errors are logged as their message and durations in seconds.
+type LogFields map[string]interface{}
+
+// String implements fmt.Stringer, rendering the fields sorted by key
+func (f LogFields) String() string {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, f[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// JSONBackend writes every log record as a single line json object with the time, level, module, message and the LogFields of the record
+type JSONBackend struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewJSONBackend creates a JSONBackend writing to the writer
+func NewJSONBackend(writer io.Writer) *JSONBackend {
+	return &JSONBackend{writer: writer}
+}
+
+// Log implements the logging.Backend interface
+func (b *JSONBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	entry := map[string]interface{}{}
+	message := []string{}
+	for _, arg := range rec.Args {
+		fields, ok := arg.(LogFields)
+		if !ok {
+			message = append(message, fmt.Sprint(arg))
+			continue
+		}
+		for key, value := range fields {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			} else if duration, ok := value.(time.Duration); ok {
+				value = duration.Seconds()
+			}
+			entry[key] = value
+		}
+	}
+	if len(message) == len(rec.Args) {
+		entry["message"] = rec.Message()
+	} else {
+		entry["message"] = strings.Join(message, " ")
+	}
+	entry["time"] = rec.Time.UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["module"] = rec.Module
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, err = b.writer.Write(append(line, '\n'))
+	return err
+}
+
+// createLogOutputs creates the additional log outputs configured via environment variables
+func createLogOutputs() ([]logging.Backend, error) {
+	outputs := []logging.Backend{}
+
+	if path := os.Getenv("LOG_FILE"); path != "" {
+		maxSizeMB, err := strconv.ParseInt(getEnv("LOG_FILE_MAX_SIZE_MB", "100"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid LOG_FILE_MAX_SIZE_MB: %v", err)
+		}
+		rotationInterval, err := time.ParseDuration(getEnv("LOG_FILE_ROTATION_INTERVAL", "24h"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid LOG_FILE_ROTATION_INTERVAL: %v", err)
+		}
+		maxBackups, err := strconv.Atoi(getEnv("LOG_FILE_MAX_BACKUPS", "7"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid LOG_FILE_MAX_BACKUPS: %v", err)
+		}
+		maxAge, err := time.ParseDuration(getEnv("LOG_FILE_MAX_AGE", "168h"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid LOG_FILE_MAX_AGE: %v", err)
+		}
+
+		file, err := OpenRotatingFile(path, maxSizeMB*1024*1024, rotationInterval, maxBackups, maxAge)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, logging.NewLogBackend(file, "", 0))
+	}
+
+	if address := os.Getenv("LOG_SYSLOG_ADDRESS"); address != "" {
+		network, host, err := parseSyslogAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := syslog.Dial(network, host, syslog.LOG_DAEMON|syslog.LOG_INFO, getEnv("LOG_SYSLOG_TAG", "progress-watchdog"))
+		if err != nil {
+			// the syslog writer reconnects on its own, but can't be created without an initial connection
+			fmt.Fprintf(os.Stderr, "Failed to connect to syslog at '%s', not sending logs to it: %s\n", address, err)
+		} else {
+			outputs = append(outputs, &logging.SyslogBackend{Writer: writer})
+		}
+	}
+
+	if address := os.Getenv("LOG_FORWARD_ADDRESS"); address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("Invalid LOG_FORWARD_ADDRESS, expected 'host:port': %v", err)
+		}
+		outputs = append(outputs, NewForwardBackend(address, getEnv("LOG_FORWARD_TAG", "multi-juicer.progress-watchdog")))
+	}
+
+	return outputs, nil
+}
+
+// parseSyslogAddress parses syslog addresses like `udp://syslog.example.com:514`
+func parseSyslogAddress(address string) (string, string, error) {
+	parts := strings.SplitN(address, "://", 2)
+	if len(parts) != 2 || (parts[0] != "udp" && parts[0] != "tcp") {
+		return "", "", fmt.Errorf("Invalid LOG_SYSLOG_ADDRESS '%s', expected 'udp://host:port' or 'tcp://host:port'", address)
+	}
+	if _, _, err := net.SplitHostPort(parts[1]); err != nil {
+		return "", "", fmt.Errorf("Invalid LOG_SYSLOG_ADDRESS '%s': %v", address, err)
+	}
+	return parts[0], parts[1], nil
+}
+
+// LogLevelResponse json format of the log level of the admin api
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// handleLogLevel returns the level of the watchdog logs, PUT changes it until the next restart or SIGHUP
+func (s *Server) handleLogLevel(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update LogLevelResponse
+		if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		level, err := parseLogLevel(update.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setLogLevel(level)
+		auditLog.Infof("'%s' set the log level to %s", principalFromContext(req.Context()).Name, level)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, LogLevelResponse{Level: currentLogLevel().String()})
+}
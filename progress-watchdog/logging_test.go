@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseSyslogAddress(t *testing.T) {
+	network, address, err := parseSyslogAddress("udp://syslog.example.com:514")
+	assert.NoError(t, err)
+	assert.Equal(t, "udp", network)
+	assert.Equal(t, "syslog.example.com:514", address)
+
+	_, _, err = parseSyslogAddress("syslog.example.com:514")
+	assert.Error(t, err)
+	_, _, err = parseSyslogAddress("tcp://syslog.example.com")
+	assert.Error(t, err)
+}
+
+func TestJSONBackendWritesLogFieldsAsFields(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := logging.MustGetLogger("JSONTest")
+	logger.SetBackend(logging.AddModuleLevel(NewJSONBackend(buffer)))
+
+	logger.Warning("Progress update failed", LogFields{"team": "foobar", "duration": 1500 * time.Millisecond, "error": errors.New("timeout")})
+	logger.Infof("Found %d JuiceShops", 3)
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(lines[0], &entry))
+	assert.Equal(t, "Progress update failed", entry["message"])
+	assert.Equal(t, "WARNING", entry["level"])
+	assert.Equal(t, "JSONTest", entry["module"])
+	assert.Equal(t, "foobar", entry["team"])
+	assert.Equal(t, 1.5, entry["duration"])
+	assert.Equal(t, "timeout", entry["error"])
+
+	entry = map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Equal(t, "Found 3 JuiceShops", entry["message"])
+}
+
+func TestLogFieldsRenderAsSortedPairsInTextLogs(t *testing.T) {
+	assert.Equal(t, "component=worker-1 team=foobar", LogFields{"team": "foobar", "component": "worker-1"}.String())
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	assert.NoError(t, validateLogFormat("json"))
+	assert.NoError(t, validateLogFormat("text"))
+	assert.EqualError(t, validateLogFormat("xml"), "Invalid log format 'xml', expected 'text' or 'json'")
+}
+
+func TestLogLevelsFilterModulesByTheirLevel(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	levels := NewLogLevels(NewJSONBackend(buffer), logging.INFO)
+	levels.SetLevel(logging.WARNING, "LevelTest")
+	logger := logging.MustGetLogger("LevelTest")
+	logger.SetBackend(levels)
+	other := logging.MustGetLogger("OtherLevelTest")
+	other.SetBackend(levels)
+
+	logger.Info("Filtered")
+	logger.Warning("Written")
+	other.Info("Written by the default level")
+	other.Debug("Filtered")
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.Contains(t, string(lines[0]), `"message":"Written"`)
+	assert.Contains(t, string(lines[1]), `"message":"Written by the default level"`)
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := parseLogLevel("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, logging.DEBUG, level)
+	level, err = parseLogLevel("WARNING")
+	assert.NoError(t, err)
+	assert.Equal(t, logging.WARNING, level)
+	_, err = parseLogLevel("verbose")
+	assert.EqualError(t, err, "Invalid log level 'verbose', expected 'debug', 'info', 'notice', 'warning', 'error' or 'critical'")
+}
+
+func TestLogLevelCanBeChangedAtRuntime(t *testing.T) {
+	defer setLogLevel(currentLogLevel())
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("PUT", "/api/admin/log-level", `{"level":"debug"}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rr.Body.String())
+	assert.Equal(t, logging.DEBUG, currentLogLevel())
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/admin/log-level"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("PUT", "/api/admin/log-level", `{"level":"verbose"}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, logging.DEBUG, currentLogLevel())
+}
@@ -3,23 +3,39 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/op/go-logging"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/speps/go-hashids"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 var log = logging.MustGetLogger("ProgressWatchdog")
@@ -36,136 +52,945 @@ type ContinueCodePayload struct {
 // ProgressUpdateJobs contains all information required by a ProgressUpdateJobs worker to do its Job
 type ProgressUpdateJobs struct {
 	Teamname         string
+	Namespace        string
 	LastContinueCode string
+	LastSolves       SolveTimes
+	// LastCodingChallengeCodes are the cached coding challenge ContinueCodes, keyed by their name
+	LastCodingChallengeCodes map[string]string
+	// Replicas is the number of ready JuiceShop replicas of the team
+	Replicas int32
+	// CreatedAt is the creation time of the JuiceShop workload, telling recreated JuiceShops apart from ones whose progress was reset
+	CreatedAt time.Time
+	// Recovered is set if the progress of the JuiceShop was already recovered once, see recoverProgress
+	Recovered bool
+	// QueuedAt is the time the job was first queued, set by the ProgressUpdateQueue
+	QueuedAt time.Time
+	// Urgent jobs are run before the routine ones, see progressUpdateJobOf
+	Urgent bool
+
+	// ctx carries the span tracing the progress update while the job runs, see Context
+	ctx context.Context
+}
+
+// Context returns the context of the running job, which the spans of its steps and the requests to its JuiceShop are part of
+func (job ProgressUpdateJobs) Context() context.Context {
+	if job.ctx == nil {
+		return context.Background()
+	}
+	return job.ctx
+}
+
+// Key identifies the team of the job across namespaces, as teams in different namespaces can have the same name
+func (job ProgressUpdateJobs) Key() string {
+	return teamKey(job.Namespace, job.Teamname)
 }
 
+// juiceShopClient is used for all requests against the JuiceShop instances, configured via `JUICE_SHOP_TIMEOUT`, `JUICE_SHOP_RETRIES`, `JUICE_SHOP_RETRY_DELAY`
+// and the `JUICE_SHOP_TLS_*` env vars
+var juiceShopClient = newJuiceShopClient(10*time.Second, 2, 200*time.Millisecond, nil)
+
 func main() {
-	logBackend := logging.NewLogBackend(os.Stdout, "", 0)
+	config, err := loadMountedConfig()
+	if err != nil {
+		panic(err.Error())
+	}
+	mountedConfig = config
+	identity := detectInstanceIdentity()
+	namespace := identity.Namespace
 
-	logFormatter := logging.NewBackendFormatter(logBackend, format)
-	logBackendLeveled := logging.AddModuleLevel(logBackend)
-	logBackendLeveled.SetLevel(logging.INFO, "")
+	flag.StringVar(&kubeconfigPath, "kubeconfig", "", "Kubeconfig used when running outside of a cluster, defaults to ~/.kube/config")
+	watchedNamespace := flag.String("namespace", defaultWatchedNamespace(namespace), "Namespace of the JuiceShop deployments to watch, empty to watch all namespaces")
+	pollInterval := flag.String("poll-interval", "5s", "Interval in which the progress of all JuiceShops is checked, changed JuiceShops are checked immediately")
+	workers := flag.String("workers", "10", "Number of workers checking the progress of the JuiceShops in parallel")
+	connectMode := flag.String("connect-mode", string(ConnectModeService), "How to connect to the JuiceShops, 'service' or 'pod-ip' to talk to a ready pod directly")
+	logFormat := flag.String("log-format", "text", "Format of the logs written to stdout, 'text' or 'json'")
+	simulate := flag.Int("simulate", 0, "Instead of watching the JuiceShops, simulate the progress updates of this many teams against simulated JuiceShops and print the throughput")
+	simulateDuration := flag.String("simulate-duration", "1m", "Duration of the simulation")
+	simulateLatency := flag.String("simulate-latency", "20ms", "Latency of the simulated JuiceShops and kubernetes api")
+	dryRunFlag := flag.Bool("dry-run", false, "Only log what would be applied to the JuiceShops or saved as progress, without changing them")
+	logLevelName := flag.String("log-level", "info", "Level of the logs, 'debug', 'info', 'notice', 'warning', 'error' or 'critical'. SIGHUP toggles debug logs while running")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n%s\n", configPrecedence)
+	}
+	flag.Parse()
+	if err := flagsFromEnv(flag.CommandLine); err != nil {
+		panic(err.Error())
+	}
+	dryRun = *dryRunFlag
 
-	log.SetBackend(logBackendLeveled)
-	logging.SetBackend(logBackendLeveled, logFormatter)
+	if err := validateLogFormat(*logFormat); err != nil {
+		panic(err.Error())
+	}
+	logLevel, err := parseLogLevel(*logLevelName)
+	if err != nil {
+		panic(err.Error())
+	}
+	logOutputs, err := createLogOutputs()
+	if err != nil {
+		panic(err.Error())
+	}
+	setupLogging(*logFormat, logLevel, logOutputs...)
 
-	config, err := rest.InClusterConfig()
+	if err := configureNaming(getEnv("ANNOTATION_PREFIX", annotationPrefix), getEnv("JUICE_SHOP_SELECTOR", juiceShopSelector)); err != nil {
+		panic(err.Error())
+	}
+	workloads, err = createWorkloads(getEnv("WORKLOAD_KIND", "Deployment"))
 	if err != nil {
 		panic(err.Error())
 	}
 
-	// creates the clientset
-	clientset, err := kubernetes.NewForConfig(config)
+	if err := configureOutboundRateLimit(); err != nil {
+		panic(err.Error())
+	}
+
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+	if *simulate > 0 {
+		options, err := parseSimulationOptions(*simulate, *workers, *pollInterval, *simulateDuration, *simulateLatency)
+		if err != nil {
+			panic(err.Error())
+		}
+		runSimulation(options, os.Stdout)
+		return
+	}
+
+	clientset := createClientset()
+
+	log.Infof("Running as '%s'", identity.ID())
+	if dryRun {
+		log.Warning("Running as dry run, the progress is only checked without changing the JuiceShops, the progress store or any kubernetes objects")
+	}
+	if *watchedNamespace == metav1.NamespaceAll {
+		log.Info("Watching JuiceShops in all namespaces")
+	} else {
+		log.Infof("Watching JuiceShops in namespace '%s'", *watchedNamespace)
+	}
+	listenAddress := getEnv("LISTEN_ADDRESS", ":8080")
+	signingSecret := []byte(os.Getenv("SIGNING_SECRET"))
+	if len(signingSecret) == 0 {
+		log.Warning("No SIGNING_SECRET configured, signed requests to the api will be rejected")
+	}
+	apiKeys, err := parseAPIKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		panic(err.Error())
+	}
+	serviceAccountRoles, err := parseServiceAccountRoles(os.Getenv("SERVICE_ACCOUNT_ROLES"))
 	if err != nil {
 		panic(err.Error())
 	}
+	authenticator := NewAuthenticator(signingSecret, apiKeys, serviceAccountRoles, clientset)
+	lifecycleHooks, err := parseLifecycleHooks(getEnv("LIFECYCLE_HOOKS", ""), clientset, namespace)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid LIFECYCLE_HOOKS: %s", err))
+	}
+	catalog, err = loadCatalog(getEnv("DEFAULT_LOCALE", "en"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid DEFAULT_LOCALE: %s", err))
+	}
+	timeZone, err := parseTimeZone(getEnv("TIME_ZONE", "UTC"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid TIME_ZONE: %s", err))
+	}
+	notificationTemplates, err := parseNotificationTemplates(getEnv("NOTIFICATION_TEMPLATES", ""), catalog.Messages(catalog.DefaultLocale()), timeZone)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid NOTIFICATION_TEMPLATES: %s", err))
+	}
+	if dryRun {
+		// notifications of solves which are never saved would be sent again with every progress update
+		hooks = NewHooks(notificationTemplates)
+	} else {
+		hooks = NewHooks(notificationTemplates, append(createExternalHooks(signingSecret), lifecycleHooks...)...)
+	}
+	discoveryInterval, err := time.ParseDuration(*pollInterval)
+	if err != nil || discoveryInterval <= 0 {
+		panic(fmt.Sprintf("Invalid POLL_INTERVAL: %s", *pollInterval))
+	}
+	workerCount, err := strconv.Atoi(*workers)
+	if err != nil || workerCount < 1 {
+		panic(fmt.Sprintf("Invalid WORKERS: %s", *workers))
+	}
+	queueCapacity, err := strconv.Atoi(getEnv("QUEUE_CAPACITY", "1000"))
+	if err != nil || queueCapacity < 1 {
+		panic(fmt.Sprintf("Invalid QUEUE_CAPACITY: %s", getEnv("QUEUE_CAPACITY", "1000")))
+	}
+	queueBackpressureThreshold, err := time.ParseDuration(getEnv("QUEUE_BACKPRESSURE_THRESHOLD", "30s"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid QUEUE_BACKPRESSURE_THRESHOLD: %s", err))
+	}
+	juiceShopTimeout, err := time.ParseDuration(getEnv("JUICE_SHOP_TIMEOUT", "10s"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_TIMEOUT: %s", err))
+	}
+	juiceShopRetries, err := strconv.Atoi(getEnv("JUICE_SHOP_RETRIES", "2"))
+	if err != nil || juiceShopRetries < 0 {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_RETRIES: %s", getEnv("JUICE_SHOP_RETRIES", "2")))
+	}
+	juiceShopRetryDelay, err := time.ParseDuration(getEnv("JUICE_SHOP_RETRY_DELAY", "200ms"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_RETRY_DELAY: %s", err))
+	}
+	var juiceShopTLSConfig *tls.Config
+	if getEnv("JUICE_SHOP_TLS", "false") == "true" {
+		juiceShopScheme = "https"
+		juiceShopTLSConfig, err = newJuiceShopTLSConfig(os.Getenv("JUICE_SHOP_TLS_CA_FILE"), os.Getenv("JUICE_SHOP_TLS_CERT_FILE"), os.Getenv("JUICE_SHOP_TLS_KEY_FILE"))
+		if err != nil {
+			panic(fmt.Sprintf("Invalid JuiceShop TLS config: %v", err))
+		}
+		juiceShopTLSConfig.ServerName = os.Getenv("JUICE_SHOP_TLS_SERVER_NAME")
+	}
+	juiceShopPort, err = strconv.Atoi(getEnv("JUICE_SHOP_PORT", "3000"))
+	if err != nil || juiceShopPort < 1 {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_PORT: %s", getEnv("JUICE_SHOP_PORT", "3000")))
+	}
+	juiceShopClient = newJuiceShopClient(juiceShopTimeout, juiceShopRetries, juiceShopRetryDelay, juiceShopTLSConfig)
+	clusterDomain = getEnv("CLUSTER_DOMAIN", clusterDomain)
+	juiceShopServiceTemplate, err = parseJuiceShopServiceTemplate(getEnv("JUICE_SHOP_SERVICE_TEMPLATE", defaultJuiceShopServiceTemplate))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_SERVICE_TEMPLATE: %s", err))
+	}
 
-	progressUpdateJobs := make(chan ProgressUpdateJobs)
+	teamIdleThreshold, err := time.ParseDuration(getEnv("TEAM_IDLE_THRESHOLD", "1h"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid TEAM_IDLE_THRESHOLD: %s", err))
+	}
 
-	workerCount := 10
-	log.Infof("Starting ProgressWatchdog with %d worker go routines", workerCount)
+	rateLimit, err := strconv.ParseFloat(getEnv("RATE_LIMIT_PER_SECOND", "5"), 64)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid RATE_LIMIT_PER_SECOND: %s", err))
+	}
+	rateLimitBurst, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST", "10"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid RATE_LIMIT_BURST: %s", err))
+	}
+	rateLimiter := NewRateLimiter(rateLimit, rateLimitBurst)
 
-	// Start 10 workers which fetch and update ContinueCodes based on the `progressUpdateJobs` queue / channel
-	for i := 0; i < workerCount; i++ {
-		go workOnProgressUpdates(progressUpdateJobs, clientset)
+	scoreboardCacheTTL, err := time.ParseDuration(getEnv("SCOREBOARD_CACHE_TTL", "10s"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid SCOREBOARD_CACHE_TTL: %s", err))
 	}
+	scoreboardCache := NewScoreboardCache(scoreboardCacheTTL)
 
-	createProgressUpdateJobs(progressUpdateJobs, clientset)
-}
+	configuredFeatures, err := parseFeatureFlags(getEnv("FEATURE_FLAGS", ""))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid FEATURE_FLAGS: %s", err))
+	}
+	featureFlags = NewFeatureFlags(configuredFeatures)
+	ctfKey = os.Getenv("CTF_KEY")
+	if featureFlags.Enabled(FeatureCTFFlags) && ctfKey == "" {
+		log.Warning("The ctfFlags feature is enabled, but no CTF_KEY is configured, so no flags are collected")
+	}
 
-// Constantly lists all JuiceShops in managed by MultiJuicer and queues progressUpdatesJobs for them
-func createProgressUpdateJobs(progressUpdateJobs chan<- ProgressUpdateJobs, clientset *kubernetes.Clientset) {
-	for {
-		// Get Instances
-		log.Debug("Looking for Instances")
-		opts := metav1.ListOptions{
-			LabelSelector: "app=juice-shop",
+	scoring, err := scoringFromEnv()
+	if err != nil {
+		panic(err.Error())
+	}
+	progressScoring = scoring
+
+	apiCheckInterval, err := time.ParseDuration(getEnv("API_CHECK_INTERVAL", "30s"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid API_CHECK_INTERVAL: %s", err))
+	}
+	apiCheckFailureThreshold, err := strconv.Atoi(getEnv("API_CHECK_FAILURE_THRESHOLD", "5"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid API_CHECK_FAILURE_THRESHOLD: %s", err))
+	}
+	apiServerCheck := NewAPIServerCheck(clientset, namespace, apiCheckInterval, apiCheckFailureThreshold)
+	go apiServerCheck.Run()
+
+	loopStallThreshold, err := time.ParseDuration(getEnv("LOOP_STALL_THRESHOLD", "5m"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid LOOP_STALL_THRESHOLD: %s", err))
+	}
+	heartbeats := NewHeartbeats(loopStallThreshold)
+	go heartbeats.Run(30 * time.Second)
+
+	metrics := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(metricLabels(identity), metrics).MustRegister(append(progressMetrics, heartbeats, kubernetesPacer, leaderGauge, panicsRecovered, outboundRateLimitWaitSeconds, dryRunChanges, prometheus.NewGoCollector())...)
+
+	cors := &CORS{
+		AllowedOrigins: parseCommaSeparatedList(getEnv("CORS_ALLOWED_ORIGINS", "")),
+		AllowedMethods: parseCommaSeparatedList(getEnv("CORS_ALLOWED_METHODS", "GET, OPTIONS")),
+	}
+
+	var solveWebhook *SolveWebhook
+	if getEnv("SOLVE_WEBHOOK", "false") == "true" {
+		solveWebhook = NewSolveWebhook(clientset, *watchedNamespace)
+	}
+
+	// cancelled on SIGTERM or SIGINT, in-flight progress updates are finished before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go toggleDebugLogsOnSIGHUP(ctx, logLevel)
+	if mountedConfig != nil {
+		log.Infof("Watching config mounted at '%s' for changes", mountedConfig.dir)
+		registerConfigReloads(mountedConfig)
+		go mountedConfig.Watch(ctx, configReloadInterval)
+	}
+
+	juiceShopCache = NewJuiceShopCache(clientset, *watchedNamespace, discoveryInterval)
+	juiceShopCache.Start(ctx)
+	progressStore = withDryRun(createProgressStore(ctx, clientset, *watchedNamespace))
+	tracerProvider, err := createTracerProvider(ctx)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create the OTLP trace exporter: %v", err))
+	}
+	if tracerProvider != nil {
+		log.Info("Exporting traces of the progress updates via OTLP")
+		otel.SetTracerProvider(tracerProvider)
+		// exports the spans of the progress updates finished during the shutdown
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+				log.Warningf("Failed to export the remaining trace spans: %s", err)
+			}
+		}()
+	}
+	podIPResolver = createPodIPResolver(ctx, clientset, *watchedNamespace, ConnectMode(*connectMode))
+
+	apiServer := &http.Server{
+		Addr: listenAddress,
+		Handler: NewServer(clientset, *watchedNamespace, ServerOptions{
+			Authenticator:   authenticator,
+			RateLimiter:     rateLimiter,
+			ScoreboardCache: scoreboardCache,
+			CORS:            cors,
+			Scoring:         scoring,
+			TimeZone:        timeZone,
+			APIServerCheck:  apiServerCheck,
+			Heartbeats:      heartbeats,
+			Metrics:         metrics,
+			SolveWebhook:    solveWebhook,
+
+			WidgetFrameAncestors: getEnv("WIDGET_FRAME_ANCESTORS", "*"),
+		}),
+	}
+	go func() {
+		log.Infof("Serving api on '%s'", listenAddress)
+		if err := apiServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
 		}
+	}()
+	defer shutdownServer(apiServer)
 
-		namespace := os.Getenv("NAMESPACE")
-		ctx := context.Background()
-		juiceShops, err := clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+	var backupStore *BackupStore
+	var backupInterval time.Duration
+	if getEnv("PROGRESS_ARCHIVE", "false") == "true" {
+		progressArchive, err = NewProgressArchive(ctx, clientset, *watchedNamespace)
 		if err != nil {
-			panic(err.Error())
+			panic(fmt.Sprintf("Failed to create the progress archive: %v", err))
 		}
+		// archived on deletion, so it's always newer than the backups
+		progressRecoverySources = append(progressRecoverySources, progressArchive)
+	}
+	if backupTarget := createBackupTarget(); backupTarget != nil && !dryRun {
+		backupStore, backupInterval = createBackupStore(backupTarget)
+		progressRecoverySources = append(progressRecoverySources, backupStore)
+	}
 
-		log.Debugf("Found %d JuiceShop running", len(juiceShops.Items))
+	// the checkpoint belongs to the production watchdog, a dry run neither resumes nor writes it
+	var queueCheckpoint *QueueCheckpoint
+	var queueCheckpointInterval time.Duration
+	if getEnv("QUEUE_CHECKPOINT", "false") == "true" && !dryRun {
+		queueCheckpointInterval, err = time.ParseDuration(getEnv("QUEUE_CHECKPOINT_INTERVAL", "10s"))
+		if err != nil || queueCheckpointInterval <= 0 {
+			panic(fmt.Sprintf("Invalid QUEUE_CHECKPOINT_INTERVAL: %s", getEnv("QUEUE_CHECKPOINT_INTERVAL", "10s")))
+		}
+		queueCheckpoint = NewQueueCheckpoint(clientset, namespace)
+	}
 
-		for _, instance := range juiceShops.Items {
-			teamname := instance.Labels["team"]
+	var instanceController *InstanceController
+	if getEnv("JUICE_SHOP_INSTANCES", "false") == "true" {
+		instanceController = NewInstanceController(createDynamicClient(), *watchedNamespace)
+	}
 
-			if instance.Status.ReadyReplicas != 1 {
-				continue
-			}
+	// CTFd is shared with the production watchdog, a dry run doesn't sync the solves into it
+	var ctfdExporter *CTFdExporter
+	if !dryRun {
+		ctfdExporter = createCTFdExporter(*watchedNamespace)
+	}
+	ctfdSyncInterval, err := time.ParseDuration(getEnv("CTFD_SYNC_INTERVAL", "1m"))
+	if err != nil || ctfdSyncInterval <= 0 {
+		panic(fmt.Sprintf("Invalid CTFD_SYNC_INTERVAL: %s", getEnv("CTFD_SYNC_INTERVAL", "1m")))
+	}
+
+	trackProgress := func(ctx context.Context) {
+		apiServerCheck.AwaitDiscovery()
+		if backupStore != nil {
+			// healed before the first progress updates, so that stale records never get applied to the JuiceShops
+			reconcileOnStartup(ctx, clientset, *watchedNamespace, backupStore)
+			go supervise("backups", func() { backupStore.Run(ctx, clientset, *watchedNamespace, scoring, backupInterval) })
+		}
+		if instanceController != nil {
+			go supervise("instance-controller", func() { instanceController.Run(ctx, clientset, discoveryInterval) })
+		}
+		if ctfdExporter != nil {
+			go supervise("ctfd-exporter", func() { ctfdExporter.Run(ctx, clientset, *watchedNamespace, ctfdSyncInterval) })
+		}
+
+		progressUpdateQueue := NewProgressUpdateQueue(queueCapacity, queueBackpressureThreshold)
+
+		log.Infof("Starting ProgressWatchdog with %d worker go routines", workerCount)
 
-			log.Debugf("Found instance for team %s", teamname)
+		// Start the workers which fetch and update ContinueCodes based on the `progressUpdateQueue`
+		workers := &sync.WaitGroup{}
+		for i := 0; i < workerCount; i++ {
+			workers.Add(1)
+			go func(name string) {
+				defer workers.Done()
+				supervise(name, func() {
+					workOnProgressUpdates(progressUpdateQueue, clientset, scoreboardCache, heartbeats, name)
+				})
+			}(fmt.Sprintf("worker-%d", i))
+		}
 
-			progressUpdateJobs <- ProgressUpdateJobs{
-				Teamname:         instance.Labels["team"],
-				LastContinueCode: instance.Annotations["multi-juicer.iteratec.dev/continueCode"],
+		// resumed once the workers run, as the checkpoint can hold more teams than fit into the queue
+		if queueCheckpoint != nil && juiceShopCache.WaitForSync(ctx) {
+			if teams, err := queueCheckpoint.Load(ctx); err != nil {
+				log.Warningf("Not resuming the queue of the last run: %v", err)
+			} else if len(teams) > 0 {
+				log.Infof("Resumed %d of %d teams queued before the restart", resumeProgressUpdateJobs(progressUpdateQueue, juiceShopCache, teams), len(teams))
 			}
+			go supervise("queue-checkpoint", func() { queueCheckpoint.Run(ctx, progressUpdateQueue, queueCheckpointInterval) })
+		}
+		if solveWebhook != nil {
+			solveWebhook.Attach(progressUpdateQueue)
+		}
+
+		createProgressUpdateJobs(ctx, progressUpdateQueue, juiceShopCache, discoveryInterval, heartbeats, NewTeamTracker(teamIdleThreshold), apiServerCheck.DiscoveryCompleted)
+
+		log.Info("Shutting down, finishing the queued progress updates")
+		if solveWebhook != nil {
+			solveWebhook.Attach(nil)
+		}
+		progressUpdateQueue.ShutDown()
+		workers.Wait()
+		if queueCheckpoint != nil {
+			queueCheckpoint.SaveOnShutdown(progressUpdateQueue)
 		}
-		time.Sleep(5 * time.Second)
 	}
+
+	// a dry run checks all teams next to the leader instead of waiting for its lease
+	if getEnv("LEADER_ELECTION", "false") == "true" && !dryRun {
+		leaseDuration, err := time.ParseDuration(getEnv("LEADER_ELECTION_LEASE_DURATION", "15s"))
+		if err != nil || leaseDuration < time.Second {
+			panic(fmt.Sprintf("Invalid LEADER_ELECTION_LEASE_DURATION: %s", getEnv("LEADER_ELECTION_LEASE_DURATION", "15s")))
+		}
+		runAsLeader(ctx, clientset, namespace, identity, leaseDuration, trackProgress)
+		return
+	}
+	// without leader election the instance is the only replica
+	leaderGauge.Set(1)
+	trackProgress(ctx)
 }
 
-func workOnProgressUpdates(progressUpdateJobs <-chan ProgressUpdateJobs, clientset *kubernetes.Clientset) {
-	for job := range progressUpdateJobs {
-		log.Debugf("Running ProgressUpdateJob for team '%s'", job.Teamname)
-		lastContinueCode := job.LastContinueCode
-		log.Debug("Fetching current ContinueCode")
-		currentContinueCode, err := getCurrentContinueCode(job.Teamname)
+// shutdownTimeout is the time open api requests get to finish on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// shutdownServer stops the api server, waiting for open requests to finish
+func shutdownServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warningf("Failed to shut down the api server gracefully: %s", err)
+	}
+}
 
+// createBackupTarget creates the target configured via `BACKUP_DIR` or the `BACKUP_S3_*` env vars, nil if backups are disabled
+func createBackupTarget() BackupTarget {
+	dir, bucket := os.Getenv("BACKUP_DIR"), os.Getenv("BACKUP_S3_BUCKET")
+	switch {
+	case dir != "" && bucket != "":
+		panic("Backups can either be stored in BACKUP_DIR or in BACKUP_S3_BUCKET, not both")
+	case dir != "":
+		return DirectoryBackupTarget(dir)
+	case bucket != "":
+		accessKeyID, secretAccessKey := os.Getenv("BACKUP_S3_ACCESS_KEY_ID"), os.Getenv("BACKUP_S3_SECRET_ACCESS_KEY")
+		if accessKeyID == "" || secretAccessKey == "" {
+			panic("BACKUP_S3_BUCKET requires credentials in BACKUP_S3_ACCESS_KEY_ID and BACKUP_S3_SECRET_ACCESS_KEY")
+		}
+		client, err := NewS3Client(getEnv("BACKUP_S3_ENDPOINT", "https://s3.amazonaws.com"), bucket, getEnv("BACKUP_S3_REGION", "us-east-1"), accessKeyID, secretAccessKey, &http.Client{Timeout: 30 * time.Second})
 		if err != nil {
-			log.Warningf("Failed to fetch ContinueCode for team '%s' from Juice Shop", job.Teamname)
-			log.Warning(err)
-			continue
+			panic(fmt.Sprintf("Invalid BACKUP_S3_ENDPOINT: %s", err))
 		}
+		return NewS3BackupTarget(client, os.Getenv("BACKUP_S3_PREFIX"))
+	}
+	return nil
+}
 
-		log.Debug("Checking Difference between ContinueCode")
+// createBackupStore creates the backup store writing to the target, configured via `BACKUP_INTERVAL` and `BACKUP_RETENTION`
+func createBackupStore(target BackupTarget) (*BackupStore, time.Duration) {
+	passphrase, err := readSnapshotPassphrase("")
+	if err != nil {
+		panic(fmt.Sprintf("Backups require a passphrase: %s", err))
+	}
+	interval, err := time.ParseDuration(getEnv("BACKUP_INTERVAL", "10m"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid BACKUP_INTERVAL: %s", err))
+	}
+	retention, err := strconv.Atoi(getEnv("BACKUP_RETENTION", "144"))
+	if err != nil || retention < 1 {
+		panic(fmt.Sprintf("Invalid BACKUP_RETENTION: %s", getEnv("BACKUP_RETENTION", "144")))
+	}
+	return NewBackupStore(target, passphrase, retention), interval
+}
 
-		currentSolvedChallenges, _ := ParseContinueCode(currentContinueCode)
-		lastSolvedChallenges, _ := ParseContinueCode(lastContinueCode)
+// defaultWatchedNamespace is the namespace of the watchdog, unless the `NAMESPACE` env var is explicitly set to an empty value to watch all namespaces
+func defaultWatchedNamespace(namespace string) string {
+	if value, ok := lookupEnv("NAMESPACE"); ok && value == "" {
+		return metav1.NamespaceAll
+	}
+	return namespace
+}
 
-		switch CompareChallengeStates(currentSolvedChallenges, lastSolvedChallenges) {
-		case ApplyCode:
-			log.Debugf("ContinueCodes differ (current vs last): (%s vs %s)", currentContinueCode, lastContinueCode)
-			log.Debug("Applying cached ContinueCode")
-			log.Infof("Last ContinueCode for team '%s' contains unsolved challenges", job.Teamname)
-			applyContinueCode(job.Teamname, lastContinueCode)
+// kubeconfigPath is the kubeconfig used outside of a cluster, e.g. for local development
+var kubeconfigPath string
 
-			log.Debug("ReFetching current ContinueCode")
-			currentContinueCode, err = getCurrentContinueCode(job.Teamname)
+// loadKubernetesConfig uses the in-cluster config when running inside a cluster and falls back to the kubeconfig otherwise
+func loadKubernetesConfig() (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err != rest.ErrNotInCluster {
+		return config, err
+	}
+	path := kubeconfigPath
+	if path == "" {
+		path = clientcmd.RecommendedHomeFile
+	}
+	log.Infof("Not running inside a cluster, using kubeconfig '%s'", path)
+	return clientcmd.BuildConfigFromFlags("", path)
+}
 
+// createKubernetesConfig creates the config of the kubernetes clients used by the watchdog
+func createKubernetesConfig() *rest.Config {
+	config, err := loadKubernetesConfig()
+	if err != nil {
+		panic(err.Error())
+	}
+	config.UserAgent = UserAgent
+	config.Wrap(newTaggingRoundTripper)
+	config.Wrap(kubernetesPacer.Wrap)
+	config.Wrap(func(next http.RoundTripper) http.RoundTripper {
+		return outboundRateLimiter.Wrap(next, isWriteRequest)
+	})
+	config.Wrap(func(next http.RoundTripper) http.RoundTripper {
+		return wrapDryRun(next, dryRunTargetKubernetes)
+	})
+	return config
+}
+
+// createClientset creates the kubernetes client used by the watchdog
+func createClientset() kubernetes.Interface {
+	clientset, err := kubernetes.NewForConfig(createKubernetesConfig())
+	if err != nil {
+		panic(err.Error())
+	}
+	return clientset
+}
+
+// createDynamicClient creates the client for the custom resources of the watchdog, e.g. the JuiceShopInstances
+func createDynamicClient() dynamic.Interface {
+	client, err := dynamic.NewForConfig(createKubernetesConfig())
+	if err != nil {
+		panic(err.Error())
+	}
+	return client
+}
+
+// getEnv returns the value of the setting in the mounted config or the environment variable, the fallback if it isn't set
+func getEnv(name, fallback string) string {
+	if value, ok := lookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// createProgressUpdateJobs subscribes to the JuiceShops of the shared cache and queues progressUpdatesJobs for them whenever their workload
+// is added or updated. The cache resyncs every interval, so that the progress of every team is still checked periodically.
+// discovered is called after every completed discovery of the teams.
+func createProgressUpdateJobs(ctx context.Context, progressUpdateQueue *ProgressUpdateQueue, juiceShops *JuiceShopCache, interval time.Duration, heartbeats *Heartbeats, teamTracker *TeamTracker, discovered func()) {
+	unsubscribe := juiceShops.Subscribe(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			queueProgressUpdateJob(progressUpdateQueue, nil, obj)
+		},
+		UpdateFunc: func(previous, obj interface{}) {
+			queueProgressUpdateJob(progressUpdateQueue, previous, obj)
+		},
+		DeleteFunc: archiveDeletedJuiceShop,
+	})
+	defer unsubscribe()
+	if !juiceShops.WaitForSync(ctx) {
+		return
+	}
+
+	for {
+		heartbeats.Beat("discovery")
+
+		instances := juiceShops.List(metav1.NamespaceAll, labels.Everything())
+		log.Debugf("Found %d JuiceShop running", len(instances))
+		dispatchLifecycleEvents(teamTracker, instances)
+		updateInstanceMetrics(instances)
+		discovered()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(kubernetesPacer.DiscoveryInterval(interval)):
+		}
+	}
+}
+
+// queueProgressUpdateJob queues the job of a JuiceShop deployment received from the informer, unless it isn't ready or paused.
+// previous is the deployment before the update, nil for added deployments. JuiceShops which just became ready, e.g. after a restart
+// which probably lost their progress, are queued as urgent.
+func queueProgressUpdateJob(progressUpdateQueue *ProgressUpdateQueue, previous, obj interface{}) {
+	instance, ok := workloads.Convert(obj)
+	if !ok {
+		return
+	}
+	job, ok := progressUpdateJobOf(instance)
+	if !ok {
+		return
+	}
+	if previousInstance, ok := workloads.Convert(previous); ok && previousInstance.Status.ReadyReplicas < 1 {
+		job.Urgent = true
+	}
+	log.Debugf("Found instance for team %s", job.Teamname)
+	job.QueuedAt = time.Now()
+	progressUpdateQueue.Add(job)
+}
+
+// progressUpdateJobOf creates the job of a JuiceShop deployment, false if its progress can't be updated as it isn't ready or paused.
+// Jobs of teams without cached progress, e.g. new teams, are urgent.
+func progressUpdateJobOf(instance *appsv1.Deployment) (ProgressUpdateJobs, bool) {
+	teamname := instance.Labels["team"]
+	if instance.Status.ReadyReplicas < 1 {
+		return ProgressUpdateJobs{}, false
+	}
+	if isPaused(*instance) {
+		log.Debugf("Skipping paused team %s", teamname)
+		return ProgressUpdateJobs{}, false
+	}
+	job := progressUpdateJobForDeployment(*instance)
+	job.Urgent = job.LastContinueCode == ""
+	return job, true
+}
+
+// dispatchLifecycleEvents dispatches the events of teams created, idle or deleted since the last discovery to the hooks
+func dispatchLifecycleEvents(teamTracker *TeamTracker, juiceShops []appsv1.Deployment) {
+	lastRequests := map[string]time.Time{}
+	for _, instance := range juiceShops {
+		lastRequests[instance.Labels["team"]] = lastRequestOf(instance)
+	}
+	now := time.Now()
+	changes := teamTracker.Update(lastRequests, now)
+	for _, team := range changes.Created {
+		hooks.Dispatch(HookEvent{Type: HookEventTeamCreated, Team: team, Time: now})
+	}
+	for _, team := range changes.Idle {
+		hooks.Dispatch(HookEvent{Type: HookEventTeamIdle, Team: team, Time: now})
+	}
+	for _, team := range changes.Deleted {
+		hooks.Dispatch(HookEvent{Type: HookEventInstanceDeleted, Team: team, Time: now})
+	}
+}
+
+// lastRequestOf returns the time of the last request of the team to its JuiceShop, as tracked by the JuiceBalancer in milliseconds since epoch.
+// Falls back to the creation of the deployment, if the team hasn't sent a request yet.
+func lastRequestOf(deployment appsv1.Deployment) time.Time {
+	milliseconds, err := strconv.ParseInt(deployment.Annotations[annotation("lastRequest")], 10, 64)
+	if err != nil {
+		return deployment.CreationTimestamp.Time
+	}
+	return time.Unix(0, milliseconds*int64(time.Millisecond))
+}
+
+// workOnProgressUpdates runs queued jobs until the queue is shut down. The worker counts as idle while it waits for a queued team
+func workOnProgressUpdates(progressUpdateQueue *ProgressUpdateQueue, clientset kubernetes.Interface, scoreboardCache *ScoreboardCache, heartbeats *Heartbeats, name string) {
+	run := func(job ProgressUpdateJobs) error {
+		heartbeats.Beat(name)
+		defer heartbeats.Idle(name)
+		start := time.Now()
+		var state UpdateState
+		// a panicking update only fails its team, which gets retried with backoff while the worker continues with the other teams
+		err := recoverPanic("progress-update", func() (err error) {
+			state, err = runProgressUpdateJob(job, clientset, scoreboardCache)
+			return err
+		})
+		logProgressUpdate(job, name, state, time.Since(start), err)
+		return err
+	}
+	heartbeats.Idle(name)
+	for progressUpdateQueue.Process(run) {
+	}
+}
+
+// logProgressUpdate logs the outcome of a progress update job with structured fields, updates which changed nothing are only logged in debug level
+func logProgressUpdate(job ProgressUpdateJobs, component string, state UpdateState, duration time.Duration, err error) {
+	fields := LogFields{"team": job.Teamname, "component": component, "duration": duration}
+	switch {
+	case err != nil:
+		fields["error"] = err
+		log.Warning("Progress update failed", fields)
+	case state == NoOp:
+		fields["state"] = state
+		log.Debug("Progress update finished", fields)
+	default:
+		fields["state"] = state
+		log.Info("Progress update finished", fields)
+	}
+}
+
+// progressUpdateJobForDeployment creates the ProgressUpdateJob for a JuiceShop deployment
+func progressUpdateJobForDeployment(instance appsv1.Deployment) ProgressUpdateJobs {
+	records := progressStore.Records(instance)
+	return ProgressUpdateJobs{
+		Teamname:         instance.Labels["team"],
+		Namespace:        instance.Namespace,
+		LastContinueCode: records[annotation("continueCode")],
+		LastSolves:       parseSolveTimes(records[annotation("solves")]),
+		Replicas:         instance.Status.ReadyReplicas,
+		CreatedAt:        instance.CreationTimestamp.Time,
+		Recovered:        instance.Annotations[annotation("progressRecoveredAt")] != "",
+
+		LastCodingChallengeCodes: codingChallengeCodesOf(instance.Annotations),
+	}
+}
+
+// runProgressUpdateJob fetches the current ContinueCode of a team and either caches it or reapplies the cached one.
+// The update is traced if tracing is enabled, starting with the time the job waited in the queue.
+func runProgressUpdateJob(job ProgressUpdateJobs, clientset kubernetes.Interface, scoreboardCache *ScoreboardCache) (UpdateState, error) {
+	start := time.Now()
+	if !job.QueuedAt.IsZero() {
+		start = job.QueuedAt
+	}
+	ctx, span := startSpanAt(context.Background(), "progress-update", start)
+	span.SetAttributes(
+		attribute.String("team", job.Teamname),
+		attribute.String("namespace", job.Namespace),
+		attribute.Int("replicas", int(job.Replicas)),
+	)
+	if !job.QueuedAt.IsZero() {
+		_, queuedSpan := startSpanAt(ctx, "progress-update.queued", job.QueuedAt)
+		queuedSpan.End()
+	}
+	job.ctx = ctx
+
+	updateState, err := updateProgress(job, clientset, scoreboardCache)
+	span.SetAttributes(attribute.String("state", string(updateState)))
+	endSpan(span, err)
+	return updateState, err
+}
+
+// updateProgress runs the progress update of the job, see runProgressUpdateJob
+func updateProgress(job ProgressUpdateJobs, clientset kubernetes.Interface, scoreboardCache *ScoreboardCache) (UpdateState, error) {
+	_, recoverSpan := startSpan(job.Context(), "progress-store.recover")
+	job, err := recoverProgress(job, clientset)
+	endSpan(recoverSpan, err)
+	if err != nil {
+		log.Warningf("Failed to recover the progress of team '%s'", job.Teamname)
+		log.Warning(err)
+		return "", err
+	}
+	if job.Replicas > 1 && featureFlags.Enabled(FeatureReplicaMerge) {
+		return runMultiReplicaProgressUpdateJob(job, clientset, scoreboardCache)
+	}
+	log.Debugf("Running ProgressUpdateJob for team '%s'", job.Teamname)
+	lastContinueCode := job.LastContinueCode
+	log.Debug("Fetching current ContinueCode")
+	currentContinueCode, err := getCurrentContinueCodeTraced(job)
+	if err != nil {
+		log.Warningf("Failed to fetch ContinueCode for team '%s' from Juice Shop", job.Teamname)
+		log.Warning(err)
+		return "", err
+	}
+
+	log.Debug("Checking Difference between ContinueCode")
+
+	currentSolvedChallenges, _ := ParseContinueCode(currentContinueCode)
+	lastSolvedChallenges, _ := ParseContinueCode(lastContinueCode)
+
+	updateState := CompareChallengeStates(currentSolvedChallenges, lastSolvedChallenges)
+	switch updateState {
+	case ApplyCode:
+		log.Debugf("ContinueCodes differ (current vs last): (%s vs %s)", currentContinueCode, lastContinueCode)
+		log.Debug("Applying cached ContinueCode")
+		log.Infof("Last ContinueCode for team '%s' contains unsolved challenges", job.Teamname)
+		if len(currentSolvedChallenges) == 0 {
+			log.Warningf("JuiceShop of team '%s' lost all of its %d solved challenges, its database was probably reset. Restoring the cached progress", job.Teamname, len(lastSolvedChallenges))
+		}
+		applyCtx, applySpan := startClientSpan(job.Context(), "juiceshop.apply-continue-code", juiceShopURL(job.Namespace, job.Teamname))
+		err = applyContinueCode(applyCtx, job.Namespace, job.Teamname, lastContinueCode)
+		endSpan(applySpan, err)
+		if err != nil {
+			log.Errorf("Failed to apply the cached ContinueCode of team '%s'", job.Teamname)
+			log.Error(err)
+			return "", err
+		}
+		if dryRun {
+			// the JuiceShop still reports its progress from before the skipped restore, verifying it would always fail
+			return updateState, nil
+		}
+		restoresApplied.Inc()
+
+		log.Debug("ReFetching current ContinueCode")
+		currentContinueCode, err = getCurrentContinueCodeTraced(job)
+		if err != nil {
+			log.Errorf("Failed to fetch ContinueCode from Juice Shop for team '%s' to reapply it", job.Teamname)
+			log.Error(err)
+			return "", err
+		}
+
+		// verifies that the restore took effect, the JuiceShop silently ignores e.g. invalid ContinueCodes
+		var restoreErr error
+		reappliedSolvedChallenges, _ := ParseContinueCode(currentContinueCode)
+		if CompareChallengeStates(reappliedSolvedChallenges, lastSolvedChallenges) == ApplyCode {
+			restoreErr = errRestoreNotVerified
+			restoreVerificationFailures.Inc()
+			log.Errorf("Restore of team '%s' didn't take effect, its JuiceShop reports %d solved challenges before and %d after applying the %d cached ones. Keeping the cached progress and retrying", job.Teamname, len(currentSolvedChallenges), len(reappliedSolvedChallenges), len(lastSolvedChallenges))
+			hooks.Dispatch(HookEvent{Type: HookEventRestoreFailed, Team: job.Teamname, ChallengesSolved: len(reappliedSolvedChallenges), Time: time.Now()})
+			// never cache a regressed state, e.g. when the database got wiped again before the cached progress was applied
+			currentContinueCode, err = EncodeContinueCode(mergeSolvedChallenges(reappliedSolvedChallenges, lastSolvedChallenges))
 			if err != nil {
-				log.Errorf("Failed to fetch ContinueCode from Juice Shop for team '%s' to reapply it", job.Teamname)
-				log.Error(err)
-				continue
+				return "", fmt.Errorf("Failed to encode merged ContinueCode: %v", err)
 			}
+		}
+
+		log.Debug("Caching current ContinueCode")
+		if err := cacheContinueCodeTraced(job, clientset, currentContinueCode); err != nil {
+			return updateState, err
+		}
+		scoreboardCache.Invalidate()
+		if restoreErr != nil {
+			// retried with the backoff of the queue instead of waiting for the next cycle
+			return updateState, restoreErr
+		}
+	case UpdateCache:
+		if err := cacheContinueCodeTraced(job, clientset, currentContinueCode); err != nil {
+			return updateState, err
+		}
+		scoreboardCache.Invalidate()
+	case NoOp:
+		log.Debug("No need to apply ContinueCode, Skipping")
+	}
+
+	codingChallengesCtx, codingChallengesSpan := startSpan(job.Context(), "coding-challenges.sync")
+	codingChallengesJob := job
+	codingChallengesJob.ctx = codingChallengesCtx
+	err = syncCodingChallengeProgress(codingChallengesJob, clientset)
+	endSpan(codingChallengesSpan, err)
+	if err != nil {
+		log.Warningf("Failed to sync coding challenge progress of team '%s'", job.Teamname)
+		log.Warning(err)
+		return updateState, err
+	}
+	return updateState, nil
+}
+
+// clusterDomain is the dns domain of the cluster the JuiceShop services are addressed with, configured via `CLUSTER_DOMAIN`
+var clusterDomain = "cluster.local"
+
+// juiceShopScheme and juiceShopPort the JuiceShops are served with, `https` if `JUICE_SHOP_TLS` is enabled and the port configured via `JUICE_SHOP_PORT`
+var (
+	juiceShopScheme = "http"
+	juiceShopPort   = 3000
+)
+
+// JuiceShopService are the values available in the `JUICE_SHOP_SERVICE_TEMPLATE`
+type JuiceShopService struct {
+	Team          string
+	Namespace     string
+	ClusterDomain string
+	Port          int
+}
+
+// defaultJuiceShopServiceTemplate addresses the services created by the JuiceBalancer with their fully qualified name,
+// so that JuiceShops in other namespaces, e.g. a namespace per team, can be reached
+const defaultJuiceShopServiceTemplate = "t-{{ .Team }}-juiceshop{{ if .Namespace }}.{{ .Namespace }}.svc.{{ .ClusterDomain }}{{ end }}"
+
+// juiceShopServiceTemplate renders the address of the JuiceShop service of a team, configured via `JUICE_SHOP_SERVICE_TEMPLATE`
+var (
+	juiceShopServiceTemplate      = template.Must(parseJuiceShopServiceTemplate(defaultJuiceShopServiceTemplate))
+	juiceShopServiceTemplateMutex sync.RWMutex
+)
+
+// setJuiceShopServiceTemplate replaces the juiceShopServiceTemplate while the workers are rendering addresses with it
+func setJuiceShopServiceTemplate(parsed *template.Template) {
+	juiceShopServiceTemplateMutex.Lock()
+	defer juiceShopServiceTemplateMutex.Unlock()
+	juiceShopServiceTemplate = parsed
+}
 
-			log.Debug("Caching current ContinueCode")
-			cacheContinueCode(clientset, job.Teamname, currentContinueCode)
-		case UpdateCache:
-			cacheContinueCode(clientset, job.Teamname, currentContinueCode)
-		case NoOp:
-			log.Debug("No need to apply ContinueCode, Skipping")
+// parseJuiceShopServiceTemplate parses the template of the JuiceShop service address, e.g. `{{ .Team }}-shop.{{ .Namespace }}:8080`.
+// The address may include a port, otherwise the port configured via `JUICE_SHOP_PORT` is used.
+func parseJuiceShopServiceTemplate(text string) (*template.Template, error) {
+	parsed, err := template.New("service").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// catches references to unknown fields on startup instead of with the first request
+	if err := parsed.Execute(ioutil.Discard, JuiceShopService{Team: "team", Namespace: "namespace", ClusterDomain: clusterDomain, Port: juiceShopPort}); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// juiceShopURL returns the base url of the JuiceShop service of the team, addressed as rendered by the juiceShopServiceTemplate.
+// In the `pod-ip` connect mode a ready pod of the team is addressed directly instead, falling back to the service if there is none.
+func juiceShopURL(namespace, teamname string) string {
+	if podIPResolver != nil {
+		if ip, ok := podIPResolver.Resolve(namespace, teamname); ok {
+			return fmt.Sprintf("%s://%s", juiceShopScheme, net.JoinHostPort(ip, strconv.Itoa(juiceShopPort)))
 		}
+		log.Debugf("Found no ready pod of team '%s', connecting to its service", teamname)
+	}
+	address := &strings.Builder{}
+	service := JuiceShopService{Team: teamname, Namespace: namespace, ClusterDomain: clusterDomain, Port: juiceShopPort}
+	juiceShopServiceTemplateMutex.RLock()
+	serviceTemplate := juiceShopServiceTemplate
+	juiceShopServiceTemplateMutex.RUnlock()
+	if err := serviceTemplate.Execute(address, service); err != nil {
+		log.Warningf("Failed to render the service address of team '%s', using its service in the namespace of the watchdog: %s", teamname, err)
+		address.Reset()
+		fmt.Fprintf(address, "t-%s-juiceshop", teamname)
+	}
+	host := strings.TrimSpace(address.String())
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, strconv.Itoa(juiceShopPort))
 	}
+	return fmt.Sprintf("%s://%s", juiceShopScheme, host)
+}
+
+// getCurrentContinueCodeTraced fetches the current ContinueCode of the team of the job in a span of its trace
+func getCurrentContinueCodeTraced(job ProgressUpdateJobs) (string, error) {
+	ctx, span := startClientSpan(job.Context(), "juiceshop.fetch-continue-code", juiceShopURL(job.Namespace, job.Teamname))
+	continueCode, err := getCurrentContinueCode(ctx, job.Namespace, job.Teamname)
+	endSpan(span, err)
+	return continueCode, err
 }
 
-func getCurrentContinueCode(teamname string) (string, error) {
-	url := fmt.Sprintf("http://t-%s-juiceshop:3000/rest/continue-code", teamname)
+func getCurrentContinueCode(ctx context.Context, namespace, teamname string) (string, error) {
+	return fetchContinueCode(ctx, juiceShopURL(namespace, teamname))
+}
+
+// fetchContinueCode fetches the current ContinueCode from the JuiceShop at the base url, e.g. a single replica
+func fetchContinueCode(ctx context.Context, baseURL string) (string, error) {
+	return fetchContinueCodeAt(ctx, baseURL+"/rest/continue-code")
+}
 
-	req, err := http.NewRequest("GET", url, bytes.NewBuffer([]byte{}))
+// fetchContinueCodeAt fetches a ContinueCode from the url of its api
+func fetchContinueCodeAt(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, bytes.NewBuffer([]byte{}))
 	if err != nil {
-		log.Warning("Failed to create http request")
-		log.Warning(err)
-		panic("Failed to create http request")
+		return "", fmt.Errorf("Failed to create http request: %v", err)
 	}
-	res, err := http.DefaultClient.Do(req)
+	res, err := juiceShopClient.Do(req)
 	if err != nil {
 		log.Warning("Failed to fetch ContinueCode from juice shop")
 		log.Warning(err)
+		continueCodeFetchFailures.Inc()
 		return "", errors.New("Failed to fetch ContinueCode")
 	}
 	defer res.Body.Close()
@@ -192,44 +1017,73 @@ func getCurrentContinueCode(teamname string) (string, error) {
 		log.Debugf("Got current ContinueCode: '%s'", continueCodePayload.ContinueCode)
 
 		return continueCodePayload.ContinueCode, nil
+	case 404:
+		return "", errContinueCodeUnsupported
 	default:
+		continueCodeFetchFailures.Inc()
 		return "", fmt.Errorf("Unexpected response status code '%d' from Juice Shop", res.StatusCode)
 	}
 }
 
-func applyContinueCode(teamname, continueCode string) {
-	url := fmt.Sprintf("http://t-%s-juiceshop:3000/rest/continue-code/apply/%s", teamname, continueCode)
+// errRestoreNotVerified is returned if the solved challenges of the JuiceShop are still missing after the cached ContinueCode was applied
+var errRestoreNotVerified = errors.New("Restored progress didn't take effect")
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer([]byte{}))
+func applyContinueCode(ctx context.Context, namespace, teamname, continueCode string) error {
+	return putContinueCode(ctx, juiceShopURL(namespace, teamname), continueCode)
+}
+
+// putContinueCode applies the ContinueCode to the JuiceShop at the base url, e.g. a single replica
+func putContinueCode(ctx context.Context, baseURL, continueCode string) error {
+	return putContinueCodeAt(ctx, fmt.Sprintf("%s/rest/continue-code/apply/%s", baseURL, continueCode))
+}
+
+// putContinueCodeAt applies a ContinueCode via the url of its apply api
+func putContinueCodeAt(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer([]byte{}))
 	if err != nil {
-		log.Warning("Failed to create http request to set the current ContinueCode")
-		log.Warning(err)
+		return fmt.Errorf("Failed to create http request to set the current ContinueCode: %v", err)
 	}
-	res, err := http.DefaultClient.Do(req)
+	res, err := juiceShopClient.Do(req)
 	if err != nil {
-		log.Warning("Failed to set the current ContinueCode to juice shop")
-		log.Warning(err)
+		return fmt.Errorf("Failed to set the current ContinueCode to juice shop: %v", err)
 	}
 	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected response status code '%d' from Juice Shop", res.StatusCode)
+	}
+	return nil
 }
 
-// UpdateProgressDeploymentDiff contains only the parts of the deployment we are interessted in updating
-type UpdateProgressDeploymentDiff struct {
-	Metadata UpdateProgressDeploymentMetadata `json:"metadata"`
-}
-
-// UpdateProgressDeploymentMetadata a shim of the k8s metadata object containing only annotations
-type UpdateProgressDeploymentMetadata struct {
-	Annotations UpdateProgressDeploymentDiffAnnotations `json:"annotations"`
+// UpdateProgressRecords the progress records cached by the `progress-watchdog`, see ProgressStore
+type UpdateProgressRecords struct {
+	ContinueCode     string
+	ChallengesSolved string
+	Solves           string
+	// DisabledChallenges and TutorialChallenges are omitted if they couldn't be fetched, keeping the previously cached ones
+	DisabledChallenges string
+	TutorialChallenges string
+	// SolveDetails are only set if the solveDetails feature is enabled
+	SolveDetails string
 }
 
-// UpdateProgressDeploymentDiffAnnotations the app specific annotations relevant to the `progress-watchdog`
-type UpdateProgressDeploymentDiffAnnotations struct {
-	ContinueCode     string `json:"multi-juicer.iteratec.dev/continueCode"`
-	ChallengesSolved string `json:"multi-juicer.iteratec.dev/challengesSolved"`
+// Records returns the records keyed by their annotations, empty optional records are omitted
+func (u UpdateProgressRecords) Records() map[string]string {
+	records := map[string]string{
+		annotation("continueCode"):     u.ContinueCode,
+		annotation("challengesSolved"): u.ChallengesSolved,
+		annotation("solves"):           u.Solves,
+	}
+	for name, value := range map[string]string{"disabledChallenges": u.DisabledChallenges, "tutorialChallenges": u.TutorialChallenges, "solveDetails": u.SolveDetails} {
+		if value != "" {
+			records[annotation(name)] = value
+		}
+	}
+	return records
 }
 
-func cacheContinueCode(clientset *kubernetes.Clientset, teamname string, continueCode string) {
+// cacheContinueCode saves the ContinueCode and the derived progress of the team. The save only applies if the cached ContinueCode is still
+// the lastContinueCode the job was based on, otherwise errProgressChanged is returned and the job is retried with the current progress.
+func cacheContinueCode(clientset kubernetes.Interface, namespace, teamname string, lastContinueCode, continueCode string, lastSolves SolveTimes, challengeConfiguration *ChallengeConfiguration) error {
 	log.Infof("Updating saved ContinueCode of team '%s'", teamname)
 
 	solvedChallenges, err := ParseContinueCode(continueCode)
@@ -237,27 +1091,80 @@ func cacheContinueCode(clientset *kubernetes.Clientset, teamname string, continu
 		log.Warningf("Could not decode continueCode '%s'", continueCode)
 	}
 
-	diff := UpdateProgressDeploymentDiff{
-		Metadata: UpdateProgressDeploymentMetadata{
-			Annotations: UpdateProgressDeploymentDiffAnnotations{
-				ContinueCode:     continueCode,
-				ChallengesSolved: fmt.Sprintf("%d", len(solvedChallenges)),
-			},
-		},
+	updatedSolves := lastSolves.Update(solvedChallenges, time.Now())
+	if challengeConfiguration != nil && challengeConfiguration.SolvedAt != nil {
+		updatedSolves = lastSolves.UpdateReported(solvedChallenges, challengeConfiguration.SolvedAt, time.Now())
 	}
-
-	jsonBytes, err := json.Marshal(diff)
+	solves, err := json.Marshal(updatedSolves)
 	if err != nil {
-		panic("Could not encode json, to update ContinueCode and challengeSolved count on deployment")
+		return fmt.Errorf("Failed to encode the solve times: %v", err)
+	}
+
+	disabled, tutorial, details := "", "", ""
+	if challengeConfiguration != nil {
+		encodedDisabled, err := json.Marshal(challengeConfiguration.Disabled)
+		if err != nil {
+			return fmt.Errorf("Failed to encode the disabled challenges: %v", err)
+		}
+		encodedTutorial, err := json.Marshal(challengeConfiguration.Tutorial)
+		if err != nil {
+			return fmt.Errorf("Failed to encode the tutorial challenges: %v", err)
+		}
+		disabled, tutorial = string(encodedDisabled), string(encodedTutorial)
+		if challengeConfiguration.SolveDetails != nil {
+			encodedDetails, err := json.Marshal(challengeConfiguration.SolveDetails)
+			if err != nil {
+				return fmt.Errorf("Failed to encode the solve details: %v", err)
+			}
+			details = string(encodedDetails)
+		}
 	}
 
-	namespace := os.Getenv("NAMESPACE")
+	records := UpdateProgressRecords{
+		ContinueCode:     continueCode,
+		ChallengesSolved: fmt.Sprintf("%d", len(solvedChallenges)),
+		Solves:           string(solves),
+
+		DisabledChallenges: disabled,
+		TutorialChallenges: tutorial,
+		SolveDetails:       details,
+	}.Records()
+
 	ctx := context.Background()
-	_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, fmt.Sprintf("t-%s-juiceshop", teamname), types.MergePatchType, jsonBytes, metav1.PatchOptions{})
-	if err != nil {
-		log.Errorf("Failed to patch new ContinueCode into deployment for team %s", teamname)
+	if err := kubernetesPacer.WaitForPatch(ctx); err != nil {
+		log.Errorf("Failed to wait for the patch rate limit of team %s", teamname)
+		log.Error(err)
+		return err
+	}
+	updated, err := progressStore.Save(ctx, clientset, namespace, teamname, records, map[string]string{annotation("continueCode"): lastContinueCode})
+	if errors.Is(err, errProgressChanged) {
+		log.Warningf("Cached ContinueCode of team %s was changed concurrently, not overwriting it", teamname)
+		progressConflicts.Inc()
+		return err
+	} else if err != nil {
+		log.Errorf("Failed to save new ContinueCode of team %s", teamname)
 		log.Error(err)
+		patchErrors.Inc()
+		return err
 	}
+	hooks.dispatchSolves(teamname, lastSolves, updatedSolves)
+	if featureFlags.Enabled(FeatureChallengeAPIScoring) {
+		cacheScore(ctx, clientset, *updated)
+	}
+	return nil
+}
+
+// cacheContinueCodeTraced caches the ContinueCode of the team of the job in a span of its trace, covering the challenge configuration
+// fetched from the JuiceShop and the save into the progress store
+func cacheContinueCodeTraced(job ProgressUpdateJobs, clientset kubernetes.Interface, continueCode string) error {
+	configurationCtx, configurationSpan := startClientSpan(job.Context(), "juiceshop.fetch-challenges", juiceShopURL(job.Namespace, job.Teamname))
+	challengeConfiguration := getChallengeConfiguration(configurationCtx, job.Namespace, job.Teamname)
+	configurationSpan.End()
+
+	_, span := startSpan(job.Context(), "progress-store.save")
+	err := cacheContinueCode(clientset, job.Namespace, job.Teamname, job.LastContinueCode, continueCode, job.LastSolves, challengeConfiguration)
+	endSpan(span, err)
+	return err
 }
 
 func contains(s []int, e int) bool {
@@ -301,12 +1208,7 @@ func CompareChallengeStates(currentSolvedChallenges, lastSolvedChallenges []int)
 
 // ParseContinueCode returns the number of challenges solved by this ContinueCode
 func ParseContinueCode(continueCode string) ([]int, error) {
-	hd := hashids.NewData()
-	hd.Salt = "this is my salt"
-	hd.MinLength = 60
-	hd.Alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
-
-	hashIDClient, _ := hashids.NewWithData(hd)
+	hashIDClient := newContinueCodeHashID()
 	decoded, err := hashIDClient.DecodeWithError(continueCode)
 
 	if err != nil {
@@ -315,3 +1217,25 @@ func ParseContinueCode(continueCode string) ([]int, error) {
 
 	return decoded, nil
 }
+
+// EncodeContinueCode creates the ContinueCode the JuiceShop would create for the solved challenges
+func EncodeContinueCode(solvedChallenges []int) (string, error) {
+	if len(solvedChallenges) == 0 {
+		return "", nil
+	}
+	sorted := make([]int, len(solvedChallenges))
+	copy(sorted, solvedChallenges)
+	sort.Ints(sorted)
+	return newContinueCodeHashID().Encode(sorted)
+}
+
+// newContinueCodeHashID creates the hashids client with the same settings the JuiceShop uses for its ContinueCodes
+func newContinueCodeHashID() *hashids.HashID {
+	hd := hashids.NewData()
+	hd.Salt = "this is my salt"
+	hd.MinLength = 60
+	hd.Alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+
+	hashIDClient, _ := hashids.NewWithData(hd)
+	return hashIDClient
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/iteratec/multi-juicer/progress-watchdog/internal/fakejuiceshop"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestParsesContinueCodes(t *testing.T) {
@@ -36,3 +46,276 @@ func TestCompareChallengeStates(t *testing.T) {
 	assert.Equal(t, ApplyCode, CompareChallengeStates([]int{1}, []int{1, 2}), "Should apply when a challenge is not contained")
 	assert.Equal(t, ApplyCode, CompareChallengeStates([]int{1, 2}, []int{3, 2, 1}), "Should apply when a challenge is not contained and the orders are different")
 }
+
+func TestEncodeContinueCodeIsInverseOfParse(t *testing.T) {
+	continueCode, err := EncodeContinueCode([]int{80, 11, 15, 16, 21, 36, 39, 53, 70, 83})
+	assert.NoError(t, err)
+	assert.Equal(t, "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg", continueCode)
+
+	continueCode, err = EncodeContinueCode([]int{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", continueCode)
+}
+
+func TestSyncNeverCachesProgressWipedByDatabaseReset(t *testing.T) {
+	// the JuiceShop keeps reporting no solves, e.g. because the reapplied progress got wiped again right away
+	fakeJuiceShops(t, "")
+	cachedContinueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+	deployment := createJuiceShopDeployment("foobar", cachedContinueCode, "3")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	failures := testutil.ToFloat64(restoreVerificationFailures)
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.Equal(t, errRestoreNotVerified, err)
+	assert.Equal(t, ApplyCode, state)
+	assert.Equal(t, failures+1, testutil.ToFloat64(restoreVerificationFailures))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, cachedContinueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+}
+
+func TestSyncFailsIfTheCachedContinueCodeCouldNotBeApplied(t *testing.T) {
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "PUT" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"continueCode":""}`))
+	})
+	cachedContinueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+	deployment := createJuiceShopDeployment("foobar", cachedContinueCode, "3")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+
+	_, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.EqualError(t, err, "Unexpected response status code '500' from Juice Shop")
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, cachedContinueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestCachesNumberOfChallengesSolvedByTheContinueCode(t *testing.T) {
+	continueCode, _ := EncodeContinueCode([]int{4, 8, 15, 16})
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+
+	cacheContinueCode(clientset, "default", "foobar", "", continueCode, SolveTimes{}, nil)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, continueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "4", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+}
+
+func TestLoadsKubeconfigOutsideOfCluster(t *testing.T) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		t.Skip("Running inside a cluster")
+	}
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+clusters:
+  - name: local
+    cluster:
+      server: https://127.0.0.1:6443
+contexts:
+  - name: local
+    context:
+      cluster: local
+current-context: local
+`), 0600))
+
+	previous := kubeconfigPath
+	kubeconfigPath = path
+	defer func() { kubeconfigPath = previous }()
+
+	config, err := loadKubernetesConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://127.0.0.1:6443", config.Host)
+}
+
+func TestJuiceShopURLIncludesNamespaceOfTheTeam(t *testing.T) {
+	assert.Equal(t, "http://t-team-a-juiceshop.event-1.svc.cluster.local:3000", juiceShopURL("event-1", "team-a"))
+	assert.Equal(t, "http://t-team-a-juiceshop:3000", juiceShopURL("", "team-a"))
+}
+
+func TestJuiceShopURLUsesTheConfiguredServiceTemplate(t *testing.T) {
+	previous := juiceShopServiceTemplate
+	defer func() { juiceShopServiceTemplate = previous }()
+
+	var err error
+	juiceShopServiceTemplate, err = parseJuiceShopServiceTemplate("{{ .Team }}-shop.{{ .Namespace }}:8080")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://team-a-shop.event-1:8080", juiceShopURL("event-1", "team-a"))
+
+	juiceShopServiceTemplate, err = parseJuiceShopServiceTemplate("{{ .Team }}-shop")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://team-a-shop:3000", juiceShopURL("event-1", "team-a"), "Should use the configured port if the template doesn't contain one")
+
+	_, err = parseJuiceShopServiceTemplate("{{ .Tenant }}-shop")
+	assert.Error(t, err)
+	_, err = parseJuiceShopServiceTemplate("{{ .Team ")
+	assert.Error(t, err)
+}
+
+func TestWatchesAllNamespacesIfNamespaceIsSetToEmptyValue(t *testing.T) {
+	previous, wasSet := os.LookupEnv("NAMESPACE")
+	defer func() {
+		if wasSet {
+			os.Setenv("NAMESPACE", previous)
+		} else {
+			os.Unsetenv("NAMESPACE")
+		}
+	}()
+
+	os.Unsetenv("NAMESPACE")
+	assert.Equal(t, "multi-juicer", defaultWatchedNamespace("multi-juicer"))
+	os.Setenv("NAMESPACE", "")
+	assert.Equal(t, metav1.NamespaceAll, defaultWatchedNamespace("multi-juicer"))
+}
+
+func TestQueuesJobsForJuiceShopsReportedByTheInformer(t *testing.T) {
+	ready := createJuiceShopDeployment("team-a", "", "0")
+	ready.Status.ReadyReplicas = 1
+	starting := createJuiceShopDeployment("team-b", "", "0")
+	clientset := fake.NewSimpleClientset(ready, starting)
+	queue := NewProgressUpdateQueue(100, time.Minute)
+	defer queue.ShutDown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go createProgressUpdateJobs(ctx, queue, startJuiceShopCache(ctx, clientset), time.Hour, NewHeartbeats(time.Minute), NewTeamTracker(time.Hour), func() {})
+
+	nextJob := func() ProgressUpdateJobs {
+		jobs := make(chan ProgressUpdateJobs, 1)
+		go queue.Process(func(job ProgressUpdateJobs) error {
+			jobs <- job
+			return nil
+		})
+		select {
+		case job := <-jobs:
+			return job
+		case <-time.After(5 * time.Second):
+			t.Fatal("No job was queued")
+			return ProgressUpdateJobs{}
+		}
+	}
+	assert.Equal(t, "team-a", nextJob().Teamname)
+
+	// the update of the starting JuiceShop to ready gets picked up without waiting for the next resync
+	starting.Status.ReadyReplicas = 1
+	_, err := clientset.AppsV1().Deployments("default").Update(ctx, starting, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "team-b", nextJob().Teamname)
+}
+
+func TestQueuesJobsOfNewAndRestartedJuiceShopsAsUrgent(t *testing.T) {
+	queue := NewProgressUpdateQueue(100, time.Minute)
+	defer queue.ShutDown()
+	restarting := createJuiceShopDeployment("restarted", "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg", "10")
+	restarted := restarting.DeepCopy()
+	restarted.Status.ReadyReplicas = 1
+	running := restarted.DeepCopy()
+	running.Labels["team"] = "running"
+	registered := createJuiceShopDeployment("registered", "", "0")
+	registered.Status.ReadyReplicas = 1
+
+	queueProgressUpdateJob(queue, restarting, restarted)
+	queueProgressUpdateJob(queue, running, running)
+	queueProgressUpdateJob(queue, nil, registered)
+
+	urgent := map[string]bool{}
+	for _, team := range queue.Checkpoint() {
+		urgent[team.Team] = team.Urgent
+	}
+	assert.Equal(t, map[string]bool{"restarted": true, "running": false, "registered": true}, urgent)
+}
+
+func TestStopsDiscoveryAndDrainsQueuedJobsOnShutdown(t *testing.T) {
+	fakeJuiceShops(t, "")
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	queue := NewProgressUpdateQueue(100, time.Minute)
+	heartbeats := NewHeartbeats(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	discoveryStopped := make(chan struct{})
+	go func() {
+		createProgressUpdateJobs(ctx, queue, startJuiceShopCache(ctx, clientset), time.Hour, heartbeats, NewTeamTracker(time.Hour), func() {})
+		close(discoveryStopped)
+	}()
+	assert.Eventually(t, func() bool { return queue.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-discoveryStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Discovery didn't stop after the context was cancelled")
+	}
+
+	queue.ShutDown()
+	workOnProgressUpdates(queue, clientset, NewScoreboardCache(0), heartbeats, "worker-0")
+	assert.Equal(t, 0, queue.queue.Len())
+}
+
+func TestCacheContinueCodeFailsIfTheCachedContinueCodeChangedSinceTheJobWasCreated(t *testing.T) {
+	lastContinueCode, _ := EncodeContinueCode([]int{1})
+	cachedByOtherReplica, _ := EncodeContinueCode([]int{1, 2, 3})
+	continueCode, _ := EncodeContinueCode([]int{1, 2})
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", cachedByOtherReplica, "3"))
+
+	err := cacheContinueCode(clientset, "default", "foobar", lastContinueCode, continueCode, SolveTimes{}, nil)
+
+	assert.ErrorIs(t, err, errProgressChanged)
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, cachedByOtherReplica, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+}
+
+// useFakeJuiceShops sends the requests to the JuiceShops to a fleet of fake JuiceShops
+func useFakeJuiceShops(t *testing.T) *fakejuiceshop.Fleet {
+	fleet := fakejuiceshop.NewFleet()
+	previous := juiceShopClient
+	juiceShopClient = fleet.Client()
+	t.Cleanup(func() { juiceShopClient = previous })
+	return fleet
+}
+
+func TestRestoresTheCachedProgressOfJuiceShopsWhichLostIt(t *testing.T) {
+	fleet := useFakeJuiceShops(t)
+	fleet.Team("foobar").Solve(1, 2, 3)
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateCache, state)
+
+	fleet.Team("foobar").Reset()
+	cached, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	state, err = runProgressUpdateJob(progressUpdateJobForDeployment(*cached), clientset, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, ApplyCode, state)
+	assert.Equal(t, []int{1, 2, 3}, fleet.Team("foobar").Solved())
+}
+
+func TestRetriesRestoresWhichDidntTakeEffect(t *testing.T) {
+	fleet := useFakeJuiceShops(t)
+	fleet.Team("foobar").IgnoreApplies()
+	deployment := createJuiceShopDeployment("foobar", "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg", "10")
+	deployment.Status.ReadyReplicas = 1
+
+	_, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), fake.NewSimpleClientset(deployment), NewScoreboardCache(0))
+	assert.Equal(t, errRestoreNotVerified, err)
+	assert.Contains(t, fleet.Team("foobar").Requests(), fakejuiceshop.RouteApplyContinueCode)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+var (
+	instancesWatchedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "progress_watchdog_instances_watched",
+		Help: "Number of JuiceShop instances watched by the watchdog",
+	})
+	continueCodeFetchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_continue_code_fetch_failures_total",
+		Help: "Number of failed requests for the current ContinueCode of a JuiceShop",
+	})
+	restoresApplied = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_restores_applied_total",
+		Help: "Number of cached ContinueCodes applied to JuiceShops to restore lost progress",
+	})
+	progressRecoveries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_progress_recoveries_total",
+		Help: "Number of recreated JuiceShops whose progress was recovered from outside of the progress store, e.g. from a backup",
+	})
+	restoreVerificationFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_restore_verification_failures_total",
+		Help: "Number of applied ContinueCodes which didn't restore all cached solves of the JuiceShop",
+	})
+	patchErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_patch_errors_total",
+		Help: "Number of failed patches of the cached progress into the JuiceShop deployments",
+	})
+	progressConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_progress_conflicts_total",
+		Help: "Number of progress saves skipped because the cached progress was changed concurrently, e.g. by another watchdog replica",
+	})
+	duplicateJobsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_duplicate_jobs_dropped_total",
+		Help: "Number of progress update jobs not queued because the team already had a pending job",
+	})
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "progress_watchdog_queue_depth",
+		Help: "Number of teams waiting in the queue for a progress update",
+	})
+	queueWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "progress_watchdog_queue_wait_seconds",
+		Help:    "Time the progress update jobs waited in the queue until a worker picked them up",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+	queueBlockedSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_queue_blocked_seconds_total",
+		Help: "Time the discovery and the solve webhooks were blocked queueing jobs because the queue was full",
+	})
+	solveWebhooksReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "progress_watchdog_solve_webhooks_received_total",
+		Help: "Number of solve webhooks of JuiceShops which queued an immediate progress update",
+	})
+	teamChallengesSolved = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "progress_watchdog_team_challenges_solved",
+		Help: "Number of challenges solved by the team, as cached on its JuiceShop deployment",
+	}, []string{"team"})
+	challengeFirstBlood = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "progress_watchdog_challenge_first_blood_timestamp_seconds",
+		Help: "Time the challenge was solved first, labeled with the team which solved it",
+	}, []string{"challenge", "team"})
+)
+
+// progressMetrics are the metrics about the tracked progress of the teams
+var progressMetrics = []prometheus.Collector{instancesWatchedGauge, continueCodeFetchFailures, restoresApplied, progressRecoveries, restoreVerificationFailures, patchErrors, progressConflicts, duplicateJobsDropped, queueDepth, queueWaitSeconds, queueBlockedSeconds, solveWebhooksReceived, teamChallengesSolved, challengeFirstBlood}
+
+// updateInstanceMetrics updates the metrics about the watched JuiceShops, teams whose JuiceShop got deleted are removed.
+// The first bloods are updated as well, as they are only tracked when the teams are listed.
+func updateInstanceMetrics(juiceShops []appsv1.Deployment) {
+	instancesWatchedGauge.Set(float64(len(juiceShops)))
+	teamChallengesSolved.Reset()
+	teams := []TeamProgress{}
+	for _, instance := range juiceShops {
+		teams = append(teams, teamProgressFromDeployment(instance))
+		challengesSolved, err := strconv.Atoi(instance.Annotations[annotation("challengesSolved")])
+		if err != nil {
+			challengesSolved = 0
+		}
+		teamChallengesSolved.WithLabelValues(instance.Labels["team"]).Set(float64(challengesSolved))
+	}
+	firstBloods.Observe(teams)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestInstanceMetricsOnlyContainWatchedTeams(t *testing.T) {
+	updateInstanceMetrics([]appsv1.Deployment{
+		*createJuiceShopDeployment("team-a", "", "3"),
+		*createJuiceShopDeployment("team-b", "", "invalid"),
+	})
+	assert.Equal(t, float64(2), testutil.ToFloat64(instancesWatchedGauge))
+	assert.Equal(t, float64(3), testutil.ToFloat64(teamChallengesSolved.WithLabelValues("team-a")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(teamChallengesSolved.WithLabelValues("team-b")))
+
+	updateInstanceMetrics([]appsv1.Deployment{*createJuiceShopDeployment("team-a", "", "4")})
+	assert.Equal(t, float64(1), testutil.ToFloat64(instancesWatchedGauge))
+	assert.Equal(t, 1, testutil.CollectAndCount(teamChallengesSolved))
+}
+
+func TestCountsFailedContinueCodeFetches(t *testing.T) {
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	before := testutil.ToFloat64(continueCodeFetchFailures)
+	_, err := getCurrentContinueCode(context.Background(), "default", "foobar")
+	assert.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(continueCodeFetchFailures))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// annotationPrefix is the prefix of the annotations of the JuiceShop deployments, configured via `ANNOTATION_PREFIX`
+var annotationPrefix = "multi-juicer.iteratec.dev/"
+
+// juiceShopSelector is the label selector of the JuiceShop deployments, services and pods, configured via `JUICE_SHOP_SELECTOR`
+var juiceShopSelector = "app=juice-shop"
+
+// annotation returns the key of the annotation with the name, e.g. `multi-juicer.iteratec.dev/continueCode` for `continueCode`
+func annotation(name string) string {
+	return annotationPrefix + name
+}
+
+// teamSelector returns the label selector of the JuiceShop of the team
+func teamSelector(teamname string) string {
+	return fmt.Sprintf("%s,team=%s", juiceShopSelector, teamname)
+}
+
+// isJuiceShop checks if the labels match the juiceShopSelector
+func isJuiceShop(objectLabels map[string]string) bool {
+	selector, err := labels.Parse(juiceShopSelector)
+	return err == nil && selector.Matches(labels.Set(objectLabels))
+}
+
+// configureNaming sets the annotation prefix and label selector, so that multiple installations or forks of MultiJuicer in one cluster don't clash.
+// The prefix is used with a trailing slash, e.g. `ctf.example.com` results in annotations like `ctf.example.com/continueCode`.
+func configureNaming(prefix, selector string) error {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" || strings.Contains(prefix, "/") {
+		return fmt.Errorf("Invalid annotation prefix '%s'", prefix)
+	}
+	if _, err := labels.Parse(selector); err != nil || selector == "" {
+		return fmt.Errorf("Invalid label selector '%s': %v", selector, err)
+	}
+	annotationPrefix = prefix + "/"
+	juiceShopSelector = selector
+	return nil
+}
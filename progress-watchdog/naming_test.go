@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func useNaming(t *testing.T, prefix, selector string) {
+	previousPrefix, previousSelector := annotationPrefix, juiceShopSelector
+	t.Cleanup(func() {
+		annotationPrefix, juiceShopSelector = previousPrefix, previousSelector
+	})
+	assert.NoError(t, configureNaming(prefix, selector))
+}
+
+func TestConfigureNamingRejectsInvalidPrefixesAndSelectors(t *testing.T) {
+	previousPrefix, previousSelector := annotationPrefix, juiceShopSelector
+	defer func() { annotationPrefix, juiceShopSelector = previousPrefix, previousSelector }()
+
+	assert.Error(t, configureNaming("", "app=juice-shop"))
+	assert.Error(t, configureNaming("ctf.example.com/juice", "app=juice-shop"))
+	assert.Error(t, configureNaming("ctf.example.com", ""))
+	assert.Error(t, configureNaming("ctf.example.com", "app in ("))
+	assert.Equal(t, previousPrefix, annotationPrefix)
+	assert.Equal(t, previousSelector, juiceShopSelector)
+}
+
+func TestReadsAndWritesProgressWithTheConfiguredAnnotationPrefix(t *testing.T) {
+	useNaming(t, "ctf.example.com", "app=ctf-juice-shop")
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "t-foobar-juiceshop",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "ctf-juice-shop", "team": "foobar"},
+			Annotations: map[string]string{"ctf.example.com/continueCode": "", "ctf.example.com/challengesSolved": "0"},
+		},
+	}
+	clientset := fake.NewSimpleClientset(deployment)
+	continueCode, _ := EncodeContinueCode([]int{1, 2})
+
+	assert.NoError(t, cacheContinueCode(clientset, "default", "foobar", "", continueCode, SolveTimes{}, nil))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, continueCode, updated.Annotations["ctf.example.com/continueCode"])
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/continueCode")
+	assert.Equal(t, 2, teamProgressFromDeployment(*updated).ChallengesSolved)
+	assert.True(t, isJuiceShop(updated.Labels))
+	assert.False(t, isJuiceShop(map[string]string{"app": "juice-shop", "team": "foobar"}))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// NotificationTemplates render the messages sent with the hook events, so that organizers can brand and localize them without code changes.
+// The templates are Go templates (https://golang.org/pkg/text/template/) with access to the fields of the HookEvent,
+// e.g. `{{ .Team }} solved challenge {{ .Challenge }} at {{ .Time.Format "15:04" }}, {{ .ChallengesSolved }} solved so far`.
+type NotificationTemplates struct {
+	templates map[HookEventType]*template.Template
+	// location the times of the events are rendered in
+	location *time.Location
+}
+
+// parseNotificationTemplates parses the templates configured as json object mapping the event types to their template.
+// Event types without configured template use the `notification.<type>` message of the default messages. Times are rendered in the location.
+func parseNotificationTemplates(value string, defaults Messages, location *time.Location) (*NotificationTemplates, error) {
+	configured := map[HookEventType]string{}
+	for _, eventType := range hookEventTypes {
+		if text, ok := defaults["notification."+string(eventType)]; ok {
+			configured[eventType] = text
+		}
+	}
+	if value != "" {
+		overrides := map[HookEventType]string{}
+		if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+			return nil, fmt.Errorf("Failed to decode notification templates: %v", err)
+		}
+		for eventType, text := range overrides {
+			configured[eventType] = text
+		}
+	}
+
+	templates := &NotificationTemplates{templates: map[HookEventType]*template.Template{}, location: location}
+	for eventType, text := range configured {
+		if !isHookEvent(eventType) {
+			return nil, fmt.Errorf("Notification template for unknown event '%s'", eventType)
+		}
+		parsed, err := template.New(string(eventType)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid notification template for event '%s': %v", eventType, err)
+		}
+		templates.templates[eventType] = parsed
+	}
+	return templates, nil
+}
+
+// Render renders the message of the event, returns an empty message if there is no template for its type
+func (t *NotificationTemplates) Render(event HookEvent) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	parsed, ok := t.templates[event.Type]
+	if !ok {
+		return "", nil
+	}
+	if t.location != nil {
+		event.Time = event.Time.In(t.location)
+	}
+	var message bytes.Buffer
+	if err := parsed.Execute(&message, event); err != nil {
+		return "", fmt.Errorf("Failed to render notification template for event '%s': %v", event.Type, err)
+	}
+	return message.String(), nil
+}
+
+func isHookEvent(eventType HookEventType) bool {
+	for _, known := range hookEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationTemplatesRenderEvents(t *testing.T) {
+	templates, err := parseNotificationTemplates(`{
+		"solve": "🎉 {{ .Team }} hat Challenge {{ .Challenge }} um {{ .Time.Format \"15:04\" }} gelöst ({{ .ChallengesSolved }} insgesamt)",
+		"teamCreated": "Willkommen {{ .Team }}!"
+	}`, nil, time.UTC)
+	assert.Nil(t, err)
+
+	message, err := templates.Render(HookEvent{Type: HookEventSolve, Team: "foobar", Challenge: 7, ChallengesSolved: 3, Time: time.Date(2021, 4, 10, 10, 30, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+	assert.Equal(t, "🎉 foobar hat Challenge 7 um 10:30 gelöst (3 insgesamt)", message)
+
+	message, err = templates.Render(HookEvent{Type: HookEventInstanceDeleted, Team: "foobar"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", message)
+
+	var noTemplates *NotificationTemplates
+	message, err = noTemplates.Render(HookEvent{Type: HookEventSolve, Team: "foobar"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", message)
+}
+
+func TestParseNotificationTemplatesValidatesTemplates(t *testing.T) {
+	_, err := parseNotificationTemplates(`{"teamArchived": "{{ .Team }}"}`, nil, time.UTC)
+	assert.EqualError(t, err, "Notification template for unknown event 'teamArchived'")
+	_, err = parseNotificationTemplates(`{"solve": "{{ .Team "}`, nil, time.UTC)
+	assert.Error(t, err)
+	_, err = parseNotificationTemplates(`not json`, nil, time.UTC)
+	assert.Error(t, err)
+
+	templates, err := parseNotificationTemplates(`{"solve": "{{ .Unknown }}"}`, nil, time.UTC)
+	assert.Nil(t, err)
+	_, err = templates.Render(HookEvent{Type: HookEventSolve})
+	assert.Error(t, err)
+}
+
+func TestHooksSendRenderedMessages(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		received <- payload
+	}))
+	defer server.Close()
+
+	templates, err := parseNotificationTemplates(`{"teamCreated": "Welcome {{ .Team }}!"}`, nil, time.UTC)
+	assert.Nil(t, err)
+	dispatcher := NewHooks(templates, &WebhookHook{URL: server.URL, Client: http.DefaultClient})
+	dispatcher.handle(HookEvent{Type: HookEventTeamCreated, Team: "foobar"})
+
+	payload := <-received
+	assert.Equal(t, "Welcome foobar!", payload["message"])
+	assert.Equal(t, "Welcome foobar!", payload["text"])
+	assert.Equal(t, "Welcome foobar!", payload["content"])
+	assert.Equal(t, "foobar", payload["team"])
+}
+
+func TestNotificationTemplatesDefaultToCatalogMessages(t *testing.T) {
+	templates, err := parseNotificationTemplates(`{"teamCreated": "Welcome {{ .Team }}!"}`, catalog.Messages("de"), time.UTC)
+	assert.Nil(t, err)
+
+	message, err := templates.Render(HookEvent{Type: HookEventTeamIdle, Team: "foobar"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Team foobar ist inaktiv", message)
+
+	message, err = templates.Render(HookEvent{Type: HookEventTeamCreated, Team: "foobar"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Welcome foobar!", message)
+}
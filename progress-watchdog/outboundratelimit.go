@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+var outboundRateLimitWaitSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "progress_watchdog_outbound_rate_limit_wait_seconds_total",
+	Help: "Time outgoing requests to the JuiceShops and the kubernetes api waited for the outbound rate limit",
+})
+
+// outboundRateLimiter limits the requests of the watchdog to the JuiceShops and the write requests to the kubernetes api,
+// unlimited unless configured via the `OUTBOUND_RATE_LIMIT*` env vars
+var outboundRateLimiter = NewOutboundRateLimiter(0, 0, 0, 0)
+
+// OutboundRateLimiter limits outgoing requests with a global token bucket shared by all hosts and a token bucket per host,
+// so that hundreds of JuiceShops can't make the watchdog flood the cluster network or the kubernetes api. Requests wait for both buckets.
+type OutboundRateLimiter struct {
+	mutex        sync.Mutex
+	global       *rate.Limiter
+	perHostLimit rate.Limit
+	perHostBurst int
+	hosts        map[string]*clientLimiter
+	lastCleanup  time.Time
+	idleTimeout  time.Duration
+	now          func() time.Time
+}
+
+// rateLimit converts a limit in requests per second into a rate.Limit, with 0 meaning unlimited
+func rateLimit(requestsPerSecond float64) rate.Limit {
+	if requestsPerSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(requestsPerSecond)
+}
+
+// NewOutboundRateLimiter creates a limiter allowing requestsPerSecond requests in total and perHostRequestsPerSecond requests to every host,
+// with bursts of up to burst and perHostBurst requests. Limits of 0 don't limit the requests.
+func NewOutboundRateLimiter(requestsPerSecond float64, burst int, perHostRequestsPerSecond float64, perHostBurst int) *OutboundRateLimiter {
+	limiter := &OutboundRateLimiter{
+		hosts:       map[string]*clientLimiter{},
+		idleTimeout: clientLimiterIdleTimeout,
+		now:         time.Now,
+	}
+	limiter.Configure(requestsPerSecond, burst, perHostRequestsPerSecond, perHostBurst)
+	return limiter
+}
+
+// Configure changes the limits, clients which already wrapped their transport use the new limits with their next request
+func (l *OutboundRateLimiter) Configure(requestsPerSecond float64, burst int, perHostRequestsPerSecond float64, perHostBurst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.global = rate.NewLimiter(rateLimit(requestsPerSecond), burst)
+	l.perHostLimit = rateLimit(perHostRequestsPerSecond)
+	l.perHostBurst = perHostBurst
+	l.hosts = map[string]*clientLimiter{}
+}
+
+// limiters returns the global token bucket and the one of the host, dropping the buckets of hosts which didn't receive requests
+// for a while, e.g. deleted JuiceShops
+func (l *OutboundRateLimiter) limiters(host string) (*rate.Limiter, *rate.Limiter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastCleanup) > l.idleTimeout {
+		for key, client := range l.hosts {
+			if now.Sub(client.lastSeen) > l.idleTimeout {
+				delete(l.hosts, key)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	limiter, ok := l.hosts[host]
+	if !ok {
+		limiter = &clientLimiter{limiter: rate.NewLimiter(l.perHostLimit, l.perHostBurst)}
+		l.hosts[host] = limiter
+	}
+	limiter.lastSeen = now
+	return l.global, limiter.limiter
+}
+
+// Wait blocks until a request to the host is allowed by the global and the per host limit, or the context is done
+func (l *OutboundRateLimiter) Wait(ctx context.Context, host string) error {
+	start := time.Now()
+	global, perHost := l.limiters(host)
+	if err := global.Wait(ctx); err != nil {
+		return fmt.Errorf("Outbound rate limit exceeded: %v", err)
+	}
+	if err := perHost.Wait(ctx); err != nil {
+		return fmt.Errorf("Outbound rate limit of host '%s' exceeded: %v", host, err)
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		outboundRateLimitWaitSeconds.Add(waited.Seconds())
+	}
+	return nil
+}
+
+// Wrap wraps the transport of a client, limiting the requests for which the filter returns true or all requests if the filter is nil
+func (l *OutboundRateLimiter) Wrap(next http.RoundTripper, filter func(req *http.Request) bool) http.RoundTripper {
+	return &RateLimitingRoundTripper{Next: next, Limiter: l, Filter: filter}
+}
+
+// RateLimitingRoundTripper waits for its OutboundRateLimiter before sending requests
+type RateLimitingRoundTripper struct {
+	Next    http.RoundTripper
+	Limiter *OutboundRateLimiter
+	Filter  func(req *http.Request) bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RateLimitingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Filter == nil || t.Filter(req) {
+		if err := t.Limiter.Wait(req.Context(), req.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	return t.Next.RoundTrip(req)
+}
+
+// isWriteRequest reports requests changing objects in the kubernetes api, e.g. the patches of the progress.
+// Reads are left to the rate limit of client-go, watches would block a token for their whole lifetime.
+func isWriteRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// configureOutboundRateLimit configures the outboundRateLimiter via `OUTBOUND_RATE_LIMIT` and `OUTBOUND_RATE_LIMIT_PER_HOST`
+// in requests per second, with bursts configured via `OUTBOUND_RATE_LIMIT_BURST` and `OUTBOUND_RATE_LIMIT_PER_HOST_BURST`
+func configureOutboundRateLimit() error {
+	requestsPerSecond, burst, err := parseOutboundRateLimit("OUTBOUND_RATE_LIMIT")
+	if err != nil {
+		return err
+	}
+	perHostRequestsPerSecond, perHostBurst, err := parseOutboundRateLimit("OUTBOUND_RATE_LIMIT_PER_HOST")
+	if err != nil {
+		return err
+	}
+	outboundRateLimiter.Configure(requestsPerSecond, burst, perHostRequestsPerSecond, perHostBurst)
+	if requestsPerSecond > 0 || perHostRequestsPerSecond > 0 {
+		log.Infof("Limiting outgoing requests to %g per second in total and %g per second per host (0 is unlimited)", requestsPerSecond, perHostRequestsPerSecond)
+	}
+	return nil
+}
+
+// parseOutboundRateLimit parses the limit and the burst of the env var, the burst defaults to the requests of one second
+func parseOutboundRateLimit(name string) (float64, int, error) {
+	requestsPerSecond, err := strconv.ParseFloat(getEnv(name, "0"), 64)
+	if err != nil || requestsPerSecond < 0 {
+		return 0, 0, fmt.Errorf("Invalid %s: %s", name, getEnv(name, "0"))
+	}
+	burst, err := strconv.Atoi(getEnv(name+"_BURST", strconv.Itoa(int(math.Max(1, math.Ceil(requestsPerSecond))))))
+	if err != nil || burst < 1 {
+		return 0, 0, fmt.Errorf("Invalid %s_BURST: %s", name, getEnv(name+"_BURST", ""))
+	}
+	return requestsPerSecond, burst, nil
+}
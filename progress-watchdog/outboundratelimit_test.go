@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundRateLimiterLimitsEveryHostOnItsOwn(t *testing.T) {
+	limiter := NewOutboundRateLimiter(0, 0, 1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, limiter.Wait(ctx, "t-foo-juiceshop:3000"))
+	assert.NoError(t, limiter.Wait(ctx, "t-bar-juiceshop:3000"))
+	assert.Error(t, limiter.Wait(ctx, "t-foo-juiceshop:3000"))
+}
+
+func TestOutboundRateLimiterSharesTheGlobalLimitBetweenHosts(t *testing.T) {
+	limiter := NewOutboundRateLimiter(1, 1, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, limiter.Wait(ctx, "t-foo-juiceshop:3000"))
+	assert.Error(t, limiter.Wait(ctx, "t-bar-juiceshop:3000"))
+}
+
+func TestOutboundRateLimiterDoesntLimitWithoutLimits(t *testing.T) {
+	limiter := NewOutboundRateLimiter(0, 0, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, limiter.Wait(ctx, "t-foo-juiceshop:3000"))
+	}
+}
+
+func TestRateLimitingRoundTripperOnlyLimitsFilteredRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	client := &http.Client{Transport: NewOutboundRateLimiter(0, 0, 1, 1).Wrap(http.DefaultTransport, isWriteRequest)}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		res, err := client.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, server.URL, nil)
+	res, err := client.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPatch, server.URL, nil)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+}
+
+func TestParsesOutboundRateLimitFromEnv(t *testing.T) {
+	os.Setenv("OUTBOUND_RATE_LIMIT_PER_HOST", "2.5")
+	defer os.Unsetenv("OUTBOUND_RATE_LIMIT_PER_HOST")
+
+	requestsPerSecond, burst, err := parseOutboundRateLimit("OUTBOUND_RATE_LIMIT_PER_HOST")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, requestsPerSecond)
+	assert.Equal(t, 3, burst)
+
+	os.Setenv("OUTBOUND_RATE_LIMIT_PER_HOST_BURST", "0")
+	defer os.Unsetenv("OUTBOUND_RATE_LIMIT_PER_HOST_BURST")
+	_, _, err = parseOutboundRateLimit("OUTBOUND_RATE_LIMIT_PER_HOST")
+	assert.EqualError(t, err, "Invalid OUTBOUND_RATE_LIMIT_PER_HOST_BURST: 0")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// patchesPerSecond limits the progress patches sent to the kubernetes api while it isn't throttling the watchdog
+	patchesPerSecond = 20
+	patchBurst       = 20
+	// maxSlowdown caps how far the watchdog slows down its discovery and patches on throttling
+	maxSlowdown = 16
+	// slowdownRecovery is the time without throttling after which the slowdown gets halved again
+	slowdownRecovery = 30 * time.Second
+)
+
+var (
+	slowdownDesc = prometheus.NewDesc(
+		"progress_watchdog_api_slowdown_factor",
+		"Factor by which the discovery interval is extended and the patch rate is reduced because the kubernetes api throttled the watchdog",
+		nil, nil,
+	)
+	throttledRequestsDesc = prometheus.NewDesc(
+		"progress_watchdog_api_throttled_requests_total",
+		"Number of requests to the kubernetes api which were rejected with 429 Too Many Requests",
+		nil, nil,
+	)
+)
+
+// kubernetesPacer paces the requests of the watchdog to the kubernetes api of all clientsets created by createClientset
+var kubernetesPacer = NewAPIPacer()
+
+// APIPacer slows down the discovery and patches of the watchdog while the kubernetes api is throttling it, e.g. by its API Priority and Fairness
+// rules, instead of retrying at the same rate and making the congestion worse. Every throttled request doubles the slowdown up to maxSlowdown,
+// every slowdownRecovery without throttling halves it again.
+type APIPacer struct {
+	now          func() time.Time
+	patchLimiter *rate.Limiter
+
+	mutex             sync.Mutex
+	slowdown          float64
+	lastAdjustment    time.Time
+	throttledRequests int
+}
+
+// NewAPIPacer creates an APIPacer which isn't slowed down yet
+func NewAPIPacer() *APIPacer {
+	return &APIPacer{
+		now:          time.Now,
+		patchLimiter: rate.NewLimiter(rate.Limit(patchesPerSecond), patchBurst),
+		slowdown:     1,
+	}
+}
+
+// Throttled records a throttled request and doubles the slowdown
+func (p *APIPacer) Throttled() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.recover()
+	p.throttledRequests++
+	if p.slowdown < maxSlowdown {
+		p.slowdown *= 2
+		if p.slowdown > maxSlowdown {
+			p.slowdown = maxSlowdown
+		}
+		log.Warningf("Kubernetes api is throttling the watchdog, slowing down discovery and patches by factor %.0f", p.slowdown)
+	}
+	p.lastAdjustment = p.now()
+	p.patchLimiter.SetLimit(rate.Limit(patchesPerSecond / p.slowdown))
+}
+
+// recover halves the slowdown for every slowdownRecovery passed without throttling, requires the mutex to be held
+func (p *APIPacer) recover() {
+	if p.slowdown <= 1 {
+		return
+	}
+	now := p.now()
+	for p.slowdown > 1 && now.Sub(p.lastAdjustment) >= slowdownRecovery {
+		p.slowdown /= 2
+		p.lastAdjustment = p.lastAdjustment.Add(slowdownRecovery)
+	}
+	if p.slowdown < 1 {
+		p.slowdown = 1
+	}
+	if p.slowdown == 1 {
+		log.Info("Kubernetes api stopped throttling the watchdog, discovery and patches are back at full speed")
+	}
+	p.patchLimiter.SetLimit(rate.Limit(patchesPerSecond / p.slowdown))
+}
+
+// Slowdown returns the current slowdown factor, 1 if the watchdog isn't slowed down
+func (p *APIPacer) Slowdown() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.recover()
+	return p.slowdown
+}
+
+// DiscoveryInterval extends the interval between two discoveries of the teams by the current slowdown
+func (p *APIPacer) DiscoveryInterval(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * p.Slowdown())
+}
+
+// WaitForPatch blocks until the next patch may be sent with the current patch rate
+func (p *APIPacer) WaitForPatch(ctx context.Context) error {
+	// updates the patch rate if the slowdown recovered in the meantime
+	p.Slowdown()
+	return p.patchLimiter.Wait(ctx)
+}
+
+// Wrap wraps the transport of a kubernetes client, recording responses with 429 Too Many Requests as throttled
+func (p *APIPacer) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &PacingRoundTripper{Next: next, Pacer: p}
+}
+
+// PacingRoundTripper reports throttled kubernetes api requests to its APIPacer
+type PacingRoundTripper struct {
+	Next  http.RoundTripper
+	Pacer *APIPacer
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *PacingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Next.RoundTrip(req)
+	if err == nil && res.StatusCode == http.StatusTooManyRequests {
+		log.Debugf("Kubernetes api throttled %s %s (retry after '%s')", req.Method, req.URL.Path, res.Header.Get("Retry-After"))
+		t.Pacer.Throttled()
+	}
+	return res, err
+}
+
+// Describe implements prometheus.Collector
+func (p *APIPacer) Describe(ch chan<- *prometheus.Desc) {
+	ch <- slowdownDesc
+	ch <- throttledRequestsDesc
+}
+
+// Collect implements prometheus.Collector
+func (p *APIPacer) Collect(ch chan<- prometheus.Metric) {
+	slowdown := p.Slowdown()
+	p.mutex.Lock()
+	throttledRequests := p.throttledRequests
+	p.mutex.Unlock()
+	ch <- prometheus.MustNewConstMetric(slowdownDesc, prometheus.GaugeValue, slowdown)
+	ch <- prometheus.MustNewConstMetric(throttledRequestsDesc, prometheus.CounterValue, float64(throttledRequests))
+}
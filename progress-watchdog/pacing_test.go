@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIPacerSlowsDownOnThrottlingAndRecovers(t *testing.T) {
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	pacer := NewAPIPacer()
+	pacer.now = func() time.Time { return now }
+
+	assert.Equal(t, 5*time.Second, pacer.DiscoveryInterval(5*time.Second))
+
+	pacer.Throttled()
+	pacer.Throttled()
+	assert.Equal(t, 4.0, pacer.Slowdown())
+	assert.Equal(t, 20*time.Second, pacer.DiscoveryInterval(5*time.Second))
+	assert.Equal(t, 5.0, float64(pacer.patchLimiter.Limit()))
+
+	for i := 0; i < 10; i++ {
+		pacer.Throttled()
+	}
+	assert.Equal(t, float64(maxSlowdown), pacer.Slowdown())
+
+	now = now.Add(slowdownRecovery)
+	assert.Equal(t, 8.0, pacer.Slowdown())
+	now = now.Add(3 * slowdownRecovery)
+	assert.Equal(t, 1.0, pacer.Slowdown())
+	assert.Equal(t, float64(patchesPerSecond), float64(pacer.patchLimiter.Limit()))
+}
+
+func TestPacingRoundTripperDetectsThrottledRequests(t *testing.T) {
+	throttle := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if throttle {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	pacer := NewAPIPacer()
+	client := &http.Client{Transport: pacer.Wrap(http.DefaultTransport)}
+
+	res, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, 2.0, pacer.Slowdown())
+	assert.Equal(t, 1, pacer.throttledRequests)
+
+	throttle = false
+	res, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, 1, pacer.throttledRequests)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PostgresQuerier runs queries with text parameters ($1, $2, ...), returning the rows of the result as text. NULL values are returned as empty strings.
+type PostgresQuerier interface {
+	Query(ctx context.Context, query string, args ...string) ([][]string, error)
+}
+
+// PostgresDatabase is a PostgresQuerier which can run transactions
+type PostgresDatabase interface {
+	PostgresQuerier
+	// Transaction runs the function in a transaction, which is committed if it succeeds and rolled back otherwise
+	Transaction(ctx context.Context, run func(tx PostgresQuerier) error) error
+}
+
+// PostgresClient runs the queries of the PostgresProgressStore on a pool of connections of database/sql, using the lib/pq driver.
+// The timeout limits every query, unless the context has an earlier deadline.
+type PostgresClient struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// postgresMaxIdleConnections is the number of connections kept open for later queries
+const postgresMaxIdleConnections = 10
+
+// postgresSSLModes are the values of `POSTGRES_SSLMODE` supported by lib/pq
+var postgresSSLModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+// NewPostgresClient creates a client of the database of the data source, a `postgres://` URL or `key=value` connection string of lib/pq.
+// Connections are opened on the first query.
+func NewPostgresClient(dataSource string, timeout time.Duration) (*PostgresClient, error) {
+	db, err := sql.Open("postgres", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxIdleConns(postgresMaxIdleConnections)
+	return &PostgresClient{db: db, timeout: timeout}, nil
+}
+
+// postgresDataSource returns the URL of the database, encrypted according to the sslmode
+func postgresDataSource(address, user, password, database, sslMode string, timeout time.Duration) string {
+	query := url.Values{}
+	query.Set("sslmode", sslMode)
+	// lib/pq only supports whole seconds
+	query.Set("connect_timeout", strconv.Itoa(int(math.Ceil(timeout.Seconds()))))
+	dataSource := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, password),
+		Host:     address,
+		Path:     "/" + database,
+		RawQuery: query.Encode(),
+	}
+	return dataSource.String()
+}
+
+// newPostgresClientFromEnv creates the client configured via `POSTGRES_ADDRESS`, `POSTGRES_USER`, `POSTGRES_PASSWORD`, `POSTGRES_DATABASE`, `POSTGRES_SSLMODE` and `POSTGRES_TIMEOUT`
+func newPostgresClientFromEnv() *PostgresClient {
+	timeout, err := time.ParseDuration(getEnv("POSTGRES_TIMEOUT", "5s"))
+	if err != nil || timeout <= 0 {
+		panic(fmt.Sprintf("Invalid POSTGRES_TIMEOUT: %s", getEnv("POSTGRES_TIMEOUT", "5s")))
+	}
+	sslMode := getEnv("POSTGRES_SSLMODE", "disable")
+	if !containsString(postgresSSLModes, sslMode) {
+		panic(fmt.Sprintf("Invalid POSTGRES_SSLMODE: %s", sslMode))
+	}
+	client, err := NewPostgresClient(postgresDataSource(getEnv("POSTGRES_ADDRESS", "postgres:5432"), getEnv("POSTGRES_USER", "postgres"), getEnv("POSTGRES_PASSWORD", ""), getEnv("POSTGRES_DATABASE", "multi_juicer"), sslMode, timeout), timeout)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create the PostgreSQL client: %v", err))
+	}
+	return client
+}
+
+// postgresQueryer is implemented by *sql.DB and *sql.Tx
+type postgresQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// query runs the query limited by the timeout, returning the rows of the result as text
+func (c *PostgresClient) query(ctx context.Context, queryer postgresQueryer, query string, args ...string) ([][]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	rows, err := queryer.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := [][]string{}
+	for rows.Next() {
+		row := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		values := make([]string, len(columns))
+		for i, value := range row {
+			values[i] = value.String
+		}
+		result = append(result, values)
+	}
+	return result, rows.Err()
+}
+
+// Query runs a single query
+func (c *PostgresClient) Query(ctx context.Context, query string, args ...string) ([][]string, error) {
+	return c.query(ctx, c.db, query, args...)
+}
+
+// postgresTx runs the queries of a transaction
+type postgresTx struct {
+	client *PostgresClient
+	tx     *sql.Tx
+}
+
+func (tx postgresTx) Query(ctx context.Context, query string, args ...string) ([][]string, error) {
+	return tx.client.query(ctx, tx.tx, query, args...)
+}
+
+// Transaction runs the function in a transaction on one connection of the pool
+func (c *PostgresClient) Transaction(ctx context.Context, run func(tx PostgresQuerier) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := run(postgresTx{client: c, tx: tx}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Warningf("Failed to roll back PostgreSQL transaction: %s", rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the connections of the pool
+func (c *PostgresClient) Close() {
+	c.db.Close()
+}
+
+// postgresSchema creates the tables of the PostgresProgressStore:
+// progress_records holds the current records of the teams, progress_history every change of their continue code
+// and challenge_solves the first solve of every challenge by a team. History and solves are kept when teams are deleted.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS progress_records (
+		namespace text NOT NULL,
+		team text NOT NULL,
+		record text NOT NULL,
+		value text NOT NULL,
+		updated_at timestamptz NOT NULL DEFAULT now(),
+		PRIMARY KEY (namespace, team, record)
+	)`,
+	`CREATE TABLE IF NOT EXISTS progress_history (
+		id bigserial PRIMARY KEY,
+		namespace text NOT NULL,
+		team text NOT NULL,
+		record text NOT NULL,
+		value text NOT NULL,
+		changed_at timestamptz NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS progress_history_team ON progress_history (namespace, team, changed_at)`,
+	`CREATE TABLE IF NOT EXISTS challenge_solves (
+		namespace text NOT NULL,
+		team text NOT NULL,
+		challenge_id integer NOT NULL,
+		solved_at timestamptz NOT NULL,
+		PRIMARY KEY (namespace, team, challenge_id)
+	)`,
+}
+
+const (
+	// postgresLockTeam serializes the saves of a team until the end of the transaction, including the first one which has no rows to lock yet
+	postgresLockTeam      = `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`
+	postgresSelectRecords = `SELECT record, value FROM progress_records WHERE namespace = $1 AND team = $2`
+	postgresUpsertRecord  = `INSERT INTO progress_records (namespace, team, record, value) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, team, record) DO UPDATE SET value = excluded.value, updated_at = now()`
+	postgresInsertHistory = `INSERT INTO progress_history (namespace, team, record, value) VALUES ($1, $2, $3, $4)`
+	postgresInsertSolve   = `INSERT INTO challenge_solves (namespace, team, challenge_id, solved_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, team, challenge_id) DO NOTHING`
+	postgresDeleteRecords = `DELETE FROM progress_records WHERE namespace = $1 AND team = $2`
+)
+
+// postgresHistoryRecords are the records whose every change is kept in progress_history
+var postgresHistoryRecords = []string{"continueCode"}
+
+// PostgresProgressStore keeps the progress records of the teams in PostgreSQL, together with the history of their continue codes and solves.
+// Only challengesSolved stays on the deployment as well, teams without records are read from their annotations and migrated with their next save.
+type PostgresProgressStore struct {
+	db      PostgresDatabase
+	timeout time.Duration
+}
+
+// NewPostgresProgressStore creates a PostgresProgressStore, creating its tables if they don't exist yet
+func NewPostgresProgressStore(ctx context.Context, db PostgresDatabase, timeout time.Duration) (*PostgresProgressStore, error) {
+	for _, statement := range postgresSchema {
+		if _, err := db.Query(ctx, statement); err != nil {
+			return nil, fmt.Errorf("Failed to create the progress tables: %v", err)
+		}
+	}
+	return &PostgresProgressStore{db: db, timeout: timeout}, nil
+}
+
+// postgresRecords returns the progress records of the rows of postgresSelectRecords
+func postgresRecords(rows [][]string) map[string]string {
+	records := map[string]string{}
+	for _, row := range rows {
+		for _, name := range progressRecordNames {
+			if len(row) == 2 && row[0] == name {
+				records[annotation(name)] = row[1]
+			}
+		}
+	}
+	return records
+}
+
+// Records returns the progress records of the team, falling back to the annotations of teams which weren't migrated yet or if PostgreSQL is unavailable
+func (s *PostgresProgressStore) Records(deployment appsv1.Deployment) map[string]string {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	rows, err := s.db.Query(ctx, postgresSelectRecords, deployment.Namespace, deployment.Labels["team"])
+	if err != nil {
+		log.Warningf("Failed to read progress of team '%s' from PostgreSQL: %s", deployment.Labels["team"], err)
+		return AnnotationProgressStore{}.Records(deployment)
+	}
+	records := postgresRecords(rows)
+	if len(records) == 0 {
+		return AnnotationProgressStore{}.Records(deployment)
+	}
+	return records
+}
+
+// Save writes the changed records of the team and mirrors challengesSolved to the deployment. Changes of the continue code are appended
+// to the history and new solves recorded with their solve time. The first save of a team migrates its progress annotations.
+// Saves of a team are serialized with an advisory lock, so that the expected records are checked against the latest progress.
+func (s *PostgresProgressStore) Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error) {
+	var stored map[string]string
+	migrated := false
+	err := s.db.Transaction(ctx, func(tx PostgresQuerier) error {
+		if _, err := tx.Query(ctx, postgresLockTeam, namespace, teamname); err != nil {
+			return err
+		}
+		rows, err := tx.Query(ctx, postgresSelectRecords, namespace, teamname)
+		if err != nil {
+			return err
+		}
+		stored = postgresRecords(rows)
+		changed := map[string]string{}
+		migrated = len(stored) == 0
+		if migrated {
+			deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+			if err != nil {
+				return err
+			}
+			stored = AnnotationProgressStore{}.Records(*deployment)
+			for key, value := range stored {
+				changed[key] = value
+			}
+		}
+		if err := checkExpectedRecords(stored, expected); err != nil {
+			return err
+		}
+		for key, value := range records {
+			if current, ok := stored[key]; !ok || current != value || migrated {
+				changed[key] = value
+			}
+			stored[key] = value
+		}
+		return s.write(ctx, tx, namespace, teamname, changed)
+	})
+	if errors.Is(err, errProgressChanged) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to save progress to PostgreSQL: %v", err)
+	}
+	if migrated {
+		log.Infof("Moved the progress of team '%s' into PostgreSQL", teamname)
+	}
+	return mirrorProgress(ctx, clientset, namespace, teamname, records, stored, migrated)
+}
+
+// write upserts the changed records and appends them to the history
+func (s *PostgresProgressStore) write(ctx context.Context, tx PostgresQuerier, namespace, teamname string, changed map[string]string) error {
+	keys := []string{}
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := tx.Query(ctx, postgresUpsertRecord, namespace, teamname, configMapKey(key), changed[key]); err != nil {
+			return err
+		}
+	}
+	for _, name := range postgresHistoryRecords {
+		if value, ok := changed[annotation(name)]; ok {
+			if _, err := tx.Query(ctx, postgresInsertHistory, namespace, teamname, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	if solves, ok := changed[annotation("solves")]; ok {
+		for challenge, solvedAt := range parseSolveTimes(solves) {
+			if _, err := tx.Query(ctx, postgresInsertSolve, namespace, teamname, strconv.Itoa(challenge), solvedAt.UTC().Format(time.RFC3339Nano)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete removes the current records of the team and its progress annotations, its history and solves are kept
+func (s *PostgresProgressStore) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	if _, err := s.db.Query(ctx, postgresDeleteRecords, namespace, teamname); err != nil {
+		return fmt.Errorf("Failed to delete progress from PostgreSQL: %v", err)
+	}
+	return AnnotationProgressStore{}.Delete(ctx, clientset, namespace, teamname)
+}
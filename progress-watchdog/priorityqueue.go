@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sync"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// priorityQueue is a workqueue.Interface handing out urgent items before all others, items of the same priority in the order they were added.
+// Like the queues of client-go, every item is queued at most once and items added while they are processed are only queued again once they are done.
+type priorityQueue struct {
+	cond *sync.Cond
+	// isUrgent decides the priority of an item whenever it gets queued
+	isUrgent func(item interface{}) bool
+
+	urgent  []interface{}
+	routine []interface{}
+	// queued contains the items waiting in one of the queues, true for urgent ones
+	queued map[interface{}]bool
+	// dirty contains the items to queue again once they are done
+	dirty        map[interface{}]bool
+	processing   map[interface{}]bool
+	shuttingDown bool
+}
+
+func newPriorityQueue(isUrgent func(item interface{}) bool) *priorityQueue {
+	return &priorityQueue{
+		cond:       sync.NewCond(&sync.Mutex{}),
+		isUrgent:   isUrgent,
+		queued:     map[interface{}]bool{},
+		dirty:      map[interface{}]bool{},
+		processing: map[interface{}]bool{},
+	}
+}
+
+// Add queues the item, items already waiting in the routine queue move to the urgent one if they became urgent
+func (q *priorityQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if q.processing[item] {
+		q.dirty[item] = true
+		return
+	}
+	urgent, queued := q.queued[item]
+	if queued && (urgent || !q.isUrgent(item)) {
+		return
+	}
+	if queued {
+		q.routine = without(q.routine, item)
+	}
+	q.push(item)
+	q.cond.Signal()
+}
+
+// push appends the item to the queue of its priority, must be called with the lock held
+func (q *priorityQueue) push(item interface{}) {
+	urgent := q.isUrgent(item)
+	if urgent {
+		q.urgent = append(q.urgent, item)
+	} else {
+		q.routine = append(q.routine, item)
+	}
+	q.queued[item] = urgent
+}
+
+// Len returns the number of queued items of both priorities
+func (q *priorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.urgent) + len(q.routine)
+}
+
+// Get waits for the next item, the oldest urgent one if there is any. Returns true once the queue is shut down and empty.
+func (q *priorityQueue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.urgent) == 0 && len(q.routine) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	var item interface{}
+	switch {
+	case len(q.urgent) > 0:
+		item, q.urgent = q.urgent[0], q.urgent[1:]
+	case len(q.routine) > 0:
+		item, q.routine = q.routine[0], q.routine[1:]
+	default:
+		return nil, true
+	}
+	delete(q.queued, item)
+	q.processing[item] = true
+	return item, false
+}
+
+// Done marks the item as processed, it is queued again if it was added in the meantime
+func (q *priorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	if q.dirty[item] {
+		delete(q.dirty, item)
+		q.push(item)
+		q.cond.Signal()
+	}
+}
+
+// ShutDown stops accepting items, Get keeps handing out the queued ones
+func (q *priorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *priorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+func without(items []interface{}, item interface{}) []interface{} {
+	remaining := make([]interface{}, 0, len(items))
+	for _, other := range items {
+		if other != item {
+			remaining = append(remaining, other)
+		}
+	}
+	return remaining
+}
+
+// rateLimitingQueue adds the rate limited retries of workqueue.RateLimitingInterface to a delaying queue, client-go only provides them
+// on top of its FIFO queue
+type rateLimitingQueue struct {
+	workqueue.DelayingInterface
+	rateLimiter workqueue.RateLimiter
+}
+
+func (q *rateLimitingQueue) AddRateLimited(item interface{}) {
+	q.DelayingInterface.AddAfter(item, q.rateLimiter.When(item))
+}
+
+func (q *rateLimitingQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+func (q *rateLimitingQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func drainPriorityQueue(q *priorityQueue) []interface{} {
+	q.ShutDown()
+	items := []interface{}{}
+	for {
+		item, shutdown := q.Get()
+		if shutdown {
+			return items
+		}
+		items = append(items, item)
+		q.Done(item)
+	}
+}
+
+func TestPriorityQueueHandsOutUrgentItemsFirst(t *testing.T) {
+	urgent := map[interface{}]bool{"new-team": true, "restarted": true}
+	q := newPriorityQueue(func(item interface{}) bool { return urgent[item] })
+	q.Add("routine-1")
+	q.Add("new-team")
+	q.Add("routine-2")
+	q.Add("restarted")
+	q.Add("routine-1")
+
+	assert.Equal(t, 4, q.Len())
+	assert.Equal(t, []interface{}{"new-team", "restarted", "routine-1", "routine-2"}, drainPriorityQueue(q))
+}
+
+func TestPriorityQueueMovesItemsWhichBecameUrgentAhead(t *testing.T) {
+	urgent := map[interface{}]bool{}
+	q := newPriorityQueue(func(item interface{}) bool { return urgent[item] })
+	q.Add("routine")
+	q.Add("foobar")
+	urgent["foobar"] = true
+	q.Add("foobar")
+
+	assert.Equal(t, []interface{}{"foobar", "routine"}, drainPriorityQueue(q))
+}
+
+func TestPriorityQueueRequeuesItemsAddedWhileTheyAreProcessed(t *testing.T) {
+	q := newPriorityQueue(func(item interface{}) bool { return false })
+	q.Add("foobar")
+	item, _ := q.Get()
+	q.Add("foobar")
+	assert.Equal(t, 0, q.Len())
+	q.Done(item)
+
+	assert.Equal(t, []interface{}{"foobar"}, drainPriorityQueue(q))
+}
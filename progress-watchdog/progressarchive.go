@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// progressArchivedAtKey is the key of the time the progress was archived in the archive ConfigMaps
+const progressArchivedAtKey = "archivedAt"
+
+// ProgressArchive keeps the final progress of teams whose JuiceShop got deleted, e.g. manually or by the cleaner, in a ConfigMap per team.
+// The ConfigMaps aren't owned by the JuiceShops, so that they outlive them. If the team registers again, its progress is recovered from the archive.
+type ProgressArchive struct {
+	clientset kubernetes.Interface
+	lister    corelisters.ConfigMapLister
+}
+
+// progressArchive archives the progress of deleted JuiceShops, nil unless enabled via `PROGRESS_ARCHIVE`
+var progressArchive *ProgressArchive
+
+// progressArchiveLabel returns the label marking the ConfigMaps of the ProgressArchive
+func progressArchiveLabel() string {
+	return annotation("progressArchive")
+}
+
+func progressArchiveName(teamname string) string {
+	return fmt.Sprintf("t-%s-progress-archive", teamname)
+}
+
+// NewProgressArchive creates a ProgressArchive reading the archived progress of the namespace from an informer, which runs until the context is cancelled
+func NewProgressArchive(ctx context.Context, clientset kubernetes.Interface, namespace string) (*ProgressArchive, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = progressArchiveLabel() + "=true"
+	}))
+	lister := factory.Core().V1().ConfigMaps().Lister()
+	factory.Start(ctx.Done())
+	for _, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("Failed to sync the progress archive")
+		}
+	}
+	return &ProgressArchive{clientset: clientset, lister: lister}, nil
+}
+
+// Archive writes the progress of the deleted JuiceShop into the archive, replacing previously archived progress of the team.
+// JuiceShops without progress aren't archived.
+func (a *ProgressArchive) Archive(ctx context.Context, deployment appsv1.Deployment, now time.Time) error {
+	records := progressStore.Records(deployment)
+	if records[annotation("continueCode")] == "" {
+		return nil
+	}
+	teamname := deployment.Labels["team"]
+	data := map[string]string{progressArchivedAtKey: now.UTC().Format(time.RFC3339)}
+	for _, key := range progressRecordKeys() {
+		if value, ok := records[key]; ok {
+			data[configMapKey(key)] = value
+		}
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   progressArchiveName(teamname),
+			Labels: map[string]string{progressArchiveLabel(): "true", "team": teamname},
+		},
+		Data: data,
+	}
+
+	configMaps := a.clientset.CoreV1().ConfigMaps(deployment.Namespace)
+	_, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write progress archive: %v", err)
+	}
+	auditLog.Infof("Archived progress of deleted team '%s' with %s solved challenges", teamname, records[annotation("challengesSolved")])
+	return nil
+}
+
+// RecoverProgress implements ProgressRecoverySource with the archived progress of the team, if it was archived before the JuiceShop was created
+func (a *ProgressArchive) RecoverProgress(ctx context.Context, namespace, teamname string, createdAt time.Time) (map[string]string, bool, error) {
+	configMap, err := a.lister.ConfigMaps(namespace).Get(progressArchiveName(teamname))
+	if k8serrors.IsNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	archivedAt, err := time.Parse(time.RFC3339, configMap.Data[progressArchivedAtKey])
+	if err != nil {
+		return nil, false, fmt.Errorf("Invalid archive time '%s'", configMap.Data[progressArchivedAtKey])
+	}
+	if !createdAt.After(archivedAt) {
+		return nil, false, nil
+	}
+	records := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := configMap.Data[configMapKey(key)]; ok {
+			records[key] = value
+		}
+	}
+	return records, true, nil
+}
+
+func (a *ProgressArchive) String() string {
+	return "the progress archive"
+}
+
+// archiveDeletedJuiceShop archives the progress of a JuiceShop deleted from the informer, if the archive is enabled
+func archiveDeletedJuiceShop(obj interface{}) {
+	if progressArchive == nil {
+		return
+	}
+	// the final state of JuiceShops deleted while the watch was disconnected is only known from the tombstone
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	instance, ok := workloads.Convert(obj)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := progressArchive.Archive(context.Background(), *instance, time.Now()); err != nil {
+			log.Warningf("Failed to archive the progress of deleted team '%s': %s", instance.Labels["team"], err)
+		}
+	}()
+}
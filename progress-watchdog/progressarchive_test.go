@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestProgressArchiveKeepsTheProgressOfDeletedTeams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	archive, err := NewProgressArchive(ctx, clientset, "default")
+	assert.NoError(t, err)
+	deletedAt := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	deployment := createJuiceShopDeployment("foobar", "abc", "2")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z","7":"2021-05-01T11:00:00Z"}`
+	deployment.Annotations["multi-juicer.iteratec.dev/passcode"] = "12345678"
+	assert.NoError(t, archive.Archive(ctx, *deployment, deletedAt))
+	assert.NoError(t, archive.Archive(ctx, *createJuiceShopDeployment("barfoo", "", "0"), deletedAt), "Should skip teams without progress")
+
+	configMaps, err := clientset.CoreV1().ConfigMaps("default").List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, configMaps.Items, 1)
+	assert.Equal(t, "t-foobar-progress-archive", configMaps.Items[0].Name)
+	assert.Equal(t, map[string]string{
+		"archivedAt":       "2021-05-01T12:00:00Z",
+		"continueCode":     "abc",
+		"challengesSolved": "2",
+		"solves":           `{"1":"2021-05-01T10:00:00Z","7":"2021-05-01T11:00:00Z"}`,
+	}, configMaps.Items[0].Data)
+	assert.Empty(t, configMaps.Items[0].OwnerReferences, "Should outlive the JuiceShop")
+
+	deployment.Annotations["multi-juicer.iteratec.dev/continueCode"] = "def"
+	assert.NoError(t, archive.Archive(ctx, *deployment, deletedAt.Add(time.Hour)))
+	archived, err := clientset.CoreV1().ConfigMaps("default").Get(ctx, "t-foobar-progress-archive", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "def", archived.Data["continueCode"], "Should replace previously archived progress")
+}
+
+func TestProgressArchiveOnlyRecoversJuiceShopsCreatedAfterTheDeletion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	archive, err := NewProgressArchive(ctx, clientset, "default")
+	assert.NoError(t, err)
+	deletedAt := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, archive.Archive(ctx, *createJuiceShopDeployment("foobar", "abc", "2"), deletedAt))
+
+	assert.Eventually(t, func() bool {
+		_, ok, _ := archive.RecoverProgress(ctx, "default", "foobar", deletedAt.Add(time.Minute))
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	records, _, err := archive.RecoverProgress(ctx, "default", "foobar", deletedAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", records["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "2", records["multi-juicer.iteratec.dev/challengesSolved"])
+
+	_, ok, err := archive.RecoverProgress(ctx, "default", "foobar", deletedAt.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, ok, "Should not recover JuiceShops older than the archive")
+	_, ok, err = archive.RecoverProgress(ctx, "default", "unknown", deletedAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestArchivesTheProgressOfJuiceShopsDeletedWhileTheWatchWasDisconnected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	archive, err := NewProgressArchive(ctx, clientset, "default")
+	assert.NoError(t, err)
+	progressArchive = archive
+	defer func() { progressArchive = nil }()
+
+	archiveDeletedJuiceShop(cache.DeletedFinalStateUnknown{Key: "default/t-foobar-juiceshop", Obj: createJuiceShopDeployment("foobar", "abc", "2")})
+	assert.Eventually(t, func() bool {
+		_, err := clientset.CoreV1().ConfigMaps("default").Get(ctx, "t-foobar-progress-archive", metav1.GetOptions{})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
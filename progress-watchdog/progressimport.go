@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// errInvalidProgressImport is returned when an imported progress contains no or invalid solved challenges
+var errInvalidProgressImport = errors.New("Imported progress contains no valid solved challenges")
+
+// ProgressImport json format of progress imported for a team, e.g. exported from another cluster or a backup.
+// Solves optionally keeps the original solve times, the challenges of the ContinueCode and of the solves are imported together.
+type ProgressImport struct {
+	ContinueCode string     `json:"continueCode,omitempty"`
+	Solves       SolveTimes `json:"solves,omitempty"`
+}
+
+// ProgressImportResult what an import did for a team. Result is empty if the JuiceShop isn't ready or paused, the imported
+// progress is then applied to it by the next progress update.
+type ProgressImportResult struct {
+	Team             string      `json:"team"`
+	ChallengesSolved int         `json:"challengesSolved"`
+	Imported         int         `json:"imported"`
+	Result           UpdateState `json:"result,omitempty"`
+}
+
+// solvedChallenges returns the sorted challenges solved by the import
+func (p ProgressImport) solvedChallenges() ([]int, error) {
+	solved, err := ParseContinueCode(p.ContinueCode)
+	if err != nil {
+		return nil, errInvalidProgressImport
+	}
+	for challenge := range p.Solves {
+		solved = append(solved, challenge)
+	}
+	solved = mergeSolvedChallenges(solved)
+	if len(solved) == 0 {
+		return nil, errInvalidProgressImport
+	}
+	return solved, nil
+}
+
+// importProgress merges the imported progress into the cached progress of the team and applies it to its JuiceShop.
+// Challenges can't be unsolved via ContinueCodes, so the import never removes solved challenges. Cached solves keep their solve time,
+// newly imported ones get the imported solve time if it isn't in the future, otherwise they are recorded as solved now.
+func importProgress(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, progress ProgressImport, scoreboardCache *ScoreboardCache, now time.Time) (ProgressImportResult, error) {
+	imported, err := progress.solvedChallenges()
+	if err != nil {
+		return ProgressImportResult{}, err
+	}
+	deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if err != nil {
+		return ProgressImportResult{}, err
+	}
+
+	job := progressUpdateJobForDeployment(*deployment)
+	cached, _ := ParseContinueCode(job.LastContinueCode)
+	solved := mergeSolvedChallenges(cached, imported)
+	continueCode, err := EncodeContinueCode(solved)
+	if err != nil {
+		return ProgressImportResult{}, fmt.Errorf("Failed to encode ContinueCode: %v", err)
+	}
+	solves := job.LastSolves.UpdateReported(solved, progress.Solves, now)
+	encodedSolves, err := json.Marshal(solves)
+	if err != nil {
+		return ProgressImportResult{}, err
+	}
+
+	records := map[string]string{
+		annotation("continueCode"):     continueCode,
+		annotation("challengesSolved"): fmt.Sprintf("%d", len(solved)),
+		annotation("solves"):           string(encodedSolves),
+	}
+	updated, err := progressStore.Save(ctx, clientset, namespace, teamname, records, map[string]string{annotation("continueCode"): job.LastContinueCode})
+	if errors.Is(err, errProgressChanged) {
+		progressConflicts.Inc()
+		return ProgressImportResult{}, err
+	} else if err != nil {
+		patchErrors.Inc()
+		return ProgressImportResult{}, err
+	}
+	scoreboardCache.Invalidate()
+	result := ProgressImportResult{Team: teamname, ChallengesSolved: len(solved), Imported: len(solved) - len(cached)}
+	auditLog.Infof("Imported progress of team '%s', %d of the %d imported challenges were newly solved", teamname, result.Imported, len(imported))
+
+	if updated.Status.ReadyReplicas < 1 || isPaused(*updated) {
+		log.Infof("Imported progress of team '%s' gets applied to its JuiceShop once it is ready and not paused", teamname)
+		return result, nil
+	}
+	result.Result, err = runProgressUpdateJob(progressUpdateJobForDeployment(*updated), clientset, scoreboardCache)
+	return result, err
+}
+
+// handleImportProgress imports the progress of the request body, e.g. `POST /api/admin/teams/{team}/progress` with `{"continueCode": "..."}`
+func (s *Server) handleImportProgress(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	progress := ProgressImport{}
+	if err := json.NewDecoder(io.LimitReader(req.Body, 1<<20)).Decode(&progress); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := importProgress(req.Context(), s.clientset, namespace, teamname, progress, s.scoreboardCache, time.Now())
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil && result.Team != "" {
+		// the import is cached, but applying it to the JuiceShop failed. It gets retried by the next progress update
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(result)
+		return
+	} else if err == errInvalidProgressImport {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, errProgressChanged) {
+		http.Error(w, "Progress of the team changed concurrently, retry the import", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to import progress of team '%s'", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
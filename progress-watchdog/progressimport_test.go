@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestImportMergesProgressIntoCacheAndAppliesItToTheJuiceShop(t *testing.T) {
+	fakeRestorableJuiceShops(t)
+	cachedContinueCode, _ := EncodeContinueCode([]int{1, 2})
+	deployment := createJuiceShopDeployment("foobar", cachedContinueCode, "2")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z","2":"2021-05-01T11:00:00Z"}`
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	importedContinueCode, _ := EncodeContinueCode([]int{2})
+	body := `{"continueCode":"` + importedContinueCode + `","solves":{"2":"2021-05-01T09:00:00Z","3":"2021-05-01T12:00:00Z"}}`
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/progress", body))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	result := ProgressImportResult{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, ProgressImportResult{Team: "foobar", ChallengesSolved: 3, Imported: 1, Result: ApplyCode}, result)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	expectedContinueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+	assert.Equal(t, expectedContinueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	// cached solves keep their solve time, newly imported ones get the imported one
+	assert.JSONEq(t, `{"1":"2021-05-01T10:00:00Z","2":"2021-05-01T11:00:00Z","3":"2021-05-01T12:00:00Z"}`, updated.Annotations["multi-juicer.iteratec.dev/solves"])
+}
+
+func TestImportOnlyCachesProgressOfTeamsWhichArentReady(t *testing.T) {
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("Unexpected request to JuiceShop: %s %s", req.Method, req.URL)
+	})
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	importedContinueCode, _ := EncodeContinueCode([]int{1, 2})
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/progress", `{"continueCode":"`+importedContinueCode+`"}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	result := ProgressImportResult{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, ProgressImportResult{Team: "foobar", ChallengesSolved: 2, Imported: 2}, result)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, importedContinueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestImportRejectsProgressWithoutValidSolves(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	for _, body := range []string{`{}`, `{"continueCode":"invalid"}`, `not json`} {
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/progress", body))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestImportReturnsNotFoundForUnknownTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/progress", `{"solves":{"1":"2021-05-01T10:00:00Z"}}`))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// progressRecordNames are the names of the annotations of the progress cached by cacheContinueCode, they are kept in the ProgressStore
+var progressRecordNames = []string{"continueCode", "challengesSolved", "solves", "disabledChallenges", "tutorialChallenges", "solveDetails"}
+
+// progressRecordKeys returns the annotations of the progress records
+func progressRecordKeys() []string {
+	keys := []string{}
+	for _, name := range progressRecordNames {
+		keys = append(keys, annotation(name))
+	}
+	return keys
+}
+
+// ProgressStore persists the progress records of the teams, keyed like the annotations in progressRecordKeys
+type ProgressStore interface {
+	// Records returns the stored progress records of the team of the deployment
+	Records(deployment appsv1.Deployment) map[string]string
+	// Save sets the passed records of the team, records which aren't passed are kept. Returns the updated deployment of the team.
+	// Fails with errProgressChanged if the stored records differ from the expected ones, e.g. because another replica saved newer progress in the meantime.
+	Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error)
+	// Delete removes all progress records of the team
+	Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error
+}
+
+// progressStore is the store of the running watchdog, configured via the `PROGRESS_STORE` env var
+var progressStore ProgressStore = AnnotationProgressStore{}
+
+// errProgressChanged is returned by saves whose expected records were changed in the meantime, the save has to be based on the current progress
+var errProgressChanged = errors.New("Progress of the team was changed concurrently")
+
+// checkExpectedRecords checks that the stored records match the expected ones, missing records are expected to be empty
+func checkExpectedRecords(records, expected map[string]string) error {
+	for key, value := range expected {
+		if records[key] != value {
+			return errProgressChanged
+		}
+	}
+	return nil
+}
+
+// resourceVersionPatch creates a merge patch which only applies if the object still has the resourceVersion, otherwise it fails with a conflict
+func resourceVersionPatch(resourceVersion string, patch map[string]interface{}) ([]byte, error) {
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		patch["metadata"] = metadata
+	}
+	metadata["resourceVersion"] = resourceVersion
+	return json.Marshal(patch)
+}
+
+// withProgressRecords returns the annotations of the deployment with the progress records of the store, e.g. to include them in snapshots
+func withProgressRecords(deployment appsv1.Deployment) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range deployment.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range progressStore.Records(deployment) {
+		annotations[key] = value
+	}
+	return annotations
+}
+
+// splitProgressRecords splits annotations, e.g. from snapshots, into the progress records, which have to be saved with the progress store, and all other annotations
+func splitProgressRecords(annotations map[string]string) (records, others map[string]string) {
+	records = map[string]string{}
+	others = map[string]string{}
+	for key, value := range annotations {
+		others[key] = value
+	}
+	for _, key := range progressRecordKeys() {
+		if value, ok := others[key]; ok {
+			records[key] = value
+			delete(others, key)
+		}
+	}
+	return records, others
+}
+
+// AnnotationProgressStore keeps the progress records as annotations of the JuiceShop deployments
+type AnnotationProgressStore struct{}
+
+// Records returns the progress annotations of the deployment
+func (AnnotationProgressStore) Records(deployment appsv1.Deployment) map[string]string {
+	records := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := deployment.Annotations[key]; ok {
+			records[key] = value
+		}
+	}
+	return records
+}
+
+// Save merge patches the records into the annotations of the deployment. The patch is based on the resourceVersion of the checked deployment
+// and retried on conflicts, so that concurrent changes of the expected records are never overwritten.
+func (AnnotationProgressStore) Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error) {
+	deploymentName := fmt.Sprintf("t-%s-juiceshop", teamname)
+	var updated *appsv1.Deployment
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := workloads.Get(ctx, clientset, namespace, deploymentName)
+		if err != nil {
+			return err
+		}
+		if err := checkExpectedRecords(AnnotationProgressStore{}.Records(*deployment), expected); err != nil {
+			return err
+		}
+		if featureFlags.Enabled(FeatureServerSideApply) {
+			updated, err = applyProgressAnnotations(ctx, clientset, *deployment, records)
+			return err
+		}
+		patch, err := resourceVersionPatch(deployment.ResourceVersion, map[string]interface{}{"metadata": map[string]interface{}{"annotations": records}})
+		if err != nil {
+			return err
+		}
+		updated, err = workloads.Patch(ctx, clientset, namespace, deploymentName, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// progressFieldManager is the field manager of the progress annotations written with server-side apply
+const progressFieldManager = "progress-watchdog"
+
+// applyProgressAnnotations applies the progress annotations of the deployment with the records set, as the progressFieldManager.
+// All progress annotations are applied, as server-side apply removes fields of the manager which are missing in the applied configuration.
+// The apply is based on the resourceVersion of the deployment and forces the ownership of the annotations, e.g. taken over from the balancer which creates them.
+func applyProgressAnnotations(ctx context.Context, clientset kubernetes.Interface, deployment appsv1.Deployment, records map[string]string) (*appsv1.Deployment, error) {
+	annotations := AnnotationProgressStore{}.Records(deployment)
+	for key, value := range records {
+		annotations[key] = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": workloads.APIVersion(),
+		"kind":       workloads.Kind(),
+		"metadata": map[string]interface{}{
+			"name":            deployment.Name,
+			"namespace":       deployment.Namespace,
+			"resourceVersion": deployment.ResourceVersion,
+			"annotations":     annotations,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	force := true
+	return workloads.Patch(ctx, clientset, deployment.Namespace, deployment.Name, types.ApplyPatchType, patch, metav1.PatchOptions{FieldManager: progressFieldManager, Force: &force})
+}
+
+// Delete removes the progress annotations of the deployment
+func (AnnotationProgressStore) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	return patchJuiceShopDeployment(ctx, clientset, namespace, teamname, removeProgressAnnotationsPatch(nil))
+}
+
+// removeProgressAnnotationsPatch creates a merge patch removing the progress annotations of a deployment, except for the kept ones
+func removeProgressAnnotationsPatch(keep map[string]string) map[string]interface{} {
+	annotations := map[string]interface{}{}
+	for _, key := range progressRecordKeys() {
+		annotations[key] = nil
+	}
+	for key, value := range keep {
+		annotations[key] = value
+	}
+	return map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}}
+}
+
+// ConfigMapProgressStore keeps the progress records in a ConfigMap per team (`t-{team}-progress`), owned by the JuiceShop deployment of the team.
+// Only challengesSolved stays on the deployment as well, as the JuiceBalancer and the metrics read it from there.
+// Teams without ConfigMap are read from their annotations and migrated with their next save.
+type ConfigMapProgressStore struct {
+	lister corelisters.ConfigMapLister
+
+	mutex sync.Mutex
+	// written contains the ConfigMaps saved by the store, until the informer caught up with them
+	written map[string]*corev1.ConfigMap
+	// deleted contains the resourceVersion of the ConfigMaps deleted by the store, until the informer caught up with the deletion
+	deleted map[string]string
+}
+
+// progressConfigMapLabel returns the label marking the ConfigMaps of the ConfigMapProgressStore
+func progressConfigMapLabel() string {
+	return annotation("progress")
+}
+
+// NewConfigMapProgressStore creates a ConfigMapProgressStore reading the ConfigMaps of the namespace from an informer, which runs until the context is cancelled
+func NewConfigMapProgressStore(ctx context.Context, clientset kubernetes.Interface, namespace string) (*ConfigMapProgressStore, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = progressConfigMapLabel() + "=true"
+	}))
+	lister := factory.Core().V1().ConfigMaps().Lister()
+	factory.Start(ctx.Done())
+	for _, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("Failed to sync the progress ConfigMaps")
+		}
+	}
+	return &ConfigMapProgressStore{lister: lister, written: map[string]*corev1.ConfigMap{}, deleted: map[string]string{}}, nil
+}
+
+func progressConfigMapName(teamname string) string {
+	return fmt.Sprintf("t-%s-progress", teamname)
+}
+
+// configMapKey strips the annotation prefix of the record, as ConfigMap keys can't contain slashes
+func configMapKey(record string) string {
+	return strings.TrimPrefix(record, annotationPrefix)
+}
+
+// newerResourceVersion checks if a is newer than b. resourceVersions are opaque, but etcd based clusters use increasing numbers.
+func newerResourceVersion(a, b string) bool {
+	versionA, errA := strconv.ParseUint(a, 10, 64)
+	versionB, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil || errB != nil {
+		return a != b
+	}
+	return versionA > versionB
+}
+
+// configMap returns the latest known ConfigMap of the team
+func (s *ConfigMapProgressStore) configMap(namespace, teamname string) (*corev1.ConfigMap, bool) {
+	key := namespace + "/" + progressConfigMapName(teamname)
+	cached, err := s.lister.ConfigMaps(namespace).Get(progressConfigMapName(teamname))
+	if err != nil {
+		cached = nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if deletedVersion, ok := s.deleted[key]; ok {
+		if cached != nil && !newerResourceVersion(cached.ResourceVersion, deletedVersion) {
+			return nil, false
+		}
+		delete(s.deleted, key)
+	}
+	if written, ok := s.written[key]; ok {
+		if cached == nil || newerResourceVersion(written.ResourceVersion, cached.ResourceVersion) {
+			return written, true
+		}
+		delete(s.written, key)
+	}
+	return cached, cached != nil
+}
+
+// Records returns the progress records of the ConfigMap of the team, falling back to the annotations of teams which weren't migrated yet
+func (s *ConfigMapProgressStore) Records(deployment appsv1.Deployment) map[string]string {
+	configMap, ok := s.configMap(deployment.Namespace, deployment.Labels["team"])
+	if !ok {
+		return AnnotationProgressStore{}.Records(deployment)
+	}
+	records := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := configMap.Data[configMapKey(key)]; ok {
+			records[key] = value
+		}
+	}
+	return records
+}
+
+// Save writes the records into the ConfigMap of the team and mirrors challengesSolved to the deployment.
+// The first save of a team migrates its progress annotations into the ConfigMap and removes them from the deployment.
+// The expected records are checked against the ConfigMap read from the api, which is patched with its resourceVersion and retried on conflicts.
+func (s *ConfigMapProgressStore) Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error) {
+	data := map[string]string{}
+	for key, value := range records {
+		data[configMapKey(key)] = value
+	}
+
+	var saved *corev1.ConfigMap
+	migrated := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, progressConfigMapName(teamname), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			saved, err = s.create(ctx, clientset, namespace, teamname, data, expected)
+			migrated = err == nil
+			return err
+		} else if err != nil {
+			return fmt.Errorf("Failed to get progress ConfigMap: %v", err)
+		}
+
+		stored := map[string]string{}
+		for _, key := range progressRecordKeys() {
+			if value, ok := current.Data[configMapKey(key)]; ok {
+				stored[key] = value
+			}
+		}
+		if err := checkExpectedRecords(stored, expected); err != nil {
+			return err
+		}
+		patch, err := resourceVersionPatch(current.ResourceVersion, map[string]interface{}{"data": data})
+		if err != nil {
+			return err
+		}
+		saved, err = clientset.CoreV1().ConfigMaps(namespace).Patch(ctx, progressConfigMapName(teamname), types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !k8serrors.IsConflict(err) {
+			return fmt.Errorf("Failed to patch progress ConfigMap: %v", err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	s.written[namespace+"/"+saved.Name] = saved
+	delete(s.deleted, namespace+"/"+saved.Name)
+	s.mutex.Unlock()
+
+	stored := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := saved.Data[configMapKey(key)]; ok {
+			stored[key] = value
+		}
+	}
+	return mirrorProgress(ctx, clientset, namespace, teamname, records, stored, migrated)
+}
+
+// mirrorProgress mirrors challengesSolved of the saved records to the deployment of the team, as the JuiceBalancer and the metrics read it from there.
+// Teams migrated by the save get their progress annotations removed, stored are all records of the team after the save.
+func mirrorProgress(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, stored map[string]string, migrated bool) (*appsv1.Deployment, error) {
+	deploymentName := fmt.Sprintf("t-%s-juiceshop", teamname)
+	mirrored := map[string]string{}
+	if challengesSolved, ok := records[annotation("challengesSolved")]; ok {
+		mirrored[annotation("challengesSolved")] = challengesSolved
+	}
+	if migrated {
+		if challengesSolved, ok := stored[annotation("challengesSolved")]; ok {
+			mirrored[annotation("challengesSolved")] = challengesSolved
+		}
+		patch, err := json.Marshal(removeProgressAnnotationsPatch(mirrored))
+		if err != nil {
+			return nil, err
+		}
+		return workloads.Patch(ctx, clientset, namespace, deploymentName, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if len(mirrored) == 0 {
+		return workloads.Get(ctx, clientset, namespace, deploymentName)
+	}
+	// the store is the source of truth, the mirror is always overwritten
+	return AnnotationProgressStore{}.Save(ctx, clientset, namespace, teamname, mirrored, nil)
+}
+
+// create creates the ConfigMap of a team which wasn't migrated yet, initialized with its progress annotations.
+// Returns a conflict if the ConfigMap was created in the meantime, e.g. by another replica, so that the save is retried against it.
+func (s *ConfigMapProgressStore) create(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, data, expected map[string]string) (*corev1.ConfigMap, error) {
+	deploymentName := fmt.Sprintf("t-%s-juiceshop", teamname)
+	deployment, err := workloads.Get(ctx, clientset, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+	annotations := AnnotationProgressStore{}.Records(*deployment)
+	if err := checkExpectedRecords(annotations, expected); err != nil {
+		return nil, err
+	}
+	initial := map[string]string{}
+	for key, value := range annotations {
+		initial[configMapKey(key)] = value
+	}
+	for key, value := range data {
+		initial[key] = value
+	}
+	controller := true
+	created, err := clientset.CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            progressConfigMapName(teamname),
+			Labels:          map[string]string{progressConfigMapLabel(): "true", "team": teamname},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: workloads.APIVersion(), Kind: workloads.Kind(), Name: deploymentName, UID: deployment.UID, Controller: &controller}},
+		},
+		Data: initial,
+	}, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return nil, k8serrors.NewConflict(corev1.Resource("configmaps"), progressConfigMapName(teamname), err)
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to create progress ConfigMap: %v", err)
+	}
+	log.Infof("Moved the progress of team '%s' into its ConfigMap", teamname)
+	return created, nil
+}
+
+// Delete removes the ConfigMap of the team and the progress annotations of its deployment
+func (s *ConfigMapProgressStore) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	if configMap, ok := s.configMap(namespace, teamname); ok {
+		err := clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		s.mutex.Lock()
+		s.deleted[namespace+"/"+configMap.Name] = configMap.ResourceVersion
+		delete(s.written, namespace+"/"+configMap.Name)
+		s.mutex.Unlock()
+	}
+	return AnnotationProgressStore{}.Delete(ctx, clientset, namespace, teamname)
+}
+
+// createProgressStore creates the store configured via `PROGRESS_STORE`, either `annotations` (default), `configmaps`, `redis` or `postgres`.
+// Redis and PostgreSQL are retried until they can be reached, e.g. when they are started together with the watchdog.
+func createProgressStore(ctx context.Context, clientset kubernetes.Interface, namespace string) ProgressStore {
+	switch getEnv("PROGRESS_STORE", "annotations") {
+	case "annotations":
+		return AnnotationProgressStore{}
+	case "configmaps":
+		store, err := NewConfigMapProgressStore(ctx, clientset, namespace)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the ConfigMap progress store: %v", err))
+		}
+		return store
+	case "redis":
+		client := newRedisClientFromEnv()
+		var store *RedisProgressStore
+		err := retryOnStartup(ctx, "create the Redis progress store", func() (err error) {
+			store, err = NewRedisProgressStore(ctx, client, getEnv("REDIS_KEY_PREFIX", "multi-juicer:progress"))
+			return err
+		})
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the Redis progress store: %v", err))
+		}
+		return store
+	case "postgres":
+		client := newPostgresClientFromEnv()
+		var store *PostgresProgressStore
+		err := retryOnStartup(ctx, "create the PostgreSQL progress store", func() (err error) {
+			store, err = NewPostgresProgressStore(ctx, client, client.timeout)
+			return err
+		})
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the PostgreSQL progress store: %v", err))
+		}
+		go func() {
+			<-ctx.Done()
+			client.Close()
+		}()
+		return store
+	default:
+		panic(fmt.Sprintf("Invalid PROGRESS_STORE: %s", getEnv("PROGRESS_STORE", "annotations")))
+	}
+}
+
+// patchProgress applies a merge patch of the deployment of the team, its progress annotations are saved to the progress store instead
+func patchProgress(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, patch map[string]interface{}) error {
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	records := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := annotations[key].(string); ok {
+			records[key] = value
+			delete(annotations, key)
+		}
+	}
+	if len(records) > 0 {
+		if _, err := progressStore.Save(ctx, clientset, namespace, teamname, records, nil); err != nil {
+			return err
+		}
+	}
+	if len(annotations) == 0 && len(patch) == 1 {
+		return nil
+	}
+	return patchJuiceShopDeployment(ctx, clientset, namespace, teamname, patch)
+}
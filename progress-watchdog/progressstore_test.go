@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func useConfigMapProgressStore(t *testing.T, clientset *fake.Clientset) *ConfigMapProgressStore {
+	ctx, cancel := context.WithCancel(context.Background())
+	store, err := NewConfigMapProgressStore(ctx, clientset, "default")
+	assert.NoError(t, err)
+	previous := progressStore
+	progressStore = store
+	t.Cleanup(func() {
+		cancel()
+		progressStore = previous
+	})
+	return store
+}
+
+func TestConfigMapProgressStoreReadsAnnotationsOfTeamsNotMigratedYet(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	store := useConfigMapProgressStore(t, fake.NewSimpleClientset(deployment))
+
+	records := store.Records(*deployment)
+	assert.Equal(t, "abc", records["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", records["multi-juicer.iteratec.dev/challengesSolved"])
+}
+
+func TestConfigMapProgressStoreMigratesProgressAnnotationsOnSave(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	store := useConfigMapProgressStore(t, clientset)
+
+	updated, err := store.Save(context.Background(), clientset, "default", "foobar", map[string]string{
+		"multi-juicer.iteratec.dev/continueCode":     "def",
+		"multi-juicer.iteratec.dev/challengesSolved": "4",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/continueCode")
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/solves")
+
+	configMap, err := clientset.CoreV1().ConfigMaps("default").Get(context.Background(), "t-foobar-progress", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"continueCode":     "def",
+		"challengesSolved": "4",
+		"solves":           `{"1":"2021-05-01T10:00:00Z"}`,
+	}, configMap.Data)
+
+	progress := teamProgressFromDeployment(*updated)
+	assert.Equal(t, "def", progress.ContinueCode)
+	assert.Equal(t, 4, progress.ChallengesSolved)
+	assert.Len(t, progress.Solves, 1)
+}
+
+func TestConfigMapProgressStoreDeletesTheProgressOfTheTeam(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	clientset := fake.NewSimpleClientset(deployment)
+	store := useConfigMapProgressStore(t, clientset)
+	_, err := store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Delete(context.Background(), clientset, "default", "foobar"))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, store.Records(*updated))
+	_, err = clientset.CoreV1().ConfigMaps("default").Get(context.Background(), "t-foobar-progress", metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestProgressStoresDontOverwriteProgressChangedConcurrently(t *testing.T) {
+	for name, create := range map[string]func(t *testing.T, clientset *fake.Clientset) ProgressStore{
+		"annotations": func(t *testing.T, clientset *fake.Clientset) ProgressStore { return AnnotationProgressStore{} },
+		"configmaps": func(t *testing.T, clientset *fake.Clientset) ProgressStore {
+			return useConfigMapProgressStore(t, clientset)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+			store := create(t, clientset)
+			_, err := store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+			assert.NoError(t, err)
+
+			_, err = store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "stale"}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+			assert.ErrorIs(t, err, errProgressChanged)
+
+			updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "def", store.Records(*updated)["multi-juicer.iteratec.dev/continueCode"])
+		})
+	}
+}
+
+func TestAnnotationProgressStoreAppliesProgressAnnotationsAsFieldManagerIfServerSideApplyIsEnabled(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	featureFlags = NewFeatureFlags(map[Feature]bool{FeatureServerSideApply: true})
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	deployment.ResourceVersion = "42"
+	clientset := fake.NewSimpleClientset(deployment)
+	var applied map[string]interface{}
+	clientset.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+		assert.NoError(t, json.Unmarshal(patch.GetPatch(), &applied))
+		return true, deployment, nil
+	})
+
+	_, err := AnnotationProgressStore{}.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":            "t-foobar-juiceshop",
+		"namespace":       "default",
+		"resourceVersion": "42",
+		"annotations": map[string]interface{}{
+			"multi-juicer.iteratec.dev/continueCode":     "def",
+			"multi-juicer.iteratec.dev/challengesSolved": "3",
+		},
+	}, applied["metadata"], "Should apply all progress annotations, so that the annotations not changed aren't removed from the field manager")
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// retryBaseDelay is the delay before the first retry of a failed team, doubling with every further failure
+	retryBaseDelay = 5 * time.Second
+	// retryMaxDelay caps the delay between retries of a failing team
+	retryMaxDelay = 5 * time.Minute
+	// retriesPerSecond limits the retries of all failing teams combined, so that downstream systems aren't flooded when many teams fail at once
+	retriesPerSecond = 10
+	retryBurst       = 50
+)
+
+// ProgressUpdateQueue queues the progress update jobs of the teams, each team is queued at most once. Urgent jobs, e.g. of new teams
+// whose progress isn't cached yet, are run before the routine ones. Teams whose job failed are retried with a per team exponential backoff.
+// The queue holds at most capacity teams, adding further teams blocks until a worker picks up a queued one, so that
+// the discovery and the solve webhooks are slowed down to the pace of the workers.
+type ProgressUpdateQueue struct {
+	queue       workqueue.RateLimitingInterface
+	rateLimiter workqueue.RateLimiter
+	// capacity is the maximum number of pending teams
+	capacity int
+	// backpressureThreshold is the time jobs may wait for a worker or producers for capacity before a warning is logged
+	backpressureThreshold time.Duration
+
+	mutex sync.Mutex
+	// space is signalled whenever a pending team was picked up or the queue was shut down
+	space *sync.Cond
+	// jobs contains the latest job of every queued team, keyed by the Key of the jobs
+	jobs map[string]ProgressUpdateJobs
+	// pending contains the teams queued but not yet picked up by a worker
+	pending map[string]bool
+	// running contains the teams whose job is run by a worker
+	running map[string]bool
+	// queuedAt contains the time the pending teams were queued
+	queuedAt    map[string]time.Time
+	shutdown    bool
+	lastWarning time.Time
+}
+
+// QueuedTeam is a team pending, running or waiting for a retry in the ProgressUpdateQueue, as stored in the checkpoints of the queue
+type QueuedTeam struct {
+	Namespace string    `json:"namespace,omitempty"`
+	Team      string    `json:"team"`
+	QueuedAt  time.Time `json:"queuedAt"`
+	// Failures is the number of failed updates of a team waiting for a retry
+	Failures int  `json:"failures,omitempty"`
+	Urgent   bool `json:"urgent,omitempty"`
+}
+
+// NewProgressUpdateQueue creates a new ProgressUpdateQueue holding at most capacity teams, backpressure exceeding the threshold is logged
+func NewProgressUpdateQueue(capacity int, backpressureThreshold time.Duration) *ProgressUpdateQueue {
+	return newProgressUpdateQueue(workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(retryBaseDelay, retryMaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(retriesPerSecond), retryBurst)},
+	), capacity, backpressureThreshold)
+}
+
+func newProgressUpdateQueue(rateLimiter workqueue.RateLimiter, capacity int, backpressureThreshold time.Duration) *ProgressUpdateQueue {
+	q := &ProgressUpdateQueue{
+		rateLimiter:           rateLimiter,
+		capacity:              capacity,
+		backpressureThreshold: backpressureThreshold,
+		jobs:                  map[string]ProgressUpdateJobs{},
+		pending:               map[string]bool{},
+		running:               map[string]bool{},
+		queuedAt:              map[string]time.Time{},
+	}
+	q.queue = &rateLimitingQueue{
+		DelayingInterface: workqueue.NewDelayingQueueWithCustomQueue(newPriorityQueue(q.isUrgent), "progress-updates"),
+		rateLimiter:       rateLimiter,
+	}
+	q.space = sync.NewCond(&q.mutex)
+	return q
+}
+
+// isUrgent returns whether the latest job of the queued team is urgent
+func (q *ProgressUpdateQueue) isUrgent(item interface{}) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.jobs[item.(string)].Urgent
+}
+
+// Add queues the job of a team. Teams which are already pending or waiting for a retry only get their job updated,
+// so every team has at most one pending job and retrying teams keep their backoff. Pending teams stay urgent until their job ran,
+// and move ahead of the routine jobs if an urgent job gets added for them. Blocks while the queue is full.
+func (q *ProgressUpdateQueue) Add(job ProgressUpdateJobs) {
+	key := job.Key()
+	q.mutex.Lock()
+	if !q.pending[key] {
+		q.awaitCapacity(job.Teamname)
+	}
+	pending := q.pending[key]
+	if previous := q.jobs[key]; pending {
+		if !previous.QueuedAt.IsZero() {
+			// the replaced job keeps waiting in the queue since it was first queued
+			job.QueuedAt = previous.QueuedAt
+		}
+		job.Urgent = job.Urgent || previous.Urgent
+	}
+	q.jobs[key] = job
+	q.pending[key] = true
+	if !pending {
+		q.queuedAt[key] = time.Now()
+	}
+	queueDepth.Set(float64(len(q.pending)))
+	q.mutex.Unlock()
+
+	if pending {
+		duplicateJobsDropped.Inc()
+		if job.Urgent && q.queue.NumRequeues(key) == 0 {
+			// moves the team ahead of the routine jobs, it is still only queued once
+			q.queue.Add(key)
+		}
+		return
+	}
+	if q.queue.NumRequeues(key) > 0 {
+		log.Debugf("Team '%s' is waiting for a retry, not queueing it again", job.Teamname)
+		duplicateJobsDropped.Inc()
+		return
+	}
+	q.queue.Add(key)
+}
+
+// awaitCapacity waits until the queue has space for another team or is shut down, the mutex has to be held
+func (q *ProgressUpdateQueue) awaitCapacity(teamname string) {
+	if len(q.pending) < q.capacity || q.shutdown {
+		return
+	}
+	start := time.Now()
+	for len(q.pending) >= q.capacity && !q.shutdown {
+		q.space.Wait()
+	}
+	blocked := time.Since(start)
+	queueBlockedSeconds.Add(blocked.Seconds())
+	if blocked > q.backpressureThreshold {
+		q.warnBackpressure("Queueing the progress update of team '%s' was blocked for %s by the full queue (%d teams), the workers can't keep up", teamname, blocked.Round(time.Millisecond), q.capacity)
+	}
+}
+
+// warnBackpressure logs a warning about backpressure, at most once per threshold so that a backlog doesn't flood the logs. The mutex has to be held.
+func (q *ProgressUpdateQueue) warnBackpressure(format string, args ...interface{}) {
+	if time.Since(q.lastWarning) < q.backpressureThreshold {
+		return
+	}
+	q.lastWarning = time.Now()
+	log.Warningf(format, args...)
+}
+
+// Process waits for the next queued team and runs its job with the passed function.
+// Returns false once the queue is shut down.
+func (q *ProgressUpdateQueue) Process(run func(job ProgressUpdateJobs) error) bool {
+	item, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(item)
+	key := item.(string)
+
+	q.mutex.Lock()
+	job, ok := q.jobs[key]
+	delete(q.pending, key)
+	q.running[key] = true
+	if queuedAt, queued := q.queuedAt[key]; queued {
+		waited := time.Since(queuedAt)
+		queueWaitSeconds.Observe(waited.Seconds())
+		if waited > q.backpressureThreshold {
+			q.warnBackpressure("Progress update of team '%s' waited %s for a worker, %d teams are pending", job.Teamname, waited.Round(time.Millisecond), len(q.pending))
+		}
+		delete(q.queuedAt, key)
+	}
+	queueDepth.Set(float64(len(q.pending)))
+	q.space.Broadcast()
+	q.mutex.Unlock()
+	defer func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		delete(q.running, key)
+	}()
+	if !ok {
+		q.queue.Forget(item)
+		return true
+	}
+
+	if err := run(job); err != nil {
+		log.Warningf("Progress update of team '%s' failed %d times in a row, retrying it", job.Teamname, q.queue.NumRequeues(item)+1)
+		q.queue.AddRateLimited(item)
+		return true
+	}
+	q.queue.Forget(item)
+	return true
+}
+
+// Resume queues the job of a team taken from a checkpoint of the queue. Teams which failed before wait for their next retry
+// with the backoff they had reached, all other teams are queued right away, keeping the time they were first queued.
+func (q *ProgressUpdateQueue) Resume(job ProgressUpdateJobs, failures int) {
+	if failures < 1 {
+		q.Add(job)
+		return
+	}
+	key := job.Key()
+	q.mutex.Lock()
+	q.jobs[key] = job
+	q.mutex.Unlock()
+	// the rate limiter only counts the failures of the team, so the backoff is restored by replaying them
+	for i := 1; i < failures; i++ {
+		q.rateLimiter.When(key)
+	}
+	q.queue.AddRateLimited(key)
+}
+
+// Checkpoint returns the teams pending, running or waiting for a retry, sorted by the time they were queued.
+// Teams whose job is running are included, as their update is lost if the watchdog gets killed before it finished.
+func (q *ProgressUpdateQueue) Checkpoint() []QueuedTeam {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	teams := []QueuedTeam{}
+	for key, job := range q.jobs {
+		failures := q.queue.NumRequeues(key)
+		if !q.pending[key] && !q.running[key] && failures == 0 {
+			continue
+		}
+		teams = append(teams, QueuedTeam{Namespace: job.Namespace, Team: job.Teamname, QueuedAt: job.QueuedAt, Failures: failures, Urgent: job.Urgent})
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if !teams[i].QueuedAt.Equal(teams[j].QueuedAt) {
+			return teams[i].QueuedAt.Before(teams[j].QueuedAt)
+		}
+		return teams[i].Namespace+"/"+teams[i].Team < teams[j].Namespace+"/"+teams[j].Team
+	})
+	return teams
+}
+
+// Len returns the number of teams waiting for a worker, teams waiting for a retry aren't counted
+func (q *ProgressUpdateQueue) Len() int {
+	return q.queue.Len()
+}
+
+// ShutDown stops the queue, workers return once they finished their current job
+func (q *ProgressUpdateQueue) ShutDown() {
+	q.mutex.Lock()
+	q.shutdown = true
+	q.space.Broadcast()
+	q.mutex.Unlock()
+	q.queue.ShutDown()
+}
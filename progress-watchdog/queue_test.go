@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newTestProgressUpdateQueue() *ProgressUpdateQueue {
+	return newProgressUpdateQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond), 100, time.Minute)
+}
+
+func TestProgressUpdateQueueRunsTheLatestJobOfATeamOnce(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	dropped := testutil.ToFloat64(duplicateJobsDropped)
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar", LastContinueCode: "old"})
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar", LastContinueCode: "new"})
+	queue.ShutDown()
+	assert.Equal(t, dropped+1, testutil.ToFloat64(duplicateJobsDropped))
+
+	jobs := []ProgressUpdateJobs{}
+	for queue.Process(func(job ProgressUpdateJobs) error {
+		jobs = append(jobs, job)
+		return nil
+	}) {
+	}
+
+	assert.Equal(t, []ProgressUpdateJobs{{Teamname: "foobar", LastContinueCode: "new"}}, jobs)
+}
+
+func TestProgressUpdateQueueRetriesFailedJobsWithBackoff(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	defer queue.ShutDown()
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar"})
+
+	failing := func(job ProgressUpdateJobs) error { return errors.New("JuiceShop not reachable") }
+	assert.True(t, queue.Process(failing))
+	assert.Equal(t, 1, queue.queue.NumRequeues("foobar"))
+
+	// teams waiting for a retry aren't queued again by the discovery loop
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar", LastContinueCode: "updated"})
+	assert.Equal(t, 0, queue.queue.Len())
+
+	var retried ProgressUpdateJobs
+	assert.True(t, queue.Process(func(job ProgressUpdateJobs) error {
+		retried = job
+		return nil
+	}))
+	assert.Equal(t, "updated", retried.LastContinueCode)
+	assert.Equal(t, 0, queue.queue.NumRequeues("foobar"))
+}
+
+func TestProgressUpdateQueueRequeuesTeamsAddedWhileTheirJobRuns(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar", LastContinueCode: "old"})
+
+	jobs := []ProgressUpdateJobs{}
+	assert.True(t, queue.Process(func(job ProgressUpdateJobs) error {
+		jobs = append(jobs, job)
+		// a running job isn't pending anymore, so the team gets queued again
+		queue.Add(ProgressUpdateJobs{Teamname: "foobar", LastContinueCode: "new"})
+		return nil
+	}))
+	queue.ShutDown()
+	for queue.Process(func(job ProgressUpdateJobs) error {
+		jobs = append(jobs, job)
+		return nil
+	}) {
+	}
+
+	assert.Equal(t, []ProgressUpdateJobs{{Teamname: "foobar", LastContinueCode: "old"}, {Teamname: "foobar", LastContinueCode: "new"}}, jobs)
+}
+
+func TestQueuesTeamsWithTheSameNameInDifferentNamespacesSeparately(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	queue.Add(ProgressUpdateJobs{Namespace: "event-1", Teamname: "foobar"})
+	queue.Add(ProgressUpdateJobs{Namespace: "event-2", Teamname: "foobar"})
+	queue.ShutDown()
+
+	namespaces := []string{}
+	for queue.Process(func(job ProgressUpdateJobs) error {
+		namespaces = append(namespaces, job.Namespace)
+		return nil
+	}) {
+	}
+	assert.Equal(t, []string{"event-1", "event-2"}, namespaces)
+}
+
+func TestCheckpointContainsPendingRunningAndRetryingTeams(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	defer queue.ShutDown()
+	start := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	queue.Add(ProgressUpdateJobs{Namespace: "default", Teamname: "failing", QueuedAt: start})
+	queue.Add(ProgressUpdateJobs{Namespace: "default", Teamname: "running", QueuedAt: start.Add(time.Second)})
+	queue.Add(ProgressUpdateJobs{Namespace: "default", Teamname: "pending", QueuedAt: start.Add(2 * time.Second)})
+	queue.Add(ProgressUpdateJobs{Namespace: "default", Teamname: "done", QueuedAt: start.Add(3 * time.Second)})
+
+	queue.Process(func(job ProgressUpdateJobs) error { return errors.New("JuiceShop not reachable") })
+	queue.Process(func(job ProgressUpdateJobs) error {
+		assert.Equal(t, []QueuedTeam{
+			{Namespace: "default", Team: "failing", QueuedAt: start, Failures: 1},
+			{Namespace: "default", Team: "running", QueuedAt: start.Add(time.Second)},
+			{Namespace: "default", Team: "pending", QueuedAt: start.Add(2 * time.Second)},
+			{Namespace: "default", Team: "done", QueuedAt: start.Add(3 * time.Second)},
+		}, queue.Checkpoint())
+		return nil
+	})
+	queue.Process(func(job ProgressUpdateJobs) error { return nil })
+	queue.Process(func(job ProgressUpdateJobs) error { return nil })
+
+	assert.Equal(t, []string{"failing"}, teamsOf(queue.Checkpoint()))
+}
+
+func TestResumedTeamsKeepTheirBackoff(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	defer queue.ShutDown()
+	queuedAt := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	queue.Resume(ProgressUpdateJobs{Teamname: "retrying", QueuedAt: queuedAt}, 3)
+	queue.Resume(ProgressUpdateJobs{Teamname: "pending", QueuedAt: queuedAt}, 0)
+
+	assert.Equal(t, 1, queue.Len())
+	assert.Equal(t, 3, queue.queue.NumRequeues("retrying"))
+	// teams waiting for a retry aren't queued again by the discovery loop
+	queue.Add(ProgressUpdateJobs{Teamname: "retrying"})
+	assert.Equal(t, 1, queue.Len())
+
+	processed := []ProgressUpdateJobs{}
+	run := func(job ProgressUpdateJobs) error {
+		processed = append(processed, job)
+		return nil
+	}
+	queue.Process(run)
+	queue.Process(run)
+	assert.Equal(t, []string{"pending", "retrying"}, []string{processed[0].Teamname, processed[1].Teamname})
+	assert.Equal(t, queuedAt, processed[0].QueuedAt)
+}
+
+func teamsOf(teams []QueuedTeam) []string {
+	names := []string{}
+	for _, team := range teams {
+		names = append(names, team.Team)
+	}
+	return names
+}
+
+func TestProgressUpdateQueueRunsUrgentJobsFirst(t *testing.T) {
+	queue := newTestProgressUpdateQueue()
+	queue.Add(ProgressUpdateJobs{Teamname: "routine", LastContinueCode: "foo"})
+	queue.Add(ProgressUpdateJobs{Teamname: "new-team", Urgent: true})
+	queue.Add(ProgressUpdateJobs{Teamname: "restarted", LastContinueCode: "bar"})
+	// pending teams move ahead once they become urgent and stay urgent until their job ran
+	queue.Add(ProgressUpdateJobs{Teamname: "restarted", LastContinueCode: "bar", Urgent: true})
+	queue.Add(ProgressUpdateJobs{Teamname: "restarted", LastContinueCode: "baz"})
+	queue.ShutDown()
+
+	jobs := []ProgressUpdateJobs{}
+	for queue.Process(func(job ProgressUpdateJobs) error {
+		jobs = append(jobs, job)
+		return nil
+	}) {
+	}
+	assert.Equal(t, []ProgressUpdateJobs{
+		{Teamname: "new-team", Urgent: true},
+		{Teamname: "restarted", LastContinueCode: "baz", Urgent: true},
+		{Teamname: "routine", LastContinueCode: "foo"},
+	}, jobs)
+}
+
+func TestProgressUpdateQueueBlocksProducersWhileItIsFull(t *testing.T) {
+	queue := newProgressUpdateQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond), 1, time.Minute)
+	defer queue.ShutDown()
+	blocked := testutil.ToFloat64(queueBlockedSeconds)
+	queue.Add(ProgressUpdateJobs{Teamname: "team-a"})
+	assert.Equal(t, float64(1), testutil.ToFloat64(queueDepth))
+
+	added := make(chan struct{})
+	go func() {
+		queue.Add(ProgressUpdateJobs{Teamname: "team-b"})
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatal("Should block adding teams to the full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// teams already pending only get their job updated
+	queue.Add(ProgressUpdateJobs{Teamname: "team-a", LastContinueCode: "updated"})
+
+	assert.True(t, queue.Process(func(job ProgressUpdateJobs) error { return nil }))
+	select {
+	case <-added:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Should add the team once a worker picked up the pending one")
+	}
+	assert.Greater(t, testutil.ToFloat64(queueBlockedSeconds), blocked)
+	assert.Equal(t, float64(1), testutil.ToFloat64(queueDepth))
+}
+
+func TestProgressUpdateQueueUnblocksProducersOnShutdown(t *testing.T) {
+	queue := newProgressUpdateQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond), 1, time.Minute)
+	queue.Add(ProgressUpdateJobs{Teamname: "team-a"})
+
+	added := make(chan struct{})
+	go func() {
+		queue.Add(ProgressUpdateJobs{Teamname: "team-b"})
+		close(added)
+	}()
+	queue.ShutDown()
+
+	select {
+	case <-added:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Should stop blocking producers once the queue is shut down")
+	}
+}
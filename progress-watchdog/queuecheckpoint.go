@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// queueCheckpointName is the name of the ConfigMap holding the checkpoint of the ProgressUpdateQueue
+	queueCheckpointName = "progress-watchdog-queue"
+	// queueCheckpointKey is the key of the queued teams in the checkpoint ConfigMap
+	queueCheckpointKey = "queue.json"
+	// queueCheckpointShutdownTimeout limits the final checkpoint written on shutdown
+	queueCheckpointShutdownTimeout = 5 * time.Second
+)
+
+// QueueCheckpoint stores the teams of the ProgressUpdateQueue in a ConfigMap, so that updates queued or waiting for a retry
+// aren't dropped when the watchdog restarts or another replica takes over the leadership.
+type QueueCheckpoint struct {
+	clientset kubernetes.Interface
+	namespace string
+	// saved is the last checkpoint written, unchanged checkpoints aren't written again
+	saved string
+}
+
+// NewQueueCheckpoint creates a QueueCheckpoint stored in the namespace of the watchdog
+func NewQueueCheckpoint(clientset kubernetes.Interface, namespace string) *QueueCheckpoint {
+	return &QueueCheckpoint{clientset: clientset, namespace: namespace}
+}
+
+// Load returns the teams of the last checkpoint, none if no checkpoint was written yet
+func (c *QueueCheckpoint) Load(ctx context.Context) ([]QueuedTeam, error) {
+	configMap, err := c.clientset.CoreV1().ConfigMaps(c.namespace).Get(ctx, queueCheckpointName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return []QueuedTeam{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to read queue checkpoint: %v", err)
+	}
+	teams := []QueuedTeam{}
+	if err := json.Unmarshal([]byte(configMap.Data[queueCheckpointKey]), &teams); err != nil {
+		return nil, fmt.Errorf("Invalid queue checkpoint: %v", err)
+	}
+	c.saved = configMap.Data[queueCheckpointKey]
+	return teams, nil
+}
+
+// Save writes the teams as the new checkpoint, unless they didn't change since the last one
+func (c *QueueCheckpoint) Save(ctx context.Context, teams []QueuedTeam) error {
+	data, err := json.Marshal(teams)
+	if err != nil {
+		return err
+	}
+	if string(data) == c.saved {
+		return nil
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: queueCheckpointName},
+		Data:       map[string]string{queueCheckpointKey: string(data)},
+	}
+	configMaps := c.clientset.CoreV1().ConfigMaps(c.namespace)
+	_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write queue checkpoint: %v", err)
+	}
+	c.saved = string(data)
+	return nil
+}
+
+// Run checkpoints the queue every interval until the context is cancelled
+func (c *QueueCheckpoint) Run(ctx context.Context, queue *ProgressUpdateQueue, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Save(ctx, queue.Checkpoint()); err != nil {
+				log.Warning(err)
+			}
+		}
+	}
+}
+
+// SaveOnShutdown writes the final checkpoint once the workers stopped, the context of the watchdog is already cancelled at that point
+func (c *QueueCheckpoint) SaveOnShutdown(queue *ProgressUpdateQueue) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueCheckpointShutdownTimeout)
+	defer cancel()
+	teams := queue.Checkpoint()
+	if err := c.Save(ctx, teams); err != nil {
+		log.Warning(err)
+		return
+	}
+	log.Infof("Checkpointed %d queued teams", len(teams))
+}
+
+// resumeProgressUpdateJobs queues the teams of the checkpoint again with the current state of their JuiceShop, ahead of the teams queued
+// by the discovery. Teams whose JuiceShop was deleted, isn't ready or got paused in the meantime are skipped.
+func resumeProgressUpdateJobs(progressUpdateQueue *ProgressUpdateQueue, juiceShops *JuiceShopCache, teams []QueuedTeam) int {
+	resumed := 0
+	for _, team := range teams {
+		instances := juiceShops.List(team.Namespace, labels.SelectorFromSet(labels.Set{"team": team.Team}))
+		if len(instances) == 0 {
+			continue
+		}
+		job, ok := progressUpdateJobOf(&instances[0])
+		if !ok {
+			continue
+		}
+		job.QueuedAt = team.QueuedAt
+		job.Urgent = job.Urgent || team.Urgent
+		progressUpdateQueue.Resume(job, team.Failures)
+		resumed++
+	}
+	return resumed
+}
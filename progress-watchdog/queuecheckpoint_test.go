@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQueueCheckpointIsStoredInAConfigMap(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	queuedAt := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	teams := []QueuedTeam{{Namespace: "default", Team: "foobar", QueuedAt: queuedAt, Failures: 2}}
+
+	teamsBefore, err := NewQueueCheckpoint(clientset, "multi-juicer").Load(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, teamsBefore)
+	assert.NoError(t, NewQueueCheckpoint(clientset, "multi-juicer").Save(context.Background(), teams))
+
+	loaded, err := NewQueueCheckpoint(clientset, "multi-juicer").Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, teams, loaded)
+}
+
+func TestUnchangedQueueCheckpointsArentWrittenAgain(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	checkpoint := NewQueueCheckpoint(clientset, "multi-juicer")
+
+	assert.NoError(t, checkpoint.Save(context.Background(), []QueuedTeam{{Team: "foobar"}}))
+	assert.NoError(t, checkpoint.Save(context.Background(), []QueuedTeam{{Team: "foobar"}}))
+	assert.NoError(t, checkpoint.Save(context.Background(), []QueuedTeam{}))
+	verbs := []string{}
+	for _, action := range clientset.Actions() {
+		verbs = append(verbs, action.GetVerb())
+	}
+	// the ConfigMap exists after the first checkpoint, so the last one is written as update
+	assert.Equal(t, []string{"create", "create", "update"}, verbs)
+}
+
+func TestResumesTheCheckpointedTeamsWhichCanStillBeUpdated(t *testing.T) {
+	ready := createJuiceShopDeployment("ready", "", "0")
+	ready.Status.ReadyReplicas = 1
+	starting := createJuiceShopDeployment("starting", "", "0")
+	juiceShops := useJuiceShopCache(t, fake.NewSimpleClientset(ready, starting))
+	queue := newTestProgressUpdateQueue()
+	defer queue.ShutDown()
+	queuedAt := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	resumed := resumeProgressUpdateJobs(queue, juiceShops, []QueuedTeam{
+		{Namespace: "default", Team: "ready", QueuedAt: queuedAt},
+		{Namespace: "default", Team: "starting", QueuedAt: queuedAt},
+		{Namespace: "default", Team: "deleted", QueuedAt: queuedAt},
+	})
+
+	assert.Equal(t, 1, resumed)
+	// without cached progress the team is urgent
+	assert.Equal(t, []QueuedTeam{{Namespace: "default", Team: "ready", QueuedAt: queuedAt, Urgent: true}}, queue.Checkpoint())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// clientLimiterIdleTimeout after which the limiter of a client which didn't send any request is dropped
+const clientLimiterIdleTimeout = 10 * time.Minute
+
+// maxClientLimiters caps the number of tracked clients, the least recently seen client is dropped to make room for new ones
+const maxClientLimiters = 10000
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter limits the requests per client, identified by their ip address
+type RateLimiter struct {
+	mutex       sync.Mutex
+	clients     map[string]*clientLimiter
+	limit       rate.Limit
+	burst       int
+	lastCleanup time.Time
+	idleTimeout time.Duration
+	maxClients  int
+	now         func() time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing each client requestsPerSecond requests with bursts of up to burst requests
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		clients:     map[string]*clientLimiter{},
+		limit:       rate.Limit(requestsPerSecond),
+		burst:       burst,
+		idleTimeout: clientLimiterIdleTimeout,
+		maxClients:  maxClientLimiters,
+		now:         time.Now,
+	}
+}
+
+// Allow checks if the client is allowed to send another request right now
+func (r *RateLimiter) Allow(client string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastCleanup) > r.idleTimeout {
+		for key, client := range r.clients {
+			if now.Sub(client.lastSeen) > r.idleTimeout {
+				delete(r.clients, key)
+			}
+		}
+		r.lastCleanup = now
+	}
+
+	limiter, ok := r.clients[client]
+	if !ok {
+		if len(r.clients) >= r.maxClients {
+			r.dropLeastRecentlySeen()
+		}
+		limiter = &clientLimiter{limiter: rate.NewLimiter(r.limit, r.burst)}
+		r.clients[client] = limiter
+	}
+	limiter.lastSeen = now
+	return limiter.limiter.AllowN(now, 1)
+}
+
+// dropLeastRecentlySeen drops the limiter of the client which sent its last request the longest time ago, the mutex has to be held
+func (r *RateLimiter) dropLeastRecentlySeen() {
+	var oldestKey string
+	var oldest time.Time
+	for key, client := range r.clients {
+		if oldestKey == "" || client.lastSeen.Before(oldest) {
+			oldestKey, oldest = key, client.lastSeen
+		}
+	}
+	delete(r.clients, oldestKey)
+}
+
+// Limit rejects requests of clients exceeding their rate limit with `429 Too Many Requests`
+func (r *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client := clientIdentifier(req)
+		if !r.Allow(client) {
+			log.Debugf("Rate limiting request to '%s' from '%s'", req.URL.Path, req.RemoteAddr)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// clientIdentifier identifies clients by their ip. Bearer tokens aren't used, the limited routes are public and don't authenticate them,
+// so every made up token would get a fresh limit.
+func clientIdentifier(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
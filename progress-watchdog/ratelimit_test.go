@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterLimitsEachClientIndependently(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	limiter := NewRateLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("ip:10.0.0.1"))
+	assert.True(t, limiter.Allow("ip:10.0.0.1"))
+	assert.False(t, limiter.Allow("ip:10.0.0.1"), "Should limit after the burst is used up")
+	assert.True(t, limiter.Allow("ip:10.0.0.2"), "Other clients should not be affected")
+
+	now = now.Add(time.Second)
+	assert.True(t, limiter.Allow("ip:10.0.0.1"), "Should allow requests again after the bucket refilled")
+}
+
+func TestRateLimiterDropsIdleClients(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	limiter := NewRateLimiter(1, 1)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("ip:10.0.0.1")
+	now = now.Add(time.Hour)
+	limiter.Allow("ip:10.0.0.2")
+
+	assert.NotContains(t, limiter.clients, "ip:10.0.0.1")
+	assert.Contains(t, limiter.clients, "ip:10.0.0.2")
+}
+
+func TestRateLimiterCapsTheNumberOfClients(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	limiter := NewRateLimiter(1, 1)
+	limiter.maxClients = 2
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("ip:10.0.0.1")
+	now = now.Add(time.Second)
+	limiter.Allow("ip:10.0.0.2")
+	now = now.Add(time.Second)
+	limiter.Allow("ip:10.0.0.3")
+
+	assert.Len(t, limiter.clients, 2)
+	assert.NotContains(t, limiter.clients, "ip:10.0.0.1", "Should drop the least recently seen client")
+}
+
+func TestIdentifiesClientsByIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+	assert.Equal(t, "ip:10.0.0.1", clientIdentifier(req))
+
+	req.Header.Set("Authorization", "Bearer random-token")
+	assert.Equal(t, "ip:10.0.0.1", clientIdentifier(req), "Unauthenticated tokens must not get their own limit")
+}
+
+func TestLimitRespondsWithTooManyRequests(t *testing.T) {
+	handler := NewRateLimiter(1, 1).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RebuildResult the progress of a team after it got rebuilt from its JuiceShop instance
+type RebuildResult struct {
+	Team             string `json:"team"`
+	Namespace        string `json:"namespace"`
+	ChallengesSolved int    `json:"challengesSolved"`
+	// PreviouslySolved is the number of solved challenges cached before the rebuild
+	PreviouslySolved int    `json:"previouslySolved"`
+	Error            string `json:"error,omitempty"`
+}
+
+// RebuildResponse json format of the fleet wide rebuild response
+type RebuildResponse struct {
+	Teams []RebuildResult `json:"teams"`
+}
+
+// rebuildProgress replaces the cached progress of the team with the solved challenges reported by its JuiceShop.
+// Unlike a sync, the cached progress is never applied to the instance, the instance is treated as the source of truth.
+func rebuildProgress(ctx context.Context, clientset kubernetes.Interface, deployment appsv1.Deployment) RebuildResult {
+	previous := teamProgressFromDeployment(deployment)
+	result := RebuildResult{Team: previous.Team, Namespace: previous.Namespace, PreviouslySolved: previous.ChallengesSolved}
+
+	if deployment.Status.ReadyReplicas < 1 {
+		result.Error = errTeamNotReady.Error()
+		return result
+	}
+
+	challenges, err := getChallengeStatuses(ctx, deployment.Namespace, result.Team)
+	if err != nil {
+		log.Warningf("Failed to rebuild progress of team '%s'", result.Team)
+		log.Warning(err)
+		result.Error = err.Error()
+		return result
+	}
+	solved := solvedChallengeIDs(challenges)
+	continueCode, err := EncodeContinueCode(solved)
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to encode ContinueCode: %v", err)
+		return result
+	}
+
+	log.Infof("Rebuilding progress of team '%s' from its JuiceShop: %d challenges solved, %d were cached", result.Team, len(solved), previous.ChallengesSolved)
+	job := progressUpdateJobForDeployment(deployment)
+	if err := cacheContinueCode(clientset, job.Namespace, job.Teamname, job.LastContinueCode, continueCode, job.LastSolves, challengeConfigurationOf(challenges)); err != nil {
+		result.Error = fmt.Sprintf("Failed to save rebuilt progress: %v", err)
+		return result
+	}
+	result.ChallengesSolved = len(solved)
+	return result
+}
+
+// rebuildTeam rebuilds the cached progress of a single team
+func rebuildTeam(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, scoreboardCache *ScoreboardCache) (RebuildResult, error) {
+	deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if err != nil {
+		return RebuildResult{}, err
+	}
+	result := rebuildProgress(ctx, clientset, *deployment)
+	scoreboardCache.Invalidate()
+	return result, nil
+}
+
+// rebuildAllTeams rebuilds the cached progress of every team, failures of single teams are reported in their result
+func rebuildAllTeams(ctx context.Context, clientset kubernetes.Interface, namespace string, scoreboardCache *ScoreboardCache) ([]RebuildResult, error) {
+	juiceShops, err := listJuiceShops(ctx, clientset, namespace, juiceShopSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []RebuildResult{}
+	for _, instance := range juiceShops {
+		results = append(results, rebuildProgress(ctx, clientset, instance))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return teamKey(results[i].Namespace, results[i].Team) < teamKey(results[j].Namespace, results[j].Team)
+	})
+	scoreboardCache.Invalidate()
+	return results, nil
+}
+
+func (s *Server) handleRebuildTeam(w http.ResponseWriter, req *http.Request, namespace, teamname string) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := rebuildTeam(req.Context(), s.clientset, namespace, teamname, s.scoreboardCache)
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if result.Error != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleRebuildAllTeams rebuilds the progress of all teams. Teams which failed to rebuild contain an error in their result
+func (s *Server) handleRebuildAllTeams(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	results, err := rebuildAllTeams(req.Context(), s.clientset, s.namespace, s.scoreboardCache)
+	if err != nil {
+		log.Error("Failed to list JuiceShop deployments to rebuild")
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, RebuildResponse{Teams: results})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func fakeJuiceShopChallenges(t *testing.T, body string) {
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/Challenges/" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+}
+
+func TestRebuildReplacesCachedProgressWithInstanceState(t *testing.T) {
+	fakeJuiceShopChallenges(t, `{"status":"success","data":[{"id":1,"solved":true},{"id":2,"solved":false},{"id":3,"solved":true},{"id":4,"solved":false,"disabledEnv":"Docker"}]}`)
+	deployment := createJuiceShopDeployment("foobar", "corrupted", "12")
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/teams/foobar/rebuild"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	result := RebuildResult{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, RebuildResult{Team: "foobar", Namespace: "default", ChallengesSolved: 2, PreviouslySolved: 12}, result)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	solved, err := ParseContinueCode(updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, solved)
+	assert.Equal(t, "[4]", updated.Annotations["multi-juicer.iteratec.dev/disabledChallenges"])
+}
+
+func TestRebuildAllReportsFailuresPerTeam(t *testing.T) {
+	fakeJuiceShopChallenges(t, `{"data":[{"id":5,"solved":true}]}`)
+	ready := createJuiceShopDeployment("ready", "", "0")
+	ready.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(ready, createJuiceShopDeployment("starting", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("POST", "/api/admin/rebuild"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := RebuildResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []RebuildResult{
+		{Team: "ready", Namespace: "default", ChallengesSolved: 1},
+		{Team: "starting", Namespace: "default", Error: errTeamNotReady.Error()},
+	}, response.Teams)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// ProgressRecoverySource knows the progress of teams outside of the progress store, e.g. from a backup. It is consulted for JuiceShops
+// without cached progress, so that teams whose JuiceShop got deleted and recreated get their progress back.
+type ProgressRecoverySource interface {
+	// RecoverProgress returns the progress records of the team, keyed like the annotations in progressRecordKeys. Only progress recorded
+	// before the JuiceShop was created is returned, newer records belong to the current JuiceShop, e.g. one whose progress was reset.
+	RecoverProgress(ctx context.Context, namespace, teamname string, createdAt time.Time) (map[string]string, bool, error)
+	// String describes the source for logs
+	String() string
+}
+
+// progressRecoverySources are consulted in order for JuiceShops without cached progress, the progress archive and the backup store if they are enabled
+var progressRecoverySources = []ProgressRecoverySource{}
+
+// recoverProgress restores the progress of a team whose JuiceShop was recreated without its cached progress from the first recovery source
+// which knows the team. The recovered progress is saved into the progress store and returned as the last progress of the job, so that it gets
+// applied to the fresh JuiceShop like any other cached progress. Returns the unchanged job if there is nothing to recover.
+// Every JuiceShop is only recovered once, so that resetting the progress of a recovered team doesn't bring the recovered progress back.
+func recoverProgress(job ProgressUpdateJobs, clientset kubernetes.Interface) (ProgressUpdateJobs, error) {
+	if job.LastContinueCode != "" || job.Recovered {
+		return job, nil
+	}
+	ctx := context.Background()
+	for _, source := range progressRecoverySources {
+		records, ok, err := source.RecoverProgress(ctx, job.Namespace, job.Teamname, job.CreatedAt)
+		if err != nil {
+			log.Warningf("Failed to look up the progress of team '%s' in %s: %s", job.Teamname, source, err)
+			continue
+		}
+		if !ok || records[annotation("continueCode")] == "" {
+			continue
+		}
+
+		recovered := map[string]string{}
+		for _, key := range progressRecordKeys() {
+			if value, ok := records[key]; ok {
+				recovered[key] = value
+			}
+		}
+		// only saved if no progress got cached in the meantime, which then is the newer one
+		_, err = progressStore.Save(ctx, clientset, job.Namespace, job.Teamname, recovered, map[string]string{annotation("continueCode"): ""})
+		if errors.Is(err, errProgressChanged) {
+			progressConflicts.Inc()
+			return job, err
+		} else if err != nil {
+			patchErrors.Inc()
+			return job, err
+		}
+		err = patchJuiceShopDeployment(ctx, clientset, job.Namespace, job.Teamname, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					annotation("progressRecoveredAt"): time.Now().UTC().Format(time.RFC3339),
+				},
+			},
+		})
+		if err != nil {
+			return job, err
+		}
+		progressRecoveries.Inc()
+		auditLog.Infof("Recovered progress of team '%s' with %s solved challenges from %s, its JuiceShop was recreated", job.Teamname, recovered[annotation("challengesSolved")], source)
+
+		job.LastContinueCode = recovered[annotation("continueCode")]
+		job.LastSolves = parseSolveTimes(recovered[annotation("solves")])
+		return job, nil
+	}
+	return job, nil
+}
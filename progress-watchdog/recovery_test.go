@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeRestorableJuiceShops makes the JuiceShops start without progress and report the last applied ContinueCode
+func fakeRestorableJuiceShops(t *testing.T) {
+	current := ""
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "PUT" && strings.Contains(req.URL.Path, "/rest/continue-code/apply/"):
+			current = req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		case req.URL.Path == "/rest/continue-code":
+			w.Write([]byte(`{"continueCode":"` + current + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func useBackupForRecovery(t *testing.T, teams ...TeamSnapshot) {
+	dir, err := ioutil.TempDir("", "backups")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	store := NewBackupStore(DirectoryBackupTarget(dir), []byte("secret"), 1)
+	assert.NoError(t, store.Save(context.Background(), Snapshot{Version: snapshotVersion, CreatedAt: eventStart, Namespace: "default", Teams: teams}))
+
+	previous := progressRecoverySources
+	progressRecoverySources = []ProgressRecoverySource{store}
+	t.Cleanup(func() { progressRecoverySources = previous })
+}
+
+func TestRecoversTheProgressOfRecreatedJuiceShopsFromTheBackup(t *testing.T) {
+	fakeRestorableJuiceShops(t)
+	continueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+	useBackupForRecovery(t, TeamSnapshot{Team: "foobar", Deployment: appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		"multi-juicer.iteratec.dev/continueCode":     continueCode,
+		"multi-juicer.iteratec.dev/challengesSolved": "3",
+		"multi-juicer.iteratec.dev/solves":           `{"1":"2021-05-01T09:00:00Z","2":"2021-05-01T09:10:00Z","3":"2021-05-01T09:20:00Z"}`,
+		"multi-juicer.iteratec.dev/passcode":         "not-recovered",
+	}}}})
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.CreationTimestamp = metav1.NewTime(eventStart.Add(time.Hour))
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+	recoveries := testutil.ToFloat64(progressRecoveries)
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, ApplyCode, state)
+	assert.Equal(t, recoveries+1, testutil.ToFloat64(progressRecoveries))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, continueCode, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	assert.Equal(t, time.Date(2021, 5, 1, 9, 10, 0, 0, time.UTC), parseSolveTimes(updated.Annotations["multi-juicer.iteratec.dev/solves"])[2].UTC(), "Should keep the original solve times")
+	assert.Empty(t, updated.Annotations["multi-juicer.iteratec.dev/passcode"], "Should only recover the progress records")
+
+	assert.NoError(t, resetTeam(context.Background(), clientset, "default", "foobar", time.Now()))
+	updated, err = clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = recoverProgress(progressUpdateJobForDeployment(*updated), clientset)
+	assert.NoError(t, err)
+	assert.Equal(t, recoveries+1, testutil.ToFloat64(progressRecoveries), "Should only recover the progress of a JuiceShop once")
+}
+
+func TestDoesntRecoverTheProgressOfJuiceShopsOlderThanTheBackup(t *testing.T) {
+	fakeRestorableJuiceShops(t)
+	continueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+	useBackupForRecovery(t, TeamSnapshot{Team: "foobar", Deployment: appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		"multi-juicer.iteratec.dev/continueCode": continueCode,
+	}}}})
+	// e.g. a team whose progress was reset after the backup
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.CreationTimestamp = metav1.NewTime(eventStart.Add(-time.Hour))
+	deployment.Status.ReadyReplicas = 1
+	clientset := fake.NewSimpleClientset(deployment)
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, NoOp, state)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+}
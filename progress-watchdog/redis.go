@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newRedisClientFromEnv creates the client configured via `REDIS_ADDRESS`, `REDIS_PASSWORD`, `REDIS_DATABASE` and `REDIS_TIMEOUT`.
+// The timeout limits connecting and every command, so that a hung Redis can't block the workers.
+func newRedisClientFromEnv() *redis.Client {
+	database, err := strconv.Atoi(getEnv("REDIS_DATABASE", "0"))
+	if err != nil || database < 0 {
+		panic(fmt.Sprintf("Invalid REDIS_DATABASE: %s", getEnv("REDIS_DATABASE", "0")))
+	}
+	timeout, err := time.ParseDuration(getEnv("REDIS_TIMEOUT", "2s"))
+	if err != nil || timeout <= 0 {
+		panic(fmt.Sprintf("Invalid REDIS_TIMEOUT: %s", getEnv("REDIS_TIMEOUT", "2s")))
+	}
+	return newRedisClient(getEnv("REDIS_ADDRESS", "redis:6379"), getEnv("REDIS_PASSWORD", ""), database, timeout)
+}
+
+func newRedisClient(address, password string, database int, timeout time.Duration) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:         address,
+		Password:     password,
+		DB:           database,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		// the store retries saves itself, based on the progress it read
+		MaxRetries: -1,
+	})
+}
+
+// RedisProgressStore keeps the progress records in a Redis hash per team (`{prefix}:{namespace}:{team}`), with fields named like the ConfigMap keys.
+// The hashes outlive the JuiceShop deployments and can be read by other components, e.g. the balancer or a scoreboard, without the kubernetes api.
+// Only challengesSolved stays on the deployment as well, teams without hash are read from their annotations and migrated with their next save.
+type RedisProgressStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// redisSaveAttempts limits the retries of saves whose transaction was aborted by a concurrent write of the hash
+const redisSaveAttempts = 5
+
+// NewRedisProgressStore creates a RedisProgressStore, failing if Redis can't be reached. The client is closed once the context is cancelled.
+func NewRedisProgressStore(ctx context.Context, client *redis.Client, keyPrefix string) (*RedisProgressStore, error) {
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("Failed to reach Redis: %v", err)
+	}
+	go func() {
+		<-ctx.Done()
+		client.Close()
+	}()
+	return &RedisProgressStore{client: client, keyPrefix: keyPrefix}, nil
+}
+
+func (s *RedisProgressStore) key(namespace, teamname string) string {
+	return fmt.Sprintf("%s:%s:%s", s.keyPrefix, namespace, teamname)
+}
+
+// redisRecords returns the progress records of the fields of a hash
+func redisRecords(hash map[string]string) map[string]string {
+	records := map[string]string{}
+	for _, key := range progressRecordKeys() {
+		if value, ok := hash[configMapKey(key)]; ok {
+			records[key] = value
+		}
+	}
+	return records
+}
+
+// Records returns the progress records of the hash of the team, falling back to the annotations of teams which weren't migrated yet or if Redis is unavailable
+func (s *RedisProgressStore) Records(deployment appsv1.Deployment) map[string]string {
+	hash, err := s.client.HGetAll(context.Background(), s.key(deployment.Namespace, deployment.Labels["team"])).Result()
+	if err != nil {
+		log.Warningf("Failed to read progress of team '%s' from Redis: %s", deployment.Labels["team"], err)
+		return AnnotationProgressStore{}.Records(deployment)
+	}
+	records := redisRecords(hash)
+	if len(records) == 0 {
+		return AnnotationProgressStore{}.Records(deployment)
+	}
+	return records
+}
+
+// Save writes the records into the hash of the team and mirrors challengesSolved to the deployment.
+// The first save of a team migrates its progress annotations into the hash and removes them from the deployment.
+// The expected records are checked against the hash, which is watched so that the transaction writing the records is aborted and retried on concurrent writes.
+func (s *RedisProgressStore) Save(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records, expected map[string]string) (*appsv1.Deployment, error) {
+	key := s.key(namespace, teamname)
+	var stored map[string]string
+	migrated := false
+	save := func(tx *redis.Tx) error {
+		hash, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		stored = redisRecords(hash)
+		migrated = len(stored) == 0
+		if migrated {
+			deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+			if err != nil {
+				return err
+			}
+			stored = AnnotationProgressStore{}.Records(*deployment)
+		}
+		if err := checkExpectedRecords(stored, expected); err != nil {
+			return err
+		}
+
+		for key, value := range records {
+			stored[key] = value
+		}
+		fields := map[string]interface{}{}
+		for key, value := range stored {
+			fields[configMapKey(key)] = value
+		}
+		// the transaction is aborted if the watched hash was written in the meantime
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, fields)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := s.client.Watch(ctx, save, key)
+		if err == nil {
+			break
+		} else if errors.Is(err, errProgressChanged) {
+			return nil, err
+		} else if err != redis.TxFailedErr {
+			return nil, fmt.Errorf("Failed to save progress to Redis: %v", err)
+		}
+		if attempt == redisSaveAttempts {
+			return nil, fmt.Errorf("Failed to save progress to Redis: the progress of the team was written concurrently %d times", attempt)
+		}
+	}
+	if migrated {
+		log.Infof("Moved the progress of team '%s' into Redis", teamname)
+	}
+	return mirrorProgress(ctx, clientset, namespace, teamname, records, stored, migrated)
+}
+
+// Delete removes the hash of the team and the progress annotations of its deployment
+func (s *RedisProgressStore) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) error {
+	if err := s.client.Del(ctx, s.key(namespace, teamname)).Err(); err != nil {
+		return fmt.Errorf("Failed to delete progress from Redis: %v", err)
+	}
+	return AnnotationProgressStore{}.Delete(ctx, clientset, namespace, teamname)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// startRedis starts an in-memory Redis server and returns a client connected to it
+func startRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := newRedisClient(server.Addr(), "", 0, time.Second)
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// execHook calls beforeExec before transactions are sent to Redis, e.g. to write the watched hash concurrently
+type execHook struct {
+	beforeExec func()
+}
+
+func (h execHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h execHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h execHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if len(cmds) > 0 && cmds[0].Name() == "multi" {
+		h.beforeExec()
+	}
+	return ctx, nil
+}
+
+func (h execHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisProgressStoreMigratesProgressAnnotationsOnSave(t *testing.T) {
+	server, client := startRedis(t)
+	deployment := createJuiceShopDeployment("foobar", "abc", "3")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	store, err := NewRedisProgressStore(context.Background(), client, "multi-juicer:progress")
+	assert.NoError(t, err)
+
+	updated, err := store.Save(context.Background(), clientset, "default", "foobar", map[string]string{
+		"multi-juicer.iteratec.dev/continueCode":     "def",
+		"multi-juicer.iteratec.dev/challengesSolved": "4",
+	}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "4", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/continueCode")
+	assert.NotContains(t, updated.Annotations, "multi-juicer.iteratec.dev/solves")
+	assert.Equal(t, map[string]string{
+		"continueCode":     "def",
+		"challengesSolved": "4",
+		"solves":           `{"1":"2021-05-01T10:00:00Z"}`,
+	}, hashOf(server, "multi-juicer:progress:default:foobar"))
+	assert.Equal(t, "def", store.Records(*updated)["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestRedisProgressStoreDoesntOverwriteProgressChangedConcurrently(t *testing.T) {
+	_, client := startRedis(t)
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	store, err := NewRedisProgressStore(context.Background(), client, "multi-juicer:progress")
+	assert.NoError(t, err)
+	_, err = store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+	assert.NoError(t, err)
+
+	_, err = store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "stale"}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+
+	assert.ErrorIs(t, err, errProgressChanged)
+}
+
+func TestRedisProgressStoreRetriesTransactionsAbortedByConcurrentWrites(t *testing.T) {
+	server, client := startRedis(t)
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	store, err := NewRedisProgressStore(context.Background(), client, "multi-juicer:progress")
+	assert.NoError(t, err)
+	concurrentWrites := 1
+	client.AddHook(execHook{beforeExec: func() {
+		if concurrentWrites > 0 {
+			concurrentWrites--
+			server.HSet("multi-juicer:progress:default:foobar", "continueCode", "xyz")
+		}
+	}})
+
+	_, err = store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, map[string]string{"multi-juicer.iteratec.dev/continueCode": "abc"})
+
+	assert.ErrorIs(t, err, errProgressChanged, "Should recheck the expected records against the concurrently written hash")
+	assert.Equal(t, "xyz", server.HGet("multi-juicer:progress:default:foobar", "continueCode"))
+}
+
+func TestRedisProgressStoreFallsBackToTheAnnotationsIfRedisIsUnavailable(t *testing.T) {
+	_, client := startRedis(t)
+	store, err := NewRedisProgressStore(context.Background(), client, "multi-juicer:progress")
+	assert.NoError(t, err)
+	store.client = newRedisClient("127.0.0.1:1", "", 0, 100*time.Millisecond)
+
+	records := store.Records(*createJuiceShopDeployment("foobar", "abc", "3"))
+
+	assert.Equal(t, "abc", records["multi-juicer.iteratec.dev/continueCode"])
+}
+
+func TestRedisProgressStoreDeletesTheProgressOfTheTeam(t *testing.T) {
+	server, client := startRedis(t)
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	store, err := NewRedisProgressStore(context.Background(), client, "multi-juicer:progress")
+	assert.NoError(t, err)
+	_, err = store.Save(context.Background(), clientset, "default", "foobar", map[string]string{"multi-juicer.iteratec.dev/continueCode": "def"}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Delete(context.Background(), clientset, "default", "foobar"))
+
+	assert.False(t, server.Exists("multi-juicer:progress:default:foobar"))
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, store.Records(*updated))
+}
+
+func hashOf(server *miniredis.Miniredis, key string) map[string]string {
+	hash := map[string]string{}
+	fields, _ := server.HKeys(key)
+	for _, field := range fields {
+		hash[field] = server.HGet(key, field)
+	}
+	return hash
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// JuiceShopReplica a ready pod of the JuiceShop deployment of a team
+type JuiceShopReplica struct {
+	Name string
+	URL  string
+}
+
+// listReadyReplicas lists the ready JuiceShop pods of the team, so that they can be contacted directly instead of through the load balancing service
+func listReadyReplicas(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string) ([]JuiceShopReplica, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: teamSelector(teamname),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := []JuiceShopReplica{}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || !isPodReady(pod) {
+			continue
+		}
+		replicas = append(replicas, JuiceShopReplica{
+			Name: pod.Name,
+			URL:  fmt.Sprintf("%s://%s:%d", juiceShopScheme, pod.Status.PodIP, juiceShopPort),
+		})
+	}
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+	return replicas, nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// mergeSolvedChallenges returns the sorted union of the solved challenges
+func mergeSolvedChallenges(solvedChallenges ...[]int) []int {
+	merged := []int{}
+	for _, solved := range solvedChallenges {
+		for _, challenge := range solved {
+			if !contains(merged, challenge) {
+				merged = append(merged, challenge)
+			}
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
+
+// runMultiReplicaProgressUpdateJob syncs the progress of a team running multiple JuiceShop replicas.
+// The solves of all replicas and the cached ones are merged, applied to every replica missing some of them and then cached.
+// Replicas whose solves differ from each other get reported as diverged.
+func runMultiReplicaProgressUpdateJob(job ProgressUpdateJobs, clientset kubernetes.Interface, scoreboardCache *ScoreboardCache) (UpdateState, error) {
+	log.Debugf("Running ProgressUpdateJob for team '%s' with %d replicas", job.Teamname, job.Replicas)
+	replicas, err := listReadyReplicas(context.Background(), clientset, job.Namespace, job.Teamname)
+	if err != nil {
+		log.Warningf("Failed to list JuiceShop replicas of team '%s'", job.Teamname)
+		log.Warning(err)
+		return "", err
+	}
+
+	lastSolvedChallenges, _ := ParseContinueCode(job.LastContinueCode)
+	solvedByReplica := map[string][]int{}
+	for _, replica := range replicas {
+		ctx, span := startClientSpan(job.Context(), "juiceshop.fetch-continue-code", replica.URL)
+		span.SetAttributes(attribute.String("replica", replica.Name))
+		continueCode, err := fetchContinueCode(ctx, replica.URL)
+		endSpan(span, err)
+		if err != nil {
+			log.Warningf("Failed to fetch ContinueCode of replica '%s' of team '%s'", replica.Name, job.Teamname)
+			log.Warning(err)
+			return "", err
+		}
+		solvedByReplica[replica.Name], _ = ParseContinueCode(continueCode)
+	}
+
+	currentSolvedChallenges := []int{}
+	for _, solved := range solvedByReplica {
+		currentSolvedChallenges = mergeSolvedChallenges(currentSolvedChallenges, solved)
+	}
+	merged := mergeSolvedChallenges(currentSolvedChallenges, lastSolvedChallenges)
+	mergedContinueCode, err := EncodeContinueCode(merged)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode merged ContinueCode: %v", err)
+	}
+
+	diverged := []string{}
+	for _, replica := range replicas {
+		if CompareChallengeStates(solvedByReplica[replica.Name], currentSolvedChallenges) != NoOp {
+			diverged = append(diverged, fmt.Sprintf("%s (%d solved)", replica.Name, len(solvedByReplica[replica.Name])))
+		}
+	}
+	if len(diverged) > 0 {
+		log.Warningf("Replicas of team '%s' diverged, %d challenges are solved in total but not on: %s", job.Teamname, len(currentSolvedChallenges), strings.Join(diverged, ", "))
+	}
+
+	updateState := CompareChallengeStates(merged, lastSolvedChallenges)
+	for _, replica := range replicas {
+		if CompareChallengeStates(solvedByReplica[replica.Name], merged) == ApplyCode {
+			log.Infof("Applying merged ContinueCode to replica '%s' of team '%s'", replica.Name, job.Teamname)
+			ctx, span := startClientSpan(job.Context(), "juiceshop.apply-continue-code", replica.URL)
+			span.SetAttributes(attribute.String("replica", replica.Name))
+			err := putContinueCode(ctx, replica.URL, mergedContinueCode)
+			endSpan(span, err)
+			if err != nil {
+				log.Warningf("Failed to apply merged ContinueCode to replica '%s' of team '%s'", replica.Name, job.Teamname)
+				log.Warning(err)
+				continue
+			}
+			restoresApplied.Inc()
+			updateState = ApplyCode
+		}
+	}
+
+	if CompareChallengeStates(merged, lastSolvedChallenges) == UpdateCache {
+		if err := cacheContinueCodeTraced(job, clientset, mergedContinueCode); err != nil {
+			return updateState, err
+		}
+		scoreboardCache.Invalidate()
+	}
+	return updateState, nil
+}
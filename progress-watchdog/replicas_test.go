@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createJuiceShopPod(name, team, podIP string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "juice-shop", "team": team},
+		},
+		Status: corev1.PodStatus{
+			PodIP:      podIP,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestListReadyReplicasSkipsPodsWhichArentReady(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopPod("b", "foobar", "10.0.0.2", true),
+		createJuiceShopPod("a", "foobar", "10.0.0.1", true),
+		createJuiceShopPod("c", "foobar", "10.0.0.3", false),
+		createJuiceShopPod("d", "other", "10.0.0.4", true),
+	)
+
+	replicas, err := listReadyReplicas(context.Background(), clientset, "default", "foobar")
+	assert.NoError(t, err)
+	assert.Equal(t, []JuiceShopReplica{
+		{Name: "a", URL: "http://10.0.0.1:3000"},
+		{Name: "b", URL: "http://10.0.0.2:3000"},
+	}, replicas)
+}
+
+func TestMergeSolvedChallenges(t *testing.T) {
+	assert.Equal(t, []int{1, 2, 3, 5}, mergeSolvedChallenges([]int{3, 1}, []int{2, 3}, []int{5}))
+	assert.Equal(t, []int{}, mergeSolvedChallenges())
+}
+
+func TestMultiReplicaJobAppliesMergedProgressToDivergedReplicas(t *testing.T) {
+	codeA, _ := EncodeContinueCode([]int{1, 2})
+	codeB, _ := EncodeContinueCode([]int{2, 3})
+	merged, _ := EncodeContinueCode([]int{1, 2, 3})
+	codes := map[string]string{"10.0.0.1:3000": codeA, "10.0.0.2:3000": codeB}
+
+	var mutex sync.Mutex
+	applied := map[string]string{}
+	fakeJuiceShopAPI(t, func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if req.Method == http.MethodPut {
+			applied[req.URL.Host] = strings.TrimPrefix(req.URL.Path, "/rest/continue-code/apply/")
+			return
+		}
+		if req.URL.Path == "/rest/continue-code" {
+			w.Write([]byte(`{"continueCode":"` + codes[req.URL.Host] + `"}`))
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	})
+
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 2
+	clientset := fake.NewSimpleClientset(
+		deployment,
+		createJuiceShopPod("a", "foobar", "10.0.0.1", true),
+		createJuiceShopPod("b", "foobar", "10.0.0.2", true),
+	)
+
+	state, err := runProgressUpdateJob(progressUpdateJobForDeployment(*deployment), clientset, NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, ApplyCode, state)
+	assert.Equal(t, map[string]string{"10.0.0.1:3000": merged, "10.0.0.2:3000": merged}, applied)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, merged, updated.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "3", updated.Annotations["multi-juicer.iteratec.dev/challengesSolved"])
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// resultsWorkbook creates the final results of the event as Excel workbook: the standings, one sheet per ranked team listing its solves and the challenge statistics.
+// The teams must already be scored.
+func resultsWorkbook(teams []TeamProgress, scoring Scoring, location *time.Location) *Workbook {
+	workbook := NewWorkbook(location)
+	ranked := rankTeams(teams)
+	progressByTeam := map[string]TeamProgress{}
+	for _, team := range teams {
+		progressByTeam[team.Key()] = team
+	}
+
+	standings := [][]interface{}{{"Position", "Team", "Score", "Challenges solved", "Created at", "Last solve"}}
+	for _, team := range ranked {
+		progress := progressByTeam[teamKey(team.Namespace, team.Team)]
+		var lastSolve time.Time
+		for _, solvedAt := range progress.Solves {
+			if solvedAt.After(lastSolve) {
+				lastSolve = solvedAt
+			}
+		}
+		standings = append(standings, []interface{}{team.Position, team.Team, team.Score, team.ChallengesSolved, progress.CreatedAt, lastSolve})
+	}
+	workbook.AddSheet("Standings", standings)
+
+	challenges := [][]interface{}{{"Challenge", "Solves", "First solve", "First solved by", "Last solve"}}
+	for _, challenge := range computeChallengeDistribution(teams) {
+		first := challenge.Solves[0]
+		last := challenge.Solves[len(challenge.Solves)-1]
+		challenges = append(challenges, []interface{}{challenge.Challenge, len(challenge.Solves), first.SolvedAt, first.Team, last.SolvedAt})
+	}
+	workbook.AddSheet("Challenges", challenges)
+
+	for _, team := range ranked {
+		progress := progressByTeam[teamKey(team.Namespace, team.Team)]
+		solved := []int{}
+		for challenge := range progress.Solves {
+			solved = append(solved, challenge)
+		}
+		sort.Slice(solved, func(i, j int) bool {
+			if !progress.Solves[solved[i]].Equal(progress.Solves[solved[j]]) {
+				return progress.Solves[solved[i]].Before(progress.Solves[solved[j]])
+			}
+			return solved[i] < solved[j]
+		})
+
+		rows := [][]interface{}{{"Challenge", "Solved at", "Tutorial", "Points"}}
+		for _, challenge := range solved {
+			solvedAt := progress.Solves[challenge]
+			rows = append(rows, []interface{}{challenge, solvedAt, contains(progress.TutorialChallenges, challenge), scoring.Points(progress, challenge, solvedAt)})
+		}
+		workbook.AddSheet(fmt.Sprintf("%d. %s", team.Position, team.Team), rows)
+	}
+	return workbook
+}
+
+// writeResultsXLSX writes the final results of the event as xlsx file, see resultsWorkbook
+func writeResultsXLSX(writer io.Writer, teams []TeamProgress, scoring Scoring, location *time.Location) error {
+	if _, err := resultsWorkbook(teams, scoring, location).WriteTo(writer); err != nil {
+		return fmt.Errorf("Failed to write results workbook: %v", err)
+	}
+	return nil
+}
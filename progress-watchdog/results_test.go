@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResultsWorkbookContainsStandingsChallengesAndTeams(t *testing.T) {
+	scoring := Scoring{TutorialSolveWeight: 0.5}
+	teams := createStatisticsTeams()
+	teams[1].TutorialChallenges = []int{1}
+	for i := range teams {
+		teams[i].Score = scoring.Score(teams[i])
+	}
+
+	workbook := resultsWorkbook(teams, scoring, time.UTC)
+	names := []string{}
+	for _, sheet := range workbook.sheets {
+		names = append(names, sheet.name)
+	}
+	assert.Equal(t, []string{"Standings", "Challenges", "1. first", "2. second", "3. third"}, names)
+
+	assert.Equal(t, []interface{}{1, "first", 3.0, 0, eventStart, eventStart.Add(80 * time.Minute)}, workbook.sheets[0].rows[1])
+	assert.Equal(t, []interface{}{1, 2, eventStart.Add(10 * time.Minute), "first", eventStart.Add(30 * time.Minute)}, workbook.sheets[1].rows[1])
+	assert.Equal(t, [][]interface{}{
+		{"Challenge", "Solved at", "Tutorial", "Points"},
+		{1, eventStart.Add(30 * time.Minute), true, 0.5},
+		{2, eventStart.Add(75 * time.Minute), false, 1.0},
+	}, workbook.sheets[3].rows)
+}
+
+func TestStatisticsEndpointServesResultsWorkbook(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/statistics?format=xlsx"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rr.Header().Get("Content-Type"))
+	assert.Contains(t, readWorkbookPart(t, rr.Body.Bytes(), "xl/workbook.xml"), `<sheet name="1. foobar" sheetId="3" r:id="rId3"/>`)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// ReviewStatus the state of a flagged solve in the review queue
+type ReviewStatus string
+
+const (
+	// ReviewPending solves were flagged and don't count until an admin approves them
+	ReviewPending ReviewStatus = "pending"
+	// ReviewApproved solves count like any other solve
+	ReviewApproved ReviewStatus = "approved"
+	// ReviewRejected solves never count
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// SolveReview records why a solve was flagged and how it was reviewed
+type SolveReview struct {
+	Status       ReviewStatus `json:"status"`
+	FlaggedBy    string       `json:"flaggedBy"`
+	FlagReason   string       `json:"flagReason"`
+	FlaggedAt    time.Time    `json:"flaggedAt"`
+	ReviewedBy   string       `json:"reviewedBy,omitempty"`
+	ReviewReason string       `json:"reviewReason,omitempty"`
+	ReviewedAt   *time.Time   `json:"reviewedAt,omitempty"`
+}
+
+// SolveReviews maps challenge ids to the review of the teams solve of the challenge
+type SolveReviews map[int]SolveReview
+
+// errChallengeNotSolved is returned when a solve is flagged which the team hasn't made
+var errChallengeNotSolved = errors.New("Team hasn't solved the challenge")
+
+// errSolveNotFlagged is returned when a solve is reviewed which wasn't flagged
+var errSolveNotFlagged = errors.New("Solve wasn't flagged for review")
+
+// parseSolveReviews decodes the json encoded solveReviews annotation, returning no reviews if it is empty or invalid
+func parseSolveReviews(annotation string) SolveReviews {
+	reviews := SolveReviews{}
+	if annotation == "" {
+		return reviews
+	}
+	if err := json.Unmarshal([]byte(annotation), &reviews); err != nil {
+		log.Warningf("Could not decode solve reviews annotation '%s'", annotation)
+		return SolveReviews{}
+	}
+	return reviews
+}
+
+// Counts checks if the solve of the challenge counts towards the score, which it doesn't while it is pending or after it got rejected
+func (r SolveReviews) Counts(challenge int) bool {
+	review, ok := r[challenge]
+	return !ok || review.Status == ReviewApproved
+}
+
+// reviewSolve flags a solve of a team for review (status pending) or records the approval / rejection of a flagged solve.
+// The reviews are patched based on the resourceVersion of the deployment and retried on conflicts, so that concurrent reviews
+// of other challenges of the team aren't lost.
+func reviewSolve(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, challenge int, status ReviewStatus, reviewer, reason string, now time.Time) (SolveReview, error) {
+	var review SolveReview
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := workloads.Get(ctx, clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+		if err != nil {
+			return err
+		}
+		reviews := parseSolveReviews(deployment.Annotations[annotation("solveReviews")])
+		var flagged bool
+		review, flagged = reviews[challenge]
+
+		switch status {
+		case ReviewPending:
+			if _, solved := parseSolveTimes(progressStore.Records(*deployment)[annotation("solves")])[challenge]; !solved {
+				return errChallengeNotSolved
+			}
+			review = SolveReview{Status: ReviewPending, FlaggedBy: reviewer, FlagReason: reason, FlaggedAt: now.UTC()}
+		default:
+			if !flagged {
+				return errSolveNotFlagged
+			}
+			reviewedAt := now.UTC()
+			review.Status = status
+			review.ReviewedBy = reviewer
+			review.ReviewReason = reason
+			review.ReviewedAt = &reviewedAt
+		}
+		reviews[challenge] = review
+
+		encoded, err := json.Marshal(reviews)
+		if err != nil {
+			return err
+		}
+		patch, err := resourceVersionPatch(deployment.ResourceVersion, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{
+					annotation("solveReviews"): string(encoded),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = workloads.Patch(ctx, clientset, namespace, deployment.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return SolveReview{}, err
+	}
+	return review, nil
+}
+
+// ReviewQueueEntry a reviewed solve listed in the review queue
+type ReviewQueueEntry struct {
+	Team      string `json:"team"`
+	Namespace string `json:"namespace"`
+	Challenge int    `json:"challenge"`
+	SolveReview
+}
+
+// ReviewQueueResponse json format of the review queue response
+type ReviewQueueResponse struct {
+	Reviews []ReviewQueueEntry `json:"reviews"`
+}
+
+// handleReviewQueue lists the flagged solves, by default only the ones still pending. Use `status=all` to list all reviews
+func (s *Server) handleReviewQueue(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = string(ReviewPending)
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	entries := []ReviewQueueEntry{}
+	for _, team := range teams {
+		for challenge, review := range team.Reviews {
+			if status == "all" || string(review.Status) == status {
+				entries = append(entries, ReviewQueueEntry{Team: team.Team, Namespace: team.Namespace, Challenge: challenge, SolveReview: review})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FlaggedAt.Before(entries[j].FlaggedAt) })
+
+	writeJSON(w, ReviewQueueResponse{Reviews: entries})
+}
+
+// ReviewRequest json format of the flag, approve and reject requests
+type ReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+// handleReviewSolve flags, approves or rejects a solve, e.g. `POST /api/admin/teams/{team}/solves/{challenge}/reject`.
+// The action and its reason are written to the audit log.
+func (s *Server) handleReviewSolve(w http.ResponseWriter, req *http.Request, namespace, teamname, challengeID, action string) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	challenge, err := strconv.Atoi(challengeID)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	statuses := map[string]ReviewStatus{"flag": ReviewPending, "approve": ReviewApproved, "reject": ReviewRejected}
+	status, ok := statuses[action]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	body := ReviewRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" && status != ReviewApproved {
+		http.Error(w, "A reason is required to flag or reject a solve", http.StatusBadRequest)
+		return
+	}
+
+	principal := principalFromContext(req.Context())
+	review, err := reviewSolve(req.Context(), s.clientset, namespace, teamname, challenge, status, principal.Name, body.Reason, time.Now())
+	if k8serrors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err == errChallengeNotSolved || err == errSolveNotFlagged {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to review solve of challenge %d by team '%s'", challenge, teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.scoreboardCache.Invalidate()
+	auditLog.Infof("'%s' (role '%s') set review of challenge %d solved by team '%s' to '%s', reason: '%s'", principal.Name, principal.Role, challenge, teamname, review.Status, body.Reason)
+
+	writeJSON(w, review)
+}
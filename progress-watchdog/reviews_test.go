@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func signedJSONRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	SignRequest(req, []byte("secret"), time.Now())
+	return req
+}
+
+func createReviewTestServer() http.Handler {
+	deployment := createJuiceShopDeployment("foobar", "abc", "2")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-04-10T10:00:00Z","2":"2021-04-10T11:00:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	return newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+}
+
+func getProgress(t *testing.T, server http.Handler) TeamProgress {
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar"))
+	progress := TeamProgress{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &progress))
+	return progress
+}
+
+func TestFlaggedSolvesOnlyCountAfterApproval(t *testing.T) {
+	server := createReviewTestServer()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/2/flag", `{"reason":"solved within a second"}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	progress := getProgress(t, server)
+	assert.Equal(t, 1, progress.ChallengesSolved)
+	assert.NotContains(t, progress.Solves, 2)
+	assert.Equal(t, ReviewPending, progress.Reviews[2].Status)
+	assert.Equal(t, "multi-juicer-service", progress.Reviews[2].FlaggedBy)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/2/approve", ""))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	progress = getProgress(t, server)
+	assert.Equal(t, 2, progress.ChallengesSolved)
+	assert.Contains(t, progress.Solves, 2)
+	assert.Equal(t, ReviewApproved, progress.Reviews[2].Status)
+}
+
+func TestRejectedSolvesDontCount(t *testing.T) {
+	server := createReviewTestServer()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/1/flag", `{"reason":"shared flag"}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/1/reject", `{"reason":"confirmed"}`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	review := SolveReview{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &review))
+	assert.Equal(t, ReviewRejected, review.Status)
+	assert.Equal(t, "confirmed", review.ReviewReason)
+	assert.NotNil(t, review.ReviewedAt)
+
+	assert.Equal(t, 1, getProgress(t, server).ChallengesSolved)
+}
+
+func TestReviewsRequireAReasonToFlagOrReject(t *testing.T) {
+	server := createReviewTestServer()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/1/flag", `{}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestReviewsRejectUnsolvedOrUnflaggedChallenges(t *testing.T) {
+	server := createReviewTestServer()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/42/flag", `{"reason":"suspicious"}`))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/1/approve", ""))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestReviewQueueListsPendingSolves(t *testing.T) {
+	server := createReviewTestServer()
+	for _, target := range []string{"/api/admin/teams/foobar/solves/1/flag", "/api/admin/teams/foobar/solves/2/flag"} {
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, signedJSONRequest("POST", target, `{"reason":"suspicious"}`))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedJSONRequest("POST", "/api/admin/teams/foobar/solves/1/approve", ""))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/admin/reviews"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := ReviewQueueResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response.Reviews, 1)
+	assert.Equal(t, "foobar", response.Reviews[0].Team)
+	assert.Equal(t, 2, response.Reviews[0].Challenge)
+	assert.Equal(t, "suspicious", response.Reviews[0].FlagReason)
+}
+
+func TestConcurrentReviewsOfATeamAreNotLost(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "2")
+	deployment.ResourceVersion = "1"
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-04-10T10:00:00Z","2":"2021-04-10T11:00:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	patches := 0
+	clientset.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if patches++; patches > 1 {
+			return false, nil, nil
+		}
+		// another organizer flags challenge 1 between the get and the patch of this review
+		concurrent := deployment.DeepCopy()
+		concurrent.ResourceVersion = "2"
+		concurrent.Annotations["multi-juicer.iteratec.dev/solveReviews"] = `{"1":{"status":"pending","flaggedBy":"other","flagReason":"shared flag","flaggedAt":"2021-04-10T12:00:00Z"}}`
+		assert.NoError(t, clientset.Tracker().Update(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, concurrent, "default"))
+		return true, nil, k8serrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, deployment.Name, nil)
+	})
+
+	_, err := reviewSolve(context.Background(), clientset, "default", "foobar", 2, ReviewPending, "admin", "solved within a second", time.Now())
+	assert.NoError(t, err)
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	reviews := parseSolveReviews(updated.Annotations["multi-juicer.iteratec.dev/solveReviews"])
+	assert.Equal(t, 2, patches, "Should retry the patch after the conflict")
+	assert.Equal(t, "other", reviews[1].FlaggedBy, "Should keep the concurrent review")
+	assert.Equal(t, "admin", reviews[2].FlaggedBy)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is appended to the name of rotated log files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is a log file which gets rotated once it exceeds its max size or rotation interval.
+// Rotated files are kept next to it, suffixed with their rotation time, until they exceed the max backups or max age.
+type RotatingFile struct {
+	Path string
+	// MaxSize in bytes after which the file gets rotated, 0 disables size based rotation
+	MaxSize int64
+	// RotationInterval after which the file gets rotated, 0 disables time based rotation
+	RotationInterval time.Duration
+	// MaxBackups is the number of rotated files kept, 0 keeps all of them
+	MaxBackups int
+	// MaxAge after which rotated files get deleted, 0 keeps them forever
+	MaxAge time.Duration
+
+	now      func() time.Time
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile opens the log file, appending to it if it already exists
+func OpenRotatingFile(path string, maxSize int64, rotationInterval time.Duration, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	file := &RotatingFile{
+		Path:             path,
+		MaxSize:          maxSize,
+		RotationInterval: rotationInterval,
+		MaxBackups:       maxBackups,
+		MaxAge:           maxAge,
+		now:              time.Now,
+	}
+	if err := file.open(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return fmt.Errorf("Failed to create log directory: %v", err)
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to stat log file: %v", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// Write implements io.Writer, rotating the file before the write if required
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	sizeExceeded := f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize
+	intervalExceeded := f.RotationInterval > 0 && f.now().Sub(f.openedAt) >= f.RotationInterval
+	if sizeExceeded || intervalExceeded {
+		if err := f.rotate(); err != nil {
+			// keep writing into the current file rather than losing the logs
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file: %s\n", err)
+		}
+	}
+
+	written, err := f.file.Write(p)
+	f.size += int64(written)
+	return written, err
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.%s", f.Path, f.now().UTC().Format(backupTimeFormat))
+	if err := os.Rename(f.Path, backup); err != nil {
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.removeExpiredBackups()
+	return nil
+}
+
+// backups returns the rotated files, newest first
+func (f *RotatingFile) backups() []string {
+	matches, err := filepath.Glob(f.Path + ".*")
+	if err != nil {
+		return nil
+	}
+	backups := []string{}
+	for _, match := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(match, f.Path+".")); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	// the time format sorts lexicographically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+func (f *RotatingFile) removeExpiredBackups() {
+	for i, backup := range f.backups() {
+		rotatedAt, _ := time.Parse(backupTimeFormat, strings.TrimPrefix(backup, f.Path+"."))
+		tooMany := f.MaxBackups > 0 && i >= f.MaxBackups
+		tooOld := f.MaxAge > 0 && f.now().Sub(rotatedAt) > f.MaxAge
+		if tooMany || tooOld {
+			if err := os.Remove(backup); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove rotated log file '%s': %s\n", backup, err)
+			}
+		}
+	}
+}
+
+// Close closes the current log file
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
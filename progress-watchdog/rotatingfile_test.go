@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFileRotatesWhenExceedingMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.log")
+	file, err := OpenRotatingFile(path, 10, 0, 0, 0)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	file.Write([]byte("12345678\n"))
+	file.Write([]byte("abc\n"))
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc\n", string(content))
+
+	backups := file.backups()
+	assert.Len(t, backups, 1)
+	content, err = ioutil.ReadFile(backups[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(content))
+}
+
+func TestRotatingFileRotatesAfterRotationInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.log")
+	file, err := OpenRotatingFile(path, 0, time.Hour, 0, 0)
+	assert.NoError(t, err)
+	defer file.Close()
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	file.now = func() time.Time { return now }
+	file.openedAt = now
+
+	file.Write([]byte("first\n"))
+	now = now.Add(30 * time.Minute)
+	file.Write([]byte("second\n"))
+	assert.Len(t, file.backups(), 0)
+
+	now = now.Add(30 * time.Minute)
+	file.Write([]byte("third\n"))
+	assert.Equal(t, []string{path + ".2021-04-10T11-00-00.000"}, file.backups())
+}
+
+func TestRotatingFileRemovesBackupsExceedingRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.log")
+	file, err := OpenRotatingFile(path, 1, 0, 2, 30*time.Minute)
+	assert.NoError(t, err)
+	defer file.Close()
+	now := time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC)
+	file.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		file.Write([]byte("line\n"))
+		now = now.Add(time.Hour)
+	}
+
+	// only the newest 2 backups are kept, of which one is already older than the max age
+	assert.Equal(t, []string{path + ".2021-04-10T14-00-00.000"}, file.backups())
+}
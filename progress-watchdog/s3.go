@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3MaxResponseSize limits the size of read responses, far above the size of the backups of large events
+const s3MaxResponseSize = 256 << 20
+
+// S3Client is a minimal client of the S3 api, covering the object operations used by the S3BackupTarget. Requests are signed with
+// AWS Signature Version 4 and use path-style urls, so that it works with AWS S3 as well as with S3-compatible stores like MinIO.
+type S3Client struct {
+	endpoint        *url.URL
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	client          *http.Client
+}
+
+// S3Error is an error response of the S3 api
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("S3 responded with status %d: %s %s", e.StatusCode, e.Code, e.Message)
+}
+
+// s3ListResult xml format of the ListObjectsV2 response, only the fields used by the client are decoded
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+// NewS3Client creates a client of the bucket at the endpoint, e.g. `https://s3.eu-central-1.amazonaws.com` or `http://minio:9000`
+func NewS3Client(endpoint, bucket, region, accessKeyID, secretAccessKey string, client *http.Client) (*S3Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("Invalid S3 endpoint '%s'", endpoint)
+	}
+	return &S3Client{endpoint: parsed, bucket: bucket, region: region, accessKeyID: accessKeyID, secretAccessKey: secretAccessKey, client: client}, nil
+}
+
+// PutObject uploads the data as object with the key
+func (c *S3Client) PutObject(ctx context.Context, key string, data []byte) error {
+	_, err := c.do(ctx, http.MethodPut, key, nil, data)
+	return err
+}
+
+// GetObject downloads the object with the key
+func (c *S3Client) GetObject(ctx context.Context, key string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, key, nil, nil)
+}
+
+// DeleteObject deletes the object with the key, deleting missing objects succeeds
+func (c *S3Client) DeleteObject(ctx context.Context, key string) error {
+	_, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	return err
+}
+
+// ListObjects lists the keys of all objects starting with the prefix, in the lexicographical order returned by S3
+func (c *S3Client) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		body, err := c.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := s3ListResult{}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("Failed to decode S3 object list: %v", err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (c *S3Client) do(ctx context.Context, method, key string, query url.Values, payload []byte) ([]byte, error) {
+	target := *c.endpoint
+	target.Path = c.endpoint.Path + "/" + c.bucket + "/" + key
+	target.RawPath = s3EscapePath(target.Path)
+	target.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(payload))
+	payloadHash := sha256.Sum256(payload)
+	signS3Request(req, hex.EncodeToString(payloadHash[:]), c.accessKeyID, c.secretAccessKey, c.region, time.Now())
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to reach S3: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, s3MaxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		s3Err := &S3Error{}
+		// the error details are optional, e.g. HEAD requests and some proxies respond without body
+		xml.Unmarshal(body, s3Err)
+		s3Err.StatusCode = res.StatusCode
+		return nil, s3Err
+	}
+	return body, nil
+}
+
+// signS3Request adds the AWS Signature Version 4 headers to the request, signing the host, the payload hash and the date
+func signS3Request(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, payloadHash, amzDate)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but the unreserved characters, as required for the canonical request of the signature
+func s3Escape(value string) string {
+	escaped := strings.Builder{}
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// s3EscapePath escapes every segment of the path, keeping the slashes
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery encodes the query sorted by parameter name, as required for the canonical request of the signature
+func s3CanonicalQuery(query url.Values) string {
+	parameters := []string{}
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	sort.Strings(parameters)
+	return strings.Join(parameters, "&")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+)
+
+// ScoreboardTeam a team listed on the scoreboard
+type ScoreboardTeam struct {
+	Position int    `json:"position"`
+	Team     string `json:"team"`
+	// Namespace of the team, teams in different namespaces can have the same name
+	Namespace        string  `json:"namespace"`
+	ChallengesSolved int     `json:"challengesSolved"`
+	Score            float64 `json:"score"`
+}
+
+// ScoreboardResponse json format of the scoreboard response
+type ScoreboardResponse struct {
+	Teams []ScoreboardTeam `json:"teams"`
+}
+
+// rankTeams orders the teams by their score. Teams with the same score share their position, unless the challengeApiScoring feature
+// is enabled: then the team which reached the score first ranks higher. Paused teams aren't ranked.
+func rankTeams(teams []TeamProgress) []ScoreboardTeam {
+	sorted := []TeamProgress{}
+	for _, team := range teams {
+		if !team.Paused {
+			sorted = append(sorted, team)
+		}
+	}
+	firstSolverWins := featureFlags.Enabled(FeatureChallengeAPIScoring)
+	tied := func(a, b TeamProgress) bool {
+		return a.Score == b.Score && (!firstSolverWins || a.Solves.LastSolvedAt().Equal(b.Solves.LastSolvedAt()))
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		if !tied(sorted[i], sorted[j]) {
+			return reachedScoreFirst(sorted[i], sorted[j])
+		}
+		return sorted[i].Key() < sorted[j].Key()
+	})
+
+	ranked := []ScoreboardTeam{}
+	for i, team := range sorted {
+		position := i + 1
+		if i > 0 && tied(team, sorted[i-1]) {
+			position = ranked[i-1].Position
+		}
+		ranked = append(ranked, ScoreboardTeam{
+			Position:         position,
+			Team:             team.Team,
+			Namespace:        team.Namespace,
+			ChallengesSolved: team.ChallengesSolved,
+			Score:            team.Score,
+		})
+	}
+	return ranked
+}
+
+// reachedScoreFirst checks if team a reached its score before team b. Teams without known solve times rank last.
+func reachedScoreFirst(a, b TeamProgress) bool {
+	reachedA, reachedB := a.Solves.LastSolvedAt(), b.Solves.LastSolvedAt()
+	if reachedA.IsZero() || reachedB.IsZero() {
+		return reachedB.IsZero() && !reachedA.IsZero()
+	}
+	return reachedA.Before(reachedB)
+}
+
+// rankedTeams returns the current ranking, using the cached one if it is still valid
+func (s *Server) rankedTeams(ctx context.Context) ([]ScoreboardTeam, error) {
+	return s.scoreboardCache.Get(func() ([]ScoreboardTeam, error) {
+		teams, err := s.listTeamProgress(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return rankTeams(teams), nil
+	})
+}
+
+func (s *Server) handleScoreboard(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ranked, err := s.rankedTeams(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeConditionalJSON(w, req, ScoreboardResponse{Teams: ranked}, s.scoreboardCache.LastModified())
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRankTeamsSortsByScore(t *testing.T) {
+	ranked := rankTeams([]TeamProgress{
+		{Team: "c", ChallengesSolved: 1, Score: 1},
+		{Team: "a", ChallengesSolved: 5, Score: 5},
+		{Team: "d", ChallengesSolved: 0, Score: 0},
+		{Team: "b", ChallengesSolved: 5, Score: 5},
+	})
+
+	assert.Equal(t, []ScoreboardTeam{
+		{Position: 1, Team: "a", ChallengesSolved: 5, Score: 5},
+		{Position: 1, Team: "b", ChallengesSolved: 5, Score: 5},
+		{Position: 3, Team: "c", ChallengesSolved: 1, Score: 1},
+		{Position: 4, Team: "d", ChallengesSolved: 0, Score: 0},
+	}, ranked)
+}
+
+func TestRankTeamsByWhoReachedTheScoreFirstIfChallengeAPIScoringIsEnabled(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	featureFlags = NewFeatureFlags(map[Feature]bool{FeatureChallengeAPIScoring: true})
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	ranked := rankTeams([]TeamProgress{
+		{Team: "a", ChallengesSolved: 2, Score: 2, Solves: SolveTimes{1: start, 2: start.Add(time.Hour)}},
+		{Team: "b", ChallengesSolved: 2, Score: 2, Solves: SolveTimes{1: start, 3: start.Add(time.Minute)}},
+		{Team: "c", ChallengesSolved: 2, Score: 2},
+		{Team: "d", ChallengesSolved: 2, Score: 2, Solves: SolveTimes{4: start, 5: start.Add(time.Minute)}},
+	})
+
+	assert.Equal(t, []ScoreboardTeam{
+		{Position: 1, Team: "b", ChallengesSolved: 2, Score: 2},
+		{Position: 1, Team: "d", ChallengesSolved: 2, Score: 2},
+		{Position: 3, Team: "a", ChallengesSolved: 2, Score: 2},
+		{Position: 4, Team: "c", ChallengesSolved: 2, Score: 2},
+	}, ranked)
+}
+
+func TestRankTeamsSkipsPausedTeams(t *testing.T) {
+	ranked := rankTeams([]TeamProgress{
+		{Team: "a", ChallengesSolved: 5, Score: 5, Paused: true},
+		{Team: "b", ChallengesSolved: 3, Score: 3},
+	})
+
+	assert.Equal(t, []ScoreboardTeam{
+		{Position: 1, Team: "b", ChallengesSolved: 3, Score: 3},
+	}, ranked)
+}
+
+func TestRankTeamsReturnsEmptyListWithoutTeams(t *testing.T) {
+	assert.Equal(t, []ScoreboardTeam{}, rankTeams([]TeamProgress{}))
+}
+
+func TestScoreboardIsPublic(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeployment("foobar", "abc", "3"),
+		createJuiceShopDeployment("barfoo", "", "5"),
+	)
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := ScoreboardResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []ScoreboardTeam{
+		{Position: 1, Team: "barfoo", Namespace: "default", ChallengesSolved: 5, Score: 5},
+		{Position: 2, Team: "foobar", Namespace: "default", ChallengesSolved: 3, Score: 3},
+	}, response.Teams)
+}
+
+func TestScoreboardHonorsIfModifiedSince(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	cache := NewScoreboardCache(time.Minute)
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), cache)
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	lastModified := rr.Header().Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	req := httptest.NewRequest("GET", "/api/scoreboard", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	cache.now = func() time.Time { return time.Now().Add(time.Hour) }
+	cache.Invalidate()
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Scoreboard should be modified after a new solve")
+}
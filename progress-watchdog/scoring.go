@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Scoring configures how much the solves of a team are worth. Solves which don't count (see withoutUncountedSolves) are always worth nothing.
+type Scoring struct {
+	// TutorialSolveWeight is the score of a solved tutorial challenge, all other challenges are worth 1
+	TutorialSolveWeight float64
+	// Rules optionally replace the points of the solves with custom formulas, see ScoringRules
+	Rules *ScoringRules
+}
+
+// progressScoring is the scoring of the running watchdog, used for the cached score annotations of the teams
+var progressScoring = Scoring{TutorialSolveWeight: 1}
+
+// Score returns the weighted score of the counted solves of the team
+func (s Scoring) Score(progress TeamProgress) float64 {
+	if len(progress.Solves) == 0 {
+		// progress cached before solve times were recorded, the solved challenges are unknown
+		return float64(progress.ChallengesSolved)
+	}
+
+	score := 0.0
+	for challenge, solvedAt := range progress.Solves {
+		score += s.Points(progress, challenge, solvedAt)
+	}
+	return score
+}
+
+// Points returns what a single counted solve of the team is worth
+func (s Scoring) Points(progress TeamProgress, challenge int, solvedAt time.Time) float64 {
+	weight := 1.0
+	if contains(progress.TutorialChallenges, challenge) {
+		weight = s.TutorialSolveWeight
+	}
+	if s.Rules == nil {
+		return weight
+	}
+	points, err := s.Rules.Points(progress, challenge, solvedAt, weight)
+	if err != nil {
+		log.Warningf("Failed to apply scoring rules to challenge %d of team '%s', scoring it with its weight: %s", challenge, progress.Team, err)
+		return weight
+	}
+	return points
+}
+
+// scoringFromEnv creates the scoring configured via `TUTORIAL_SOLVE_WEIGHT`, `SCORING_ELIGIBILITY_RULE` and `SCORING_POINTS_RULE`
+func scoringFromEnv() (Scoring, error) {
+	tutorialSolveWeight, err := parseSolveWeight(getEnv("TUTORIAL_SOLVE_WEIGHT", "1"))
+	if err != nil {
+		return Scoring{}, fmt.Errorf("Invalid TUTORIAL_SOLVE_WEIGHT: %v", err)
+	}
+	rules, err := NewScoringRules(getEnv("SCORING_ELIGIBILITY_RULE", ""), getEnv("SCORING_POINTS_RULE", ""))
+	if err != nil {
+		return Scoring{}, err
+	}
+	return Scoring{TutorialSolveWeight: tutorialSolveWeight, Rules: rules}, nil
+}
+
+// parseSolveWeight parses a weight a solve is scored with, which must be between 0 and 1
+func parseSolveWeight(value string) (float64, error) {
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if weight < 0 || weight > 1 {
+		return 0, fmt.Errorf("Weight must be between 0 and 1, got %s", value)
+	}
+	return weight, nil
+}
+
+// cacheScore stores the current score of the team in the score annotation of its JuiceShop deployment, e.g. for `kubectl get` or other MultiJuicer components.
+// The annotation is only a snapshot, the api always scores with the current reviews and scoring.
+func cacheScore(ctx context.Context, clientset kubernetes.Interface, deployment appsv1.Deployment) {
+	progress := teamProgressFromDeployment(deployment)
+	score := strconv.FormatFloat(progressScoring.Score(progress), 'f', -1, 64)
+	if deployment.Annotations[annotation("score")] == score {
+		return
+	}
+	if err := kubernetesPacer.WaitForPatch(ctx); err != nil {
+		log.Warningf("Failed to wait for the patch rate limit of team '%s': %s", progress.Team, err)
+		return
+	}
+	err := patchJuiceShopDeployment(ctx, clientset, deployment.Namespace, progress.Team, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				annotation("score"): score,
+			},
+		},
+	})
+	if err != nil {
+		log.Warningf("Failed to cache score of team '%s': %s", progress.Team, err)
+		patchErrors.Inc()
+	}
+}
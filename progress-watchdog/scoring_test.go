@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoringWeightsTutorialSolves(t *testing.T) {
+	progress := TeamProgress{
+		ChallengesSolved:   3,
+		Solves:             SolveTimes{1: time.Now(), 2: time.Now(), 3: time.Now()},
+		TutorialChallenges: []int{1, 2},
+	}
+
+	assert.Equal(t, 3.0, Scoring{TutorialSolveWeight: 1}.Score(progress))
+	assert.Equal(t, 2.0, Scoring{TutorialSolveWeight: 0.5}.Score(progress))
+	assert.Equal(t, 1.0, Scoring{TutorialSolveWeight: 0}.Score(progress))
+}
+
+func TestScoringFallsBackToSolvedChallengesWithoutSolveTimes(t *testing.T) {
+	assert.Equal(t, 4.0, Scoring{TutorialSolveWeight: 0}.Score(TeamProgress{ChallengesSolved: 4}))
+}
+
+func TestParseSolveWeight(t *testing.T) {
+	weight, err := parseSolveWeight("0.25")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, weight)
+
+	_, err = parseSolveWeight("2")
+	assert.Error(t, err)
+	_, err = parseSolveWeight("half")
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// ScoringRules are custom scoring formulas and eligibility rules written as CEL expressions (https://github.com/google/cel-spec),
+// so that every event format can be scored without code changes. Both expressions are evaluated for every counted solve and can use:
+//   - `team` (string) the name of the team
+//   - `teamCreatedAt` (timestamp) when the team was created
+//   - `challenge` (int) the id of the solved challenge
+//   - `tutorial` (bool) whether the challenge is part of the tutorial
+//   - `solvedAt` (timestamp) when the challenge was solved
+//   - `solves` (int) the number of counted solves of the team
+//   - `weight` (double) the points of the solve without rules, see Scoring
+type ScoringRules struct {
+	// eligibility decides if a solve is scored at all, e.g. `solvedAt < timestamp("2021-04-10T18:00:00Z")`
+	eligibility cel.Program
+	// points calculates the points of an eligible solve, e.g. `challenge in [1, 2, 3] ? 2.0 * weight : weight`
+	points cel.Program
+}
+
+var scoringRuleDeclarations = cel.Declarations(
+	decls.NewVar("team", decls.String),
+	decls.NewVar("teamCreatedAt", decls.Timestamp),
+	decls.NewVar("challenge", decls.Int),
+	decls.NewVar("tutorial", decls.Bool),
+	decls.NewVar("solvedAt", decls.Timestamp),
+	decls.NewVar("solves", decls.Int),
+	decls.NewVar("weight", decls.Double),
+)
+
+// NewScoringRules compiles the eligibility and points expressions, empty expressions are skipped. Returns nil if both are empty.
+func NewScoringRules(eligibility, points string) (*ScoringRules, error) {
+	if eligibility == "" && points == "" {
+		return nil, nil
+	}
+	env, err := cel.NewEnv(scoringRuleDeclarations)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create scoring rule environment: %v", err)
+	}
+
+	rules := &ScoringRules{}
+	if eligibility != "" {
+		rules.eligibility, err = compileScoringRule(env, eligibility, decls.Bool)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid eligibility rule: %v", err)
+		}
+	}
+	if points != "" {
+		rules.points, err = compileScoringRule(env, points, decls.Double, decls.Int)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid points rule: %v", err)
+		}
+	}
+	return rules, nil
+}
+
+func compileScoringRule(env *cel.Env, expression string, resultTypes ...*exprpb.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	validResultType := false
+	for _, resultType := range resultTypes {
+		if proto.Equal(ast.ResultType(), resultType) {
+			validResultType = true
+		}
+	}
+	if !validResultType {
+		return nil, fmt.Errorf("Expression '%s' has an invalid result type", expression)
+	}
+	return env.Program(ast)
+}
+
+// Points returns the points of a single solve of the team, 0 if it isn't eligible
+func (r *ScoringRules) Points(progress TeamProgress, challenge int, solvedAt time.Time, weight float64) (float64, error) {
+	variables := map[string]interface{}{
+		"team":          progress.Team,
+		"teamCreatedAt": progress.CreatedAt,
+		"challenge":     challenge,
+		"tutorial":      contains(progress.TutorialChallenges, challenge),
+		"solvedAt":      solvedAt,
+		"solves":        len(progress.Solves),
+		"weight":        weight,
+	}
+
+	if r.eligibility != nil {
+		result, _, err := r.eligibility.Eval(variables)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to evaluate eligibility rule: %v", err)
+		}
+		if eligible, ok := result.Value().(bool); !ok || !eligible {
+			return 0, nil
+		}
+	}
+
+	if r.points == nil {
+		return weight, nil
+	}
+	result, _, err := r.points.Eval(variables)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to evaluate points rule: %v", err)
+	}
+	var points float64
+	switch value := result.Value().(type) {
+	case float64:
+		points = value
+	case int64:
+		points = float64(value)
+	default:
+		return 0, fmt.Errorf("Points rule returned '%v' instead of a number", result.Value())
+	}
+	if math.IsNaN(points) || math.IsInf(points, 0) {
+		return 0, fmt.Errorf("Points rule returned '%v' instead of a finite number", points)
+	}
+	return points, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoringRulesReplaceSolvePoints(t *testing.T) {
+	progress := TeamProgress{
+		Team:             "foobar",
+		ChallengesSolved: 3,
+		Solves: SolveTimes{
+			1: time.Date(2021, 4, 10, 10, 0, 0, 0, time.UTC),
+			2: time.Date(2021, 4, 10, 11, 0, 0, 0, time.UTC),
+			3: time.Date(2021, 4, 10, 19, 0, 0, 0, time.UTC),
+		},
+		TutorialChallenges: []int{1},
+	}
+
+	rules, err := NewScoringRules(`solvedAt < timestamp("2021-04-10T18:00:00Z")`, `tutorial ? weight : (challenge == 2 ? 5.0 * weight : weight)`)
+	assert.Nil(t, err)
+	assert.Equal(t, 5.5, Scoring{TutorialSolveWeight: 0.5, Rules: rules}.Score(progress))
+
+	rules, err = NewScoringRules(`team != "foobar"`, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, Scoring{TutorialSolveWeight: 1, Rules: rules}.Score(progress))
+
+	rules, err = NewScoringRules("", "solves")
+	assert.Nil(t, err)
+	assert.Equal(t, 9.0, Scoring{TutorialSolveWeight: 1, Rules: rules}.Score(progress))
+}
+
+func TestScoringRulesFallBackToWeightOnEvaluationErrors(t *testing.T) {
+	progress := TeamProgress{Team: "foobar", ChallengesSolved: 1, Solves: SolveTimes{1: time.Now()}}
+	rules, err := NewScoringRules("", "weight / double(challenge - 1)")
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, Scoring{TutorialSolveWeight: 1, Rules: rules}.Score(progress))
+}
+
+func TestNewScoringRulesValidatesExpressions(t *testing.T) {
+	rules, err := NewScoringRules("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, rules)
+
+	_, err = NewScoringRules("challenge", "")
+	assert.EqualError(t, err, "Invalid eligibility rule: Expression 'challenge' has an invalid result type")
+	_, err = NewScoringRules("", `"ten"`)
+	assert.Error(t, err)
+	_, err = NewScoringRules("", "unknownVariable * 2")
+	assert.Error(t, err)
+	_, err = NewScoringRules("solvedAt <", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// TeamProgress the progress of a team as cached by the watchdog
+type TeamProgress struct {
+	Team string `json:"team"`
+	// Namespace of the JuiceShop of the team, teams in different namespaces can have the same name
+	Namespace        string     `json:"namespace"`
+	ContinueCode     string     `json:"continueCode"`
+	ChallengesSolved int        `json:"challengesSolved"`
+	Solves           SolveTimes `json:"solves,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	// Paused teams are neither synced nor listed on the scoreboard
+	Paused bool `json:"paused"`
+	// Reviews of flagged solves. Solves pending review or rejected are excluded from Solves and ChallengesSolved
+	Reviews SolveReviews `json:"reviews,omitempty"`
+	// DisabledChallenges are disabled in the environment of the teams JuiceShop, their solves are excluded as well
+	DisabledChallenges []int `json:"disabledChallenges,omitempty"`
+	// TutorialChallenges are part of the guided tutorial, their solves can be weighted differently by the scoring
+	TutorialChallenges []int `json:"tutorialChallenges,omitempty"`
+	// SolveDetails of the solved challenges as reported by the JuiceShop, only tracked if the solveDetails feature is enabled
+	SolveDetails []SolveDetail `json:"solveDetails,omitempty"`
+	// Score is the weighted sum of the counted solves, see Scoring
+	Score float64 `json:"score"`
+}
+
+// Key identifies the team across namespaces, see teamKey
+func (p TeamProgress) Key() string {
+	return teamKey(p.Namespace, p.Team)
+}
+
+// teamKey identifies a team across namespaces as `{namespace}/{team}`, as teams in different namespaces can have the same name
+func teamKey(namespace, teamname string) string {
+	if namespace == "" {
+		return teamname
+	}
+	return namespace + "/" + teamname
+}
+
+// ProgressListResponse json format of the progress list response
+type ProgressListResponse struct {
+	Teams []TeamProgress `json:"teams"`
+}
+
+// Server serves the progress api of the watchdog to other MultiJuicer components
+type Server struct {
+	clientset kubernetes.Interface
+	// namespace of the watched JuiceShops, metav1.NamespaceAll if all namespaces are watched
+	namespace            string
+	scoreboardCache      *ScoreboardCache
+	scoring              Scoring
+	timeZone             *time.Location
+	widgetFrameAncestors string
+}
+
+// ServerOptions contains the shared components used by the api server
+type ServerOptions struct {
+	Authenticator   *Authenticator
+	RateLimiter     *RateLimiter
+	ScoreboardCache *ScoreboardCache
+	CORS            *CORS
+	Scoring         Scoring
+	// TimeZone reports are rendered in, defaults to UTC
+	TimeZone *time.Location
+	// APIServerCheck is served as readiness probe under `/readyz`, if set
+	APIServerCheck *APIServerCheck
+	// Heartbeats of the watchdog loops are served as liveness probe under `/healthz`, if set
+	Heartbeats *Heartbeats
+	// Metrics are served in the prometheus text format under `/metrics`, if set
+	Metrics prometheus.Gatherer
+	// SolveWebhook receives the solve webhooks of the JuiceShops under `/api/webhooks/solve/{team}`, if set
+	SolveWebhook *SolveWebhook
+	// WidgetFrameAncestors is the `frame-ancestors` CSP directive of the widget, restricting which sites can embed it
+	WidgetFrameAncestors string
+}
+
+// NewServer creates the http handler for the watchdog api, serving the teams of the watched namespace or of all namespaces for metav1.NamespaceAll.
+// Every non public route requires an authenticated principal with the scope required by the route.
+// Public routes are rate limited per client instead. Responses are gzip compressed for clients accepting it.
+func NewServer(clientset kubernetes.Interface, namespace string, options ServerOptions) http.Handler {
+	server := &Server{
+		clientset:            clientset,
+		namespace:            namespace,
+		scoreboardCache:      options.ScoreboardCache,
+		scoring:              options.Scoring,
+		timeZone:             options.TimeZone,
+		widgetFrameAncestors: options.WidgetFrameAncestors,
+	}
+
+	if server.timeZone == nil {
+		server.timeZone = time.UTC
+	}
+
+	authenticator := options.Authenticator
+	mux := http.NewServeMux()
+	mux.Handle("/api/progress", authenticator.requireScope(ScopeRead, http.HandlerFunc(server.handleListProgress)))
+	mux.Handle("/api/progress/", authenticator.requireScope(ScopeRead, http.HandlerFunc(server.handleGetProgress)))
+	mux.Handle("/api/statistics", authenticator.requireScope(ScopeRead, http.HandlerFunc(server.handleStatistics)))
+	mux.Handle("/api/compare", authenticator.requireScope(ScopeRead, http.HandlerFunc(server.handleCompareTeams)))
+	mux.Handle("/api/statistics/challenges", authenticator.requireScope(ScopeRead, http.HandlerFunc(server.handleChallengeDistribution)))
+	mux.Handle("/api/admin/reviews", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleReviewQueue)))
+	mux.Handle("/api/admin/rebuild", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleRebuildAllTeams)))
+	mux.Handle("/api/admin/features", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleFeatures)))
+	mux.Handle("/api/admin/features/", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleFeatures)))
+	mux.Handle("/api/admin/log-level", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleLogLevel)))
+	mux.Handle("/api/admin/teams/", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleAdminTeams)))
+	mux.Handle("/api/admin/bulk", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleBulkAction)))
+	mux.Handle("/api/v1/admin/", authenticator.requireScope(ScopeAdmin, http.HandlerFunc(server.handleAdminV1)))
+
+	mux.Handle("/api/scoreboard", options.RateLimiter.Limit(http.HandlerFunc(server.handleScoreboard)))
+	mux.Handle("/api/scoreboard/timeline", options.RateLimiter.Limit(http.HandlerFunc(server.handleTimeline)))
+	mux.Handle("/api/scoreboard/first-bloods", options.RateLimiter.Limit(http.HandlerFunc(server.handleFirstBloods)))
+	mux.Handle("/api/i18n", options.RateLimiter.Limit(http.HandlerFunc(server.handleI18n)))
+	mux.Handle("/api/v1/admin/openapi.yaml", options.RateLimiter.Limit(http.HandlerFunc(server.handleAdminV1Spec)))
+	mux.Handle("/scoreboard/", scoreboardUIHandler())
+	mux.Handle("/widget", options.RateLimiter.Limit(http.HandlerFunc(server.handleWidget)))
+
+	if options.SolveWebhook != nil {
+		mux.Handle("/api/webhooks/solve/", options.RateLimiter.Limit(http.HandlerFunc(options.SolveWebhook.handleSolve)))
+	}
+
+	if options.APIServerCheck != nil {
+		mux.HandleFunc("/readyz", options.APIServerCheck.handleReadiness)
+	}
+	if options.Heartbeats != nil {
+		mux.HandleFunc("/healthz", options.Heartbeats.handleHealth)
+	}
+	if options.Metrics != nil {
+		// responses are already compressed by compressResponses
+		mux.Handle("/metrics", promhttp.HandlerFor(options.Metrics, promhttp.HandlerOpts{DisableCompression: true}))
+	}
+
+	return options.CORS.Handler(compressResponses(mux))
+}
+
+func (s *Server) handleListProgress(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeConditionalJSON(w, req, ProgressListResponse{Teams: teams}, time.Time{})
+}
+
+func (s *Server) handleGetProgress(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamname := strings.TrimPrefix(req.URL.Path, "/api/progress/")
+	if teamname == "" || strings.Contains(teamname, "/") {
+		http.NotFound(w, req)
+		return
+	}
+
+	namespace, ok := s.teamNamespace(w, req)
+	if !ok {
+		return
+	}
+	progress, err := s.getTeamProgress(req.Context(), namespace, teamname)
+	if errors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeConditionalJSON(w, req, progress, time.Time{})
+}
+
+// requestedTeamNamespace returns the namespace of the team a request is about. If all namespaces are watched, the namespace of the team
+// has to be passed as `namespace` query parameter, as teams in different namespaces can have the same name.
+func requestedTeamNamespace(req *http.Request, watchedNamespace string) (string, bool) {
+	if watchedNamespace != metav1.NamespaceAll {
+		return watchedNamespace, true
+	}
+	namespace := req.URL.Query().Get("namespace")
+	return namespace, namespace != ""
+}
+
+// teamNamespace returns the namespace of the team of the request, responding with `400 Bad Request` if it's missing, see requestedTeamNamespace
+func (s *Server) teamNamespace(w http.ResponseWriter, req *http.Request) (string, bool) {
+	namespace, ok := requestedTeamNamespace(req, s.namespace)
+	if !ok {
+		http.Error(w, "Missing namespace of the team", http.StatusBadRequest)
+	}
+	return namespace, ok
+}
+
+// getTeamProgress reads the cached progress of a single team from its JuiceShop deployment
+func (s *Server) getTeamProgress(ctx context.Context, namespace, teamname string) (TeamProgress, error) {
+	deployment, err := workloads.Get(ctx, s.clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Errorf("Failed to get JuiceShop deployment for team '%s'", teamname)
+			log.Error(err)
+		}
+		return TeamProgress{}, err
+	}
+	progress := teamProgressFromDeployment(*deployment)
+	progress.Score = s.scoring.Score(progress)
+	return progress, nil
+}
+
+// listTeamProgress reads the cached progress of all teams from their JuiceShop deployments, scored with the configured scoring
+func (s *Server) listTeamProgress(ctx context.Context) ([]TeamProgress, error) {
+	teams, err := listTeamProgress(ctx, s.clientset, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range teams {
+		teams[i].Score = s.scoring.Score(teams[i])
+	}
+	return teams, nil
+}
+
+// listTeamProgress reads the cached progress of all teams from their JuiceShop deployments
+func listTeamProgress(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]TeamProgress, error) {
+	juiceShops, err := listJuiceShops(ctx, clientset, namespace, juiceShopSelector)
+	if err != nil {
+		log.Error("Failed to list JuiceShop deployments")
+		log.Error(err)
+		return nil, err
+	}
+
+	teams := []TeamProgress{}
+	for _, instance := range juiceShops {
+		teams = append(teams, teamProgressFromDeployment(instance))
+	}
+	return teams, nil
+}
+
+// withoutUncountedSolves removes the solves which don't count from the team progress.
+// Solves don't count while they are pending review, after they got rejected or if the challenge is disabled in the teams environment.
+func withoutUncountedSolves(progress TeamProgress) TeamProgress {
+	if len(progress.Reviews) == 0 && len(progress.DisabledChallenges) == 0 {
+		return progress
+	}
+	solves := SolveTimes{}
+	for challenge, solvedAt := range progress.Solves {
+		if progress.Reviews.Counts(challenge) && !contains(progress.DisabledChallenges, challenge) {
+			solves[challenge] = solvedAt
+		} else if progress.ChallengesSolved > 0 {
+			progress.ChallengesSolved--
+		}
+	}
+	progress.Solves = solves
+	if progress.SolveDetails != nil {
+		details := []SolveDetail{}
+		for _, detail := range progress.SolveDetails {
+			if _, ok := solves[detail.Challenge]; ok {
+				details = append(details, detail)
+			}
+		}
+		progress.SolveDetails = details
+	}
+	return progress
+}
+
+// isPaused checks if progress tracking was paused for the team of the deployment
+func isPaused(deployment appsv1.Deployment) bool {
+	return deployment.Annotations[annotation("paused")] == "true"
+}
+
+func teamProgressFromDeployment(deployment appsv1.Deployment) TeamProgress {
+	records := progressStore.Records(deployment)
+	challengesSolved, err := strconv.Atoi(records[annotation("challengesSolved")])
+	if err != nil {
+		challengesSolved = 0
+	}
+	return withoutUncountedSolves(TeamProgress{
+		Team:             deployment.Labels["team"],
+		Namespace:        deployment.Namespace,
+		ContinueCode:     records[annotation("continueCode")],
+		ChallengesSolved: challengesSolved,
+		Solves:           parseSolveTimes(records[annotation("solves")]),
+		CreatedAt:        deployment.CreationTimestamp.Time,
+		Paused:           isPaused(deployment),
+		Reviews:          parseSolveReviews(deployment.Annotations[annotation("solveReviews")]),
+
+		DisabledChallenges: parseChallengeList(records[annotation("disabledChallenges")]),
+		TutorialChallenges: parseChallengeList(records[annotation("tutorialChallenges")]),
+		SolveDetails:       parseSolveDetails(records[annotation("solveDetails")]),
+	})
+}
+
+// writeConditionalJSON writes the payload with an ETag derived from its content and an optional Last-Modified date.
+// Requests with a matching If-None-Match or If-Modified-Since header get a `304 Not Modified` response.
+func writeConditionalJSON(w http.ResponseWriter, req *http.Request, payload interface{}, lastModified time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Error("Failed to encode json response")
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16])))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, req, "", lastModified, bytes.NewReader(body))
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Error("Failed to write json response")
+		log.Error(err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createJuiceShopDeployment(team, continueCode, challengesSolved string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "t-" + team + "-juiceshop",
+			Namespace: "default",
+			Labels: map[string]string{
+				"app":  "juice-shop",
+				"team": team,
+			},
+			Annotations: map[string]string{
+				"multi-juicer.iteratec.dev/continueCode":     continueCode,
+				"multi-juicer.iteratec.dev/challengesSolved": challengesSolved,
+			},
+		},
+	}
+}
+
+// createJuiceShopDeploymentIn creates the deployment of the team in another namespace, e.g. with a namespace per team
+func createJuiceShopDeploymentIn(namespace, team, continueCode, challengesSolved string) *appsv1.Deployment {
+	deployment := createJuiceShopDeployment(team, continueCode, challengesSolved)
+	deployment.Namespace = namespace
+	return deployment
+}
+
+func newTestServer(clientset kubernetes.Interface, authenticator *Authenticator, scoreboardCache *ScoreboardCache) http.Handler {
+	return newTestServerWatching("default", clientset, authenticator, scoreboardCache)
+}
+
+func newTestServerWatching(namespace string, clientset kubernetes.Interface, authenticator *Authenticator, scoreboardCache *ScoreboardCache) http.Handler {
+	return NewServer(clientset, namespace, ServerOptions{
+		Authenticator:   authenticator,
+		RateLimiter:     NewRateLimiter(10, 10),
+		ScoreboardCache: scoreboardCache,
+		CORS:            &CORS{},
+
+		WidgetFrameAncestors: "*",
+	})
+}
+
+func signedRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	SignRequest(req, []byte("secret"), time.Now())
+	return req
+}
+
+func TestServerListsProgressOfAllTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeployment("foobar", "abc", "3"),
+		createJuiceShopDeployment("barfoo", "", "0"),
+	)
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := ProgressListResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.ElementsMatch(t, []TeamProgress{
+		{Team: "foobar", Namespace: "default", ContinueCode: "abc", ChallengesSolved: 3, Score: 3},
+		{Team: "barfoo", Namespace: "default", ContinueCode: "", ChallengesSolved: 0},
+	}, response.Teams)
+}
+
+func TestServerReturnsProgressOfSingleTeam(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	progress := TeamProgress{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &progress))
+	assert.Equal(t, TeamProgress{Team: "foobar", Namespace: "default", ContinueCode: "abc", ChallengesSolved: 3, Score: 3}, progress)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/unknown"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestServerServesTheTeamsOfAllNamespaces(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeploymentIn("team-a", "foobar", "abc", "3"),
+		createJuiceShopDeploymentIn("team-b", "foobar", "", "0"),
+	)
+	server := newTestServerWatching(metav1.NamespaceAll, clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	response := ProgressListResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.ElementsMatch(t, []TeamProgress{
+		{Team: "foobar", Namespace: "team-a", ContinueCode: "abc", ChallengesSolved: 3, Score: 3},
+		{Team: "foobar", Namespace: "team-b", ContinueCode: "", ChallengesSolved: 0},
+	}, response.Teams)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar?namespace=team-a"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	progress := TeamProgress{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &progress))
+	assert.Equal(t, TeamProgress{Team: "foobar", Namespace: "team-a", ContinueCode: "abc", ChallengesSolved: 3, Score: 3}, progress)
+}
+
+func TestServerRejectsUnsignedRequests(t *testing.T) {
+	server := newTestServer(fake.NewSimpleClientset(), NewAuthenticator([]byte("secret"), nil, nil, nil), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/progress", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestServerRespondsWithNotModifiedForMatchingETags(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "abc", "3"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/progress/foobar"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := signedRequest("GET", "/api/progress/foobar")
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	req = signedRequest("GET", "/api/progress/foobar")
+	req.Header.Set("If-None-Match", `"outdated"`)
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestServerServesEmbeddedScoreboardUI(t *testing.T) {
+	server := newTestServer(fake.NewSimpleClientset(), NewAuthenticator(nil, nil, nil, nil), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/scoreboard/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<title data-i18n="scoreboard.title">MultiJuicer Scoreboard</title>`)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/scoreboard", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rr.Code, "Should redirect to the trailing slash path")
+}
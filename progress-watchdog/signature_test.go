@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiesSignedRequests(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+
+	req := httptest.NewRequest("POST", "/api/progress?foo=bar", strings.NewReader(`{"team":"foobar"}`))
+	assert.NoError(t, SignRequest(req, secret, now))
+
+	assert.NoError(t, verifySignature(req, secret, now.Add(time.Minute)), "Signature should be valid")
+	assert.Error(t, verifySignature(req, []byte("other-secret"), now), "Signature should not be valid with another secret")
+	assert.Error(t, verifySignature(req, secret, now.Add(10*time.Minute)), "Signature should expire")
+}
+
+func TestRejectsRequestsWithTamperedBody(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+
+	signed := httptest.NewRequest("POST", "/api/progress", strings.NewReader(`{"team":"foobar"}`))
+	assert.NoError(t, SignRequest(signed, secret, now))
+
+	tampered := httptest.NewRequest("POST", "/api/progress", strings.NewReader(`{"team":"other"}`))
+	tampered.Header = signed.Header
+	assert.Error(t, verifySignature(tampered, secret, now))
+}
+
+func TestRejectsSignedRequestsWithOversizedBody(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+
+	req := httptest.NewRequest("POST", "/api/progress", strings.NewReader(strings.Repeat("a", maxSignedBodySize+1)))
+	assert.NoError(t, SignRequest(req, secret, now))
+
+	assert.Error(t, verifySignature(req, secret, now))
+}
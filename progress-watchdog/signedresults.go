@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ResultsSolve a single entry of the solve log of the signed results
+type ResultsSolve struct {
+	Sequence  int       `json:"sequence"`
+	Team      string    `json:"team"`
+	Challenge int       `json:"challenge"`
+	SolvedAt  time.Time `json:"solvedAt"`
+	// Hash chains the solve to all solves before it, see chainHash
+	Hash string `json:"hash"`
+}
+
+// SignedResults are the final results of an event with a hash chain over the solve log. Together with the detached signature of
+// their json encoding, published results can be verified against later edits of scores or solves, see verifySignedResults.
+type SignedResults struct {
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Standings   []ScoreboardTeam `json:"standings"`
+	Solves      []ResultsSolve   `json:"solves"`
+	// ChainHead is the hash of the last solve, it pins the whole solve log
+	ChainHead string `json:"chainHead"`
+}
+
+// chainGenesis is the hash the first solve of the chain is linked to
+var chainGenesis = hex.EncodeToString(make([]byte, sha256.Size))
+
+// chainHash hashes a solve together with the hash of the solve before it, so that changing, removing or reordering any solve changes all following hashes
+func chainHash(previous string, solve ResultsSolve) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%d\n%s\n%d\n%s\n", previous, solve.Sequence, strconv.Quote(solve.Team), solve.Challenge, solve.SolvedAt.UTC().Format(time.RFC3339Nano))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// buildSignedResults creates the results of the scored teams with the solve log ordered by solve time
+func buildSignedResults(teams []TeamProgress, now time.Time) SignedResults {
+	solves := []ResultsSolve{}
+	for _, team := range teams {
+		if team.Paused {
+			continue
+		}
+		for challenge, solvedAt := range team.Solves {
+			solves = append(solves, ResultsSolve{Team: team.Team, Challenge: challenge, SolvedAt: solvedAt.UTC()})
+		}
+	}
+	sort.Slice(solves, func(i, j int) bool {
+		if !solves[i].SolvedAt.Equal(solves[j].SolvedAt) {
+			return solves[i].SolvedAt.Before(solves[j].SolvedAt)
+		}
+		if solves[i].Team != solves[j].Team {
+			return solves[i].Team < solves[j].Team
+		}
+		return solves[i].Challenge < solves[j].Challenge
+	})
+
+	head := chainGenesis
+	for i := range solves {
+		solves[i].Sequence = i + 1
+		solves[i].Hash = chainHash(head, solves[i])
+		head = solves[i].Hash
+	}
+	return SignedResults{GeneratedAt: now.UTC(), Standings: rankTeams(teams), Solves: solves, ChainHead: head}
+}
+
+// signResults encodes the results and creates the base64 encoded detached ed25519 signature of exactly these bytes
+func signResults(results SignedResults, key ed25519.PrivateKey) ([]byte, string, error) {
+	encoded, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	encoded = append(encoded, '\n')
+	return encoded, base64.StdEncoding.EncodeToString(ed25519.Sign(key, encoded)), nil
+}
+
+// verifySignedResults checks the detached signature of the encoded results and the integrity of their solve log
+func verifySignedResults(encoded []byte, signature string, publicKey ed25519.PublicKey) (SignedResults, error) {
+	decodedSignature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace([]byte(signature))))
+	if err != nil {
+		return SignedResults{}, fmt.Errorf("Invalid signature encoding: %v", err)
+	}
+	if !ed25519.Verify(publicKey, encoded, decodedSignature) {
+		return SignedResults{}, errors.New("Signature doesn't match the results, they were modified or signed with another key")
+	}
+
+	results := SignedResults{}
+	if err := json.Unmarshal(encoded, &results); err != nil {
+		return SignedResults{}, fmt.Errorf("Failed to decode results: %v", err)
+	}
+	head := chainGenesis
+	for i, solve := range results.Solves {
+		if solve.Sequence != i+1 || chainHash(head, solve) != solve.Hash {
+			return SignedResults{}, fmt.Errorf("Hash chain is broken at solve %d", i+1)
+		}
+		head = solve.Hash
+	}
+	if head != results.ChainHead {
+		return SignedResults{}, errors.New("Chain head doesn't match the solve log")
+	}
+	return results, nil
+}
+
+// parseResultsSigningKey parses a PEM encoded PKCS #8 ed25519 private key, e.g. created with `openssl genpkey -algorithm ed25519`
+func parseResultsSigningKey(value []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(value)
+	if block == nil {
+		return nil, errors.New("Signing key isn't PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("Signing key isn't an ed25519 key")
+	}
+	return privateKey, nil
+}
+
+// parseResultsPublicKey parses a PEM encoded PKIX ed25519 public key, e.g. created with `openssl pkey -pubout`
+func parseResultsPublicKey(value []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(value)
+	if block == nil {
+		return nil, errors.New("Public key isn't PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("Public key isn't an ed25519 key")
+	}
+	return publicKey, nil
+}
+
+// encodeResultsPublicKey encodes the public key of the signing key as PEM, to be published alongside the results
+func encodeResultsPublicKey(key ed25519.PrivateKey) ([]byte, error) {
+	encoded, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encoded}), nil
+}
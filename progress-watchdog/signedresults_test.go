@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createResultsSigningKey(t *testing.T) (ed25519.PrivateKey, []byte) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	encoded, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded})
+}
+
+func TestSignedResultsChainSolvesInOrder(t *testing.T) {
+	teams := createStatisticsTeams()
+	for i := range teams {
+		teams[i].Score = float64(len(teams[i].Solves))
+	}
+	results := buildSignedResults(teams, eventStart.Add(24*time.Hour))
+
+	assert.Len(t, results.Solves, 5)
+	assert.Equal(t, ResultsSolve{Sequence: 1, Team: "first", Challenge: 1, SolvedAt: eventStart.Add(10 * time.Minute), Hash: results.Solves[0].Hash}, results.Solves[0])
+	assert.Equal(t, chainHash(chainGenesis, results.Solves[0]), results.Solves[0].Hash)
+	assert.Equal(t, chainHash(results.Solves[0].Hash, results.Solves[1]), results.Solves[1].Hash)
+	assert.Equal(t, results.Solves[4].Hash, results.ChainHead)
+	assert.Equal(t, "first", results.Standings[0].Team)
+}
+
+func TestVerifySignedResultsDetectsTampering(t *testing.T) {
+	key, keyPEM := createResultsSigningKey(t)
+	parsed, err := parseResultsSigningKey(keyPEM)
+	assert.NoError(t, err)
+	assert.Equal(t, key, parsed)
+	publicKeyPEM, err := encodeResultsPublicKey(key)
+	assert.NoError(t, err)
+	publicKey, err := parseResultsPublicKey(publicKeyPEM)
+	assert.NoError(t, err)
+
+	results := buildSignedResults(createStatisticsTeams(), eventStart)
+	encoded, signature, err := signResults(results, key)
+	assert.NoError(t, err)
+
+	verified, err := verifySignedResults(encoded, signature, publicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, results.ChainHead, verified.ChainHead)
+
+	edited := bytes.Replace(encoded, []byte(`"team": "second"`), []byte(`"team": "third"`), 1)
+	_, err = verifySignedResults(edited, signature, publicKey)
+	assert.EqualError(t, err, "Signature doesn't match the results, they were modified or signed with another key")
+
+	// results re-signed with the right key after editing still have a broken chain
+	results.Solves[2].Team = "third"
+	encoded, signature, err = signResults(results, key)
+	assert.NoError(t, err)
+	_, err = verifySignedResults(encoded, signature, publicKey)
+	assert.EqualError(t, err, "Hash chain is broken at solve 3")
+
+	otherKey, _ := createResultsSigningKey(t)
+	_, err = verifySignedResults(encoded, signature, otherKey.Public().(ed25519.PublicKey))
+	assert.Error(t, err)
+}
+
+func TestResultsVerifyCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key, _ := createResultsSigningKey(t)
+	encoded, signature, err := signResults(buildSignedResults(createStatisticsTeams(), eventStart), key)
+	assert.NoError(t, err)
+	publicKey, err := encodeResultsPublicKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "results.json"), encoded, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "results.json.sig"), []byte(signature+"\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "results.pub"), publicKey, 0644))
+
+	out := &bytes.Buffer{}
+	exitCode := runResultsCommand([]string{"verify", "--public-key", filepath.Join(dir, "results.pub"), filepath.Join(dir, "results.json"), filepath.Join(dir, "results.json.sig")}, out)
+	assert.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(out.String(), "Results generated at 2021-05-01T10:00:00Z are authentic: 3 teams, 5 solves"), out.String())
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iteratec/multi-juicer/progress-watchdog/internal/fakejuiceshop"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	// simulatedChallenges is the number of challenges of the simulated JuiceShops
+	simulatedChallenges = 100
+	// simulationTick is the interval in which the simulated teams solve challenges
+	simulationTick = 100 * time.Millisecond
+	// simulatedSolveProbability is the chance of a simulated team to solve another challenge every tick, about one every 10 seconds
+	simulatedSolveProbability = 0.01
+	// simulatedResetProbability is the chance of a simulated JuiceShop to lose its progress every tick, e.g. because it restarted
+	simulatedResetProbability = 0.0005
+)
+
+// SimulationOptions configure a simulation run, see runSimulation
+type SimulationOptions struct {
+	Teams        int
+	Workers      int
+	PollInterval time.Duration
+	Duration     time.Duration
+	// Latency is added to every request to the simulated JuiceShops and every patch of the fake kubernetes api
+	Latency time.Duration
+}
+
+// SimulationReport the throughput of the watchdog measured by a simulation run
+type SimulationReport struct {
+	Options         SimulationOptions
+	Updates         int
+	Failures        int
+	UpdateDurations []time.Duration
+	QueueWaits      []time.Duration
+	MaxQueueLength  int
+	Patches         int64
+	JuiceShopCalls  int64
+}
+
+// runSimulation runs the progress updates of the watchdog for synthetic teams against fake JuiceShops and a fake kubernetes api,
+// e.g. to benchmark the worker count and the pacing of patches before an event with hundreds of teams. The simulated teams keep solving
+// challenges and their JuiceShops occasionally lose their progress, so that the progress gets cached and restored like during an event.
+func runSimulation(options SimulationOptions, out io.Writer) SimulationReport {
+	log.Infof("Simulating %d teams with %d workers for %s", options.Teams, options.Workers, options.Duration)
+	previousClient, previousWorkloads, previousStore := juiceShopClient, workloads, progressStore
+	defer func() { juiceShopClient, workloads, progressStore = previousClient, previousWorkloads, previousStore }()
+
+	fleet := fakejuiceshop.NewFleet()
+	fleet.Latency = options.Latency
+	for i := 1; i <= options.Teams; i++ {
+		fleet.Team(simulatedTeam(i)).DisableCodingChallenges()
+	}
+	juiceShopClient = fleet.Client()
+	workloads = DeploymentWorkloads{}
+	progressStore = AnnotationProgressStore{}
+
+	report := SimulationReport{Options: options}
+	clientset := fake.NewSimpleClientset(simulatedDeployments(options.Teams)...)
+	clientset.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt64(&report.Patches, 1)
+		time.Sleep(options.Latency)
+		// handled by the default reactor of the fake clientset
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Duration)
+	defer cancel()
+	juiceShops := NewJuiceShopCache(clientset, metav1.NamespaceDefault, options.PollInterval)
+	juiceShops.Start(ctx)
+	go simulateSolves(ctx, fleet, options.Teams)
+	// every team fits into the queue, the report shows how long they wait for the workers instead
+	queue := NewProgressUpdateQueue(options.Teams, options.PollInterval)
+	scoreboardCache := NewScoreboardCache(0)
+
+	mutex := sync.Mutex{}
+	workers := &sync.WaitGroup{}
+	for i := 0; i < options.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for queue.Process(func(job ProgressUpdateJobs) error {
+				start := time.Now()
+				_, err := runProgressUpdateJob(job, clientset, scoreboardCache)
+				mutex.Lock()
+				defer mutex.Unlock()
+				report.Updates++
+				if err != nil {
+					report.Failures++
+				}
+				report.UpdateDurations = append(report.UpdateDurations, time.Since(start))
+				report.QueueWaits = append(report.QueueWaits, start.Sub(job.QueuedAt))
+				return err
+			}) {
+			}
+		}()
+	}
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mutex.Lock()
+				if length := queue.Len(); length > report.MaxQueueLength {
+					report.MaxQueueLength = length
+				}
+				mutex.Unlock()
+			}
+		}
+	}()
+
+	createProgressUpdateJobs(ctx, queue, juiceShops, options.PollInterval, NewHeartbeats(options.Duration), NewTeamTracker(options.Duration), func() {})
+	queue.ShutDown()
+	workers.Wait()
+	for _, team := range fleet.Teams() {
+		report.JuiceShopCalls += int64(len(fleet.Team(team).Requests()))
+	}
+
+	report.Write(out)
+	return report
+}
+
+// simulatedTeam is the name of the i-th simulated team
+func simulatedTeam(i int) string {
+	return fmt.Sprintf("team-%04d", i)
+}
+
+// simulatedDeployments creates the JuiceShop deployments of the simulated teams
+func simulatedDeployments(teams int) []runtime.Object {
+	selectorLabels, err := labels.ConvertSelectorToLabelsMap(juiceShopSelector)
+	if err != nil {
+		selectorLabels = labels.Set{"app": "juice-shop"}
+	}
+	deployments := []runtime.Object{}
+	for i := 1; i <= teams; i++ {
+		team := simulatedTeam(i)
+		deploymentLabels := map[string]string{"team": team}
+		for key, value := range selectorLabels {
+			deploymentLabels[key] = value
+		}
+		deployments = append(deployments, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("t-%s-juiceshop", team),
+				Namespace:         metav1.NamespaceDefault,
+				Labels:            deploymentLabels,
+				Annotations:       map[string]string{},
+				CreationTimestamp: metav1.Now(),
+			},
+			Status: appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+		})
+	}
+	return deployments
+}
+
+// simulateSolves lets the teams of the fleet solve challenges until the context is cancelled. Every tick each team has a chance to solve
+// another challenge, and a smaller one that its JuiceShop loses its progress.
+func simulateSolves(ctx context.Context, fleet *fakejuiceshop.Fleet, teams int) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ticker := time.NewTicker(simulationTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for i := 1; i <= teams; i++ {
+				juiceShop := fleet.Team(simulatedTeam(i))
+				switch {
+				case random.Float64() < simulatedResetProbability:
+					juiceShop.Reset()
+				case random.Float64() < simulatedSolveProbability:
+					juiceShop.Solve(random.Intn(simulatedChallenges) + 1)
+				}
+			}
+		}
+	}
+}
+
+// Write prints the report in a human readable form
+func (r SimulationReport) Write(out io.Writer) {
+	seconds := r.Options.Duration.Seconds()
+	fmt.Fprintf(out, "Simulated %d teams with %d workers for %s, polling every %s with %s latency\n", r.Options.Teams, r.Options.Workers, r.Options.Duration, r.Options.PollInterval, r.Options.Latency)
+	fmt.Fprintf(out, "Progress updates:   %d (%.1f/s), %d failed\n", r.Updates, float64(r.Updates)/seconds, r.Failures)
+	fmt.Fprintf(out, "Update duration:    p50 %s, p95 %s, p99 %s\n", percentile(r.UpdateDurations, 0.5), percentile(r.UpdateDurations, 0.95), percentile(r.UpdateDurations, 0.99))
+	fmt.Fprintf(out, "Queue wait:         p50 %s, p95 %s, p99 %s\n", percentile(r.QueueWaits, 0.5), percentile(r.QueueWaits, 0.95), percentile(r.QueueWaits, 0.99))
+	fmt.Fprintf(out, "Max queue length:   %d\n", r.MaxQueueLength)
+	fmt.Fprintf(out, "Kubernetes patches: %d (%.1f/s)\n", r.Patches, float64(r.Patches)/seconds)
+	fmt.Fprintf(out, "JuiceShop requests: %d (%.1f/s)\n", r.JuiceShopCalls, float64(r.JuiceShopCalls)/seconds)
+}
+
+// percentile returns the duration below which the fraction of the durations are, 0 without durations
+func percentile(durations []time.Duration, fraction float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(fraction * float64(len(sorted)-1))
+	return sorted[index].Round(time.Microsecond)
+}
+
+// parseSimulationOptions validates the flags of a simulation run
+func parseSimulationOptions(teams int, workers, pollInterval, duration, latency string) (SimulationOptions, error) {
+	options := SimulationOptions{Teams: teams}
+	var err error
+	if options.Workers, err = strconv.Atoi(workers); err != nil || options.Workers < 1 {
+		return options, fmt.Errorf("Invalid WORKERS: %s", workers)
+	}
+	if options.PollInterval, err = time.ParseDuration(pollInterval); err != nil || options.PollInterval <= 0 {
+		return options, fmt.Errorf("Invalid POLL_INTERVAL: %s", pollInterval)
+	}
+	if options.Duration, err = time.ParseDuration(duration); err != nil || options.Duration <= 0 {
+		return options, fmt.Errorf("Invalid SIMULATE_DURATION: %s", duration)
+	}
+	if options.Latency, err = time.ParseDuration(latency); err != nil || options.Latency < 0 {
+		return options, fmt.Errorf("Invalid SIMULATE_LATENCY: %s", latency)
+	}
+	return options, nil
+}
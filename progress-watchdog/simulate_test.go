@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestSimulationUpdatesTheProgressOfAllTeams(t *testing.T) {
+	out := &bytes.Buffer{}
+	report := runSimulation(SimulationOptions{Teams: 5, Workers: 2, PollInterval: 50 * time.Millisecond, Duration: 500 * time.Millisecond}, out)
+
+	assert.GreaterOrEqual(t, report.Updates, 5)
+	assert.Equal(t, 0, report.Failures)
+	assert.Len(t, report.UpdateDurations, report.Updates)
+	assert.Greater(t, report.JuiceShopCalls, int64(0))
+	assert.Contains(t, out.String(), "Simulated 5 teams with 2 workers for 500ms")
+}
+
+func TestSimulatedDeploymentsMatchTheJuiceShopSelector(t *testing.T) {
+	deployments := simulatedDeployments(2)
+	assert.Len(t, deployments, 2)
+	deployment := deployments[1].(*appsv1.Deployment)
+	assert.Equal(t, "t-team-0002-juiceshop", deployment.Name)
+	assert.Equal(t, map[string]string{"app": "juice-shop", "team": "team-0002"}, deployment.Labels)
+	assert.Equal(t, int32(1), deployment.Status.ReadyReplicas)
+}
+
+func TestPercentileOfDurations(t *testing.T) {
+	durations := []time.Duration{5 * time.Millisecond, time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}
+	assert.Equal(t, 3*time.Millisecond, percentile(durations, 0.5))
+	assert.Equal(t, 5*time.Millisecond, percentile(durations, 1))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.5))
+}
+
+func TestParseSimulationOptions(t *testing.T) {
+	options, err := parseSimulationOptions(500, "20", "5s", "2m", "50ms")
+	assert.NoError(t, err)
+	assert.Equal(t, SimulationOptions{Teams: 500, Workers: 20, PollInterval: 5 * time.Second, Duration: 2 * time.Minute, Latency: 50 * time.Millisecond}, options)
+
+	_, err = parseSimulationOptions(500, "20", "5s", "forever", "50ms")
+	assert.EqualError(t, err, "Invalid SIMULATE_DURATION: forever")
+}
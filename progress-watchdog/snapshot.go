@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// snapshotVersion is increased on incompatible changes of the Snapshot format
+	snapshotVersion  = 1
+	snapshotSaltSize = 16
+)
+
+// snapshotMagic identifies encrypted snapshot archives
+var snapshotMagic = []byte("MJSNAP1\n")
+
+// errSnapshotPassphrase is returned when a snapshot can't be decrypted, either because of a wrong passphrase or a corrupted archive
+var errSnapshotPassphrase = errors.New("Failed to decrypt snapshot, the passphrase is wrong or the archive is corrupted")
+
+// snapshotConfigVariables are the env vars configuring the event which are stored in snapshots. Env vars containing secrets are left out.
+var snapshotConfigVariables = []string{
+	"TUTORIAL_SOLVE_WEIGHT",
+	"SCORING_ELIGIBILITY_RULE",
+	"SCORING_POINTS_RULE",
+	"FEATURE_FLAGS",
+	"TIME_ZONE",
+	"DEFAULT_LOCALE",
+	"NOTIFICATION_TEMPLATES",
+	"TEAM_IDLE_THRESHOLD",
+	"LIFECYCLE_HOOKS",
+}
+
+// Snapshot contains the whole state of an event: the JuiceShop deployments and services of all teams, including their cached progress,
+// solve history and passcodes, and the configuration of the watchdog. Snapshots are stored as encrypted archive, see writeSnapshot.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Namespace the snapshot was created in, empty if it contains the teams of all namespaces
+	Namespace string            `json:"namespace"`
+	Config    map[string]string `json:"config"`
+	Teams     []TeamSnapshot    `json:"teams"`
+}
+
+// TeamSnapshot the state of a single team. Score and ChallengesSolved are informational, they are recalculated after a restore.
+type TeamSnapshot struct {
+	Team string `json:"team"`
+	// Namespace of the team, empty in snapshots created before it was recorded per team, see Snapshot.namespaceOf
+	Namespace        string            `json:"namespace,omitempty"`
+	Score            float64           `json:"score"`
+	ChallengesSolved int               `json:"challengesSolved"`
+	Deployment       appsv1.Deployment `json:"deployment"`
+	Service          *corev1.Service   `json:"service,omitempty"`
+}
+
+// namespaceOf returns the namespace the team was snapshotted in
+func (s Snapshot) namespaceOf(team TeamSnapshot) string {
+	if team.Namespace != "" {
+		return team.Namespace
+	}
+	return s.Namespace
+}
+
+// RestoreResult what a restore did for a single team
+type RestoreResult struct {
+	Team      string `json:"team"`
+	Namespace string `json:"namespace"`
+	// Action is either `created` if the JuiceShop of the team was recreated or `updated` if only its progress was restored
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// snapshotMeta keeps only the metadata of an object which can be recreated in another cluster
+func snapshotMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            meta.Name,
+		Labels:          meta.Labels,
+		Annotations:     meta.Annotations,
+		OwnerReferences: meta.OwnerReferences,
+	}
+}
+
+// createSnapshot captures the state of all teams in the namespace, or in all namespaces if it is metav1.NamespaceAll
+func createSnapshot(ctx context.Context, clientset kubernetes.Interface, namespace string, scoring Scoring, now time.Time) (Snapshot, error) {
+	juiceShops, err := DeploymentWorkloads{}.List(ctx, clientset, namespace, juiceShopSelector)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("Failed to list JuiceShop deployments: %v", err)
+	}
+	servicesByName := map[string]corev1.Service{}
+	err = listPaged(ctx, juiceShopSelector, func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+		return clientset.CoreV1().Services(namespace).List(ctx, options)
+	}, func(obj runtime.Object) {
+		service := obj.(*corev1.Service)
+		servicesByName[service.Namespace+"/"+service.Name] = *service
+	})
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("Failed to list JuiceShop services: %v", err)
+	}
+
+	snapshot := Snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: now,
+		Namespace: namespace,
+		Config:    map[string]string{},
+		Teams:     []TeamSnapshot{},
+	}
+	for _, name := range snapshotConfigVariables {
+		if value, ok := lookupEnv(name); ok {
+			snapshot.Config[name] = value
+		}
+	}
+
+	for _, deployment := range juiceShops {
+		progress := teamProgressFromDeployment(deployment)
+		// the progress records are included, so that snapshots are independent of the progress store
+		deployment.Annotations = withProgressRecords(deployment)
+		team := TeamSnapshot{
+			Team:             progress.Team,
+			Namespace:        deployment.Namespace,
+			Score:            scoring.Score(progress),
+			ChallengesSolved: progress.ChallengesSolved,
+			Deployment: appsv1.Deployment{
+				ObjectMeta: snapshotMeta(deployment.ObjectMeta),
+				Spec:       deployment.Spec,
+			},
+		}
+		if service, ok := servicesByName[deployment.Namespace+"/"+deployment.Name]; ok {
+			spec := service.Spec
+			// assigned by the cluster, they can't be reused in another one
+			spec.ClusterIP = ""
+			spec.ClusterIPs = nil
+			team.Service = &corev1.Service{ObjectMeta: snapshotMeta(service.ObjectMeta), Spec: spec}
+		}
+		snapshot.Teams = append(snapshot.Teams, team)
+	}
+	sort.Slice(snapshot.Teams, func(i, j int) bool {
+		return teamKey(snapshot.Teams[i].Namespace, snapshot.Teams[i].Team) < teamKey(snapshot.Teams[j].Namespace, snapshot.Teams[j].Team)
+	})
+	return snapshot, nil
+}
+
+// snapshotCipher creates the AES-256-GCM cipher of a snapshot with a key derived from the passphrase
+func snapshotCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeSnapshot writes the snapshot as gzip compressed json, encrypted with AES-256-GCM using a key derived from the passphrase
+func writeSnapshot(writer io.Writer, snapshot Snapshot, passphrase []byte) error {
+	compressed := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(compressed)
+	if err := json.NewEncoder(gzipWriter).Encode(snapshot); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	salt := make([]byte, snapshotSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := snapshotCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	header := append(append(append([]byte{}, snapshotMagic...), salt...), nonce...)
+	archive := aead.Seal(header, nonce, compressed.Bytes(), snapshotMagic)
+	_, err = writer.Write(archive)
+	return err
+}
+
+// readSnapshot decrypts and decodes a snapshot written by writeSnapshot
+func readSnapshot(reader io.Reader, passphrase []byte) (Snapshot, error) {
+	archive, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if !bytes.HasPrefix(archive, snapshotMagic) {
+		return Snapshot{}, errors.New("Not a MultiJuicer snapshot")
+	}
+	archive = archive[len(snapshotMagic):]
+	if len(archive) < snapshotSaltSize {
+		return Snapshot{}, errSnapshotPassphrase
+	}
+	salt, archive := archive[:snapshotSaltSize], archive[snapshotSaltSize:]
+
+	aead, err := snapshotCipher(passphrase, salt)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(archive) < aead.NonceSize() {
+		return Snapshot{}, errSnapshotPassphrase
+	}
+	compressed, err := aead.Open(nil, archive[:aead.NonceSize()], archive[aead.NonceSize():], snapshotMagic)
+	if err != nil {
+		return Snapshot{}, errSnapshotPassphrase
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{}
+	if err := json.NewDecoder(gzipReader).Decode(&snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("Failed to decode snapshot: %v", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return Snapshot{}, fmt.Errorf("Unsupported snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// restoredAnnotations returns the annotations of the snapshot with the last request set to now,
+// so that the cleaner doesn't delete the restored teams as idle right away
+func restoredAnnotations(annotations map[string]string, now time.Time) map[string]string {
+	restored := map[string]string{}
+	for key, value := range annotations {
+		restored[key] = value
+	}
+	restored[annotation("lastRequest")] = strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	restored[annotation("lastRequestReadable")] = now.String()
+	return restored
+}
+
+// ownerReferencesIn points the owner references of restored objects to the JuiceBalancer of the cluster they are restored into.
+// Returns nil if the JuiceBalancer doesn't exist (yet), the objects are created without owner then.
+func ownerReferencesIn(ctx context.Context, clientset kubernetes.Interface, namespace string, references []metav1.OwnerReference) []metav1.OwnerReference {
+	if len(references) == 0 {
+		return nil
+	}
+	balancer, err := clientset.AppsV1().Deployments(namespace).Get(ctx, "juice-balancer", metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Failed to get JuiceBalancer deployment, restoring objects without owner: %s", err)
+		return nil
+	}
+	restored := []metav1.OwnerReference{}
+	for _, reference := range references {
+		if reference.Kind == "Deployment" && reference.Name == balancer.Name {
+			reference.UID = balancer.UID
+			restored = append(restored, reference)
+		}
+	}
+	return restored
+}
+
+// restoreSnapshot rebuilds the teams of the snapshot in the namespace, or in the namespaces they were snapshotted in if it is empty.
+// Missing JuiceShops are recreated with their cached progress, which the watchdog applies to them once they are ready.
+// Existing JuiceShops only get their cached progress, solve history and passcode restored. A dry run only reports what would be done.
+func restoreSnapshot(ctx context.Context, clientset kubernetes.Interface, namespace string, snapshot Snapshot, dryRun bool, now time.Time) []RestoreResult {
+	results := []RestoreResult{}
+	for _, team := range snapshot.Teams {
+		teamNamespace := namespace
+		if teamNamespace == "" {
+			teamNamespace = snapshot.namespaceOf(team)
+		}
+		result := RestoreResult{Team: team.Team, Namespace: teamNamespace}
+		action, err := restoreTeam(ctx, clientset, teamNamespace, team, dryRun, now)
+		if err != nil {
+			log.Warningf("Failed to restore team '%s' in namespace '%s': %s", team.Team, teamNamespace, err)
+			result.Error = err.Error()
+		} else {
+			result.Action = action
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func restoreTeam(ctx context.Context, clientset kubernetes.Interface, namespace string, team TeamSnapshot, dryRun bool, now time.Time) (string, error) {
+	deployments := clientset.AppsV1().Deployments(namespace)
+	_, err := deployments.Get(ctx, team.Deployment.Name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return "", err
+	}
+	// the progress is saved with the progress store, which doesn't read it from the annotations once the team has progress in the store
+	records, annotations := splitProgressRecords(restoredAnnotations(team.Deployment.Annotations, now))
+
+	if err == nil {
+		if !dryRun {
+			patch := map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": annotations,
+				},
+			}
+			if err := patchJuiceShopDeployment(ctx, clientset, namespace, team.Team, patch); err != nil {
+				return "", err
+			}
+			if err := restoreProgressRecords(ctx, clientset, namespace, team.Team, records); err != nil {
+				return "", err
+			}
+		}
+		return "updated", nil
+	}
+
+	if dryRun {
+		return "created", nil
+	}
+	deployment := team.Deployment.DeepCopy()
+	deployment.Namespace = namespace
+	deployment.Annotations = annotations
+	deployment.OwnerReferences = ownerReferencesIn(ctx, clientset, namespace, deployment.OwnerReferences)
+	if _, err := deployments.Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	if err := restoreProgressRecords(ctx, clientset, namespace, team.Team, records); err != nil {
+		return "", err
+	}
+	if team.Service != nil {
+		service := team.Service.DeepCopy()
+		service.Namespace = namespace
+		service.OwnerReferences = ownerReferencesIn(ctx, clientset, namespace, service.OwnerReferences)
+		if _, err := clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("Failed to create service: %v", err)
+		}
+	}
+	log.Infof("Recreated JuiceShop of team '%s' from snapshot", team.Team)
+	return "created", nil
+}
+
+// restoreProgressRecords saves the progress records of a snapshot with the progress store, overwriting the progress the team has now
+func restoreProgressRecords(ctx context.Context, clientset kubernetes.Interface, namespace, teamname string, records map[string]string) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if _, err := progressStore.Save(ctx, clientset, namespace, teamname, records, nil); err != nil {
+		return fmt.Errorf("Failed to restore progress: %v", err)
+	}
+	return nil
+}
+
+// configDifferences lists the event configuration of the snapshot which differs from the current one. It can't be restored
+// automatically, as it's part of the helm values of the installation.
+func configDifferences(config map[string]string, current func(string) string) []string {
+	differences := []string{}
+	for _, name := range snapshotConfigVariables {
+		if config[name] != current(name) {
+			differences = append(differences, fmt.Sprintf("%s is '%s' in the snapshot, but '%s' now", name, config[name], current(name)))
+		}
+	}
+	return differences
+}
+
+// readSnapshotPassphrase reads the passphrase of snapshots from the file or, without file, the `SNAPSHOT_PASSPHRASE` env var
+func readSnapshotPassphrase(file string) ([]byte, error) {
+	passphrase := []byte(os.Getenv("SNAPSHOT_PASSPHRASE"))
+	if file != "" {
+		var err error
+		passphrase, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase = bytes.TrimRight(passphrase, "\r\n")
+	}
+	if len(strings.TrimSpace(string(passphrase))) == 0 {
+		return nil, errors.New("No snapshot passphrase configured, set --passphrase-file or SNAPSHOT_PASSPHRASE")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createSnapshotTestDeployment(team string) *appsv1.Deployment {
+	deployment := createJuiceShopDeployment(team, "abc", "1")
+	deployment.UID = types.UID("uid-" + team)
+	deployment.ResourceVersion = "42"
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:10:00Z"}`
+	deployment.Annotations["multi-juicer.iteratec.dev/passcode"] = "hash-" + team
+	deployment.Annotations["multi-juicer.iteratec.dev/lastRequest"] = "1619863200000"
+	deployment.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "juice-balancer", UID: "old-balancer"}}
+	return deployment
+}
+
+func createSnapshotTestService(team string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "t-" + team + "-juiceshop",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "juice-shop", "team": team},
+		},
+		Spec: corev1.ServiceSpec{ClusterIP: "10.0.0.1", Ports: []corev1.ServicePort{{Port: 3000}}},
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createSnapshotTestDeployment("foobar"), createSnapshotTestService("foobar"))
+	snapshot, err := createSnapshot(context.Background(), clientset, "default", Scoring{TutorialSolveWeight: 1}, eventStart)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Teams, 1)
+	assert.Equal(t, 1.0, snapshot.Teams[0].Score)
+	assert.Empty(t, snapshot.Teams[0].Deployment.ResourceVersion)
+	assert.Empty(t, snapshot.Teams[0].Deployment.UID)
+	assert.Empty(t, snapshot.Teams[0].Service.Spec.ClusterIP)
+
+	archive := &bytes.Buffer{}
+	assert.NoError(t, writeSnapshot(archive, snapshot, []byte("correct horse")))
+	assert.NotContains(t, archive.String(), "foobar")
+
+	restored, err := readSnapshot(bytes.NewReader(archive.Bytes()), []byte("correct horse"))
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Teams[0].Team, restored.Teams[0].Team)
+	assert.Equal(t, snapshot.Teams[0].Deployment.Annotations, restored.Teams[0].Deployment.Annotations)
+	assert.True(t, snapshot.CreatedAt.Equal(restored.CreatedAt))
+
+	_, err = readSnapshot(bytes.NewReader(archive.Bytes()), []byte("wrong horse"))
+	assert.Equal(t, errSnapshotPassphrase, err)
+	_, err = readSnapshot(bytes.NewReader([]byte("not a snapshot")), []byte("correct horse"))
+	assert.Error(t, err)
+}
+
+func TestSnapshotKeepsTheTeamsOfAllNamespacesApart(t *testing.T) {
+	teamA, teamB := createSnapshotTestDeployment("foobar"), createSnapshotTestDeployment("foobar")
+	teamA.Namespace, teamB.Namespace = "team-a", "team-b"
+	service := createSnapshotTestService("foobar")
+	service.Namespace = "team-a"
+	snapshot, err := createSnapshot(context.Background(), fake.NewSimpleClientset(teamA, teamB, service), metav1.NamespaceAll, Scoring{}, eventStart)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Teams, 2)
+	assert.Equal(t, "team-a", snapshot.Teams[0].Namespace)
+	assert.NotNil(t, snapshot.Teams[0].Service)
+	assert.Equal(t, "team-b", snapshot.Teams[1].Namespace)
+	assert.Nil(t, snapshot.Teams[1].Service, "Should only include the service of the team in the same namespace")
+
+	clientset := fake.NewSimpleClientset()
+	results := restoreSnapshot(context.Background(), clientset, metav1.NamespaceAll, snapshot, false, eventStart)
+	assert.Equal(t, []RestoreResult{{Team: "foobar", Namespace: "team-a", Action: "created"}, {Team: "foobar", Namespace: "team-b", Action: "created"}}, results)
+	for _, namespace := range []string{"team-a", "team-b"} {
+		_, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+		assert.NoError(t, err, namespace)
+	}
+}
+
+func TestRestoreRecreatesMissingTeamsAndUpdatesExistingOnes(t *testing.T) {
+	snapshot, err := createSnapshot(context.Background(), fake.NewSimpleClientset(
+		createSnapshotTestDeployment("existing"), createSnapshotTestDeployment("missing"), createSnapshotTestService("missing"),
+	), "default", Scoring{}, eventStart)
+	assert.NoError(t, err)
+
+	balancer := createJuiceShopDeployment("balancer", "", "")
+	balancer.Name = "juice-balancer"
+	balancer.Labels = map[string]string{"app": "juice-balancer"}
+	balancer.UID = "new-balancer"
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("existing", "", "0"), balancer)
+	now := time.Date(2021, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	dryRun := restoreSnapshot(context.Background(), clientset, "default", snapshot, true, now)
+	assert.Equal(t, []RestoreResult{{Team: "existing", Namespace: "default", Action: "updated"}, {Team: "missing", Namespace: "default", Action: "created"}}, dryRun)
+	_, err = clientset.AppsV1().Deployments("default").Get(context.Background(), "t-missing-juiceshop", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	results := restoreSnapshot(context.Background(), clientset, "default", snapshot, false, now)
+	assert.Equal(t, dryRun, results)
+
+	existing := getTestDeployment(t, clientset, "existing")
+	assert.Equal(t, "abc", existing.Annotations["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, "hash-existing", existing.Annotations["multi-juicer.iteratec.dev/passcode"])
+	assert.Equal(t, "1620468000000", existing.Annotations["multi-juicer.iteratec.dev/lastRequest"])
+
+	missing := getTestDeployment(t, clientset, "missing")
+	assert.Equal(t, `{"1":"2021-05-01T10:10:00Z"}`, missing.Annotations["multi-juicer.iteratec.dev/solves"])
+	assert.Equal(t, types.UID("new-balancer"), missing.OwnerReferences[0].UID)
+	_, err = clientset.CoreV1().Services("default").Get(context.Background(), "t-missing-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestRestoreSavesTheProgressWithTheProgressStore(t *testing.T) {
+	snapshot, err := createSnapshot(context.Background(), fake.NewSimpleClientset(createSnapshotTestDeployment("existing")), "default", Scoring{}, eventStart)
+	assert.NoError(t, err)
+
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("existing", "", "0"))
+	store := useConfigMapProgressStore(t, clientset)
+	// the team already has a ConfigMap, the store doesn't read its progress annotations anymore
+	_, err = store.Save(context.Background(), clientset, "default", "existing", map[string]string{"multi-juicer.iteratec.dev/continueCode": "stale"}, nil)
+	assert.NoError(t, err)
+
+	results := restoreSnapshot(context.Background(), clientset, "default", snapshot, false, time.Date(2021, 5, 8, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, []RestoreResult{{Team: "existing", Namespace: "default", Action: "updated"}}, results)
+
+	existing := getTestDeployment(t, clientset, "existing")
+	records := store.Records(*existing)
+	assert.Equal(t, "abc", records["multi-juicer.iteratec.dev/continueCode"])
+	assert.Equal(t, `{"1":"2021-05-01T10:10:00Z"}`, records["multi-juicer.iteratec.dev/solves"])
+	assert.Equal(t, "hash-existing", existing.Annotations["multi-juicer.iteratec.dev/passcode"], "Should restore the other annotations on the deployment")
+	assert.NotContains(t, existing.Annotations, "multi-juicer.iteratec.dev/continueCode")
+}
+
+func TestConfigDifferences(t *testing.T) {
+	current := map[string]string{"TIME_ZONE": "UTC"}
+	differences := configDifferences(map[string]string{"TIME_ZONE": "Europe/Berlin"}, func(name string) string { return current[name] })
+	assert.Equal(t, []string{"TIME_ZONE is 'Europe/Berlin' in the snapshot, but 'UTC' now"}, differences)
+}
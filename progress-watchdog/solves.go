@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// ctfKey is the key the JuiceShops generate the flags of the challenges with, configured via `CTF_KEY` like for the JuiceShops
+var ctfKey string
+
+// SolveTimes maps the ids of the solved challenges of a team to the time the watchdog first saw them solved
+type SolveTimes map[int]time.Time
+
+// parseSolveTimes decodes the json encoded solves annotation, returning no solves if it is empty or invalid
+func parseSolveTimes(annotation string) SolveTimes {
+	solves := SolveTimes{}
+	if annotation == "" {
+		return solves
+	}
+	if err := json.Unmarshal([]byte(annotation), &solves); err != nil {
+		log.Warningf("Could not decode solves annotation '%s'", annotation)
+		return SolveTimes{}
+	}
+	return solves
+}
+
+// Update returns the solve times of the currently solved challenges.
+// Challenges solved before keep their original solve time, newly solved ones are recorded with the passed time.
+func (s SolveTimes) Update(solvedChallenges []int, now time.Time) SolveTimes {
+	updated := SolveTimes{}
+	for _, challenge := range solvedChallenges {
+		if solvedAt, ok := s[challenge]; ok {
+			updated[challenge] = solvedAt
+		} else {
+			updated[challenge] = now.UTC().Truncate(time.Second)
+		}
+	}
+	return updated
+}
+
+// UpdateReported works like Update, but newly solved challenges are recorded with the solve time reported by the JuiceShop,
+// which is more precise than the time the watchdog first saw them. Reported times in the future are ignored.
+func (s SolveTimes) UpdateReported(solvedChallenges []int, reported SolveTimes, now time.Time) SolveTimes {
+	updated := s.Update(solvedChallenges, now)
+	for challenge, solvedAt := range updated {
+		if _, known := s[challenge]; known {
+			continue
+		}
+		if reportedAt, ok := reported[challenge]; ok && !reportedAt.IsZero() && reportedAt.Before(solvedAt) {
+			updated[challenge] = reportedAt.UTC().Truncate(time.Second)
+		}
+	}
+	return updated
+}
+
+// reportedSolveTimesOf collects the solve times of the solved challenges as reported by the challenge api of the JuiceShop.
+// Challenges restored with a ContinueCode are reported as solved at the time they got restored, but keep their recorded solve time, see UpdateReported.
+func reportedSolveTimesOf(challenges []ChallengeStatus) SolveTimes {
+	solves := SolveTimes{}
+	for _, challenge := range challenges {
+		if challenge.Solved {
+			solves[challenge.ID] = challenge.UpdatedAt.UTC()
+		}
+	}
+	return solves
+}
+
+// SolveDetail a solved challenge as reported by the challenge api of the JuiceShop
+type SolveDetail struct {
+	Challenge int       `json:"challenge"`
+	Key       string    `json:"key"`
+	SolvedAt  time.Time `json:"solvedAt"`
+	// Flag is the CTF flag of the challenge, only set if the ctfFlags feature is enabled
+	Flag string `json:"flag,omitempty"`
+}
+
+// ctfFlag generates the CTF flag of the challenge like the JuiceShop and juice-shop-ctf-cli do, the hex encoded HMAC-SHA1 of its name
+func ctfFlag(key, challengeName string) string {
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(challengeName))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// solveDetailsOf collects the details of the solved challenges, ordered by challenge id, with their flags if the ctfFlags feature is enabled.
+// Challenges restored with a ContinueCode are reported as solved at the time they got restored by the JuiceShop.
+func solveDetailsOf(challenges []ChallengeStatus) []SolveDetail {
+	withFlags := featureFlags.Enabled(FeatureCTFFlags) && ctfKey != ""
+	details := []SolveDetail{}
+	for _, challenge := range challenges {
+		if challenge.Solved {
+			detail := SolveDetail{Challenge: challenge.ID, Key: challenge.Key, SolvedAt: challenge.UpdatedAt.UTC()}
+			if withFlags {
+				detail.Flag = ctfFlag(ctfKey, challenge.Name)
+			}
+			details = append(details, detail)
+		}
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].Challenge < details[j].Challenge })
+	return details
+}
+
+// parseSolveDetails decodes the json encoded solveDetails annotation, returning no details if it is empty or invalid
+func parseSolveDetails(annotation string) []SolveDetail {
+	if annotation == "" {
+		return nil
+	}
+	details := []SolveDetail{}
+	if err := json.Unmarshal([]byte(annotation), &details); err != nil {
+		log.Warningf("Could not decode solveDetails annotation '%s'", annotation)
+		return nil
+	}
+	return details
+}
+
+// LastSolvedAt returns the time of the latest solve, the time the team reached its current score. Zero if there are no solves.
+func (s SolveTimes) LastSolvedAt() time.Time {
+	last := time.Time{}
+	for _, solvedAt := range s {
+		if solvedAt.After(last) {
+			last = solvedAt
+		}
+	}
+	return last
+}
+
+// TimelinePoint the cumulative score of a team at a point in time
+type TimelinePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Score     int       `json:"score"`
+}
+
+// Timeline returns the cumulative score after each solve, ordered by time
+func (s SolveTimes) Timeline() []TimelinePoint {
+	times := make([]time.Time, 0, len(s))
+	for _, solvedAt := range s {
+		times = append(times, solvedAt)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	points := []TimelinePoint{}
+	for i, solvedAt := range times {
+		// multiple solves detected at the same time are merged into a single point
+		if len(points) > 0 && points[len(points)-1].Timestamp.Equal(solvedAt) {
+			points[len(points)-1].Score = i + 1
+			continue
+		}
+		points = append(points, TimelinePoint{Timestamp: solvedAt, Score: i + 1})
+	}
+	return points
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParsesSolveTimes(t *testing.T) {
+	solves := parseSolveTimes(`{"1":"2021-05-01T10:00:00Z","12":"2021-05-01T11:30:00Z"}`)
+	assert.Equal(t, SolveTimes{
+		1:  time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC),
+		12: time.Date(2021, 5, 1, 11, 30, 0, 0, time.UTC),
+	}, solves)
+
+	assert.Equal(t, SolveTimes{}, parseSolveTimes(""))
+	assert.Equal(t, SolveTimes{}, parseSolveTimes("not json"))
+}
+
+func TestUpdateKeepsOriginalSolveTimes(t *testing.T) {
+	firstSolve := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	updated := SolveTimes{1: firstSolve, 2: firstSolve}.Update([]int{1, 3}, now)
+
+	assert.Equal(t, SolveTimes{1: firstSolve, 3: now}, updated, "Should keep known solve times, add new solves and drop no longer solved challenges")
+}
+
+func TestTimelineReturnsCumulativeScores(t *testing.T) {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	solves := SolveTimes{
+		5: start.Add(2 * time.Hour),
+		1: start,
+		2: start.Add(time.Hour),
+		3: start.Add(time.Hour),
+	}
+
+	assert.Equal(t, []TimelinePoint{
+		{Timestamp: start, Score: 1},
+		{Timestamp: start.Add(time.Hour), Score: 3},
+		{Timestamp: start.Add(2 * time.Hour), Score: 4},
+	}, solves.Timeline())
+	assert.Equal(t, []TimelinePoint{}, SolveTimes{}.Timeline())
+}
+
+func TestCollectsSolveDetailsOfSolvedChallenges(t *testing.T) {
+	solvedAt := time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC)
+	details := solveDetailsOf([]ChallengeStatus{
+		{ID: 7, Key: "loginAdminChallenge", Solved: true, UpdatedAt: solvedAt.Add(time.Hour)},
+		{ID: 3, Key: "xssChallenge", Solved: false, UpdatedAt: solvedAt},
+		{ID: 1, Key: "scoreBoardChallenge", Solved: true, UpdatedAt: solvedAt},
+	})
+	assert.Equal(t, []SolveDetail{
+		{Challenge: 1, Key: "scoreBoardChallenge", SolvedAt: solvedAt},
+		{Challenge: 7, Key: "loginAdminChallenge", SolvedAt: solvedAt.Add(time.Hour)},
+	}, details)
+
+	encoded, err := json.Marshal(details)
+	assert.NoError(t, err)
+	assert.Equal(t, details, parseSolveDetails(string(encoded)))
+	assert.Nil(t, parseSolveDetails("not json"))
+}
+
+func TestCachesSolveDetailsOnlyIfFeatureIsEnabled(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	challenges := []ChallengeStatus{{ID: 1, Key: "scoreBoardChallenge", Solved: true, UpdatedAt: time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC)}}
+	assert.Nil(t, challengeConfigurationOf(challenges).SolveDetails)
+
+	featureFlags = NewFeatureFlags(map[Feature]bool{FeatureSolveDetails: true})
+	continueCode, _ := EncodeContinueCode([]int{1})
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	cacheContinueCode(clientset, "default", "foobar", "", continueCode, SolveTimes{}, challengeConfigurationOf(challenges))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"challenge":1,"key":"scoreBoardChallenge","solvedAt":"2021-05-01T10:30:00Z"}]`, updated.Annotations["multi-juicer.iteratec.dev/solveDetails"])
+	assert.Equal(t, challenges[0].UpdatedAt, teamProgressFromDeployment(*updated).SolveDetails[0].SolvedAt)
+}
+
+func TestCollectsTheCTFFlagsOfSolvedChallengesIfFeatureIsEnabled(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	defer func() { ctfKey = "" }()
+	solvedAt := time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC)
+	challenges := []ChallengeStatus{{ID: 1, Key: "scoreBoardChallenge", Name: "Score Board", Solved: true, UpdatedAt: solvedAt}}
+	ctfKey = "zLp@.-6fMW6L-7R3b!9uR_K!NfkkTr"
+	assert.Nil(t, challengeConfigurationOf(challenges).SolveDetails)
+
+	featureFlags = NewFeatureFlags(map[Feature]bool{FeatureCTFFlags: true})
+	assert.Equal(t, []SolveDetail{
+		// as generated by the JuiceShop and juice-shop-ctf-cli for the default key of the helm chart
+		{Challenge: 1, Key: "scoreBoardChallenge", SolvedAt: solvedAt, Flag: "804ba6b98b739652d65e6d182c85f04ae43c29d3"},
+	}, challengeConfigurationOf(challenges).SolveDetails)
+
+	ctfKey = ""
+	assert.Equal(t, "", challengeConfigurationOf(challenges).SolveDetails[0].Flag, "Should only collect flags if the key is known")
+}
+
+func TestUpdateReportedUsesSolveTimesOfTheJuiceShopForNewSolves(t *testing.T) {
+	firstSolve := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	reportedSolve := time.Date(2021, 5, 1, 11, 59, 57, 0, time.UTC)
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	updated := SolveTimes{1: firstSolve}.UpdateReported([]int{1, 2, 3}, SolveTimes{1: now, 2: reportedSolve, 3: now.Add(time.Hour)}, now)
+
+	assert.Equal(t, SolveTimes{1: firstSolve, 2: reportedSolve, 3: now}, updated, "Should keep known solve times, e.g. of restored challenges, and ignore reported times in the future")
+}
+
+func TestCachesScoreOnlyIfChallengeAPIScoringIsEnabled(t *testing.T) {
+	defer func() { featureFlags = NewFeatureFlags(nil) }()
+	solvedAt := time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC)
+	challenges := []ChallengeStatus{{ID: 1, Solved: true, UpdatedAt: solvedAt}, {ID: 2, Solved: true, UpdatedAt: solvedAt}}
+	continueCode, _ := EncodeContinueCode([]int{1, 2})
+	assert.Nil(t, challengeConfigurationOf(challenges).SolvedAt)
+
+	featureFlags = NewFeatureFlags(map[Feature]bool{FeatureChallengeAPIScoring: true})
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	cacheContinueCode(clientset, "default", "foobar", "", continueCode, SolveTimes{}, challengeConfigurationOf(challenges))
+
+	updated, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", updated.Annotations["multi-juicer.iteratec.dev/score"])
+	assert.Equal(t, SolveTimes{1: solvedAt, 2: solvedAt}, parseSolveTimes(updated.Annotations["multi-juicer.iteratec.dev/solves"]))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SolveWebhook receives the solve webhooks of the JuiceShops, configured via their `SOLUTIONS_WEBHOOK` env var,
+// and queues an immediate progress update of the team instead of waiting for the next poll.
+// The payload of the webhook isn't trusted: the progress is always fetched from the JuiceShop of the team itself,
+// so unauthenticated calls can at most trigger additional syncs, which are deduplicated by the queue.
+type SolveWebhook struct {
+	clientset kubernetes.Interface
+	// namespace the teams are watched in, teams in other namespaces are only accepted if all namespaces are watched
+	namespace string
+
+	mutex sync.RWMutex
+	// queue of the progress tracking, nil while the instance doesn't track the progress, e.g. as standby replica
+	queue *ProgressUpdateQueue
+}
+
+// NewSolveWebhook creates a SolveWebhook for the teams in the namespace
+func NewSolveWebhook(clientset kubernetes.Interface, namespace string) *SolveWebhook {
+	return &SolveWebhook{clientset: clientset, namespace: namespace}
+}
+
+// Attach routes the received webhooks into the queue, until it gets detached by passing nil
+func (s *SolveWebhook) Attach(queue *ProgressUpdateQueue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queue = queue
+}
+
+// handleSolve queues the progress update of the team for `POST /api/webhooks/solve/{team}`.
+// If all namespaces are watched, the namespace of the team is passed as `namespace` query parameter.
+func (s *SolveWebhook) handleSolve(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	teamname := strings.TrimPrefix(req.URL.Path, "/api/webhooks/solve/")
+	if teamname == "" || strings.Contains(teamname, "/") {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, ok := requestedTeamNamespace(req, s.namespace)
+	if !ok {
+		http.Error(w, "Missing namespace of the team", http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.RLock()
+	queue := s.queue
+	s.mutex.RUnlock()
+	if queue == nil {
+		// the next poll of the tracking replica picks up the solve instead
+		http.Error(w, "Progress isn't tracked by this instance", http.StatusServiceUnavailable)
+		return
+	}
+
+	deployment, err := workloads.Get(req.Context(), s.clientset, namespace, fmt.Sprintf("t-%s-juiceshop", teamname))
+	if k8serrors.IsNotFound(err) || (err == nil && !isJuiceShop(deployment.Labels)) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to get JuiceShop of team '%s' for solve webhook", teamname)
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if deployment.Status.ReadyReplicas < 1 || isPaused(*deployment) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	solveWebhooksReceived.Inc()
+	log.Debugf("Received solve webhook of team '%s', queueing progress update", teamname)
+	job := progressUpdateJobForDeployment(*deployment)
+	job.QueuedAt = time.Now()
+	queue.Add(job)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSolveWebhookQueuesProgressUpdateOfTheTeam(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "1")
+	deployment.Status.ReadyReplicas = 1
+	webhook := NewSolveWebhook(fake.NewSimpleClientset(deployment), "default")
+	queue := newTestProgressUpdateQueue()
+	webhook.Attach(queue)
+
+	rr := httptest.NewRecorder()
+	webhook.handleSolve(rr, httptest.NewRequest("POST", "/api/webhooks/solve/foobar", nil))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	queue.ShutDown()
+	jobs := []ProgressUpdateJobs{}
+	for queue.Process(func(job ProgressUpdateJobs) error {
+		jobs = append(jobs, job)
+		return nil
+	}) {
+	}
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "foobar", jobs[0].Teamname)
+	assert.Equal(t, "abc", jobs[0].LastContinueCode)
+}
+
+func TestSolveWebhookRejectsUnknownTeams(t *testing.T) {
+	webhook := NewSolveWebhook(fake.NewSimpleClientset(), "default")
+	webhook.Attach(newTestProgressUpdateQueue())
+
+	rr := httptest.NewRecorder()
+	webhook.handleSolve(rr, httptest.NewRequest("POST", "/api/webhooks/solve/foobar", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestSolveWebhookIsUnavailableWhileProgressIsNotTracked(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "abc", "1")
+	deployment.Status.ReadyReplicas = 1
+	webhook := NewSolveWebhook(fake.NewSimpleClientset(deployment), "default")
+
+	rr := httptest.NewRecorder()
+	webhook.handleSolve(rr, httptest.NewRequest("POST", "/api/webhooks/solve/foobar", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestSolveWebhookRequiresNamespaceIfAllNamespacesAreWatched(t *testing.T) {
+	webhook := NewSolveWebhook(fake.NewSimpleClientset(), "")
+	webhook.Attach(newTestProgressUpdateQueue())
+
+	rr := httptest.NewRecorder()
+	webhook.handleSolve(rr, httptest.NewRequest("POST", "/api/webhooks/solve/foobar", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// scoreboardUIHandler serves the embedded scoreboard page
+func scoreboardUIHandler() http.Handler {
+	scoreboardFiles, err := fs.Sub(staticFiles, "static/scoreboard")
+	if err != nil {
+		panic("Failed to load embedded scoreboard ui")
+	}
+	return http.StripPrefix("/scoreboard/", http.FileServer(http.FS(scoreboardFiles)))
+}
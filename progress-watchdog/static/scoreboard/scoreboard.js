@@ -0,0 +1,65 @@
+const refreshInterval = 5000;
+
+let messages = {
+  'scoreboard.lastUpdated': 'Last updated {time}',
+  'scoreboard.updateFailed': 'Failed to update the scoreboard, retrying...',
+};
+
+async function loadMessages() {
+  try {
+    const response = await fetch('../api/i18n');
+    if (!response.ok) {
+      throw new Error(`Unexpected status code ${response.status}`);
+    }
+    const { locale, messages: loaded } = await response.json();
+    messages = loaded;
+    document.documentElement.lang = locale;
+    for (const element of document.querySelectorAll('[data-i18n]')) {
+      const text = messages[element.dataset.i18n];
+      if (text) {
+        element.textContent = text;
+      }
+    }
+  } catch (error) {
+    console.error('Failed to load translations, falling back to english', error);
+  }
+}
+
+function renderTeams(teams) {
+  const tbody = document.getElementById('teams');
+  tbody.replaceChildren(
+    ...teams.map(({ position, team, challengesSolved }) => {
+      const row = document.createElement('tr');
+      for (const value of [position, team, challengesSolved]) {
+        const cell = document.createElement('td');
+        cell.textContent = value;
+        row.appendChild(cell);
+      }
+      return row;
+    })
+  );
+}
+
+async function refresh() {
+  const status = document.getElementById('status');
+  try {
+    const response = await fetch('../api/scoreboard');
+    if (!response.ok) {
+      throw new Error(`Unexpected status code ${response.status}`);
+    }
+    const { teams } = await response.json();
+    renderTeams(teams);
+    status.textContent = messages['scoreboard.lastUpdated'].replace(
+      '{time}',
+      new Date().toLocaleTimeString(document.documentElement.lang)
+    );
+  } catch (error) {
+    console.error('Failed to fetch scoreboard', error);
+    status.textContent = messages['scoreboard.updateFailed'];
+  }
+}
+
+loadMessages().then(() => {
+  refresh();
+  setInterval(refresh, refreshInterval);
+});
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxListedChallenges limits the most / least solved challenges listed in the statistics
+const maxListedChallenges = 5
+
+// ChallengeStatistic how often a challenge was solved
+type ChallengeStatistic struct {
+	Challenge int `json:"challenge"`
+	Solves    int `json:"solves"`
+}
+
+// HourStatistic how many challenges were solved within an hour
+type HourStatistic struct {
+	Hour   time.Time `json:"hour"`
+	Solves int       `json:"solves"`
+}
+
+// DurationDistribution summarizes a set of durations, all values are in seconds
+type DurationDistribution struct {
+	Count   int     `json:"count"`
+	Min     float64 `json:"min"`
+	Median  float64 `json:"median"`
+	Average float64 `json:"average"`
+	Max     float64 `json:"max"`
+}
+
+// EventStatistics end of event statistics over all teams
+type EventStatistics struct {
+	Teams                 int                  `json:"teams"`
+	TotalSolves           int                  `json:"totalSolves"`
+	AverageSolvesPerTeam  float64              `json:"averageSolvesPerTeam"`
+	MostSolvedChallenges  []ChallengeStatistic `json:"mostSolvedChallenges"`
+	LeastSolvedChallenges []ChallengeStatistic `json:"leastSolvedChallenges"`
+	// TimeToFirstSolve is measured from the creation of the team to its first solve
+	TimeToFirstSolve DurationDistribution `json:"timeToFirstSolve"`
+	BusiestHour      *HourStatistic       `json:"busiestHour"`
+	SolvesPerHour    []HourStatistic      `json:"solvesPerHour"`
+}
+
+func summarizeDurations(durations []time.Duration) DurationDistribution {
+	if len(durations) == 0 {
+		return DurationDistribution{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	total := time.Duration(0)
+	for _, duration := range durations {
+		total += duration
+	}
+
+	middle := len(durations) / 2
+	median := durations[middle]
+	if len(durations)%2 == 0 {
+		median = (durations[middle-1] + durations[middle]) / 2
+	}
+
+	return DurationDistribution{
+		Count:   len(durations),
+		Min:     durations[0].Seconds(),
+		Median:  median.Seconds(),
+		Average: (total / time.Duration(len(durations))).Seconds(),
+		Max:     durations[len(durations)-1].Seconds(),
+	}
+}
+
+// computeStatistics aggregates the solves of all teams into the event statistics, solves are grouped by the hours of the passed time zone
+func computeStatistics(teams []TeamProgress, location *time.Location) EventStatistics {
+	statistics := EventStatistics{
+		Teams:                 len(teams),
+		MostSolvedChallenges:  []ChallengeStatistic{},
+		LeastSolvedChallenges: []ChallengeStatistic{},
+		SolvesPerHour:         []HourStatistic{},
+	}
+
+	solvesPerChallenge := map[int]int{}
+	solvesPerHour := map[time.Time]int{}
+	timesToFirstSolve := []time.Duration{}
+
+	for _, team := range teams {
+		var firstSolve time.Time
+		for challenge, solvedAt := range team.Solves {
+			statistics.TotalSolves++
+			solvesPerChallenge[challenge]++
+			solvesPerHour[startOfHour(solvedAt, location)]++
+			if firstSolve.IsZero() || solvedAt.Before(firstSolve) {
+				firstSolve = solvedAt
+			}
+		}
+		if !firstSolve.IsZero() && !team.CreatedAt.IsZero() && firstSolve.After(team.CreatedAt) {
+			timesToFirstSolve = append(timesToFirstSolve, firstSolve.Sub(team.CreatedAt))
+		}
+	}
+
+	if len(teams) > 0 {
+		statistics.AverageSolvesPerTeam = float64(statistics.TotalSolves) / float64(len(teams))
+	}
+	statistics.TimeToFirstSolve = summarizeDurations(timesToFirstSolve)
+
+	challenges := []ChallengeStatistic{}
+	for challenge, solves := range solvesPerChallenge {
+		challenges = append(challenges, ChallengeStatistic{Challenge: challenge, Solves: solves})
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		if challenges[i].Solves != challenges[j].Solves {
+			return challenges[i].Solves > challenges[j].Solves
+		}
+		return challenges[i].Challenge < challenges[j].Challenge
+	})
+	for i := 0; i < len(challenges) && i < maxListedChallenges; i++ {
+		statistics.MostSolvedChallenges = append(statistics.MostSolvedChallenges, challenges[i])
+	}
+	for i := len(challenges) - 1; i >= 0 && len(statistics.LeastSolvedChallenges) < maxListedChallenges; i-- {
+		statistics.LeastSolvedChallenges = append(statistics.LeastSolvedChallenges, challenges[i])
+	}
+
+	for hour, solves := range solvesPerHour {
+		statistics.SolvesPerHour = append(statistics.SolvesPerHour, HourStatistic{Hour: hour, Solves: solves})
+	}
+	sort.Slice(statistics.SolvesPerHour, func(i, j int) bool {
+		return statistics.SolvesPerHour[i].Hour.Before(statistics.SolvesPerHour[j].Hour)
+	})
+	for i, hour := range statistics.SolvesPerHour {
+		if statistics.BusiestHour == nil || hour.Solves > statistics.BusiestHour.Solves {
+			statistics.BusiestHour = &statistics.SolvesPerHour[i]
+		}
+	}
+
+	return statistics
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// writeStatisticsCSV writes the statistics as `statistic,key,value` rows
+func writeStatisticsCSV(writer io.Writer, statistics EventStatistics) error {
+	rows := [][]string{
+		{"statistic", "key", "value"},
+		{"teams", "", strconv.Itoa(statistics.Teams)},
+		{"totalSolves", "", strconv.Itoa(statistics.TotalSolves)},
+		{"averageSolvesPerTeam", "", formatFloat(statistics.AverageSolvesPerTeam)},
+	}
+	for _, challenge := range statistics.MostSolvedChallenges {
+		rows = append(rows, []string{"mostSolvedChallenges", strconv.Itoa(challenge.Challenge), strconv.Itoa(challenge.Solves)})
+	}
+	for _, challenge := range statistics.LeastSolvedChallenges {
+		rows = append(rows, []string{"leastSolvedChallenges", strconv.Itoa(challenge.Challenge), strconv.Itoa(challenge.Solves)})
+	}
+	rows = append(rows,
+		[]string{"timeToFirstSolve", "count", strconv.Itoa(statistics.TimeToFirstSolve.Count)},
+		[]string{"timeToFirstSolve", "minSeconds", formatFloat(statistics.TimeToFirstSolve.Min)},
+		[]string{"timeToFirstSolve", "medianSeconds", formatFloat(statistics.TimeToFirstSolve.Median)},
+		[]string{"timeToFirstSolve", "averageSeconds", formatFloat(statistics.TimeToFirstSolve.Average)},
+		[]string{"timeToFirstSolve", "maxSeconds", formatFloat(statistics.TimeToFirstSolve.Max)},
+	)
+	if statistics.BusiestHour != nil {
+		rows = append(rows, []string{"busiestHour", statistics.BusiestHour.Hour.Format(time.RFC3339), strconv.Itoa(statistics.BusiestHour.Solves)})
+	}
+	for _, hour := range statistics.SolvesPerHour {
+		rows = append(rows, []string{"solvesPerHour", hour.Hour.Format(time.RFC3339), strconv.Itoa(hour.Solves)})
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return fmt.Errorf("Failed to write statistics csv: %v", err)
+	}
+	return nil
+}
+
+// handleStatistics serves the event statistics as json or, with `format=csv`, as csv. With `format=xlsx` the final results
+// of the event are served as Excel workbook instead, see resultsWorkbook
+func (s *Server) handleStatistics(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	statistics := computeStatistics(teams, s.timeZone)
+
+	switch req.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, statistics)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="statistics.csv"`)
+		if err := writeStatisticsCSV(w, statistics); err != nil {
+			log.Error(err)
+		}
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", `attachment; filename="results.xlsx"`)
+		if err := writeResultsXLSX(w, teams, s.scoring, s.timeZone); err != nil {
+			log.Error(err)
+		}
+	default:
+		http.Error(w, "Unsupported format, use 'json', 'csv' or 'xlsx'", http.StatusBadRequest)
+	}
+}
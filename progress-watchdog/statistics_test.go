@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var eventStart = time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func createStatisticsTeams() []TeamProgress {
+	return []TeamProgress{
+		{
+			Team:      "first",
+			CreatedAt: eventStart,
+			Solves: SolveTimes{
+				1: eventStart.Add(10 * time.Minute),
+				2: eventStart.Add(70 * time.Minute),
+				3: eventStart.Add(80 * time.Minute),
+			},
+		},
+		{
+			Team:      "second",
+			CreatedAt: eventStart,
+			Solves: SolveTimes{
+				1: eventStart.Add(30 * time.Minute),
+				2: eventStart.Add(75 * time.Minute),
+			},
+		},
+		{
+			Team:      "third",
+			CreatedAt: eventStart,
+			Solves:    SolveTimes{},
+		},
+	}
+}
+
+func TestComputesEventStatistics(t *testing.T) {
+	statistics := computeStatistics(createStatisticsTeams(), time.UTC)
+
+	assert.Equal(t, 3, statistics.Teams)
+	assert.Equal(t, 5, statistics.TotalSolves)
+	assert.InDelta(t, 5.0/3.0, statistics.AverageSolvesPerTeam, 0.001)
+	assert.Equal(t, []ChallengeStatistic{{1, 2}, {2, 2}, {3, 1}}, statistics.MostSolvedChallenges)
+	assert.Equal(t, []ChallengeStatistic{{3, 1}, {2, 2}, {1, 2}}, statistics.LeastSolvedChallenges)
+	assert.Equal(t, DurationDistribution{Count: 2, Min: 600, Median: 1200, Average: 1200, Max: 1800}, statistics.TimeToFirstSolve)
+	assert.Equal(t, &HourStatistic{Hour: eventStart.Add(time.Hour), Solves: 3}, statistics.BusiestHour)
+	assert.Equal(t, []HourStatistic{
+		{Hour: eventStart, Solves: 2},
+		{Hour: eventStart.Add(time.Hour), Solves: 3},
+	}, statistics.SolvesPerHour)
+}
+
+func TestComputesStatisticsWithoutTeams(t *testing.T) {
+	statistics := computeStatistics([]TeamProgress{}, time.UTC)
+
+	assert.Equal(t, 0, statistics.Teams)
+	assert.Equal(t, 0.0, statistics.AverageSolvesPerTeam)
+	assert.Nil(t, statistics.BusiestHour)
+	assert.Empty(t, statistics.MostSolvedChallenges)
+}
+
+func TestWritesStatisticsCSV(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, writeStatisticsCSV(out, computeStatistics(createStatisticsTeams(), time.UTC)))
+
+	csv := out.String()
+	assert.True(t, strings.HasPrefix(csv, "statistic,key,value\nteams,,3\ntotalSolves,,5\naverageSolvesPerTeam,,1.67\n"))
+	assert.Contains(t, csv, "busiestHour,2021-05-01T11:00:00Z,3\n")
+	assert.Contains(t, csv, "timeToFirstSolve,medianSeconds,1200.00\n")
+}
+
+func TestStatisticsEndpointRequiresAuthentication(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("foobar", "", "0"))
+	server := newTestServer(clientset, NewAuthenticator([]byte("secret"), nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/statistics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = httptest.NewRecorder()
+	server.ServeHTTP(rr, signedRequest("GET", "/api/statistics?format=csv"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "teams,,1\n")
+}
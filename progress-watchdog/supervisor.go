@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// supervisorRestartDelay is the delay before restarting a panicked component, doubling with every further panic in a row
+	supervisorRestartDelay = 100 * time.Millisecond
+	// supervisorMaxRestartDelay caps the delay between restarts of a component which keeps panicking
+	supervisorMaxRestartDelay = time.Minute
+	// supervisorStableAfter is the time a restarted component has to run without panicking to start over with the shortest delay
+	supervisorStableAfter = 5 * time.Minute
+	// startupRetryDelay is the delay before retrying a failed startup step, doubling up to startupMaxRetryDelay
+	startupRetryDelay    = time.Second
+	startupMaxRetryDelay = 30 * time.Second
+)
+
+var panicsRecovered = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "progress_watchdog_panics_recovered_total",
+	Help: "Number of panics recovered instead of crashing the watchdog, by the component which panicked",
+}, []string{"component"})
+
+// recoverPanic runs the function and turns a panic into an error, logged with its stack trace. A panicking progress update then only
+// fails the update of its team, which is retried like any other failed update.
+func recoverPanic(component string, run func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicsRecovered.WithLabelValues(component).Inc()
+			log.Errorf("Recovered from panic in %s: %v\n%s", component, recovered, debug.Stack())
+			err = fmt.Errorf("Panic in %s: %v", component, recovered)
+		}
+	}()
+	return run()
+}
+
+// supervise runs the function until it returns, restarting it with a backoff whenever it panics, so that a bug in a single worker
+// or background loop doesn't take down the whole watchdog and the progress updates in flight.
+func supervise(component string, run func()) {
+	delay := supervisorRestartDelay
+	for {
+		start := time.Now()
+		err := recoverPanic(component, func() error {
+			run()
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		if time.Since(start) > supervisorStableAfter {
+			delay = supervisorRestartDelay
+		}
+		log.Warningf("Restarting %s in %s", component, delay)
+		time.Sleep(delay)
+		if delay *= 2; delay > supervisorMaxRestartDelay {
+			delay = supervisorMaxRestartDelay
+		}
+	}
+}
+
+// retryOnStartup retries a startup step until it succeeds, e.g. connecting to a progress store which isn't reachable yet.
+// Returns the last error once the context is cancelled.
+func retryOnStartup(ctx context.Context, step string, run func() error) error {
+	return retryWithBackoff(ctx, step, startupRetryDelay, run)
+}
+
+func retryWithBackoff(ctx context.Context, step string, delay time.Duration, run func() error) error {
+	for {
+		err := run()
+		if err == nil {
+			return nil
+		}
+		log.Warningf("Failed to %s, retrying in %s: %v", step, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > startupMaxRetryDelay {
+			delay = startupMaxRetryDelay
+		}
+	}
+}
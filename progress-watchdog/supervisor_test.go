@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPanicTurnsPanicsIntoErrors(t *testing.T) {
+	recovered := testutil.ToFloat64(panicsRecovered.WithLabelValues("test"))
+
+	err := recoverPanic("test", func() error {
+		var deployments map[string]string
+		deployments["foobar"] = "crash"
+		return nil
+	})
+
+	assert.EqualError(t, err, "Panic in test: assignment to entry in nil map")
+	assert.Equal(t, recovered+1, testutil.ToFloat64(panicsRecovered.WithLabelValues("test")))
+	assert.Equal(t, errors.New("JuiceShop not reachable"), recoverPanic("test", func() error { return errors.New("JuiceShop not reachable") }))
+}
+
+func TestSuperviseRestartsPanickingComponentsUntilTheyReturn(t *testing.T) {
+	runs := 0
+	supervise("test", func() {
+		runs++
+		if runs < 3 {
+			panic("worker crashed")
+		}
+	})
+	assert.Equal(t, 3, runs)
+}
+
+func TestRetryWithBackoffRetriesUntilTheStepSucceeds(t *testing.T) {
+	attempts := 0
+	err := retryWithBackoff(context.Background(), "reach Redis", time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryWithBackoffGivesUpOnceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := retryWithBackoff(ctx, "reach Redis", time.Hour, func() error { return errors.New("connection refused") })
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestPanickingProgressUpdatesAreRetriedWithoutStoppingTheWorker(t *testing.T) {
+	fleet := useFakeJuiceShops(t)
+	fleet.Team("foobar").Solve(1)
+	fleet.Team("barfoo").Solve(2)
+	queue := newTestProgressUpdateQueue()
+	queue.Add(ProgressUpdateJobs{Teamname: "foobar", Namespace: "default"})
+	queue.Add(ProgressUpdateJobs{Teamname: "barfoo", Namespace: "default"})
+	recovered := testutil.ToFloat64(panicsRecovered.WithLabelValues("progress-update"))
+
+	done := make(chan struct{})
+	go func() {
+		// without a clientset caching the progress panics
+		workOnProgressUpdates(queue, nil, NewScoreboardCache(0), NewHeartbeats(time.Minute), "worker-0")
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return queue.queue.NumRequeues("default/foobar") > 0 && queue.queue.NumRequeues("default/barfoo") > 0
+	}, time.Second, time.Millisecond)
+	queue.ShutDown()
+	<-done
+	assert.GreaterOrEqual(t, testutil.ToFloat64(panicsRecovered.WithLabelValues("progress-update")), recovered+2)
+}
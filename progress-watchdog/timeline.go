@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+const (
+	defaultTimelinePoints = 50
+	maxTimelinePoints     = 1000
+)
+
+// TeamTimeline the score progression of a single team
+type TeamTimeline struct {
+	Team   string          `json:"team"`
+	Points []TimelinePoint `json:"points"`
+}
+
+// TimelineResponse json format of the score timeline response
+type TimelineResponse struct {
+	Teams []TeamTimeline `json:"teams"`
+}
+
+// downsample reduces the points to at most max evenly spaced points, always keeping the latest one
+func downsample(points []TimelinePoint, max int) []TimelinePoint {
+	if len(points) <= max {
+		return points
+	}
+	if max <= 1 {
+		return points[len(points)-1:]
+	}
+
+	sampled := make([]TimelinePoint, 0, max)
+	step := float64(len(points)-1) / float64(max-1)
+	for i := 0; i < max; i++ {
+		sampled = append(sampled, points[int(float64(i)*step+0.5)])
+	}
+	return sampled
+}
+
+// handleTimeline serves the cumulative score over time of each team, for charting the score progression.
+// Supports the `points` query parameter limiting the number of points per team.
+func (s *Server) handleTimeline(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teams, err := s.listTeamProgress(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Team < teams[j].Team })
+
+	maxPoints := queryInt(req, "points", defaultTimelinePoints, 1, maxTimelinePoints)
+	timelines := []TeamTimeline{}
+	for _, team := range teams {
+		if team.Paused {
+			continue
+		}
+		timelines = append(timelines, TeamTimeline{
+			Team:   team.Team,
+			Points: downsample(team.Solves.Timeline(), maxPoints),
+		})
+	}
+	writeConditionalJSON(w, req, TimelineResponse{Teams: timelines}, s.scoreboardCache.LastModified())
+}
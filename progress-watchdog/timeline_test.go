@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func createTimelinePoints(count int) []TimelinePoint {
+	points := []TimelinePoint{}
+	for i := 0; i < count; i++ {
+		points = append(points, TimelinePoint{Timestamp: time.Unix(int64(i), 0), Score: i + 1})
+	}
+	return points
+}
+
+func TestDownsampleKeepsFirstAndLastPoint(t *testing.T) {
+	points := createTimelinePoints(100)
+
+	sampled := downsample(points, 10)
+	assert.Len(t, sampled, 10)
+	assert.Equal(t, points[0], sampled[0])
+	assert.Equal(t, points[99], sampled[9])
+
+	assert.Equal(t, []TimelinePoint{points[99]}, downsample(points, 1))
+	assert.Equal(t, points[:5], downsample(points[:5], 10), "Should not change timelines with less points than the maximum")
+}
+
+func TestTimelineEndpointServesScoreProgression(t *testing.T) {
+	deployment := createJuiceShopDeployment("foobar", "", "2")
+	deployment.Annotations["multi-juicer.iteratec.dev/solves"] = `{"1":"2021-05-01T10:00:00Z","12":"2021-05-01T11:30:00Z"}`
+	clientset := fake.NewSimpleClientset(deployment)
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scoreboard/timeline", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := TimelineResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []TeamTimeline{
+		{Team: "foobar", Points: []TimelinePoint{
+			{Timestamp: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC), Score: 1},
+			{Timestamp: time.Date(2021, 5, 1, 11, 30, 0, 0, time.UTC), Score: 2},
+		}},
+	}, response.Teams)
+}
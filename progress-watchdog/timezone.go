@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	// embeds the time zone database, so that time zones can be loaded in images without tzdata
+	_ "time/tzdata"
+)
+
+// parseTimeZone loads the time zone the event runs in, e.g. `Europe/Berlin`. Reports and notifications render their timestamps in it,
+// so that they match the wall clock of the participants instead of UTC.
+func parseTimeZone(name string) (*time.Location, error) {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown time zone '%s': %v", name, err)
+	}
+	return location, nil
+}
+
+// startOfHour returns the start of the wall clock hour of the time in the location.
+// Unlike time.Truncate this respects time zones whose offset isn't a multiple of an hour.
+func startOfHour(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, location)
+}
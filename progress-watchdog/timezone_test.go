@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeZone(t *testing.T) {
+	location, err := parseTimeZone("Europe/Berlin")
+	assert.Nil(t, err)
+	assert.Equal(t, "Europe/Berlin", location.String())
+
+	_, err = parseTimeZone("Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
+
+func TestStartOfHourRespectsTimeZoneOffsets(t *testing.T) {
+	kolkata, err := parseTimeZone("Asia/Kolkata")
+	assert.Nil(t, err)
+
+	// 10:40 UTC is 16:10 in India (UTC+5:30)
+	hour := startOfHour(time.Date(2021, 5, 1, 10, 40, 0, 0, time.UTC), kolkata)
+	assert.Equal(t, time.Date(2021, 5, 1, 16, 0, 0, 0, kolkata), hour)
+	assert.Equal(t, time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC), hour.UTC())
+}
+
+func TestStatisticsAreRenderedInTimeZone(t *testing.T) {
+	berlin, err := parseTimeZone("Europe/Berlin")
+	assert.Nil(t, err)
+
+	statistics := computeStatistics(createStatisticsTeams(), berlin)
+	assert.Equal(t, time.Date(2021, 5, 1, 13, 0, 0, 0, berlin), statistics.BusiestHour.Hour)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, writeStatisticsCSV(out, statistics))
+	assert.True(t, strings.Contains(out.String(), "busiestHour,2021-05-01T13:00:00+02:00,3"), out.String())
+}
+
+func TestNotificationTimesAreRenderedInTimeZone(t *testing.T) {
+	berlin, err := parseTimeZone("Europe/Berlin")
+	assert.Nil(t, err)
+	templates, err := parseNotificationTemplates(`{"solve": "{{ .Team }} at {{ .Time.Format \"15:04\" }}"}`, nil, berlin)
+	assert.Nil(t, err)
+
+	message, err := templates.Render(HookEvent{Type: HookEventSolve, Team: "foobar", Time: time.Date(2021, 5, 1, 10, 30, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+	assert.Equal(t, "foobar at 12:30", message)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans of the progress updates
+const tracerName = "github.com/iteratec/multi-juicer/progress-watchdog"
+
+func init() {
+	// the trace context is sent with every outgoing request as W3C `traceparent` header, see TaggingRoundTripper
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// createTracerProvider creates the tracer provider exporting the spans via OTLP over http, configured via the standard OpenTelemetry env vars,
+// e.g. `OTEL_EXPORTER_OTLP_ENDPOINT`, `OTEL_EXPORTER_OTLP_HEADERS`, `OTEL_SERVICE_NAME` or `OTEL_TRACES_SAMPLER` and `OTEL_TRACES_SAMPLER_ARG`.
+// Returns nil if no endpoint is configured.
+func createTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	if getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "") == "" && getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "") == "" {
+		return nil, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default service name
+	serviceResource, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "progress-watchdog")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(serviceResource)), nil
+}
+
+// startSpan starts a span of an operation, part of the span of the context if it has one.
+// The spans are created by the global tracer provider, which records nothing unless tracing is enabled, see createTracerProvider.
+func startSpan(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// startClientSpan starts a span of a request to another service, e.g. a JuiceShop
+func startClientSpan(ctx context.Context, name, url string) (context.Context, trace.Span) {
+	return startSpan(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.String("http.url", url)))
+}
+
+// startSpanAt starts a span of an operation which started at the passed time, e.g. the wait of a job in the queue
+func startSpanAt(ctx context.Context, name string, start time.Time) (context.Context, trace.Span) {
+	return startSpan(ctx, name, trace.WithTimestamp(start))
+}
+
+// endSpan ends the span, marking it as failed if the error is set
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// useTestTracer records the spans with the sampler and returns the ended ones
+func useTestTracer(t *testing.T, sampler sdktrace.Sampler) func() []sdktrace.ReadOnlySpan {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder.Ended
+}
+
+func attributeOf(span sdktrace.ReadOnlySpan, key string) interface{} {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.AsInterface()
+		}
+	}
+	return nil
+}
+
+func TestTracesTheStepsOfProgressUpdates(t *testing.T) {
+	ended := useTestTracer(t, sdktrace.AlwaysSample())
+	fakeJuiceShops(t, "LRo3lzE7XYnWkwaZNdE7i3Hku6TqCQiW8i5NF96H2b0yPxve5Mq4pK18VJmg")
+	deployment := createJuiceShopDeployment("foobar", "", "0")
+	deployment.Status.ReadyReplicas = 1
+	job := progressUpdateJobForDeployment(*deployment)
+	job.QueuedAt = time.Now().Add(-time.Second)
+
+	state, err := runProgressUpdateJob(job, fake.NewSimpleClientset(deployment), NewScoreboardCache(0))
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateCache, state)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range ended() {
+		spans[span.Name()] = span
+	}
+	root := spans["progress-update"]
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, job.QueuedAt.UnixNano(), root.StartTime().UnixNano())
+	assert.Equal(t, "foobar", attributeOf(root, "team"))
+	assert.Equal(t, "UpdateCache", attributeOf(root, "state"))
+	for _, name := range []string{"progress-update.queued", "progress-store.recover", "juiceshop.fetch-continue-code", "juiceshop.fetch-challenges", "progress-store.save", "coding-challenges.sync"} {
+		assert.Contains(t, spans, name)
+		assert.Equal(t, root.SpanContext().TraceID(), spans[name].SpanContext().TraceID(), name)
+		assert.Equal(t, root.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+	assert.Equal(t, trace.SpanKindClient, spans["juiceshop.fetch-continue-code"].SpanKind())
+	assert.Equal(t, "http://t-foobar-juiceshop.default.svc.cluster.local:3000", attributeOf(spans["juiceshop.fetch-continue-code"], "http.url"))
+}
+
+func TestSendsTheTraceContextToTheJuiceShops(t *testing.T) {
+	useTestTracer(t, sdktrace.AlwaysSample())
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		traceparents <- req.Header.Get("traceparent")
+		w.Write([]byte(`{"continueCode":"abc"}`))
+	}))
+	defer server.Close()
+
+	ctx, span := startClientSpan(context.Background(), "juiceshop.fetch-continue-code", server.URL)
+	_, err := fetchContinueCode(ctx, server.URL)
+	span.End()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", <-traceparents)
+}
+
+func TestMarksFailedSpansAsError(t *testing.T) {
+	ended := useTestTracer(t, sdktrace.AlwaysSample())
+	_, span := startSpan(context.Background(), "progress-update")
+	endSpan(span, errors.New("Failed to fetch ContinueCode"))
+
+	spans := ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "Failed to fetch ContinueCode", spans[0].Status().Description)
+}
+
+func TestRecordsNoSpansOfUnsampledTraces(t *testing.T) {
+	ended := useTestTracer(t, sdktrace.TraceIDRatioBased(0))
+	ctx, span := startSpan(context.Background(), "progress-update")
+	_, child := startClientSpan(ctx, "juiceshop.fetch-continue-code", "http://juiceshop")
+	child.SetAttributes(attribute.String("replica", "a"))
+	endSpan(child, nil)
+	endSpan(span, nil)
+
+	assert.Empty(t, ended())
+}
+
+func TestTracingIsDisabledWithoutOTLPEndpoint(t *testing.T) {
+	provider, err := createTracerProvider(context.Background())
+
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+}
+
+func TestCreatesTheTracerProviderOfTheOTLPEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://otel-collector:4318")
+
+	provider, err := createTracerProvider(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, provider)
+	assert.NoError(t, provider.Shutdown(context.Background()))
+}
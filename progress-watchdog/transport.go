@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// UserAgent is sent with every request the watchdog makes, so its traffic can be told apart from participant traffic
+const UserAgent = "multi-juicer-progress-watchdog"
+
+// RequestIDHeader carries a unique id for every request the watchdog sends
+const RequestIDHeader = "X-Request-Id"
+
+// TaggingRoundTripper sets the watchdog User-Agent and a fresh request id on every outgoing request and logs the ids.
+// Requests sent within a span carry its trace context as W3C `traceparent` header.
+type TaggingRoundTripper struct {
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TaggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent)
+	if req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, newRequestID())
+	}
+	requestID := req.Header.Get(RequestIDHeader)
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+
+	res, err := t.Next.RoundTrip(req)
+	// logged on INFO, so that the requests can be matched with the logs of the JuiceShops in audits
+	fields := LogFields{"method": req.Method, "url": req.URL.String(), "requestId": requestID}
+	if err != nil {
+		fields["error"] = err
+		log.Info("Request failed", fields)
+		return nil, err
+	}
+	fields["status"] = res.StatusCode
+	log.Info("Sent request", fields)
+	return res, nil
+}
+
+// newTaggingRoundTripper wraps the passed RoundTripper, using the http.DefaultTransport if it is nil
+func newTaggingRoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &TaggingRoundTripper{Next: next}
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		log.Warning("Failed to generate random request id")
+		log.Warning(err)
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// RetryingRoundTripper retries requests failing with transient errors (network errors or a 502, 503 or 504 status), e.g. while a JuiceShop restarts.
+// The delay before each retry doubles, starting at BaseDelay. Retries stop early once the context of the request is done.
+type RetryingRoundTripper struct {
+	Next      http.RoundTripper
+	Retries   int
+	BaseDelay time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.BaseDelay
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			// the body of the previous attempt was consumed already
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		res, err := t.Next.RoundTrip(attemptReq)
+		if attempt >= t.Retries || !isTransientFailure(res, err) || (req.Body != nil && req.GetBody == nil) {
+			return res, err
+		}
+		if err != nil {
+			log.Debugf("%s %s failed, retrying in %s: %s", req.Method, req.URL.String(), delay, err)
+		} else {
+			log.Debugf("%s %s failed with status %d, retrying in %s", req.Method, req.URL.String(), res.StatusCode, delay)
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func isTransientFailure(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// newJuiceShopClient creates the client for the requests against the JuiceShops. The timeout limits each call including its retries,
+// so that a hung JuiceShop can't block a worker forever. The tlsConfig is used for JuiceShops served via https, nil uses the system defaults.
+func newJuiceShopClient(timeout time.Duration, retries int, baseDelay time.Duration, tlsConfig *tls.Config) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if tlsConfig != nil {
+		configured := http.DefaultTransport.(*http.Transport).Clone()
+		configured.TLSClientConfig = tlsConfig
+		transport = configured
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &RetryingRoundTripper{Next: outboundRateLimiter.Wrap(wrapDryRun(newTaggingRoundTripper(transport), dryRunTargetJuiceShop), nil), Retries: retries, BaseDelay: baseDelay},
+	}
+}
+
+// newJuiceShopTLSConfig creates the tls config for JuiceShops served via https. The JuiceShops are verified against the CA bundle if set,
+// otherwise against the system CAs. The client certificate is presented to JuiceShops requiring mTLS if its cert and key file are set.
+func newJuiceShopTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		bundle, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("CA bundle '%s' doesn't contain any PEM encoded certificate", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
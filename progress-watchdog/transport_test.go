@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggingRoundTripperSetsUserAgentAndRequestID(t *testing.T) {
+	var userAgent, requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		requestID = r.Header.Get(RequestIDHeader)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTaggingRoundTripper(nil)}
+	res, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, UserAgent, userAgent)
+	assert.Len(t, requestID, 32, "Request id should be 16 hex encoded bytes")
+}
+
+func TestTaggingRoundTripperKeepsExistingRequestID(t *testing.T) {
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(RequestIDHeader)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTaggingRoundTripper(nil)}
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set(RequestIDHeader, "my-request-id")
+	res, err := client.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, "my-request-id", requestID)
+}
+
+func TestTaggingRoundTripperLogsTheRequestIDsOnInfo(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log.SetBackend(NewLogLevels(NewJSONBackend(buffer), logging.INFO))
+	defer setupLogging("text", logging.INFO)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTaggingRoundTripper(nil)}
+	req, _ := http.NewRequest("GET", server.URL+"/rest/continue-code", nil)
+	req.Header.Set(RequestIDHeader, "my-request-id")
+	res, err := client.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "Sent request", entry["message"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, server.URL+"/rest/continue-code", entry["url"])
+	assert.Equal(t, "my-request-id", entry["requestId"])
+	assert.Equal(t, float64(200), entry["status"])
+}
+
+func TestNewRequestIDsAreUnique(t *testing.T) {
+	assert.NotEqual(t, newRequestID(), newRequestID())
+}
+
+func TestJuiceShopClientRetriesTransientFailures(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"continueCode":"abc"}`))
+	}))
+	defer server.Close()
+
+	previous := juiceShopClient
+	defer func() { juiceShopClient = previous }()
+	juiceShopClient = newJuiceShopClient(time.Second, 2, time.Millisecond, nil)
+	continueCode, err := fetchContinueCode(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", continueCode)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	juiceShopClient = newJuiceShopClient(time.Second, 1, time.Millisecond, nil)
+	_, err = fetchContinueCode(context.Background(), server.URL)
+	assert.EqualError(t, err, "Unexpected response status code '503' from Juice Shop")
+	assert.Equal(t, 2, attempts)
+}
+
+func TestJuiceShopClientDoesntRetryClientErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	res, err := newJuiceShopClient(time.Second, 3, time.Millisecond, nil).Get(server.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 1, attempts)
+}
+
+func TestJuiceShopClientTimesOutOnHungInstances(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	start := time.Now()
+	_, err := newJuiceShopClient(50*time.Millisecond, 3, time.Millisecond, nil).Get(server.URL)
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+// writePEM writes the pem block into a file of the directory and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, content []byte) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: content}), 0600))
+	return path
+}
+
+// writeClientCertificate writes a self signed client certificate and its key into the directory
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	encodedKey, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return writePEM(t, dir, "client.crt", "CERTIFICATE", certificate), writePEM(t, dir, "client.key", "EC PRIVATE KEY", encodedKey)
+}
+
+func TestJuiceShopClientVerifiesJuiceShopsAgainstTheCABundleAndPresentsTheClientCertificate(t *testing.T) {
+	var clientCertificates int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCertificates = len(r.TLS.PeerCertificates)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writePEM(t, dir, "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	withoutCA, err := newJuiceShopTLSConfig("", certFile, keyFile)
+	assert.NoError(t, err)
+	_, err = newJuiceShopClient(time.Second, 0, time.Millisecond, withoutCA).Get(server.URL)
+	assert.Error(t, err, "Should reject the JuiceShop if its certificate isn't signed by a trusted CA")
+
+	withoutClientCertificate, err := newJuiceShopTLSConfig(caFile, "", "")
+	assert.NoError(t, err)
+	_, err = newJuiceShopClient(time.Second, 0, time.Millisecond, withoutClientCertificate).Get(server.URL)
+	assert.Error(t, err, "Should fail if the JuiceShop requires a client certificate")
+
+	config, err := newJuiceShopTLSConfig(caFile, certFile, keyFile)
+	assert.NoError(t, err)
+	res, err := newJuiceShopClient(time.Second, 0, time.Millisecond, config).Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, clientCertificates)
+}
+
+func TestNewJuiceShopTLSConfigRejectsInvalidCABundles(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, ioutil.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	_, err := newJuiceShopTLSConfig(invalid, "", "")
+	assert.Error(t, err)
+	_, err = newJuiceShopTLSConfig(filepath.Join(dir, "missing.crt"), "", "")
+	assert.Error(t, err)
+}
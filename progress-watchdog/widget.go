@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultWidgetTeams          = 10
+	maxWidgetTeams              = 100
+	defaultWidgetRefreshSeconds = 30
+	minWidgetRefreshSeconds     = 5
+)
+
+var widgetTemplate = template.Must(template.ParseFS(staticFiles, "static/widget/widget.html"))
+
+// WidgetData is passed to the widget template
+type WidgetData struct {
+	RefreshSeconds int
+	Teams          []ScoreboardTeam
+	Locale         string
+	Messages       Messages
+}
+
+// queryInt reads an integer query parameter, falling back to the default if it is missing or invalid and clamping it to [min, max]
+func queryInt(req *http.Request, name string, fallback, min, max int) int {
+	value, err := strconv.Atoi(req.URL.Query().Get(name))
+	if err != nil {
+		return fallback
+	}
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// handleWidget renders the top teams as a small self refreshing html page meant to be embedded as an iframe.
+// Supports the `top` (number of teams), `refresh` (seconds between reloads) and `locale` query parameters.
+func (s *Server) handleWidget(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ranked, err := s.rankedTeams(req.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	top := queryInt(req, "top", defaultWidgetTeams, 1, maxWidgetTeams)
+	if len(ranked) > top {
+		ranked = ranked[:top]
+	}
+
+	locale := catalog.LocaleOf(req)
+	body := &bytes.Buffer{}
+	err = widgetTemplate.Execute(body, WidgetData{
+		RefreshSeconds: queryInt(req, "refresh", defaultWidgetRefreshSeconds, minWidgetRefreshSeconds, 3600),
+		Teams:          ranked,
+		Locale:         locale,
+		Messages:       catalog.Messages(locale),
+	})
+	if err != nil {
+		log.Error("Failed to render scoreboard widget")
+		log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors "+s.widgetFrameAncestors)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQueryIntClampsValues(t *testing.T) {
+	assert.Equal(t, 10, queryInt(httptest.NewRequest("GET", "/widget", nil), "top", 10, 1, 100))
+	assert.Equal(t, 10, queryInt(httptest.NewRequest("GET", "/widget?top=abc", nil), "top", 10, 1, 100))
+	assert.Equal(t, 3, queryInt(httptest.NewRequest("GET", "/widget?top=3", nil), "top", 10, 1, 100))
+	assert.Equal(t, 1, queryInt(httptest.NewRequest("GET", "/widget?top=-5", nil), "top", 10, 1, 100))
+	assert.Equal(t, 100, queryInt(httptest.NewRequest("GET", "/widget?top=5000", nil), "top", 10, 1, 100))
+}
+
+func TestWidgetRendersTopTeams(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createJuiceShopDeployment("first", "", "5"),
+		createJuiceShopDeployment("second", "", "3"),
+		createJuiceShopDeployment("third", "", "1"),
+	)
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/widget?top=2&refresh=10", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "frame-ancestors *", rr.Header().Get("Content-Security-Policy"))
+	body := rr.Body.String()
+	assert.Contains(t, body, `<meta http-equiv="refresh" content="10" />`)
+	assert.Contains(t, body, "first")
+	assert.Contains(t, body, "second")
+	assert.NotContains(t, body, "third")
+	assert.True(t, strings.Index(body, "first") < strings.Index(body, "second"), "Teams should be ordered by their position")
+}
+
+func TestWidgetEscapesTeamnames(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createJuiceShopDeployment("<script>", "", "5"))
+	server := newTestServer(clientset, NewAuthenticator(nil, nil, nil, clientset), NewScoreboardCache(0))
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/widget", nil))
+	assert.NotContains(t, rr.Body.String(), "<script>")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+// listPageSize is the number of objects requested per list request, so that the responses stay small for events with thousands of teams
+var listPageSize int64 = 500
+
+// listPaged lists the objects matching the label selector page by page using continue tokens and calls each for every object.
+// Falls back to a single full list if the continue token expires while paging, e.g. because the list took too long.
+func listPaged(ctx context.Context, labelSelector string, list pager.ListPageFunc, each func(obj runtime.Object)) error {
+	listPager := pager.New(list)
+	listPager.PageSize = listPageSize
+	result, _, err := listPager.List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return err
+	}
+	return meta.EachListItem(result, func(obj runtime.Object) error {
+		each(obj)
+		return nil
+	})
+}
+
+// Workloads abstracts the kind of the workloads running the JuiceShops of the teams, e.g. StatefulSets for JuiceShops with persistent volumes.
+// Workloads of every kind are represented as deployments with their metadata and number of ready replicas, so that the tracking doesn't depend on the kind.
+// The workload of a team is named `t-{team}-juiceshop` regardless of its kind.
+type Workloads interface {
+	// APIVersion and Kind of the workloads, e.g. for owner references
+	APIVersion() string
+	Kind() string
+	List(ctx context.Context, clientset kubernetes.Interface, namespace, labelSelector string) ([]appsv1.Deployment, error)
+	Get(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error)
+	Patch(ctx context.Context, clientset kubernetes.Interface, namespace, name string, patchType types.PatchType, patch []byte, options metav1.PatchOptions) (*appsv1.Deployment, error)
+	Delete(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error
+	// Informer returns the informer of the workloads of the factory
+	Informer(factory informers.SharedInformerFactory) cache.SharedIndexInformer
+	// Convert converts a workload received from the informer, returns false for objects of other kinds
+	Convert(obj interface{}) (*appsv1.Deployment, bool)
+}
+
+// workloads are the workloads of the running watchdog, configured via `WORKLOAD_KIND`
+var workloads Workloads = DeploymentWorkloads{}
+
+// createWorkloads creates the workloads of the kind, either `Deployment` (default), `StatefulSet` or `Pod`
+func createWorkloads(kind string) (Workloads, error) {
+	switch kind {
+	case "Deployment":
+		return DeploymentWorkloads{}, nil
+	case "StatefulSet":
+		return StatefulSetWorkloads{}, nil
+	case "Pod":
+		return PodWorkloads{}, nil
+	default:
+		return nil, fmt.Errorf("Invalid workload kind '%s'", kind)
+	}
+}
+
+// DeploymentWorkloads runs the JuiceShops as Deployments, as created by the JuiceBalancer
+type DeploymentWorkloads struct{}
+
+func (DeploymentWorkloads) APIVersion() string { return "apps/v1" }
+func (DeploymentWorkloads) Kind() string       { return "Deployment" }
+
+func (DeploymentWorkloads) List(ctx context.Context, clientset kubernetes.Interface, namespace, labelSelector string) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	err := listPaged(ctx, labelSelector, func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+		return clientset.AppsV1().Deployments(namespace).List(ctx, options)
+	}, func(obj runtime.Object) {
+		deployments = append(deployments, *obj.(*appsv1.Deployment))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (DeploymentWorkloads) Get(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error) {
+	return clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (DeploymentWorkloads) Patch(ctx context.Context, clientset kubernetes.Interface, namespace, name string, patchType types.PatchType, patch []byte, options metav1.PatchOptions) (*appsv1.Deployment, error) {
+	return clientset.AppsV1().Deployments(namespace).Patch(ctx, name, patchType, patch, options)
+}
+
+func (DeploymentWorkloads) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	return clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (DeploymentWorkloads) Informer(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Apps().V1().Deployments().Informer()
+}
+
+func (DeploymentWorkloads) Convert(obj interface{}) (*appsv1.Deployment, bool) {
+	deployment, ok := obj.(*appsv1.Deployment)
+	return deployment, ok
+}
+
+// StatefulSetWorkloads runs the JuiceShops as StatefulSets, e.g. to keep their database on a persistent volume
+type StatefulSetWorkloads struct{}
+
+func (StatefulSetWorkloads) APIVersion() string { return "apps/v1" }
+func (StatefulSetWorkloads) Kind() string       { return "StatefulSet" }
+
+// deploymentOfStatefulSet represents the StatefulSet as deployment
+func deploymentOfStatefulSet(statefulSet appsv1.StatefulSet) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: statefulSet.ObjectMeta,
+		Spec:       appsv1.DeploymentSpec{Replicas: statefulSet.Spec.Replicas, Selector: statefulSet.Spec.Selector},
+		Status:     appsv1.DeploymentStatus{Replicas: statefulSet.Status.Replicas, ReadyReplicas: statefulSet.Status.ReadyReplicas},
+	}
+}
+
+func (StatefulSetWorkloads) List(ctx context.Context, clientset kubernetes.Interface, namespace, labelSelector string) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	err := listPaged(ctx, labelSelector, func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+		return clientset.AppsV1().StatefulSets(namespace).List(ctx, options)
+	}, func(obj runtime.Object) {
+		deployments = append(deployments, *deploymentOfStatefulSet(*obj.(*appsv1.StatefulSet)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (StatefulSetWorkloads) Get(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error) {
+	statefulSet, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return deploymentOfStatefulSet(*statefulSet), nil
+}
+
+func (StatefulSetWorkloads) Patch(ctx context.Context, clientset kubernetes.Interface, namespace, name string, patchType types.PatchType, patch []byte, options metav1.PatchOptions) (*appsv1.Deployment, error) {
+	statefulSet, err := clientset.AppsV1().StatefulSets(namespace).Patch(ctx, name, patchType, patch, options)
+	if err != nil {
+		return nil, err
+	}
+	return deploymentOfStatefulSet(*statefulSet), nil
+}
+
+func (StatefulSetWorkloads) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	return clientset.AppsV1().StatefulSets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (StatefulSetWorkloads) Informer(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Apps().V1().StatefulSets().Informer()
+}
+
+func (StatefulSetWorkloads) Convert(obj interface{}) (*appsv1.Deployment, bool) {
+	statefulSet, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return nil, false
+	}
+	return deploymentOfStatefulSet(*statefulSet), true
+}
+
+// PodWorkloads runs the JuiceShops as plain Pods, without a controller restarting them
+type PodWorkloads struct{}
+
+func (PodWorkloads) APIVersion() string { return "v1" }
+func (PodWorkloads) Kind() string       { return "Pod" }
+
+// deploymentOfPod represents the Pod as deployment with a single replica, which is ready if the pod is
+func deploymentOfPod(pod corev1.Pod) *appsv1.Deployment {
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: pod.ObjectMeta,
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: replicas},
+	}
+	if isPodReady(pod) {
+		deployment.Status.ReadyReplicas = 1
+	}
+	return deployment
+}
+
+func (PodWorkloads) List(ctx context.Context, clientset kubernetes.Interface, namespace, labelSelector string) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	err := listPaged(ctx, labelSelector, func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+		return clientset.CoreV1().Pods(namespace).List(ctx, options)
+	}, func(obj runtime.Object) {
+		deployments = append(deployments, *deploymentOfPod(*obj.(*corev1.Pod)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (PodWorkloads) Get(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return deploymentOfPod(*pod), nil
+}
+
+func (PodWorkloads) Patch(ctx context.Context, clientset kubernetes.Interface, namespace, name string, patchType types.PatchType, patch []byte, options metav1.PatchOptions) (*appsv1.Deployment, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Patch(ctx, name, patchType, patch, options)
+	if err != nil {
+		return nil, err
+	}
+	return deploymentOfPod(*pod), nil
+}
+
+func (PodWorkloads) Delete(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	return clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (PodWorkloads) Informer(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	return factory.Core().V1().Pods().Informer()
+}
+
+func (PodWorkloads) Convert(obj interface{}) (*appsv1.Deployment, bool) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, false
+	}
+	return deploymentOfPod(*pod), true
+}
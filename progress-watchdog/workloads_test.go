@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func useWorkloads(t *testing.T, kind string) {
+	previous := workloads
+	t.Cleanup(func() { workloads = previous })
+	created, err := createWorkloads(kind)
+	assert.NoError(t, err)
+	workloads = created
+}
+
+func createJuiceShopStatefulSet(team, continueCode, challengesSolved string) *appsv1.StatefulSet {
+	deployment := createJuiceShopDeployment(team, continueCode, challengesSolved)
+	return &appsv1.StatefulSet{
+		ObjectMeta: deployment.ObjectMeta,
+		Status:     appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 1},
+	}
+}
+
+func TestCreateWorkloadsRejectsUnknownKinds(t *testing.T) {
+	_, err := createWorkloads("DaemonSet")
+	assert.Error(t, err)
+}
+
+func TestCachesProgressOfJuiceShopsRunningAsStatefulSets(t *testing.T) {
+	useWorkloads(t, "StatefulSet")
+	clientset := fake.NewSimpleClientset(createJuiceShopStatefulSet("foobar", "", "0"))
+	continueCode, _ := EncodeContinueCode([]int{1, 2, 3})
+
+	assert.NoError(t, cacheContinueCode(clientset, "default", "foobar", "", continueCode, SolveTimes{}, nil))
+
+	statefulSet, err := clientset.AppsV1().StatefulSets("default").Get(context.Background(), "t-foobar-juiceshop", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, continueCode, statefulSet.Annotations["multi-juicer.iteratec.dev/continueCode"])
+
+	teams, err := listTeamProgress(context.Background(), clientset, "default")
+	assert.NoError(t, err)
+	assert.Len(t, teams, 1)
+	assert.Equal(t, 3, teams[0].ChallengesSolved)
+}
+
+func TestPodWorkloadsAreReadyIfTheirPodIsReady(t *testing.T) {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "t-foobar-juiceshop", Labels: map[string]string{"app": "juice-shop", "team": "foobar"}}}
+	assert.Equal(t, int32(0), deploymentOfPod(pod).Status.ReadyReplicas)
+
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	converted, ok := PodWorkloads{}.Convert(&pod)
+	assert.True(t, ok)
+	assert.Equal(t, int32(1), converted.Status.ReadyReplicas)
+	assert.Equal(t, "foobar", progressUpdateJobForDeployment(*converted).Teamname)
+
+	_, ok = PodWorkloads{}.Convert(&appsv1.Deployment{})
+	assert.False(t, ok)
+}
+
+func TestListsJuiceShopsPageByPage(t *testing.T) {
+	previous := listPageSize
+	listPageSize = 2
+	t.Cleanup(func() { listPageSize = previous })
+
+	requests := []string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/apis/apps/v1/namespaces/default/deployments", req.URL.Path)
+		assert.Equal(t, "app=juice-shop", req.URL.Query().Get("labelSelector"))
+		requests = append(requests, req.URL.RawQuery)
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(req.URL.Query().Get("continue"))
+
+		list := appsv1.DeploymentList{TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DeploymentList"}}
+		for i := offset; i < offset+limit && i < 5; i++ {
+			list.Items = append(list.Items, *createJuiceShopDeployment(fmt.Sprintf("team-%d", i), "", "0"))
+		}
+		if offset+limit < 5 {
+			list.Continue = strconv.Itoa(offset + limit)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}))
+	defer api.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: api.URL})
+	assert.NoError(t, err)
+
+	juiceShops, err := DeploymentWorkloads{}.List(context.Background(), clientset, "default", "app=juice-shop")
+	assert.NoError(t, err)
+	assert.Len(t, requests, 3)
+	teams := []string{}
+	for _, juiceShop := range juiceShops {
+		teams = append(teams, juiceShop.Labels["team"])
+	}
+	assert.Equal(t, []string{"team-0", "team-1", "team-2", "team-3", "team-4"}, teams)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSheetNameLength is the longest sheet name Excel accepts
+const maxSheetNameLength = 31
+
+// reservedSheetName is used by Excel itself (for tracked changes), workbooks with a sheet of that name, in any case, can't be opened
+const reservedSheetName = "History"
+
+// Workbook is a minimal writer for Excel workbooks (Office Open XML), supporting sheets of plain strings, numbers and times.
+// The first row of every sheet is written in bold as its header.
+type Workbook struct {
+	location *time.Location
+	sheets   []workbookSheet
+}
+
+type workbookSheet struct {
+	name string
+	rows [][]interface{}
+}
+
+// NewWorkbook creates an empty workbook, times are written as text in the passed time zone
+func NewWorkbook(location *time.Location) *Workbook {
+	return &Workbook{location: location}
+}
+
+// AddSheet appends a sheet. The name is shortened and stripped of characters Excel doesn't allow, a number is appended if it's taken already
+// or reserved by Excel.
+// Cells can be strings, ints, float64s, bools or time.Times.
+func (w *Workbook) AddSheet(name string, rows [][]interface{}) {
+	w.sheets = append(w.sheets, workbookSheet{name: w.uniqueSheetName(name), rows: rows})
+}
+
+func (w *Workbook) uniqueSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+
+	candidate := truncateRunes(name, maxSheetNameLength)
+	for i := 2; w.hasSheet(candidate); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (w *Workbook) hasSheet(name string) bool {
+	if strings.EqualFold(name, reservedSheetName) {
+		return true
+	}
+	for _, sheet := range w.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateRunes(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
+
+// WriteTo writes the workbook as xlsx file
+func (w *Workbook) WriteTo(writer io.Writer) (int64, error) {
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", w.contentTypes()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", w.workbook()},
+		{"xl/_rels/workbook.xml.rels", w.workbookRelationships()},
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border/></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for i, sheet := range w.sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), w.worksheet(sheet)})
+	}
+
+	for _, file := range files {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := io.WriteString(entry, file.content); err != nil {
+			return 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	return buffer.WriteTo(writer)
+}
+
+func (w *Workbook) contentTypes() string {
+	content := &strings.Builder{}
+	content.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range w.sheets {
+		fmt.Fprintf(content, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	content.WriteString(`</Types>`)
+	return content.String()
+}
+
+func (w *Workbook) workbook() string {
+	content := &strings.Builder{}
+	content.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range w.sheets {
+		fmt.Fprintf(content, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(sheet.name), i+1, i+1)
+	}
+	content.WriteString(`</sheets></workbook>`)
+	return content.String()
+}
+
+func (w *Workbook) workbookRelationships() string {
+	content := &strings.Builder{}
+	content.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range w.sheets {
+		fmt.Fprintf(content, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(content, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	content.WriteString(`</Relationships>`)
+	return content.String()
+}
+
+func (w *Workbook) worksheet(sheet workbookSheet) string {
+	content := &strings.Builder{}
+	content.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range sheet.rows {
+		style := ""
+		if i == 0 {
+			style = ` s="1"`
+		}
+		fmt.Fprintf(content, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			reference := fmt.Sprintf("%s%d", columnName(j), i+1)
+			switch value := cell.(type) {
+			case nil:
+				continue
+			case int:
+				fmt.Fprintf(content, `<c r="%s"%s><v>%d</v></c>`, reference, style, value)
+			case float64:
+				fmt.Fprintf(content, `<c r="%s"%s><v>%s</v></c>`, reference, style, strconv.FormatFloat(value, 'f', -1, 64))
+			case bool:
+				boolean := 0
+				if value {
+					boolean = 1
+				}
+				fmt.Fprintf(content, `<c r="%s"%s t="b"><v>%d</v></c>`, reference, style, boolean)
+			case time.Time:
+				if value.IsZero() {
+					continue
+				}
+				fmt.Fprintf(content, `<c r="%s"%s t="inlineStr"><is><t>%s</t></is></c>`, reference, style, value.In(w.location).Format(time.RFC3339))
+			default:
+				fmt.Fprintf(content, `<c r="%s"%s t="inlineStr"><is><t>%s</t></is></c>`, reference, style, escapeXML(fmt.Sprint(value)))
+			}
+		}
+		content.WriteString(`</row>`)
+	}
+	content.WriteString(`</sheetData></worksheet>`)
+	return content.String()
+}
+
+// columnName converts the zero based index of a column to its name, e.g. 0 to A and 27 to AB
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escapeXML(value string) string {
+	escaped := &strings.Builder{}
+	xml.EscapeText(escaped, []byte(value))
+	return escaped.String()
+}
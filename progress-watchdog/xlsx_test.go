@@ -0,0 +1,72 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readWorkbookPart(t *testing.T, workbook []byte, name string) string {
+	archive, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	assert.Nil(t, err)
+	for _, file := range archive.File {
+		if file.Name == name {
+			reader, err := file.Open()
+			assert.Nil(t, err)
+			defer reader.Close()
+			content, err := ioutil.ReadAll(reader)
+			assert.Nil(t, err)
+			return string(content)
+		}
+	}
+	t.Fatalf("Workbook doesn't contain '%s'", name)
+	return ""
+}
+
+func TestWorkbookWritesSheets(t *testing.T) {
+	workbook := NewWorkbook(time.UTC)
+	workbook.AddSheet("Teams", [][]interface{}{
+		{"Team", "Score", "Paused", "Created at"},
+		{"<foo&bar>", 1.5, true, eventStart},
+	})
+	out := &bytes.Buffer{}
+	_, err := workbook.WriteTo(out)
+	assert.Nil(t, err)
+
+	assert.Contains(t, readWorkbookPart(t, out.Bytes(), "xl/workbook.xml"), `<sheet name="Teams" sheetId="1" r:id="rId1"/>`)
+	sheet := readWorkbookPart(t, out.Bytes(), "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<c r="A1" s="1" t="inlineStr"><is><t>Team</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t>&lt;foo&amp;bar&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>1.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="b"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="D2" t="inlineStr"><is><t>2021-05-01T10:00:00Z</t></is></c>`)
+	assert.Contains(t, readWorkbookPart(t, out.Bytes(), "[Content_Types].xml"), `/xl/worksheets/sheet1.xml`)
+}
+
+func TestWorkbookSanitizesSheetNames(t *testing.T) {
+	workbook := NewWorkbook(time.UTC)
+	workbook.AddSheet("a/b:c", nil)
+	workbook.AddSheet("a-very-long-team-name-exceeding-the-limit", nil)
+	workbook.AddSheet("A-VERY-LONG-TEAM-NAME-EXCEEDING-THE-LIMIT", nil)
+	workbook.AddSheet("", nil)
+	workbook.AddSheet("history", nil)
+
+	assert.Equal(t, "a_b_c", workbook.sheets[0].name)
+	assert.Equal(t, "a-very-long-team-name-exceeding", workbook.sheets[1].name)
+	assert.Equal(t, "A-VERY-LONG-TEAM-NAME-EXCEE (2)", workbook.sheets[2].name)
+	assert.Equal(t, "Sheet", workbook.sheets[3].name)
+	assert.Equal(t, "history (2)", workbook.sheets[4].name, "Should not use the name Excel reserves")
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AB", columnName(27))
+	assert.Equal(t, "ZZ", columnName(701))
+	assert.Equal(t, "AAA", columnName(702))
+}
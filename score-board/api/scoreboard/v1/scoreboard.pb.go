@@ -0,0 +1,740 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0
+// 	protoc        (unknown)
+// source: scoreboard/v1/scoreboard.proto
+
+package scoreboardv1
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type ListTeamsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListTeamsRequest) Reset() {
+	*x = ListTeamsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListTeamsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTeamsRequest) ProtoMessage() {}
+
+func (x *ListTeamsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTeamsRequest.ProtoReflect.Descriptor instead.
+func (*ListTeamsRequest) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{0}
+}
+
+type ListTeamsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Teams []*RankedTeam `protobuf:"bytes,1,rep,name=teams,proto3" json:"teams,omitempty"`
+}
+
+func (x *ListTeamsResponse) Reset() {
+	*x = ListTeamsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListTeamsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTeamsResponse) ProtoMessage() {}
+
+func (x *ListTeamsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTeamsResponse.ProtoReflect.Descriptor instead.
+func (*ListTeamsResponse) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListTeamsResponse) GetTeams() []*RankedTeam {
+	if x != nil {
+		return x.Teams
+	}
+	return nil
+}
+
+// RankedTeam is a team listed in the ranking
+type RankedTeam struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// position of the team, teams tied in solves and solve time share their position
+	Position         int32  `protobuf:"varint,1,opt,name=position,proto3" json:"position,omitempty"`
+	Team             string `protobuf:"bytes,2,opt,name=team,proto3" json:"team,omitempty"`
+	ChallengesSolved int32  `protobuf:"varint,3,opt,name=challenges_solved,json=challengesSolved,proto3" json:"challenges_solved,omitempty"`
+	// last_solved_at is the time of the latest solve of the team, unset if unknown
+	LastSolvedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_solved_at,json=lastSolvedAt,proto3" json:"last_solved_at,omitempty"`
+}
+
+func (x *RankedTeam) Reset() {
+	*x = RankedTeam{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RankedTeam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankedTeam) ProtoMessage() {}
+
+func (x *RankedTeam) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankedTeam.ProtoReflect.Descriptor instead.
+func (*RankedTeam) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RankedTeam) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *RankedTeam) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *RankedTeam) GetChallengesSolved() int32 {
+	if x != nil {
+		return x.ChallengesSolved
+	}
+	return 0
+}
+
+func (x *RankedTeam) GetLastSolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSolvedAt
+	}
+	return nil
+}
+
+type GetTeamProgressRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Team string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+}
+
+func (x *GetTeamProgressRequest) Reset() {
+	*x = GetTeamProgressRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTeamProgressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTeamProgressRequest) ProtoMessage() {}
+
+func (x *GetTeamProgressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTeamProgressRequest.ProtoReflect.Descriptor instead.
+func (*GetTeamProgressRequest) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetTeamProgressRequest) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+type GetTeamProgressResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Team string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+	// position of the team in the ranking, 0 for paused teams
+	Position         int32 `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`
+	ChallengesSolved int32 `protobuf:"varint,3,opt,name=challenges_solved,json=challengesSolved,proto3" json:"challenges_solved,omitempty"`
+	// last_solved_at is the time of the latest solve of the team, unset if unknown
+	LastSolvedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_solved_at,json=lastSolvedAt,proto3" json:"last_solved_at,omitempty"`
+	Paused       bool                   `protobuf:"varint,5,opt,name=paused,proto3" json:"paused,omitempty"`
+	// solves of the team counted by the watchdog, ordered by their solve time
+	Solves []*Solve `protobuf:"bytes,6,rep,name=solves,proto3" json:"solves,omitempty"`
+}
+
+func (x *GetTeamProgressResponse) Reset() {
+	*x = GetTeamProgressResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTeamProgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTeamProgressResponse) ProtoMessage() {}
+
+func (x *GetTeamProgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTeamProgressResponse.ProtoReflect.Descriptor instead.
+func (*GetTeamProgressResponse) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetTeamProgressResponse) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *GetTeamProgressResponse) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *GetTeamProgressResponse) GetChallengesSolved() int32 {
+	if x != nil {
+		return x.ChallengesSolved
+	}
+	return 0
+}
+
+func (x *GetTeamProgressResponse) GetLastSolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSolvedAt
+	}
+	return nil
+}
+
+func (x *GetTeamProgressResponse) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *GetTeamProgressResponse) GetSolves() []*Solve {
+	if x != nil {
+		return x.Solves
+	}
+	return nil
+}
+
+type WatchSolvesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// team to watch the solves of, all teams if empty
+	Team string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+}
+
+func (x *WatchSolvesRequest) Reset() {
+	*x = WatchSolvesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchSolvesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchSolvesRequest) ProtoMessage() {}
+
+func (x *WatchSolvesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchSolvesRequest.ProtoReflect.Descriptor instead.
+func (*WatchSolvesRequest) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchSolvesRequest) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+type WatchSolvesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Solve *Solve `protobuf:"bytes,1,opt,name=solve,proto3" json:"solve,omitempty"`
+}
+
+func (x *WatchSolvesResponse) Reset() {
+	*x = WatchSolvesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchSolvesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchSolvesResponse) ProtoMessage() {}
+
+func (x *WatchSolvesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchSolvesResponse.ProtoReflect.Descriptor instead.
+func (*WatchSolvesResponse) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchSolvesResponse) GetSolve() *Solve {
+	if x != nil {
+		return x.Solve
+	}
+	return nil
+}
+
+// Solve is the solve of a challenge by a team
+type Solve struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Team string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+	// challenge is the id of the challenge in the JuiceShop
+	Challenge int32                  `protobuf:"varint,2,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	SolvedAt  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=solved_at,json=solvedAt,proto3" json:"solved_at,omitempty"`
+}
+
+func (x *Solve) Reset() {
+	*x = Solve{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Solve) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Solve) ProtoMessage() {}
+
+func (x *Solve) ProtoReflect() protoreflect.Message {
+	mi := &file_scoreboard_v1_scoreboard_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Solve.ProtoReflect.Descriptor instead.
+func (*Solve) Descriptor() ([]byte, []int) {
+	return file_scoreboard_v1_scoreboard_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Solve) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *Solve) GetChallenge() int32 {
+	if x != nil {
+		return x.Challenge
+	}
+	return 0
+}
+
+func (x *Solve) GetSolvedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SolvedAt
+	}
+	return nil
+}
+
+var File_scoreboard_v1_scoreboard_proto protoreflect.FileDescriptor
+
+var file_scoreboard_v1_scoreboard_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2f, 0x76, 0x31, 0x2f,
+	0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x19, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x63,
+	0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31, 0x1a, 0x1f, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x12, 0x0a, 0x10,
+	0x4c, 0x69, 0x73, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x22, 0x50, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x05, 0x74, 0x65, 0x61, 0x6d, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63,
+	0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31,
+	0x2e, 0x52, 0x61, 0x6e, 0x6b, 0x65, 0x64, 0x54, 0x65, 0x61, 0x6d, 0x52, 0x05, 0x74, 0x65, 0x61,
+	0x6d, 0x73, 0x22, 0xab, 0x01, 0x0a, 0x0a, 0x52, 0x61, 0x6e, 0x6b, 0x65, 0x64, 0x54, 0x65, 0x61,
+	0x6d, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61,
+	0x6d, 0x12, 0x2b, 0x0a, 0x11, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x73, 0x5f,
+	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x63, 0x68,
+	0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x73, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x12, 0x40,
+	0x0a, 0x0e, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x0c, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x41, 0x74,
+	0x22, 0x2c, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x67, 0x72,
+	0x65, 0x73, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65,
+	0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x22, 0x8a,
+	0x02, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65,
+	0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x12, 0x1a,
+	0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x11, 0x63, 0x68,
+	0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x73, 0x5f, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65,
+	0x73, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x12, 0x40, 0x0a, 0x0e, 0x6c, 0x61, 0x73, 0x74, 0x5f,
+	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0c, 0x6c, 0x61, 0x73,
+	0x74, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x41, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x75,
+	0x73, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70, 0x61, 0x75, 0x73, 0x65,
+	0x64, 0x12, 0x38, 0x0a, 0x06, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x20, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2e,
+	0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f,
+	0x6c, 0x76, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x73, 0x22, 0x28, 0x0a, 0x12, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x74, 0x65, 0x61, 0x6d, 0x22, 0x4d, 0x0a, 0x13, 0x57, 0x61, 0x74, 0x63, 0x68, 0x53, 0x6f,
+	0x6c, 0x76, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x05,
+	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x6d, 0x75,
+	0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62,
+	0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x52, 0x05, 0x73,
+	0x6f, 0x6c, 0x76, 0x65, 0x22, 0x72, 0x0a, 0x05, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61,
+	0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x12,
+	0x37, 0x0a, 0x09, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x08,
+	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x41, 0x74, 0x32, 0xde, 0x02, 0x0a, 0x0a, 0x53, 0x63, 0x6f,
+	0x72, 0x65, 0x42, 0x6f, 0x61, 0x72, 0x64, 0x12, 0x66, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x54,
+	0x65, 0x61, 0x6d, 0x73, 0x12, 0x2b, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63,
+	0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x2c, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2e,
+	0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x78, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x31, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72,
+	0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x65, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x32, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a, 0x75, 0x69,
+	0x63, 0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x6e, 0x0a, 0x0b, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x73, 0x12, 0x2d, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69,
+	0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72,
+	0x64, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2e, 0x2e, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x6a,
+	0x75, 0x69, 0x63, 0x65, 0x72, 0x2e, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64,
+	0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x53, 0x6f, 0x6c, 0x76, 0x65, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x4d, 0x5a, 0x4b, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x74, 0x65, 0x72, 0x61, 0x74, 0x65, 0x63,
+	0x2f, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x2d, 0x6a, 0x75, 0x69, 0x63, 0x65, 0x72, 0x2f, 0x73, 0x63,
+	0x6f, 0x72, 0x65, 0x2d, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x73, 0x63,
+	0x6f, 0x72, 0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x2f, 0x76, 0x31, 0x3b, 0x73, 0x63, 0x6f, 0x72,
+	0x65, 0x62, 0x6f, 0x61, 0x72, 0x64, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_scoreboard_v1_scoreboard_proto_rawDescOnce sync.Once
+	file_scoreboard_v1_scoreboard_proto_rawDescData = file_scoreboard_v1_scoreboard_proto_rawDesc
+)
+
+func file_scoreboard_v1_scoreboard_proto_rawDescGZIP() []byte {
+	file_scoreboard_v1_scoreboard_proto_rawDescOnce.Do(func() {
+		file_scoreboard_v1_scoreboard_proto_rawDescData = protoimpl.X.CompressGZIP(file_scoreboard_v1_scoreboard_proto_rawDescData)
+	})
+	return file_scoreboard_v1_scoreboard_proto_rawDescData
+}
+
+var file_scoreboard_v1_scoreboard_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_scoreboard_v1_scoreboard_proto_goTypes = []interface{}{
+	(*ListTeamsRequest)(nil),        // 0: multijuicer.scoreboard.v1.ListTeamsRequest
+	(*ListTeamsResponse)(nil),       // 1: multijuicer.scoreboard.v1.ListTeamsResponse
+	(*RankedTeam)(nil),              // 2: multijuicer.scoreboard.v1.RankedTeam
+	(*GetTeamProgressRequest)(nil),  // 3: multijuicer.scoreboard.v1.GetTeamProgressRequest
+	(*GetTeamProgressResponse)(nil), // 4: multijuicer.scoreboard.v1.GetTeamProgressResponse
+	(*WatchSolvesRequest)(nil),      // 5: multijuicer.scoreboard.v1.WatchSolvesRequest
+	(*WatchSolvesResponse)(nil),     // 6: multijuicer.scoreboard.v1.WatchSolvesResponse
+	(*Solve)(nil),                   // 7: multijuicer.scoreboard.v1.Solve
+	(*timestamppb.Timestamp)(nil),   // 8: google.protobuf.Timestamp
+}
+var file_scoreboard_v1_scoreboard_proto_depIdxs = []int32{
+	2, // 0: multijuicer.scoreboard.v1.ListTeamsResponse.teams:type_name -> multijuicer.scoreboard.v1.RankedTeam
+	8, // 1: multijuicer.scoreboard.v1.RankedTeam.last_solved_at:type_name -> google.protobuf.Timestamp
+	8, // 2: multijuicer.scoreboard.v1.GetTeamProgressResponse.last_solved_at:type_name -> google.protobuf.Timestamp
+	7, // 3: multijuicer.scoreboard.v1.GetTeamProgressResponse.solves:type_name -> multijuicer.scoreboard.v1.Solve
+	7, // 4: multijuicer.scoreboard.v1.WatchSolvesResponse.solve:type_name -> multijuicer.scoreboard.v1.Solve
+	8, // 5: multijuicer.scoreboard.v1.Solve.solved_at:type_name -> google.protobuf.Timestamp
+	0, // 6: multijuicer.scoreboard.v1.ScoreBoard.ListTeams:input_type -> multijuicer.scoreboard.v1.ListTeamsRequest
+	3, // 7: multijuicer.scoreboard.v1.ScoreBoard.GetTeamProgress:input_type -> multijuicer.scoreboard.v1.GetTeamProgressRequest
+	5, // 8: multijuicer.scoreboard.v1.ScoreBoard.WatchSolves:input_type -> multijuicer.scoreboard.v1.WatchSolvesRequest
+	1, // 9: multijuicer.scoreboard.v1.ScoreBoard.ListTeams:output_type -> multijuicer.scoreboard.v1.ListTeamsResponse
+	4, // 10: multijuicer.scoreboard.v1.ScoreBoard.GetTeamProgress:output_type -> multijuicer.scoreboard.v1.GetTeamProgressResponse
+	6, // 11: multijuicer.scoreboard.v1.ScoreBoard.WatchSolves:output_type -> multijuicer.scoreboard.v1.WatchSolvesResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_scoreboard_v1_scoreboard_proto_init() }
+func file_scoreboard_v1_scoreboard_proto_init() {
+	if File_scoreboard_v1_scoreboard_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_scoreboard_v1_scoreboard_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListTeamsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListTeamsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RankedTeam); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetTeamProgressRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetTeamProgressResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchSolvesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchSolvesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_scoreboard_v1_scoreboard_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Solve); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_scoreboard_v1_scoreboard_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_scoreboard_v1_scoreboard_proto_goTypes,
+		DependencyIndexes: file_scoreboard_v1_scoreboard_proto_depIdxs,
+		MessageInfos:      file_scoreboard_v1_scoreboard_proto_msgTypes,
+	}.Build()
+	File_scoreboard_v1_scoreboard_proto = out.File
+	file_scoreboard_v1_scoreboard_proto_rawDesc = nil
+	file_scoreboard_v1_scoreboard_proto_goTypes = nil
+	file_scoreboard_v1_scoreboard_proto_depIdxs = nil
+}
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: scoreboard/v1/scoreboard.proto
+
+package scoreboardv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ScoreBoardClient is the client API for ScoreBoard service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ScoreBoardClient interface {
+	// ListTeams returns the ranking of the teams, paused teams aren't ranked
+	ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error)
+	// GetTeamProgress returns the progress of a team, fails with NOT_FOUND for unknown teams
+	GetTeamProgress(ctx context.Context, in *GetTeamProgressRequest, opts ...grpc.CallOption) (*GetTeamProgressResponse, error)
+	// WatchSolves streams the solves detected after the call. The stream fails with UNAVAILABLE if the client doesn't keep up with the
+	// solves or the score board shuts down, clients are expected to call again.
+	WatchSolves(ctx context.Context, in *WatchSolvesRequest, opts ...grpc.CallOption) (ScoreBoard_WatchSolvesClient, error)
+}
+
+type scoreBoardClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScoreBoardClient(cc grpc.ClientConnInterface) ScoreBoardClient {
+	return &scoreBoardClient{cc}
+}
+
+func (c *scoreBoardClient) ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error) {
+	out := new(ListTeamsResponse)
+	err := c.cc.Invoke(ctx, "/multijuicer.scoreboard.v1.ScoreBoard/ListTeams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoreBoardClient) GetTeamProgress(ctx context.Context, in *GetTeamProgressRequest, opts ...grpc.CallOption) (*GetTeamProgressResponse, error) {
+	out := new(GetTeamProgressResponse)
+	err := c.cc.Invoke(ctx, "/multijuicer.scoreboard.v1.ScoreBoard/GetTeamProgress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoreBoardClient) WatchSolves(ctx context.Context, in *WatchSolvesRequest, opts ...grpc.CallOption) (ScoreBoard_WatchSolvesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ScoreBoard_ServiceDesc.Streams[0], "/multijuicer.scoreboard.v1.ScoreBoard/WatchSolves", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &scoreBoardWatchSolvesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ScoreBoard_WatchSolvesClient interface {
+	Recv() (*WatchSolvesResponse, error)
+	grpc.ClientStream
+}
+
+type scoreBoardWatchSolvesClient struct {
+	grpc.ClientStream
+}
+
+func (x *scoreBoardWatchSolvesClient) Recv() (*WatchSolvesResponse, error) {
+	m := new(WatchSolvesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ScoreBoardServer is the server API for ScoreBoard service.
+// All implementations must embed UnimplementedScoreBoardServer
+// for forward compatibility
+type ScoreBoardServer interface {
+	// ListTeams returns the ranking of the teams, paused teams aren't ranked
+	ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error)
+	// GetTeamProgress returns the progress of a team, fails with NOT_FOUND for unknown teams
+	GetTeamProgress(context.Context, *GetTeamProgressRequest) (*GetTeamProgressResponse, error)
+	// WatchSolves streams the solves detected after the call. The stream fails with UNAVAILABLE if the client doesn't keep up with the
+	// solves or the score board shuts down, clients are expected to call again.
+	WatchSolves(*WatchSolvesRequest, ScoreBoard_WatchSolvesServer) error
+	mustEmbedUnimplementedScoreBoardServer()
+}
+
+// UnimplementedScoreBoardServer must be embedded to have forward compatible implementations.
+type UnimplementedScoreBoardServer struct {
+}
+
+func (UnimplementedScoreBoardServer) ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTeams not implemented")
+}
+func (UnimplementedScoreBoardServer) GetTeamProgress(context.Context, *GetTeamProgressRequest) (*GetTeamProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTeamProgress not implemented")
+}
+func (UnimplementedScoreBoardServer) WatchSolves(*WatchSolvesRequest, ScoreBoard_WatchSolvesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSolves not implemented")
+}
+func (UnimplementedScoreBoardServer) mustEmbedUnimplementedScoreBoardServer() {}
+
+// UnsafeScoreBoardServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScoreBoardServer will
+// result in compilation errors.
+type UnsafeScoreBoardServer interface {
+	mustEmbedUnimplementedScoreBoardServer()
+}
+
+func RegisterScoreBoardServer(s grpc.ServiceRegistrar, srv ScoreBoardServer) {
+	s.RegisterService(&ScoreBoard_ServiceDesc, srv)
+}
+
+func _ScoreBoard_ListTeams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTeamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoreBoardServer).ListTeams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/multijuicer.scoreboard.v1.ScoreBoard/ListTeams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoreBoardServer).ListTeams(ctx, req.(*ListTeamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ScoreBoard_GetTeamProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTeamProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoreBoardServer).GetTeamProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/multijuicer.scoreboard.v1.ScoreBoard/GetTeamProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoreBoardServer).GetTeamProgress(ctx, req.(*GetTeamProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ScoreBoard_WatchSolves_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchSolvesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScoreBoardServer).WatchSolves(m, &scoreBoardWatchSolvesServer{stream})
+}
+
+type ScoreBoard_WatchSolvesServer interface {
+	Send(*WatchSolvesResponse) error
+	grpc.ServerStream
+}
+
+type scoreBoardWatchSolvesServer struct {
+	grpc.ServerStream
+}
+
+func (x *scoreBoardWatchSolvesServer) Send(m *WatchSolvesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ScoreBoard_ServiceDesc is the grpc.ServiceDesc for ScoreBoard service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ScoreBoard_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "multijuicer.scoreboard.v1.ScoreBoard",
+	HandlerType: (*ScoreBoardServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTeams",
+			Handler:    _ScoreBoard_ListTeams_Handler,
+		},
+		{
+			MethodName: "GetTeamProgress",
+			Handler:    _ScoreBoard_GetTeamProgress_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchSolves",
+			Handler:       _ScoreBoard_WatchSolves_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scoreboard/v1/scoreboard.proto",
+}
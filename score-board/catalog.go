@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// catalogRetryInterval is the time after which fetching the challenges is retried if none of the JuiceShops could be reached
+	catalogRetryInterval = 30 * time.Second
+	// catalogFetchAttempts is the number of JuiceShops the challenges are requested from before giving up until the retry
+	catalogFetchAttempts = 3
+)
+
+// Challenge is a challenge of the JuiceShop
+type Challenge struct {
+	ID         int    `json:"id"`
+	Key        string `json:"key"`
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// ChallengeCatalog knows the challenges of the JuiceShops, fetched from the challenge api of one of them. All JuiceShops of an event
+// run the same version, so that their challenges are the same.
+type ChallengeCatalog struct {
+	// urlOf returns the url of the challenge api of the JuiceShop of the team
+	urlOf  func(team string) string
+	client *http.Client
+	ttl    time.Duration
+
+	mutex      sync.Mutex
+	challenges map[int]Challenge
+	nextFetch  time.Time
+}
+
+// NewChallengeCatalog creates a catalog fetching the challenges from the JuiceShop services of the namespace, refetched after the ttl
+func NewChallengeCatalog(namespace string, port int, ttl time.Duration) *ChallengeCatalog {
+	return &ChallengeCatalog{
+		urlOf: func(team string) string {
+			return fmt.Sprintf("http://t-%s-juiceshop.%s:%d/api/Challenges/", team, namespace, port)
+		},
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        ttl,
+		challenges: map[int]Challenge{},
+	}
+}
+
+// Challenges returns the challenges by id, fetched from the JuiceShop of one of the teams if the cached ones are outdated. Returns the
+// previously fetched challenges if none of the JuiceShops could be reached, no challenges if they never could be.
+func (c *ChallengeCatalog) Challenges(teams []TeamProgress) map[int]Challenge {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if time.Now().Before(c.nextFetch) || len(teams) == 0 {
+		return c.challenges
+	}
+	for i, team := range teams {
+		if i == catalogFetchAttempts {
+			break
+		}
+		challenges, err := c.fetch(team.Team)
+		if err != nil {
+			log.Debugf("Failed to fetch the challenges from the JuiceShop of team '%s': %s", team.Team, err)
+			continue
+		}
+		c.challenges = challenges
+		c.nextFetch = time.Now().Add(c.ttl)
+		return c.challenges
+	}
+	log.Warningf("Failed to fetch the challenges from the JuiceShops, retrying in %s", catalogRetryInterval)
+	c.nextFetch = time.Now().Add(catalogRetryInterval)
+	return c.challenges
+}
+
+func (c *ChallengeCatalog) fetch(team string) (map[int]Challenge, error) {
+	res, err := c.client.Get(c.urlOf(team))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response status code '%d' from Juice Shop", res.StatusCode)
+	}
+	payload := struct {
+		Data []Challenge `json:"data"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("Failed to parse JSON from Juice Shop challenge response: %v", err)
+	}
+	challenges := map[int]Challenge{}
+	for _, challenge := range payload.Data {
+		challenges[challenge.ID] = challenge
+	}
+	return challenges, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// knownChallenges creates a catalog of the challenges which never fetches them from a JuiceShop
+func knownChallenges(challenges ...Challenge) *ChallengeCatalog {
+	catalog := &ChallengeCatalog{challenges: map[int]Challenge{}, nextFetch: time.Now().Add(time.Hour)}
+	for _, challenge := range challenges {
+		catalog.challenges[challenge.ID] = challenge
+	}
+	return catalog
+}
+
+func TestChallengeCatalogFetchesTheChallengesFromTheFirstReachableJuiceShop(t *testing.T) {
+	requests := []string{}
+	juiceShop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path)
+		if strings.HasPrefix(req.URL.Path, "/broken/") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":[{"id":1,"key":"scoreBoardChallenge","name":"Score Board","category":"Miscellaneous","difficulty":1,"solved":false}]}`))
+	}))
+	defer juiceShop.Close()
+	catalog := NewChallengeCatalog("default", 3000, time.Hour)
+	catalog.urlOf = func(team string) string { return juiceShop.URL + "/" + team + "/api/Challenges/" }
+
+	challenges := catalog.Challenges([]TeamProgress{{Team: "broken"}, {Team: "foo"}})
+	cached := catalog.Challenges([]TeamProgress{{Team: "foo"}})
+
+	assert.Equal(t, map[int]Challenge{1: {ID: 1, Key: "scoreBoardChallenge", Name: "Score Board", Category: "Miscellaneous", Difficulty: 1}}, challenges)
+	assert.Equal(t, challenges, cached)
+	assert.Equal(t, []string{"/broken/api/Challenges/", "/foo/api/Challenges/"}, requests)
+}
+
+func TestChallengeCatalogKeepsTheChallengesAndWaitsBeforeRetryingIfNoJuiceShopIsReachable(t *testing.T) {
+	requests := 0
+	juiceShop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer juiceShop.Close()
+	catalog := knownChallenges(Challenge{ID: 1, Category: "XSS"})
+	catalog.nextFetch = time.Time{}
+	catalog.urlOf = func(team string) string { return juiceShop.URL }
+	catalog.client = juiceShop.Client()
+	teams := []TeamProgress{{Team: "a"}, {Team: "b"}, {Team: "c"}, {Team: "d"}}
+
+	assert.Equal(t, "XSS", catalog.Challenges(teams)[1].Category)
+	assert.Equal(t, "XSS", catalog.Challenges(teams)[1].Category)
+	assert.Equal(t, catalogFetchAttempts, requests)
+}
@@ -0,0 +1,6 @@
+// The client is generated from the proto definition of the score board api with `npm run generate`, e.g.
+//
+//   const client = new ScoreBoardClient('score-board:9090', credentials.createInsecure());
+//   client.watchSolves({ team: '' }).on('data', ({ solve }) => console.log(solve));
+export * from './generated/scoreboard/v1/scoreboard';
+export { credentials } from '@grpc/grpc-js';
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// exportColumns are the columns of the csv export, which has a row per solve and a row without solve for teams which didn't solve anything
+var exportColumns = []string{"position", "team", "challengesSolved", "lastSolvedAt", "paused", "challengeId", "challengeKey", "challengeName", "category", "difficulty", "solvedAt"}
+
+// ExportSolve is a solve of a team in the score export. The details of the challenge are empty if it's unknown to the catalog.
+type ExportSolve struct {
+	Challenge  int       `json:"challenge"`
+	Key        string    `json:"key,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Difficulty int       `json:"difficulty,omitempty"`
+	SolvedAt   time.Time `json:"solvedAt"`
+}
+
+// ExportTeam is a team in the score export
+type ExportTeam struct {
+	// Position is nil for paused teams, as they aren't ranked
+	Position         *int          `json:"position"`
+	Team             string        `json:"team"`
+	ChallengesSolved int           `json:"challengesSolved"`
+	LastSolvedAt     *time.Time    `json:"lastSolvedAt"`
+	Paused           bool          `json:"paused"`
+	Solves           []ExportSolve `json:"solves"`
+}
+
+// ScoreExport json format of the score export, a full dump of the teams and their solves
+type ScoreExport struct {
+	ExportedAt time.Time    `json:"exportedAt"`
+	Teams      []ExportTeam `json:"teams"`
+}
+
+// exportScore dumps the teams ordered by their position with their solves ordered by solve time, paused teams are listed last.
+func exportScore(teams []TeamProgress, challenges map[int]Challenge, now time.Time) ScoreExport {
+	positions := map[string]int{}
+	for _, ranked := range rankTeams(teams) {
+		positions[ranked.Team] = ranked.Position
+	}
+	sorted := append([]TeamProgress{}, teams...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, aRanked := positions[sorted[i].Team]
+		b, bRanked := positions[sorted[j].Team]
+		if aRanked != bRanked {
+			return aRanked
+		}
+		if a != b {
+			return a < b
+		}
+		return sorted[i].Team < sorted[j].Team
+	})
+
+	export := ScoreExport{ExportedAt: now.UTC(), Teams: []ExportTeam{}}
+	for _, team := range sorted {
+		exported := ExportTeam{Team: team.Team, ChallengesSolved: team.ChallengesSolved, Paused: team.Paused, Solves: []ExportSolve{}}
+		if position, ok := positions[team.Team]; ok {
+			exported.Position = &position
+		}
+		if !team.LastSolvedAt.IsZero() {
+			lastSolvedAt := team.LastSolvedAt.UTC()
+			exported.LastSolvedAt = &lastSolvedAt
+		}
+		for _, event := range solvesOf(team) {
+			challenge := challenges[event.Challenge]
+			exported.Solves = append(exported.Solves, ExportSolve{
+				Challenge:  event.Challenge,
+				Key:        challenge.Key,
+				Name:       challenge.Name,
+				Category:   challenge.Category,
+				Difficulty: challenge.Difficulty,
+				SolvedAt:   event.SolvedAt,
+			})
+		}
+		export.Teams = append(export.Teams, exported)
+	}
+	return export
+}
+
+func writeExportCSV(w http.ResponseWriter, export ScoreExport) {
+	writer := csv.NewWriter(w)
+	writer.Write(exportColumns)
+	for _, team := range export.Teams {
+		position, lastSolvedAt := "", ""
+		if team.Position != nil {
+			position = strconv.Itoa(*team.Position)
+		}
+		if team.LastSolvedAt != nil {
+			lastSolvedAt = team.LastSolvedAt.Format(time.RFC3339)
+		}
+		row := []string{position, team.Team, strconv.Itoa(team.ChallengesSolved), lastSolvedAt, strconv.FormatBool(team.Paused)}
+		if len(team.Solves) == 0 {
+			writer.Write(append(row, "", "", "", "", "", ""))
+			continue
+		}
+		for _, solve := range team.Solves {
+			difficulty := ""
+			if solve.Difficulty != 0 {
+				difficulty = strconv.Itoa(solve.Difficulty)
+			}
+			writer.Write(append(row, strconv.Itoa(solve.Challenge), solve.Key, solve.Name, solve.Category, difficulty, solve.SolvedAt.Format(time.RFC3339)))
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Errorf("Failed to write csv export: %s", err)
+	}
+}
+
+// newScoreExportHandler serves the score export as download, either as `csv` or as `json` (default) depending on the `format` query parameter
+func newScoreExportHandler(source ProgressSource, catalog *ChallengeCatalog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format := req.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			http.Error(w, "Unknown format, must be either 'csv' or 'json'", http.StatusBadRequest)
+			return
+		}
+
+		teams := source.Teams()
+		export := exportScore(teams, catalog.Challenges(teams), time.Now())
+		filename := fmt.Sprintf("multi-juicer-score-%s.%s", export.ExportedAt.Format("20060102-150405"), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			writeExportCSV(w, export)
+			return
+		}
+		writeJSON(w, export)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newExportTestServer() http.Handler {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	source := staticSource{
+		{Team: "foo", ChallengesSolved: 1, Position: 2, LastSolvedAt: start.Add(time.Hour), Solves: map[int]time.Time{2: start.Add(time.Hour)}},
+		{Team: "paused", ChallengesSolved: 4, Paused: true},
+		{Team: "bar", ChallengesSolved: 2, Position: 1, LastSolvedAt: start.Add(time.Minute), Solves: map[int]time.Time{99: start.Add(time.Minute), 1: start}},
+		{Team: "new", Position: 3},
+	}
+	catalog := knownChallenges(
+		Challenge{ID: 1, Key: "scoreBoardChallenge", Name: "Score Board", Category: "Miscellaneous", Difficulty: 1},
+		Challenge{ID: 2, Key: "localXssChallenge", Name: "DOM XSS, reflected", Category: "XSS", Difficulty: 1},
+	)
+	return NewServer(NewScoreFeed(source), NewSolveFeed(source), catalog)
+}
+
+func TestScoreExportDumpsTheTeamsAndTheirSolvesAsCSV(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newExportTestServer().ServeHTTP(rr, httptest.NewRequest("GET", "/api/score/export?format=csv", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="multi-juicer-score-\d{8}-\d{6}\.csv"$`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, `position,team,challengesSolved,lastSolvedAt,paused,challengeId,challengeKey,challengeName,category,difficulty,solvedAt
+1,bar,2,2021-05-01T10:01:00Z,false,1,scoreBoardChallenge,Score Board,Miscellaneous,1,2021-05-01T10:00:00Z
+1,bar,2,2021-05-01T10:01:00Z,false,99,,,,,2021-05-01T10:01:00Z
+2,foo,1,2021-05-01T11:00:00Z,false,2,localXssChallenge,"DOM XSS, reflected",XSS,1,2021-05-01T11:00:00Z
+3,new,0,,false,,,,,,
+,paused,4,,true,,,,,,
+`, rr.Body.String())
+}
+
+func TestScoreExportDumpsTheTeamsAndTheirSolvesAsJSON(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newExportTestServer().ServeHTTP(rr, httptest.NewRequest("GET", "/api/score/export", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Regexp(t, `\.json"$`, rr.Header().Get("Content-Disposition"))
+	assert.Regexp(t, `"exportedAt":"[^"]+"`, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"teams":[{"position":1,"team":"bar","challengesSolved":2,"lastSolvedAt":"2021-05-01T10:01:00Z","paused":false,"solves":[`+
+		`{"challenge":1,"key":"scoreBoardChallenge","name":"Score Board","category":"Miscellaneous","difficulty":1,"solvedAt":"2021-05-01T10:00:00Z"},`+
+		`{"challenge":99,"solvedAt":"2021-05-01T10:01:00Z"}]}`)
+	assert.Contains(t, rr.Body.String(), `{"position":null,"team":"paused","challengesSolved":4,"lastSolvedAt":null,"paused":true,"solves":[]}]}`)
+}
+
+func TestScoreExportRejectsUnknownFormats(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newExportTestServer().ServeHTTP(rr, httptest.NewRequest("GET", "/api/score/export?format=xlsx", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
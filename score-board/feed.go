@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// feedWriteTimeout is the time a client of the live feed gets to receive an update before it is disconnected
+const feedWriteTimeout = 10 * time.Second
+
+// ScoreFeed keeps the current score and pushes it to its subscribers whenever the ranking changes
+type ScoreFeed struct {
+	source ProgressSource
+
+	mutex       sync.Mutex
+	score       ScoreResponse
+	subscribers map[int]chan ScoreResponse
+	nextID      int
+	closed      bool
+}
+
+// NewScoreFeed creates the feed of the score of the teams of the source
+func NewScoreFeed(source ProgressSource) *ScoreFeed {
+	f := &ScoreFeed{source: source, subscribers: map[int]chan ScoreResponse{}}
+	f.score = f.compute()
+	source.OnChange(f.update)
+	return f
+}
+
+func (f *ScoreFeed) compute() ScoreResponse {
+	teams := rankTeams(f.source.Teams())
+	return ScoreResponse{Teams: teams, TotalTeams: len(teams)}
+}
+
+// update recomputes the score and notifies the subscribers if it changed, e.g. not on solves of paused teams
+func (f *ScoreFeed) update() {
+	score := f.compute()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed || reflect.DeepEqual(score, f.score) {
+		return
+	}
+	f.score = score
+	for _, updates := range f.subscribers {
+		push(updates, score)
+	}
+}
+
+// push replaces the score waiting to be sent to a subscriber, slow subscribers only get the latest score
+func push(updates chan ScoreResponse, score ScoreResponse) {
+	select {
+	case <-updates:
+	default:
+	}
+	updates <- score
+}
+
+// Score returns the current score
+func (f *ScoreFeed) Score() ScoreResponse {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.score
+}
+
+// Subscribe returns a channel receiving the current score and every change of it, until the subscription is cancelled with the
+// returned function or the feed is closed
+func (f *ScoreFeed) Subscribe() (<-chan ScoreResponse, func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	updates := make(chan ScoreResponse, 1)
+	if f.closed {
+		close(updates)
+		return updates, func() {}
+	}
+	id := f.nextID
+	f.nextID++
+	f.subscribers[id] = updates
+	updates <- f.score
+	return updates, func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		delete(f.subscribers, id)
+	}
+}
+
+// Close ends all subscriptions, so that the live connections get closed on shutdown
+func (f *ScoreFeed) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.closed = true
+	for id, updates := range f.subscribers {
+		close(updates)
+		delete(f.subscribers, id)
+	}
+}
+
+// newLiveScoreHandler serves the score via WebSocket, sending it as json message on connect and after every change of the ranking.
+// Connections from all origins are accepted, like the score itself is public.
+func newLiveScoreHandler(feed *ScoreFeed) http.Handler {
+	return websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			updates, unsubscribe := feed.Subscribe()
+			defer unsubscribe()
+
+			// clients aren't expected to send anything, reading only detects when they disconnect
+			disconnected := make(chan struct{})
+			go func() {
+				defer close(disconnected)
+				var message string
+				for websocket.Message.Receive(conn, &message) == nil {
+				}
+			}()
+
+			for {
+				select {
+				case score, ok := <-updates:
+					if !ok {
+						return
+					}
+					conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+					if err := websocket.JSON.Send(conn, score); err != nil {
+						log.Debugf("Failed to send score to live feed client '%s': %s", conn.Request().RemoteAddr, err)
+						return
+					}
+				case <-disconnected:
+					return
+				}
+			}
+		},
+	}
+}
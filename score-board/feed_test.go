@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// changingSource is a source whose progress is changed by the tests
+type changingSource struct {
+	mutex    sync.Mutex
+	teams    []TeamProgress
+	handlers []func()
+}
+
+func (s *changingSource) Start(ctx context.Context) error { return nil }
+
+func (s *changingSource) Teams() []TeamProgress {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.teams
+}
+
+func (s *changingSource) OnChange(handler func()) {
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *changingSource) set(teams ...TeamProgress) {
+	s.mutex.Lock()
+	s.teams = teams
+	s.mutex.Unlock()
+	for _, handler := range s.handlers {
+		handler()
+	}
+}
+
+func TestScoreFeedPushesTheScoreOnlyWhenTheRankingChanged(t *testing.T) {
+	source := &changingSource{teams: []TeamProgress{{Team: "foo", ChallengesSolved: 1, Position: 1}}}
+	feed := NewScoreFeed(source)
+	updates, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+	assert.Equal(t, 1, (<-updates).Teams[0].ChallengesSolved)
+
+	source.set(TeamProgress{Team: "foo", ChallengesSolved: 1, Position: 1})
+	assert.Empty(t, updates, "Should not push unchanged scores")
+
+	source.set(TeamProgress{Team: "foo", ChallengesSolved: 2, Position: 1})
+	source.set(TeamProgress{Team: "foo", ChallengesSolved: 3, Position: 1})
+	assert.Len(t, updates, 1, "Should only keep the latest score for slow subscribers")
+	assert.Equal(t, 3, (<-updates).Teams[0].ChallengesSolved)
+	assert.Equal(t, 3, feed.Score().Teams[0].ChallengesSolved)
+}
+
+func TestScoreFeedEndsTheSubscriptionsWhenClosed(t *testing.T) {
+	feed := NewScoreFeed(&changingSource{})
+	updates, _ := feed.Subscribe()
+	<-updates
+
+	feed.Close()
+
+	_, ok := <-updates
+	assert.False(t, ok)
+	late, _ := feed.Subscribe()
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestLiveScoreEndpointPushesScoreChangesToWebSocketClients(t *testing.T) {
+	source := &changingSource{teams: []TeamProgress{{Team: "foo", ChallengesSolved: 1, Position: 1}}}
+	feed := NewScoreFeed(source)
+	server := httptest.NewServer(NewServer(feed, NewSolveFeed(source), knownChallenges()))
+	defer server.Close()
+
+	conn, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1)+"/api/score/live", "", "https://projector.example.com")
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	score := ScoreResponse{}
+	assert.NoError(t, websocket.JSON.Receive(conn, &score))
+	assert.Equal(t, ScoreResponse{Teams: []ScoreTeam{{Position: 1, Team: "foo", ChallengesSolved: 1}}, TotalTeams: 1}, score)
+
+	source.set(TeamProgress{Team: "foo", ChallengesSolved: 1, Position: 2}, TeamProgress{Team: "bar", ChallengesSolved: 2, Position: 1})
+	assert.NoError(t, websocket.JSON.Receive(conn, &score))
+	assert.Equal(t, ScoreResponse{
+		Teams:      []ScoreTeam{{Position: 1, Team: "bar", ChallengesSolved: 2}, {Position: 2, Team: "foo", ChallengesSolved: 1}},
+		TotalTeams: 2,
+	}, score)
+
+	feed.Close()
+	var message string
+	assert.Error(t, websocket.Message.Receive(conn, &message), "Should close the connection when the feed is closed")
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"sync"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// graphQLMaxDepth limits the nesting of queries, e.g. of the solves of the challenges of solves
+const graphQLMaxDepth = 8
+
+//go:embed schema.graphql
+var graphQLSchema string
+
+// newGraphQLHandler serves the GraphQL api of the score board, answering queries posted as json
+func newGraphQLHandler(source ProgressSource, catalog *ChallengeCatalog) http.Handler {
+	schema := graphql.MustParseSchema(graphQLSchema, &graphQLResolver{source: source, catalog: catalog}, graphql.MaxDepth(graphQLMaxDepth))
+	return &relay.Handler{Schema: schema}
+}
+
+// graphQLResolver resolves the queries, every field of a query reads the progress of the teams once
+type graphQLResolver struct {
+	source  ProgressSource
+	catalog *ChallengeCatalog
+}
+
+// scoreSnapshot is the progress of the teams a query field is resolved against
+type scoreSnapshot struct {
+	teams     []TeamProgress
+	positions map[string]int
+
+	catalog     *ChallengeCatalog
+	catalogOnce sync.Once
+	challenges  map[int]Challenge
+}
+
+func (r *graphQLResolver) snapshot() *scoreSnapshot {
+	s := &scoreSnapshot{teams: r.source.Teams(), positions: map[string]int{}, catalog: r.catalog}
+	for _, team := range rankTeams(s.teams) {
+		s.positions[team.Team] = team.Position
+	}
+	return s
+}
+
+// knownChallenges returns the challenges of the catalog, only fetched by queries asking for them
+func (s *scoreSnapshot) knownChallenges() map[int]Challenge {
+	s.catalogOnce.Do(func() {
+		s.challenges = s.catalog.Challenges(s.teams)
+	})
+	return s.challenges
+}
+
+// challengeIDs returns the ids of the known and of the solved challenges, ordered by id
+func (s *scoreSnapshot) challengeIDs() []int {
+	ids := map[int]bool{}
+	for id := range s.knownChallenges() {
+		ids[id] = true
+	}
+	for _, team := range s.teams {
+		for id := range team.Solves {
+			ids[id] = true
+		}
+	}
+	sorted := []int{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+// categoryOf returns the category of the challenge, nil if it's unknown
+func (s *scoreSnapshot) categoryOf(id int) *string {
+	if challenge, ok := s.knownChallenges()[id]; ok {
+		return &challenge.Category
+	}
+	return nil
+}
+
+// categoryKey identifies the category of the challenge in maps, empty for unknown challenges
+func (s *scoreSnapshot) categoryKey(id int) string {
+	if category := s.categoryOf(id); category != nil {
+		return *category
+	}
+	return ""
+}
+
+// categoryName returns the name of the category of the key, nil for the unknown challenges
+func categoryName(key string) *string {
+	if key == "" {
+		return nil
+	}
+	return &key
+}
+
+func (s *scoreSnapshot) solveResolvers(events []SolveEvent) []*solveResolver {
+	resolvers := []*solveResolver{}
+	for _, event := range events {
+		resolvers = append(resolvers, &solveResolver{snapshot: s, event: event})
+	}
+	return resolvers
+}
+
+func (r *graphQLResolver) Teams(args struct{ First *int32 }) []*teamResolver {
+	s := r.snapshot()
+	byName := map[string]TeamProgress{}
+	for _, team := range s.teams {
+		byName[team.Team] = team
+	}
+	resolvers := []*teamResolver{}
+	for _, ranked := range rankTeams(s.teams) {
+		if args.First != nil && len(resolvers) >= int(*args.First) {
+			break
+		}
+		resolvers = append(resolvers, &teamResolver{snapshot: s, progress: byName[ranked.Team]})
+	}
+	return resolvers
+}
+
+func (r *graphQLResolver) Team(args struct{ Name string }) *teamResolver {
+	s := r.snapshot()
+	for _, team := range s.teams {
+		if team.Team == args.Name {
+			return &teamResolver{snapshot: s, progress: team}
+		}
+	}
+	return nil
+}
+
+func (r *graphQLResolver) Challenges() []*challengeResolver {
+	s := r.snapshot()
+	resolvers := []*challengeResolver{}
+	for _, id := range s.challengeIDs() {
+		resolvers = append(resolvers, &challengeResolver{snapshot: s, id: id})
+	}
+	return resolvers
+}
+
+func (r *graphQLResolver) Categories() []*categoryResolver {
+	s := r.snapshot()
+	byCategory := map[string][]int{}
+	for _, id := range s.challengeIDs() {
+		byCategory[s.categoryKey(id)] = append(byCategory[s.categoryKey(id)], id)
+	}
+	resolvers := []*categoryResolver{}
+	for key, ids := range byCategory {
+		resolvers = append(resolvers, &categoryResolver{snapshot: s, key: key, challenges: ids})
+	}
+	// the unknown challenges are listed last
+	sort.Slice(resolvers, func(i, j int) bool {
+		if resolvers[i].key == "" || resolvers[j].key == "" {
+			return resolvers[j].key == ""
+		}
+		return resolvers[i].key < resolvers[j].key
+	})
+	return resolvers
+}
+
+type teamResolver struct {
+	snapshot *scoreSnapshot
+	progress TeamProgress
+}
+
+func (r *teamResolver) Name() string {
+	return r.progress.Team
+}
+
+func (r *teamResolver) Position() *int32 {
+	position, ok := r.snapshot.positions[r.progress.Team]
+	if !ok {
+		return nil
+	}
+	result := int32(position)
+	return &result
+}
+
+func (r *teamResolver) ChallengesSolved() int32 {
+	return int32(r.progress.ChallengesSolved)
+}
+
+func (r *teamResolver) LastSolvedAt() *graphql.Time {
+	if r.progress.LastSolvedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.progress.LastSolvedAt.UTC()}
+}
+
+func (r *teamResolver) Paused() bool {
+	return r.progress.Paused
+}
+
+func (r *teamResolver) Solves() []*solveResolver {
+	return r.snapshot.solveResolvers(solvesOf(r.progress))
+}
+
+func (r *teamResolver) Categories() []*teamCategoryResolver {
+	totals := map[string]int32{}
+	for _, id := range r.snapshot.challengeIDs() {
+		totals[r.snapshot.categoryKey(id)]++
+	}
+	solved := map[string]int32{}
+	for id := range r.progress.Solves {
+		solved[r.snapshot.categoryKey(id)]++
+	}
+	keys := []string{}
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resolvers := []*teamCategoryResolver{}
+	for _, key := range keys {
+		resolvers = append(resolvers, &teamCategoryResolver{name: categoryName(key), solved: solved[key], total: totals[key]})
+	}
+	return resolvers
+}
+
+type solveResolver struct {
+	snapshot *scoreSnapshot
+	event    SolveEvent
+}
+
+func (r *solveResolver) Team() string {
+	return r.event.Team
+}
+
+func (r *solveResolver) Challenge() *challengeResolver {
+	return &challengeResolver{snapshot: r.snapshot, id: r.event.Challenge}
+}
+
+func (r *solveResolver) SolvedAt() graphql.Time {
+	return graphql.Time{Time: r.event.SolvedAt}
+}
+
+type challengeResolver struct {
+	snapshot *scoreSnapshot
+	id       int
+}
+
+func (r *challengeResolver) known() (Challenge, bool) {
+	challenge, ok := r.snapshot.knownChallenges()[r.id]
+	return challenge, ok
+}
+
+func (r *challengeResolver) ID() int32 {
+	return int32(r.id)
+}
+
+func (r *challengeResolver) Key() *string {
+	if challenge, ok := r.known(); ok {
+		return &challenge.Key
+	}
+	return nil
+}
+
+func (r *challengeResolver) Name() *string {
+	if challenge, ok := r.known(); ok {
+		return &challenge.Name
+	}
+	return nil
+}
+
+func (r *challengeResolver) Category() *string {
+	return r.snapshot.categoryOf(r.id)
+}
+
+func (r *challengeResolver) Difficulty() *int32 {
+	if challenge, ok := r.known(); ok {
+		difficulty := int32(challenge.Difficulty)
+		return &difficulty
+	}
+	return nil
+}
+
+func (r *challengeResolver) Solves() []*solveResolver {
+	events := []SolveEvent{}
+	for _, team := range r.snapshot.teams {
+		if solvedAt, ok := team.Solves[r.id]; ok {
+			events = append(events, SolveEvent{Team: team.Team, Challenge: r.id, SolvedAt: solvedAt.UTC()})
+		}
+	}
+	sortSolves(events)
+	return r.snapshot.solveResolvers(events)
+}
+
+type categoryResolver struct {
+	snapshot   *scoreSnapshot
+	key        string
+	challenges []int
+}
+
+func (r *categoryResolver) Name() *string {
+	return categoryName(r.key)
+}
+
+func (r *categoryResolver) Challenges() []*challengeResolver {
+	resolvers := []*challengeResolver{}
+	for _, id := range r.challenges {
+		resolvers = append(resolvers, &challengeResolver{snapshot: r.snapshot, id: id})
+	}
+	return resolvers
+}
+
+func (r *categoryResolver) Solves() int32 {
+	solves := int32(0)
+	for _, team := range r.snapshot.teams {
+		for _, id := range r.challenges {
+			if _, ok := team.Solves[id]; ok {
+				solves++
+			}
+		}
+	}
+	return solves
+}
+
+type teamCategoryResolver struct {
+	name   *string
+	solved int32
+	total  int32
+}
+
+func (r *teamCategoryResolver) Name() *string {
+	return r.name
+}
+
+func (r *teamCategoryResolver) Solved() int32 {
+	return r.solved
+}
+
+func (r *teamCategoryResolver) Total() int32 {
+	return r.total
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// queryGraphQL posts the query to the GraphQL api and returns the json response
+func queryGraphQL(t *testing.T, handler http.Handler, query string) string {
+	body, err := json.Marshal(map[string]string{"query": query})
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(string(body))))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	return rr.Body.String()
+}
+
+func newGraphQLTestServer() http.Handler {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	source := staticSource{
+		{Team: "foo", ChallengesSolved: 1, Position: 2, LastSolvedAt: start.Add(time.Hour), Solves: map[int]time.Time{2: start.Add(time.Hour)}},
+		{Team: "bar", ChallengesSolved: 3, Position: 1, LastSolvedAt: start.Add(2 * time.Hour), Solves: map[int]time.Time{1: start, 2: start.Add(time.Minute), 99: start.Add(2 * time.Hour)}},
+		{Team: "paused", ChallengesSolved: 4, Paused: true},
+	}
+	catalog := knownChallenges(
+		Challenge{ID: 1, Key: "scoreBoardChallenge", Name: "Score Board", Category: "Miscellaneous", Difficulty: 1},
+		Challenge{ID: 2, Key: "localXssChallenge", Name: "DOM XSS", Category: "XSS", Difficulty: 1},
+		Challenge{ID: 3, Key: "persistedXssUserChallenge", Name: "Client-side XSS Protection", Category: "XSS", Difficulty: 3},
+	)
+	return NewServer(NewScoreFeed(source), NewSolveFeed(source), catalog)
+}
+
+func TestGraphQLServesTheTopTeams(t *testing.T) {
+	response := queryGraphQL(t, newGraphQLTestServer(), `{ teams(first: 1) { name position challengesSolved lastSolvedAt } }`)
+
+	assert.JSONEq(t, `{"data":{"teams":[{"name":"bar","position":1,"challengesSolved":3,"lastSolvedAt":"2021-05-01T12:00:00Z"}]}}`, response)
+}
+
+func TestGraphQLServesTheHistoryAndCategoriesOfATeam(t *testing.T) {
+	response := queryGraphQL(t, newGraphQLTestServer(), `{
+		team(name: "bar") { position solves { solvedAt challenge { id name category } } categories { name solved total } }
+		paused: team(name: "paused") { position paused }
+		unknown: team(name: "unknown") { name }
+	}`)
+
+	assert.JSONEq(t, `{"data":{
+		"team":{"position":1,"solves":[
+			{"solvedAt":"2021-05-01T10:00:00Z","challenge":{"id":1,"name":"Score Board","category":"Miscellaneous"}},
+			{"solvedAt":"2021-05-01T10:01:00Z","challenge":{"id":2,"name":"DOM XSS","category":"XSS"}},
+			{"solvedAt":"2021-05-01T12:00:00Z","challenge":{"id":99,"name":null,"category":null}}
+		],"categories":[
+			{"name":null,"solved":1,"total":1},
+			{"name":"Miscellaneous","solved":1,"total":1},
+			{"name":"XSS","solved":1,"total":2}
+		]},
+		"paused":{"position":null,"paused":true},
+		"unknown":null
+	}}`, response)
+}
+
+func TestGraphQLServesTheSolvesByCategory(t *testing.T) {
+	response := queryGraphQL(t, newGraphQLTestServer(), `{ categories { name solves challenges { id solves { team } } } }`)
+
+	assert.JSONEq(t, `{"data":{"categories":[
+		{"name":"Miscellaneous","solves":1,"challenges":[{"id":1,"solves":[{"team":"bar"}]}]},
+		{"name":"XSS","solves":2,"challenges":[{"id":2,"solves":[{"team":"bar"},{"team":"foo"}]},{"id":3,"solves":[]}]},
+		{"name":null,"solves":1,"challenges":[{"id":99,"solves":[{"team":"bar"}]}]}
+	]}}`, response)
+}
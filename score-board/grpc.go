@@ -0,0 +1,99 @@
+package main
+
+//go:generate buf generate --template api/buf.gen.yaml api
+
+import (
+	"context"
+	"time"
+
+	scoreboardv1 "github.com/iteratec/multi-juicer/score-board/api/scoreboard/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// scoreBoardService serves the progress of the teams via the versioned gRPC api
+type scoreBoardService struct {
+	scoreboardv1.UnimplementedScoreBoardServer
+	source ProgressSource
+	feed   *ScoreFeed
+	solves *SolveFeed
+}
+
+// NewGRPCServer creates the gRPC server of the score board api
+func NewGRPCServer(source ProgressSource, feed *ScoreFeed, solves *SolveFeed) *grpc.Server {
+	server := grpc.NewServer()
+	scoreboardv1.RegisterScoreBoardServer(server, &scoreBoardService{source: source, feed: feed, solves: solves})
+	return server
+}
+
+// timestampOf converts the time, nil if it is unknown
+func timestampOf(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func solveOf(event SolveEvent) *scoreboardv1.Solve {
+	return &scoreboardv1.Solve{Team: event.Team, Challenge: int32(event.Challenge), SolvedAt: timestampOf(event.SolvedAt)}
+}
+
+func (s *scoreBoardService) ListTeams(ctx context.Context, req *scoreboardv1.ListTeamsRequest) (*scoreboardv1.ListTeamsResponse, error) {
+	response := &scoreboardv1.ListTeamsResponse{}
+	for _, team := range s.feed.Score().Teams {
+		rankedTeam := &scoreboardv1.RankedTeam{Position: int32(team.Position), Team: team.Team, ChallengesSolved: int32(team.ChallengesSolved)}
+		if team.LastSolvedAt != nil {
+			rankedTeam.LastSolvedAt = timestampOf(*team.LastSolvedAt)
+		}
+		response.Teams = append(response.Teams, rankedTeam)
+	}
+	return response, nil
+}
+
+func (s *scoreBoardService) GetTeamProgress(ctx context.Context, req *scoreboardv1.GetTeamProgressRequest) (*scoreboardv1.GetTeamProgressResponse, error) {
+	teams := s.source.Teams()
+	for _, team := range teams {
+		if team.Team != req.Team {
+			continue
+		}
+		response := &scoreboardv1.GetTeamProgressResponse{
+			Team:             team.Team,
+			ChallengesSolved: int32(team.ChallengesSolved),
+			LastSolvedAt:     timestampOf(team.LastSolvedAt),
+			Paused:           team.Paused,
+		}
+		for _, ranked := range rankTeams(teams) {
+			if ranked.Team == team.Team {
+				response.Position = int32(ranked.Position)
+			}
+		}
+		for _, event := range solvesOf(team) {
+			response.Solves = append(response.Solves, solveOf(event))
+		}
+		return response, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "Team '%s' not found", req.Team)
+}
+
+func (s *scoreBoardService) WatchSolves(req *scoreboardv1.WatchSolvesRequest, stream scoreboardv1.ScoreBoard_WatchSolvesServer) error {
+	events, unsubscribe := s.solves.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "Solve stream ended, either the client didn't keep up with the solves or the score board is shutting down")
+			}
+			if req.Team != "" && event.Team != req.Team {
+				continue
+			}
+			if err := stream.Send(&scoreboardv1.WatchSolvesResponse{Solve: solveOf(event)}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	scoreboardv1 "github.com/iteratec/multi-juicer/score-board/api/scoreboard/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPCServer serves the grpc api of the source in memory and returns a client of it
+func dialGRPCServer(t *testing.T, source ProgressSource, solves *SolveFeed) scoreboardv1.ScoreBoardClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(source, NewScoreFeed(source), solves)
+	go server.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return scoreboardv1.NewScoreBoardClient(conn)
+}
+
+func TestGRPCServerListsTheRankedTeamsAndTheProgressOfSingleTeams(t *testing.T) {
+	solvedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	source := staticSource{
+		{Team: "foo", ChallengesSolved: 1, Position: 2},
+		{Team: "bar", ChallengesSolved: 2, Position: 1, LastSolvedAt: solvedAt.Add(time.Hour), Solves: map[int]time.Time{7: solvedAt.Add(time.Hour), 3: solvedAt}},
+		{Team: "paused", ChallengesSolved: 5, Paused: true},
+	}
+	client := dialGRPCServer(t, source, NewSolveFeed(source))
+
+	teams, err := client.ListTeams(context.Background(), &scoreboardv1.ListTeamsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, teams.Teams, 2)
+	assert.Equal(t, "bar", teams.Teams[0].Team)
+	assert.Equal(t, int32(2), teams.Teams[0].ChallengesSolved)
+	assert.Equal(t, solvedAt.Add(time.Hour), teams.Teams[0].LastSolvedAt.AsTime())
+	assert.Equal(t, int32(2), teams.Teams[1].Position)
+	assert.Nil(t, teams.Teams[1].LastSolvedAt)
+
+	progress, err := client.GetTeamProgress(context.Background(), &scoreboardv1.GetTeamProgressRequest{Team: "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), progress.Position)
+	assert.Len(t, progress.Solves, 2)
+	assert.Equal(t, int32(3), progress.Solves[0].Challenge)
+	assert.Equal(t, solvedAt, progress.Solves[0].SolvedAt.AsTime())
+	assert.Equal(t, int32(7), progress.Solves[1].Challenge)
+
+	paused, err := client.GetTeamProgress(context.Background(), &scoreboardv1.GetTeamProgressRequest{Team: "paused"})
+	assert.NoError(t, err)
+	assert.True(t, paused.Paused)
+	assert.Equal(t, int32(0), paused.Position)
+
+	_, err = client.GetTeamProgress(context.Background(), &scoreboardv1.GetTeamProgressRequest{Team: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCServerStreamsTheSolvesOfTheWatchedTeam(t *testing.T) {
+	source := &changingSource{}
+	solves := NewSolveFeed(source)
+	client := dialGRPCServer(t, source, solves)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchSolves(ctx, &scoreboardv1.WatchSolvesRequest{Team: "foo"})
+	assert.NoError(t, err)
+	// the subscription is made when the server handles the call, which the client doesn't wait for
+	assert.Eventually(t, func() bool {
+		solves.mutex.Lock()
+		defer solves.mutex.Unlock()
+		return len(solves.subscribers) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	solvedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	source.set(
+		TeamProgress{Team: "bar", Solves: map[int]time.Time{1: solvedAt}},
+		TeamProgress{Team: "foo", Solves: map[int]time.Time{2: solvedAt.Add(time.Minute)}},
+	)
+
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", response.Solve.Team)
+	assert.Equal(t, int32(2), response.Solve.Challenge)
+	assert.Equal(t, solvedAt.Add(time.Minute), response.Solve.SolvedAt.AsTime())
+
+	solves.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
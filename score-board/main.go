@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("ScoreBoard")
+
+const (
+	// shutdownTimeout is the time open requests get to finish on shutdown
+	shutdownTimeout = 5 * time.Second
+	// challengeCatalogTTL is the time after which the challenges are refetched from the JuiceShops, e.g. after they got updated
+	challengeCatalogTTL = 10 * time.Minute
+)
+
+func main() {
+	logging.SetBackend(logging.NewLogBackend(os.Stdout, "", 0))
+
+	namespace := getEnv("NAMESPACE", "default")
+	pollInterval, err := time.ParseDuration(getEnv("POLL_INTERVAL", "5s"))
+	if err != nil || pollInterval <= 0 {
+		panic(fmt.Sprintf("Invalid POLL_INTERVAL: %s", getEnv("POLL_INTERVAL", "5s")))
+	}
+	juiceShopPort, err := strconv.Atoi(getEnv("JUICE_SHOP_PORT", "3000"))
+	if err != nil {
+		panic(fmt.Sprintf("Invalid JUICE_SHOP_PORT: %s", err))
+	}
+	// the progress api of the watchdog requires requests signed with the secret it shares with the other components
+	secret := getEnv("PROGRESS_WATCHDOG_SIGNING_SECRET", "")
+	if secret == "" {
+		panic("Invalid PROGRESS_WATCHDOG_SIGNING_SECRET: must be set")
+	}
+	source := NewWatchdogSource(getEnv("PROGRESS_WATCHDOG_URL", "http://progress-watchdog:8080"), []byte(secret), pollInterval, &http.Client{Timeout: 10 * time.Second})
+
+	// cancelled on SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if err := source.Start(ctx); err != nil {
+		panic(err.Error())
+	}
+
+	feed := NewScoreFeed(source)
+	solves := NewSolveFeed(source)
+	listenAddress := getEnv("LISTEN_ADDRESS", ":8080")
+	catalog := NewChallengeCatalog(namespace, juiceShopPort, challengeCatalogTTL)
+	server := &http.Server{Addr: listenAddress, Handler: NewServer(feed, solves, catalog)}
+	go func() {
+		log.Infof("Serving score board of namespace '%s' on '%s'", namespace, listenAddress)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	grpcListenAddress := getEnv("GRPC_LISTEN_ADDRESS", ":9090")
+	grpcServer := NewGRPCServer(source, feed, solves)
+	go func() {
+		listener, err := net.Listen("tcp", grpcListenAddress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Serving score board grpc api on '%s'", grpcListenAddress)
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	// live connections are hijacked and streams never idle, so that neither would be closed by the shutdown of the server
+	feed.Close()
+	solves.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Warningf("Failed to shut down the server gracefully: %s", err)
+	}
+	grpcServer.GracefulStop()
+}
+
+// getEnv returns the value of the environment variable or the fallback if it isn't set
+func getEnv(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ScoreTeam is a team listed in the score
+type ScoreTeam struct {
+	Position         int        `json:"position"`
+	Team             string     `json:"team"`
+	ChallengesSolved int        `json:"challengesSolved"`
+	Score            float64    `json:"score"`
+	LastSolvedAt     *time.Time `json:"lastSolvedAt,omitempty"`
+}
+
+// ScoreResponse json format of the score response
+type ScoreResponse struct {
+	Teams      []ScoreTeam `json:"teams"`
+	TotalTeams int         `json:"totalTeams"`
+}
+
+// rankTeams lists the teams in the order of the scoreboard of the progress watchdog, so that they are ranked by the same counted solves
+// and configured scoring. Teams without position, e.g. paused ones, aren't ranked.
+func rankTeams(teams []TeamProgress) []ScoreTeam {
+	sorted := []TeamProgress{}
+	for _, team := range teams {
+		if !team.Paused && team.Position > 0 {
+			sorted = append(sorted, team)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.Team < b.Team
+	})
+
+	ranked := []ScoreTeam{}
+	for _, team := range sorted {
+		scoreTeam := ScoreTeam{Position: team.Position, Team: team.Team, ChallengesSolved: team.ChallengesSolved, Score: team.Score}
+		if !team.LastSolvedAt.IsZero() {
+			lastSolvedAt := team.LastSolvedAt.UTC()
+			scoreTeam.LastSolvedAt = &lastSolvedAt
+		}
+		ranked = append(ranked, scoreTeam)
+	}
+	return ranked
+}
+
+// NewServer creates the handler of the score board api, serving the score of the feed under `/api/score` and pushing its changes
+// to the WebSocket clients of `/api/score/live`. A full dump of the teams and their solves is exported under `/api/score/export`.
+// New solves are streamed as Server-Sent Events under `/api/solves/stream`, all views on the progress can be queried via GraphQL
+// under `/api/graphql`.
+func NewServer(feed *ScoreFeed, solves *SolveFeed, catalog *ChallengeCatalog) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/score", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, feed.Score())
+	})
+	mux.Handle("/api/score/live", newLiveScoreHandler(feed))
+	mux.Handle("/api/score/export", newScoreExportHandler(feed.source, catalog))
+	mux.Handle("/api/solves/stream", newSolveStreamHandler(solves))
+	mux.Handle("/api/graphql", newGraphQLHandler(feed.source, catalog))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("Failed to encode json response: %s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
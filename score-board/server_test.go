@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticSource []TeamProgress
+
+func (s staticSource) Start(ctx context.Context) error { return nil }
+func (s staticSource) Teams() []TeamProgress           { return s }
+func (s staticSource) OnChange(handler func())         {}
+
+func TestRankTeamsFollowsThePositionsOfTheWatchdog(t *testing.T) {
+	solvedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	ranked := rankTeams([]TeamProgress{
+		{Team: "second", ChallengesSolved: 5, Score: 20, Position: 2, LastSolvedAt: solvedAt},
+		{Team: "tied", ChallengesSolved: 3, Score: 20, Position: 2},
+		{Team: "leader", ChallengesSolved: 4, Score: 30, Position: 1},
+		{Team: "paused", ChallengesSolved: 9, Score: 90, Paused: true},
+		{Team: "unranked", ChallengesSolved: 1, Score: 10},
+	})
+
+	assert.Equal(t, []ScoreTeam{
+		{Position: 1, Team: "leader", ChallengesSolved: 4, Score: 30},
+		{Position: 2, Team: "second", ChallengesSolved: 5, Score: 20, LastSolvedAt: &solvedAt},
+		{Position: 2, Team: "tied", ChallengesSolved: 3, Score: 20},
+	}, ranked)
+}
+
+func TestScoreEndpointServesTheRankedTeams(t *testing.T) {
+	source := staticSource{{Team: "foo", ChallengesSolved: 1, Score: 10, Position: 2}, {Team: "bar", ChallengesSolved: 3, Score: 30, Position: 1}}
+	server := NewServer(NewScoreFeed(source), NewSolveFeed(source), knownChallenges())
+	rr := httptest.NewRecorder()
+
+	server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/score", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	response := ScoreResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, ScoreResponse{
+		Teams:      []ScoreTeam{{Position: 1, Team: "bar", ChallengesSolved: 3, Score: 30}, {Position: 2, Team: "foo", ChallengesSolved: 1, Score: 10}},
+		TotalTeams: 2,
+	}, response)
+}
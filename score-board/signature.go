@@ -0,0 +1,32 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Same scheme as the progress watchdog, see progress-watchdog/signature.go
+const (
+	signatureHeader          = "X-MultiJuicer-Signature"
+	signatureTimestampHeader = "X-MultiJuicer-Timestamp"
+)
+
+// computeSignature signs the timestamp, method, request uri and body of a request
+func computeSignature(secret []byte, timestamp, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", timestamp, method, requestURI)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRequest adds the signature headers to a request without body, authenticating it at the progress watchdog with the shared secret
+func signRequest(req *http.Request, secret []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(signatureTimestampHeader, timestamp)
+	req.Header.Set(signatureHeader, computeSignature(secret, timestamp, req.Method, req.URL.RequestURI(), nil))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// solveStreamBuffer is the number of solve events buffered for a client of the stream, slower clients get disconnected
+	solveStreamBuffer = 100
+	// solveStreamKeepAlive is the interval of the comments sent to idle clients of the stream, so that proxies don't close the connection
+	solveStreamKeepAlive = 30 * time.Second
+)
+
+// SolveEvent is a challenge solve of a team
+type SolveEvent struct {
+	Team      string    `json:"team"`
+	Challenge int       `json:"challenge"`
+	SolvedAt  time.Time `json:"solvedAt"`
+}
+
+// SolveFeed detects new solves in the progress of the teams and sends them to its subscribers
+type SolveFeed struct {
+	source ProgressSource
+
+	mutex sync.Mutex
+	// known are the solves by team which were already seen. Teams are never removed, so that solves aren't sent again when the JuiceShop
+	// of a team gets recreated and its progress restored.
+	known       map[string]map[int]bool
+	subscribers map[int]chan SolveEvent
+	nextID      int
+	closed      bool
+}
+
+// NewSolveFeed creates the feed of the solves of the teams of the source. Solves the source already knows about aren't sent.
+func NewSolveFeed(source ProgressSource) *SolveFeed {
+	f := &SolveFeed{source: source, known: map[string]map[int]bool{}, subscribers: map[int]chan SolveEvent{}}
+	f.detect()
+	source.OnChange(f.update)
+	return f
+}
+
+// detect returns the solves which weren't seen before, ordered by their solve time
+func (f *SolveFeed) detect() []SolveEvent {
+	events := []SolveEvent{}
+	for _, team := range f.source.Teams() {
+		if f.known[team.Team] == nil {
+			f.known[team.Team] = map[int]bool{}
+		}
+		for challenge, solvedAt := range team.Solves {
+			if !f.known[team.Team][challenge] {
+				f.known[team.Team][challenge] = true
+				events = append(events, SolveEvent{Team: team.Team, Challenge: challenge, SolvedAt: solvedAt.UTC()})
+			}
+		}
+	}
+	sortSolves(events)
+	return events
+}
+
+// sortSolves orders the solves by their solve time, solves at the same time by team and challenge
+func sortSolves(events []SolveEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].SolvedAt.Equal(events[j].SolvedAt) {
+			return events[i].SolvedAt.Before(events[j].SolvedAt)
+		}
+		if events[i].Team != events[j].Team {
+			return events[i].Team < events[j].Team
+		}
+		return events[i].Challenge < events[j].Challenge
+	})
+}
+
+// solvesOf returns the solves of the team ordered by their solve time
+func solvesOf(team TeamProgress) []SolveEvent {
+	events := []SolveEvent{}
+	for challenge, solvedAt := range team.Solves {
+		events = append(events, SolveEvent{Team: team.Team, Challenge: challenge, SolvedAt: solvedAt.UTC()})
+	}
+	sortSolves(events)
+	return events
+}
+
+func (f *SolveFeed) update() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		return
+	}
+	for _, event := range f.detect() {
+		for id, events := range f.subscribers {
+			select {
+			case events <- event:
+			default:
+				log.Warningf("Disconnecting solve stream client which didn't keep up with the solves")
+				close(events)
+				delete(f.subscribers, id)
+			}
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the solves detected from now on. The channel is closed if the subscriber doesn't keep up with
+// the solves or the feed is closed.
+func (f *SolveFeed) Subscribe() (<-chan SolveEvent, func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	events := make(chan SolveEvent, solveStreamBuffer)
+	if f.closed {
+		close(events)
+		return events, func() {}
+	}
+	id := f.nextID
+	f.nextID++
+	f.subscribers[id] = events
+	return events, func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		delete(f.subscribers, id)
+	}
+}
+
+// Close ends all subscriptions, so that the open streams get closed on shutdown
+func (f *SolveFeed) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.closed = true
+	for id, events := range f.subscribers {
+		close(events)
+		delete(f.subscribers, id)
+	}
+}
+
+// newSolveStreamHandler streams the solves of the feed as Server-Sent Events of type `solve` with the json encoded SolveEvent as data
+func newSolveStreamHandler(feed *SolveFeed) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming Not Supported", http.StatusInternalServerError)
+			return
+		}
+		events, unsubscribe := feed.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// disables the response buffering of nginx based ingresses
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(solveStreamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					log.Errorf("Failed to encode solve event: %s", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: solve\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-req.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveFeedSendsOnlyNewSolvesOrderedBySolveTime(t *testing.T) {
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	source := &changingSource{teams: []TeamProgress{{Team: "foo", Solves: map[int]time.Time{1: start}}}}
+	feed := NewSolveFeed(source)
+	events, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+
+	source.set(
+		TeamProgress{Team: "foo", Solves: map[int]time.Time{1: start, 3: start.Add(2 * time.Minute)}},
+		TeamProgress{Team: "bar", Solves: map[int]time.Time{2: start.Add(time.Minute)}},
+	)
+	// the JuiceShop of foo got recreated with its progress restored
+	source.set(TeamProgress{Team: "bar", Solves: map[int]time.Time{2: start.Add(time.Minute)}})
+	source.set(
+		TeamProgress{Team: "foo", Solves: map[int]time.Time{1: start, 3: start.Add(2 * time.Minute)}},
+		TeamProgress{Team: "bar", Solves: map[int]time.Time{2: start.Add(time.Minute)}},
+	)
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, SolveEvent{Team: "bar", Challenge: 2, SolvedAt: start.Add(time.Minute)}, <-events)
+	assert.Equal(t, SolveEvent{Team: "foo", Challenge: 3, SolvedAt: start.Add(2 * time.Minute)}, <-events)
+}
+
+func TestSolveFeedDisconnectsSubscribersNotKeepingUp(t *testing.T) {
+	source := &changingSource{}
+	feed := NewSolveFeed(source)
+	events, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+
+	solves := map[int]time.Time{}
+	for challenge := 0; challenge <= solveStreamBuffer; challenge++ {
+		solves[challenge] = time.Date(2021, 5, 1, 10, challenge%60, 0, 0, time.UTC)
+	}
+	source.set(TeamProgress{Team: "foo", Solves: solves})
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, solveStreamBuffer, received)
+}
+
+func TestSolveStreamEndpointSendsSolvesAsServerSentEvents(t *testing.T) {
+	source := &changingSource{}
+	solves := NewSolveFeed(source)
+	server := httptest.NewServer(NewServer(NewScoreFeed(source), solves, knownChallenges()))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/solves/stream")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	source.set(TeamProgress{Team: "foo", Solves: map[int]time.Time{7: time.Date(2021, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))}})
+	reader := bufio.NewReader(res.Body)
+	event := ""
+	for !strings.HasSuffix(event, "\n\n") {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		event += line
+	}
+	assert.Equal(t, "event: solve\ndata: {\"team\":\"foo\",\"challenge\":7,\"solvedAt\":\"2021-05-01T08:00:00Z\"}\n\n", event)
+
+	solves.Close()
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err, "Should end the stream when the feed is closed")
+}
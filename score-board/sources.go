@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TeamProgress is the progress of a team as tracked and scored by the progress watchdog
+type TeamProgress struct {
+	Team string
+	// ChallengesSolved counts the solves the watchdog counts, e.g. without solves pending review or of disabled challenges
+	ChallengesSolved int
+	// Score is the weighted sum of the counted solves, according to the scoring configured in the watchdog
+	Score float64
+	// Position is the rank of the team on the scoreboard of the watchdog, 0 if it isn't ranked
+	Position int
+	// LastSolvedAt is the time of the latest counted solve of the team, zero if unknown
+	LastSolvedAt time.Time
+	// Solves are the solve times of the counted solves by challenge id
+	Solves map[int]time.Time
+	// Paused teams aren't ranked
+	Paused bool
+}
+
+// ProgressSource reads the progress of the teams tracked by the progress watchdog
+type ProgressSource interface {
+	// Start starts watching the progress until the context is cancelled, returns once the progress of all teams is known
+	Start(ctx context.Context) error
+	// Teams returns the current progress of all teams
+	Teams() []TeamProgress
+	// OnChange registers a handler called whenever the progress of a team might have changed, calls are not made concurrently
+	OnChange(handler func())
+}
+
+// lastSolvedAt returns the latest solve time of the solves
+func lastSolvedAt(solves map[int]time.Time) time.Time {
+	latest := time.Time{}
+	for _, solvedAt := range solves {
+		if solvedAt.After(latest) {
+			latest = solvedAt
+		}
+	}
+	return latest
+}
+
+// watchdogProgressResponse json format of the progress list of the watchdog, `/api/progress`
+type watchdogProgressResponse struct {
+	Teams []struct {
+		Team             string            `json:"team"`
+		Namespace        string            `json:"namespace"`
+		ChallengesSolved int               `json:"challengesSolved"`
+		Solves           map[int]time.Time `json:"solves"`
+		Paused           bool              `json:"paused"`
+		Score            float64           `json:"score"`
+	} `json:"teams"`
+}
+
+// watchdogScoreboardResponse json format of the scoreboard of the watchdog, `/api/scoreboard`
+type watchdogScoreboardResponse struct {
+	Teams []struct {
+		Position  int    `json:"position"`
+		Team      string `json:"team"`
+		Namespace string `json:"namespace"`
+	} `json:"teams"`
+}
+
+// WatchdogSource polls the progress api of the watchdog, so that the teams are counted, scored and ranked exactly like on its own
+// scoreboard, independent of the progress store the watchdog uses. Requests are signed with the secret shared with the watchdog.
+type WatchdogSource struct {
+	url      string
+	secret   []byte
+	interval time.Duration
+	client   *http.Client
+
+	mutex    sync.Mutex
+	teams    []TeamProgress
+	handlers []func()
+}
+
+// NewWatchdogSource creates a WatchdogSource polling the watchdog at the url, e.g. `http://progress-watchdog:8080`, in the interval
+func NewWatchdogSource(url string, secret []byte, interval time.Duration, client *http.Client) *WatchdogSource {
+	return &WatchdogSource{url: strings.TrimSuffix(url, "/"), secret: secret, interval: interval, client: client}
+}
+
+// Start fetches the progress of the teams, retrying until the watchdog responds, and polls it in the background afterwards
+func (s *WatchdogSource) Start(ctx context.Context) error {
+	for {
+		teams, err := s.fetch(ctx)
+		if err == nil {
+			s.mutex.Lock()
+			s.teams = teams
+			s.mutex.Unlock()
+			break
+		}
+		log.Warningf("Failed to fetch the progress of the teams from the watchdog, retrying in %s: %s", s.interval, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Failed to fetch the progress of the teams from the watchdog: %v", err)
+		case <-time.After(s.interval):
+		}
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.interval):
+				s.poll(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// poll refetches the progress of the teams and calls the handlers if it changed. The last known progress is kept if the watchdog is unavailable.
+func (s *WatchdogSource) poll(ctx context.Context) {
+	teams, err := s.fetch(ctx)
+	if err != nil {
+		log.Warningf("Failed to fetch the progress of the teams from the watchdog: %s", err)
+		return
+	}
+	s.mutex.Lock()
+	changed := !reflect.DeepEqual(teams, s.teams)
+	s.teams = teams
+	handlers := s.handlers
+	s.mutex.Unlock()
+	if changed {
+		for _, handler := range handlers {
+			handler()
+		}
+	}
+}
+
+// Teams returns the progress of the teams of the last poll
+func (s *WatchdogSource) Teams() []TeamProgress {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.teams
+}
+
+// OnChange calls the handler whenever a poll returned a changed progress
+func (s *WatchdogSource) OnChange(handler func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// fetch combines the counted progress of the teams with their positions on the scoreboard of the watchdog
+func (s *WatchdogSource) fetch(ctx context.Context) ([]TeamProgress, error) {
+	progress := watchdogProgressResponse{}
+	if err := s.get(ctx, "/api/progress", &progress); err != nil {
+		return nil, err
+	}
+	scoreboard := watchdogScoreboardResponse{}
+	if err := s.get(ctx, "/api/scoreboard", &scoreboard); err != nil {
+		return nil, err
+	}
+	// teams in different namespaces can have the same name
+	positions := map[string]int{}
+	for _, team := range scoreboard.Teams {
+		positions[team.Namespace+"/"+team.Team] = team.Position
+	}
+
+	teams := []TeamProgress{}
+	for _, team := range progress.Teams {
+		solves := team.Solves
+		if solves == nil {
+			solves = map[int]time.Time{}
+		}
+		teams = append(teams, TeamProgress{
+			Team:             team.Team,
+			ChallengesSolved: team.ChallengesSolved,
+			Score:            team.Score,
+			Position:         positions[team.Namespace+"/"+team.Team],
+			LastSolvedAt:     lastSolvedAt(solves),
+			Solves:           solves,
+			Paused:           team.Paused,
+		})
+	}
+	return teams, nil
+}
+
+func (s *WatchdogSource) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+path, nil)
+	if err != nil {
+		return err
+	}
+	signRequest(req, s.secret, time.Now())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected response status code '%d' from '%s'", res.StatusCode, path)
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("Failed to parse the response of '%s': %v", path, err)
+	}
+	return nil
+}
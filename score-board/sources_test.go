@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWatchdog serves the progress api of the watchdog, rejecting requests which aren't signed with the secret
+type fakeWatchdog struct {
+	mutex      sync.Mutex
+	progress   string
+	scoreboard string
+	requests   []*http.Request
+}
+
+func (w *fakeWatchdog) set(progress, scoreboard string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.progress = progress
+	w.scoreboard = scoreboard
+}
+
+func (w *fakeWatchdog) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.requests = append(w.requests, req)
+	timestamp := req.Header.Get(signatureTimestampHeader)
+	if req.Header.Get(signatureHeader) != computeSignature([]byte("secret"), timestamp, req.Method, req.URL.RequestURI(), nil) {
+		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch req.URL.Path {
+	case "/api/progress":
+		rw.Write([]byte(w.progress))
+	case "/api/scoreboard":
+		rw.Write([]byte(w.scoreboard))
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+func startWatchdogSource(t *testing.T, watchdog http.Handler, secret string) (*WatchdogSource, error) {
+	server := httptest.NewServer(watchdog)
+	t.Cleanup(server.Close)
+	source := NewWatchdogSource(server.URL+"/", []byte(secret), 10*time.Millisecond, server.Client())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	return source, source.Start(ctx)
+}
+
+func TestSignsRequestsLikeTheWatchdog(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/progress", nil)
+
+	signRequest(req, []byte("secret"), time.Unix(1600000000, 0))
+
+	assert.Equal(t, "1600000000", req.Header.Get(signatureTimestampHeader))
+	assert.Equal(t, "abdae499b2d1f44fd75a68ce1ffd568785da0a03828f5c045bea538d1e34c0f0", req.Header.Get(signatureHeader))
+}
+
+func TestWatchdogSourceReadsTheCountedProgressAndPositionsOfTheWatchdog(t *testing.T) {
+	watchdog := &fakeWatchdog{}
+	watchdog.set(
+		`{"teams":[
+			{"team":"foobar","challengesSolved":2,"score":30,"solves":{"1":"2021-05-01T10:00:00Z","2":"2021-05-01T11:00:00Z"},"reviews":{"3":"pending"}},
+			{"team":"paused","challengesSolved":1,"score":10,"paused":true,"solves":{"1":"2021-05-01T10:00:00Z"}},
+			{"team":"new","challengesSolved":0,"score":0}
+		]}`,
+		`{"teams":[{"position":1,"team":"foobar","challengesSolved":2,"score":30},{"position":2,"team":"new","challengesSolved":0,"score":0}]}`,
+	)
+
+	source, err := startWatchdogSource(t, watchdog, "secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []TeamProgress{
+		{Team: "foobar", ChallengesSolved: 2, Score: 30, Position: 1, LastSolvedAt: time.Date(2021, 5, 1, 11, 0, 0, 0, time.UTC), Solves: map[int]time.Time{
+			1: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC),
+			2: time.Date(2021, 5, 1, 11, 0, 0, 0, time.UTC),
+		}},
+		{Team: "paused", ChallengesSolved: 1, Score: 10, Paused: true, LastSolvedAt: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC), Solves: map[int]time.Time{
+			1: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC),
+		}},
+		{Team: "new", Position: 2, Solves: map[int]time.Time{}},
+	}, source.Teams())
+}
+
+func TestWatchdogSourceMatchesThePositionsOfTeamsByNamespace(t *testing.T) {
+	watchdog := &fakeWatchdog{}
+	watchdog.set(
+		`{"teams":[{"team":"foobar","namespace":"team-a","challengesSolved":0},{"team":"foobar","namespace":"team-b","challengesSolved":1,"score":10}]}`,
+		`{"teams":[{"position":1,"team":"foobar","namespace":"team-b"},{"position":2,"team":"foobar","namespace":"team-a"}]}`,
+	)
+
+	source, err := startWatchdogSource(t, watchdog, "secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.Teams()[0].Position)
+	assert.Equal(t, 1, source.Teams()[1].Position)
+}
+
+func TestWatchdogSourceNotifiesAboutChangedProgress(t *testing.T) {
+	watchdog := &fakeWatchdog{}
+	watchdog.set(`{"teams":[{"team":"foobar","challengesSolved":0}]}`, `{"teams":[{"position":1,"team":"foobar"}]}`)
+	source, err := startWatchdogSource(t, watchdog, "secret")
+	assert.NoError(t, err)
+	changes := make(chan []TeamProgress, 10)
+	source.OnChange(func() { changes <- source.Teams() })
+
+	watchdog.set(`{"teams":[{"team":"foobar","challengesSolved":1,"score":10}]}`, `{"teams":[{"position":1,"team":"foobar"}]}`)
+
+	select {
+	case teams := <-changes:
+		assert.Equal(t, 1, teams[0].ChallengesSolved)
+		assert.Equal(t, float64(10), teams[0].Score)
+	case <-time.After(time.Second):
+		t.Fatal("Should notify about the changed progress")
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, changes, 0, "Should not notify about unchanged progress")
+}
+
+func TestWatchdogSourceFailsToStartWithTheWrongSecret(t *testing.T) {
+	watchdog := &fakeWatchdog{}
+	watchdog.set(`{"teams":[]}`, `{"teams":[]}`)
+
+	_, err := startWatchdogSource(t, watchdog, "wrong-secret")
+
+	assert.Error(t, err)
+	assert.NotEmpty(t, watchdog.requests)
+}